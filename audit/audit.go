@@ -0,0 +1,20 @@
+package audit
+
+import (
+	"context"
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/andhikagama/lmnlo/models/filter"
+)
+
+// Repository is an append-only store of audit events
+type Repository interface {
+	Store(ctx context.Context, ev *entity.AuditEvent) error
+	// Fetch return events newest first
+	Fetch(ctx context.Context, f *filter.Audit) ([]*entity.AuditEvent, error)
+}
+
+// Usecase represents business logic
+type Usecase interface {
+	Record(ctx context.Context, o *entity.Origin, action string, targetID int64, before interface{}, after interface{}) error
+	Fetch(ctx context.Context, f *filter.Audit) ([]*entity.AuditEvent, error)
+}
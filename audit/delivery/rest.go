@@ -0,0 +1,91 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/andhikagama/lmnlo/audit"
+	cmware "github.com/andhikagama/lmnlo/cmiddleware"
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/andhikagama/lmnlo/models/filter"
+	"github.com/andhikagama/lmnlo/models/response"
+	"github.com/labstack/echo"
+)
+
+// AuditHTTPHandler ...
+type AuditHTTPHandler struct {
+	Usecase audit.Usecase
+}
+
+// NewAuditHTTPHandler ...
+func NewAuditHTTPHandler(g *echo.Group, u audit.Usecase, cm cmware.Usecase) {
+	handler := &AuditHTTPHandler{
+		Usecase: u,
+	}
+
+	g.GET(`/audit`, handler.Fetch, cm.RequirePermission(entity.PermissionAuditRead))
+}
+
+// Fetch lists audit events newest first, filtered by actor_id, target_id
+// and a from/to range in RFC 3339. Paging works as for users, with the
+// X-Cursor header.
+func (h *AuditHTTPHandler) Fetch(c echo.Context) error {
+	f := &filter.Audit{Num: int64(200)}
+
+	ints := map[string]*int64{
+		`num`:       &f.Num,
+		`cursor`:    &f.Cursor,
+		`actor_id`:  &f.ActorID,
+		`target_id`: &f.TargetID,
+	}
+
+	for param, dst := range ints {
+		if c.QueryParam(param) == `` {
+			continue
+		}
+
+		n, err := strconv.ParseInt(c.QueryParam(param), 10, 64)
+		if err != nil || n < 0 {
+			return c.JSON(http.StatusBadRequest, &response.Wrapper{
+				Message: response.ErrBadRequest.Error(),
+			})
+		}
+		*dst = n
+	}
+
+	times := map[string]*time.Time{
+		`from`: &f.From,
+		`to`:   &f.To,
+	}
+
+	for param, dst := range times {
+		if c.QueryParam(param) == `` {
+			continue
+		}
+
+		t, err := time.Parse(time.RFC3339, c.QueryParam(param))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, &response.Wrapper{
+				Message: response.ErrBadRequest.Error(),
+			})
+		}
+		*dst = t
+	}
+
+	res, err := h.Usecase.Fetch(c.Request().Context(), f)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, &response.Wrapper{
+			Message: response.ErrServer.Error(),
+		})
+	}
+
+	strNextCursor := strconv.FormatInt(f.Cursor, 10)
+	if len(res) > 0 {
+		strNextCursor = strconv.FormatInt(res[len(res)-1].ID, 10)
+	}
+
+	c.Response().Header().Set(`X-Cursor`, strNextCursor)
+
+	return c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,67 @@
+package http_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	handler "github.com/andhikagama/lmnlo/audit/delivery"
+	"github.com/andhikagama/lmnlo/audit/mocks"
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/andhikagama/lmnlo/models/filter"
+)
+
+func TestFetch(t *testing.T) {
+	from := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name   string
+		query  string
+		filter *filter.Audit
+		err    error
+		code   int
+		cursor string
+	}{
+		{`success`, `?actor_id=1&target_id=2&from=2019-01-01T00:00:00Z&to=2019-01-02T00:00:00Z&num=10&cursor=50`, &filter.Audit{ActorID: 1, TargetID: 2, From: from, To: from.Add(24 * time.Hour), Num: 10, Cursor: 50}, nil, http.StatusOK, `7`},
+		{`defaults`, ``, &filter.Audit{Num: 200}, nil, http.StatusOK, `7`},
+		{`bad-actor`, `?actor_id=abc`, nil, nil, http.StatusBadRequest, ``},
+		{`bad-time`, `?from=yesterday`, nil, nil, http.StatusBadRequest, ``},
+		{`error`, ``, &filter.Audit{Num: 200}, errors.New(`error`), http.StatusInternalServerError, ``},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockUCase := new(mocks.Usecase)
+			if tc.filter != nil {
+				var res []*entity.AuditEvent
+				if tc.err == nil {
+					res = []*entity.AuditEvent{{ID: 8}, {ID: 7}}
+				}
+				mockUCase.On("Fetch", mock.Anything, tc.filter).Return(res, tc.err).Once()
+			}
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.GET, "/audit"+tc.query, strings.NewReader(""))
+			rec := httptest.NewRecorder()
+
+			c := e.NewContext(req, rec)
+			c.SetPath("audit")
+
+			handler := handler.AuditHTTPHandler{
+				Usecase: mockUCase,
+			}
+			handler.Fetch(c)
+
+			assert.Equal(t, tc.code, rec.Code)
+			assert.Equal(t, tc.cursor, rec.Header().Get(`X-Cursor`))
+			mockUCase.AssertExpectations(t)
+		})
+	}
+}
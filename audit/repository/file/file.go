@@ -0,0 +1,139 @@
+package file
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+
+	"github.com/andhikagama/lmnlo/audit"
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/andhikagama/lmnlo/models/filter"
+)
+
+type auditRepository struct {
+	path string
+
+	mu   sync.Mutex
+	file *os.File
+	seq  int64
+}
+
+// NewAuditRepository return a repository appending events to the JSON
+// lines file at path, one event per line. Events without ID are numbered
+// after the last one in the file.
+func NewAuditRepository(path string) (audit.Repository, error) {
+	r := &auditRepository{path: path}
+
+	events, err := r.read()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ev := range events {
+		if ev.ID > r.seq {
+			r.seq = ev.ID
+		}
+	}
+
+	r.file, err = os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (r *auditRepository) Store(ctx context.Context, ev *entity.AuditEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if ev.ID == 0 {
+		ev.ID = r.seq + 1
+	}
+
+	line, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+
+	if _, err := r.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+
+	if ev.ID > r.seq {
+		r.seq = ev.ID
+	}
+
+	return r.file.Sync()
+}
+
+// Fetch scans the whole file, it suits the occasional query rather than
+// reporting
+func (r *auditRepository) Fetch(ctx context.Context, f *filter.Audit) ([]*entity.AuditEvent, error) {
+	r.mu.Lock()
+	events, err := r.read()
+	r.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	results := []*entity.AuditEvent{}
+	for i := len(events) - 1; i >= 0 && int64(len(results)) < f.Num; i-- {
+		if matches(events[i], f) {
+			results = append(results, events[i])
+		}
+	}
+
+	return results, nil
+}
+
+func (r *auditRepository) read() ([]*entity.AuditEvent, error) {
+	file, err := os.Open(r.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	events := []*entity.AuditEvent{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		ev := new(entity.AuditEvent)
+		if err := json.Unmarshal(scanner.Bytes(), ev); err != nil {
+			return nil, err
+		}
+		events = append(events, ev)
+	}
+
+	return events, scanner.Err()
+}
+
+func matches(ev *entity.AuditEvent, f *filter.Audit) bool {
+	if f.ActorID != 0 && ev.ActorID != f.ActorID {
+		return false
+	}
+
+	if f.TargetID != 0 && ev.TargetID != f.TargetID {
+		return false
+	}
+
+	if !f.From.IsZero() && ev.CreatedAt.Before(f.From) {
+		return false
+	}
+
+	if !f.To.IsZero() && !ev.CreatedAt.Before(f.To) {
+		return false
+	}
+
+	return f.Cursor == 0 || ev.ID < f.Cursor
+}
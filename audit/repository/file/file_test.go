@@ -0,0 +1,98 @@
+package file_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/andhikagama/lmnlo/audit/repository/file"
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/andhikagama/lmnlo/models/filter"
+)
+
+// tempFile return a path in a new directory, which the caller removes
+func tempFile(t *testing.T) string {
+	dir, err := ioutil.TempDir(``, `audit`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return filepath.Join(dir, `audit.log`)
+}
+
+func TestStore(t *testing.T) {
+	path := tempFile(t)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	repo, err := file.NewAuditRepository(path)
+	assert.NoError(t, err)
+
+	first := &entity.AuditEvent{ActorID: 1, Action: entity.AuditUserRegister, TargetID: 1}
+	assert.NoError(t, repo.Store(context.TODO(), first))
+	// Numbered by the database sink that ran first
+	assert.NoError(t, repo.Store(context.TODO(), &entity.AuditEvent{ID: 5, ActorID: 1, Action: entity.AuditUserUpdate, TargetID: 1}))
+
+	raw, _ := ioutil.ReadFile(path)
+	lines := strings.Split(strings.TrimSpace(string(raw)), "\n")
+	assert.Len(t, lines, 2)
+	assert.Equal(t, int64(1), first.ID)
+	assert.Contains(t, lines[1], `"action":"user.update"`)
+
+	// Reopened files continue the numbering
+	repo, err = file.NewAuditRepository(path)
+	assert.NoError(t, err)
+
+	next := &entity.AuditEvent{Action: entity.AuditUserDelete}
+	assert.NoError(t, repo.Store(context.TODO(), next))
+	assert.Equal(t, int64(6), next.ID)
+}
+
+func TestFetch(t *testing.T) {
+	path := tempFile(t)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	repo, err := file.NewAuditRepository(path)
+	assert.NoError(t, err)
+
+	start := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 4; i++ {
+		repo.Store(context.TODO(), &entity.AuditEvent{
+			ActorID:   int64(1 + i%2),
+			Action:    entity.AuditUserUpdate,
+			TargetID:  3,
+			CreatedAt: start.Add(time.Duration(i) * time.Hour),
+		})
+	}
+
+	cases := []struct {
+		name string
+		f    *filter.Audit
+		ids  []int64
+	}{
+		{`all`, &filter.Audit{Num: 10}, []int64{4, 3, 2, 1}},
+		{`num`, &filter.Audit{Num: 2}, []int64{4, 3}},
+		{`cursor`, &filter.Audit{Num: 10, Cursor: 3}, []int64{2, 1}},
+		{`actor`, &filter.Audit{Num: 10, ActorID: 2}, []int64{4, 2}},
+		{`target`, &filter.Audit{Num: 10, TargetID: 4}, []int64{}},
+		{`range`, &filter.Audit{Num: 10, From: start.Add(time.Hour), To: start.Add(3 * time.Hour)}, []int64{3, 2}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := repo.Fetch(context.TODO(), tc.f)
+
+			assert.NoError(t, err)
+			ids := []int64{}
+			for _, ev := range res {
+				ids = append(ids, ev.ID)
+			}
+			assert.Equal(t, tc.ids, ids)
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/andhikagama/lmnlo/audit"
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/andhikagama/lmnlo/models/filter"
+	sq "github.com/elgris/sqrl"
+	"github.com/sirupsen/logrus"
+)
+
+type auditRepository struct {
+	Conn *sql.DB
+}
+
+// NewAuditRepository return a repository backed by the audit_log table.
+// Rows are only ever inserted.
+func NewAuditRepository(Conn *sql.DB) audit.Repository {
+	return &auditRepository{Conn}
+}
+
+func (m *auditRepository) Store(ctx context.Context, ev *entity.AuditEvent) error {
+	trx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	var diff interface{}
+	if len(ev.Diff) > 0 {
+		diff = []byte(ev.Diff)
+	}
+
+	query := sq.Insert(`audit_log`)
+	query.Columns(`actor_id`, `impersonator_id`, `action`, `target_id`, `diff`, `ip`, `request_id`, `create_time`)
+	query.Values(ev.ActorID, ev.ImpersonatorID, ev.Action, ev.TargetID, diff, ev.IP, ev.RequestID, ev.CreatedAt)
+
+	sql, args, _ := query.ToSql()
+	stmt, err := trx.PrepareContext(ctx, sql)
+	if err != nil {
+		trx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	r, err := stmt.ExecContext(ctx, args...)
+	if err != nil {
+		trx.Rollback()
+		return err
+	}
+
+	ev.ID, err = r.LastInsertId()
+	if err != nil {
+		trx.Rollback()
+		return err
+	}
+
+	return trx.Commit()
+}
+
+func (m *auditRepository) Fetch(ctx context.Context, f *filter.Audit) ([]*entity.AuditEvent, error) {
+	query := sq.Select(`id, actor_id, impersonator_id, action, target_id, diff, ip, request_id, create_time`)
+	query.From(`audit_log`)
+
+	if f.ActorID != 0 {
+		query.Where(`actor_id = ?`, f.ActorID)
+	}
+
+	if f.TargetID != 0 {
+		query.Where(`target_id = ?`, f.TargetID)
+	}
+
+	if !f.From.IsZero() {
+		query.Where(`create_time >= ?`, f.From)
+	}
+
+	if !f.To.IsZero() {
+		query.Where(`create_time < ?`, f.To)
+	}
+
+	if f.Cursor != 0 {
+		query.Where(`id < ?`, f.Cursor)
+	}
+
+	query.OrderBy(`id DESC`).Limit(uint64(f.Num))
+
+	sql, args, _ := query.ToSql()
+	rows, err := m.Conn.QueryContext(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []*entity.AuditEvent{}
+	for rows.Next() {
+		ev := new(entity.AuditEvent)
+		var diff []byte
+
+		err := rows.Scan(
+			&ev.ID,
+			&ev.ActorID,
+			&ev.ImpersonatorID,
+			&ev.Action,
+			&ev.TargetID,
+			&diff,
+			&ev.IP,
+			&ev.RequestID,
+			&ev.CreatedAt,
+		)
+
+		if err != nil {
+			logrus.Error(err, ev.ID)
+			return nil, err
+		}
+
+		ev.Diff = diff
+		results = append(results, ev)
+	}
+
+	return results, rows.Err()
+}
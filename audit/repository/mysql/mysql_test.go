@@ -0,0 +1,110 @@
+package mysql_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/andhikagama/lmnlo/audit/repository/mysql"
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/andhikagama/lmnlo/models/filter"
+)
+
+var columns = []string{`id`, `actor_id`, `impersonator_id`, `action`, `target_id`, `diff`, `ip`, `request_id`, `create_time`}
+
+func TestStore(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	ev := &entity.AuditEvent{
+		ActorID:   1,
+		Action:    entity.AuditUserUpdate,
+		TargetID:  2,
+		Diff:      []byte(`{"email":{"before":"a","after":"b"}}`),
+		IP:        `10.0.0.1`,
+		RequestID: `request`,
+		CreatedAt: time.Now(),
+	}
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`INSERT INTO audit_log`).
+			ExpectExec().
+			WithArgs(int64(1), int64(0), entity.AuditUserUpdate, int64(2), []byte(ev.Diff), `10.0.0.1`, `request`, ev.CreatedAt).
+			WillReturnResult(sqlmock.NewResult(7, 1))
+		mock.ExpectCommit()
+
+		repo := mysql.NewAuditRepository(db)
+		err := repo.Store(context.TODO(), ev)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(7), ev.ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("error", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`INSERT INTO audit_log`).
+			ExpectExec().
+			WillReturnError(errors.New(`error`))
+		mock.ExpectRollback()
+
+		repo := mysql.NewAuditRepository(db)
+		err := repo.Store(context.TODO(), &entity.AuditEvent{Action: entity.AuditUserDelete})
+
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestFetch(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		from := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+		to := from.Add(24 * time.Hour)
+		rows := sqlmock.NewRows(columns).
+			AddRow(9, 1, 0, entity.AuditUserUpdate, 2, []byte(`{"email":{"before":"a","after":"b"}}`), `10.0.0.1`, `request`, from).
+			AddRow(8, 1, 5, entity.AuditUserDelete, 3, nil, `10.0.0.1`, ``, from)
+
+		mock.ExpectQuery(`SELECT (.+) FROM audit_log WHERE actor_id = \? AND target_id = \? AND create_time >= \? AND create_time < \? AND id < \? ORDER BY id DESC LIMIT 2`).
+			WithArgs(int64(1), int64(2), from, to, int64(10)).
+			WillReturnRows(rows)
+
+		repo := mysql.NewAuditRepository(db)
+		res, err := repo.Fetch(context.TODO(), &filter.Audit{ActorID: 1, TargetID: 2, From: from, To: to, Cursor: 10, Num: 2})
+
+		assert.NoError(t, err)
+		assert.Len(t, res, 2)
+		assert.JSONEq(t, `{"email":{"before":"a","after":"b"}}`, string(res[0].Diff))
+		assert.Equal(t, int64(5), res[1].ImpersonatorID)
+		assert.Nil(t, res[1].Diff)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("error", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT (.+) FROM audit_log`).WillReturnError(errors.New(`error`))
+
+		repo := mysql.NewAuditRepository(db)
+		res, err := repo.Fetch(context.TODO(), &filter.Audit{Num: 10})
+
+		assert.Error(t, err)
+		assert.Nil(t, res)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
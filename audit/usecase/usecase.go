@@ -0,0 +1,133 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/andhikagama/lmnlo/audit"
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/andhikagama/lmnlo/models/filter"
+)
+
+// _Redacted fields never make it into a diff
+var _Redacted = map[string]bool{
+	`password`:        true,
+	`token`:           true,
+	`refresh_token`:   true,
+	`challenge_token`: true,
+	`key`:             true,
+}
+
+type change struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+type auditUsecase struct {
+	sinks []audit.Repository
+}
+
+// NewAuditUsecase return a usecase recording into every sink, the first
+// one answers queries
+func NewAuditUsecase(sinks ...audit.Repository) audit.Usecase {
+	return &auditUsecase{sinks}
+}
+
+// Record stores an event of o doing action to targetID. Before and after
+// are the target's state around the action, either may be nil, and only
+// the fields that differ are kept.
+func (a *auditUsecase) Record(ctx context.Context, o *entity.Origin, action string, targetID int64, before interface{}, after interface{}) error {
+	diff, err := Diff(before, after)
+	if err != nil {
+		return err
+	}
+
+	ev := &entity.AuditEvent{
+		Action:    action,
+		TargetID:  targetID,
+		Diff:      diff,
+		CreatedAt: time.Now(),
+	}
+
+	if o != nil {
+		ev.ActorID = o.ActorID
+		ev.ImpersonatorID = o.ImpersonatorID
+		ev.IP = o.IP
+		ev.RequestID = o.RequestID
+	}
+
+	// A failing sink must not keep the event from the others
+	var firstErr error
+	for _, sink := range a.sinks {
+		if err := sink.Store(ctx, ev); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+func (a *auditUsecase) Fetch(ctx context.Context, f *filter.Audit) ([]*entity.AuditEvent, error) {
+	if len(a.sinks) == 0 {
+		return make([]*entity.AuditEvent, 0), nil
+	}
+
+	return a.sinks[0].Fetch(ctx, f)
+}
+
+// Diff compares the JSON fields of before and after, nil when nothing
+// changed
+func Diff(before interface{}, after interface{}) (json.RawMessage, error) {
+	b, err := fields(before)
+	if err != nil {
+		return nil, err
+	}
+
+	af, err := fields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]change{}
+	for key, value := range b {
+		if !reflect.DeepEqual(value, af[key]) {
+			changes[key] = change{Before: value, After: af[key]}
+		}
+	}
+
+	for key, value := range af {
+		if _, ok := b[key]; !ok {
+			changes[key] = change{After: value}
+		}
+	}
+
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	return json.Marshal(changes)
+}
+
+func fields(v interface{}) (map[string]interface{}, error) {
+	res := map[string]interface{}{}
+	if v == nil || reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil() {
+		return res, nil
+	}
+
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return nil, err
+	}
+
+	for key := range _Redacted {
+		delete(res, key)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,102 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/andhikagama/lmnlo/audit/mocks"
+	"github.com/andhikagama/lmnlo/audit/usecase"
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/andhikagama/lmnlo/models/filter"
+)
+
+func TestRecord(t *testing.T) {
+	o := &entity.Origin{ActorID: 1, ImpersonatorID: 9, IP: `10.0.0.1`, RequestID: `request`}
+
+	t.Run("success", func(t *testing.T) {
+		mockDB := new(mocks.Repository)
+		mockFile := new(mocks.Repository)
+		for _, sink := range []*mocks.Repository{mockDB, mockFile} {
+			sink.On("Store", mock.Anything, mock.MatchedBy(func(ev *entity.AuditEvent) bool {
+				return ev.ActorID == 1 && ev.ImpersonatorID == 9 && ev.IP == `10.0.0.1` && ev.RequestID == `request` &&
+					ev.Action == entity.AuditUserUpdate && ev.TargetID == 2 && !ev.CreatedAt.IsZero() &&
+					string(ev.Diff) == `{"email":{"before":"old@lmnlo.io","after":"new@lmnlo.io"}}`
+			})).Return(nil).Once()
+		}
+		u := usecase.NewAuditUsecase(mockDB, mockFile)
+
+		err := u.Record(context.TODO(), o, entity.AuditUserUpdate, 2, &entity.User{ID: 2, Email: `old@lmnlo.io`}, &entity.User{ID: 2, Email: `new@lmnlo.io`})
+
+		assert.NoError(t, err)
+		mockDB.AssertExpectations(t)
+		mockFile.AssertExpectations(t)
+	})
+
+	t.Run("failing-sink", func(t *testing.T) {
+		mockDB := new(mocks.Repository)
+		mockFile := new(mocks.Repository)
+		mockDB.On("Store", mock.Anything, mock.AnythingOfType("*entity.AuditEvent")).Return(errors.New(`error`)).Once()
+		mockFile.On("Store", mock.Anything, mock.AnythingOfType("*entity.AuditEvent")).Return(nil).Once()
+		u := usecase.NewAuditUsecase(mockDB, mockFile)
+
+		err := u.Record(context.TODO(), nil, entity.AuditUserLoginFailed, 0, nil, nil)
+
+		assert.Error(t, err)
+		mockDB.AssertExpectations(t)
+		mockFile.AssertExpectations(t)
+	})
+}
+
+func TestDiff(t *testing.T) {
+	cases := []struct {
+		name     string
+		before   interface{}
+		after    interface{}
+		expected string
+	}{
+		{`unchanged`, &entity.User{ID: 1, Email: `a`}, &entity.User{ID: 1, Email: `a`}, ``},
+		{`changed`, &entity.User{ID: 1, Address: `a`}, &entity.User{ID: 1, Address: `b`}, `{"address":{"before":"a","after":"b"}}`},
+		{`created`, nil, &entity.User{ID: 1}, `{"address":{"before":null,"after":""},"email":{"before":null,"after":""},"id":{"before":null,"after":1}}`},
+		{`nil-pointer`, (*entity.User)(nil), (*entity.User)(nil), ``},
+		{`redacted`, &entity.User{ID: 1, Password: `a`, Token: `a`}, &entity.User{ID: 1, Password: `b`, RefreshToken: `b`}, ``},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			diff, err := usecase.Diff(tc.before, tc.after)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, string(diff))
+		})
+	}
+}
+
+func TestFetch(t *testing.T) {
+	t.Run("first-sink", func(t *testing.T) {
+		f := &filter.Audit{Num: 10}
+		mockDB := new(mocks.Repository)
+		mockFile := new(mocks.Repository)
+		mockDB.On("Fetch", mock.Anything, f).Return([]*entity.AuditEvent{{ID: 1}}, nil).Once()
+		u := usecase.NewAuditUsecase(mockDB, mockFile)
+
+		res, err := u.Fetch(context.TODO(), f)
+
+		assert.NoError(t, err)
+		assert.Len(t, res, 1)
+		mockDB.AssertExpectations(t)
+		mockFile.AssertExpectations(t)
+	})
+
+	t.Run("no-sink", func(t *testing.T) {
+		u := usecase.NewAuditUsecase()
+
+		res, err := u.Fetch(context.TODO(), &filter.Audit{Num: 10})
+
+		assert.NoError(t, err)
+		assert.Empty(t, res)
+	})
+}
@@ -0,0 +1,68 @@
+package authserver
+
+import (
+	"context"
+	"github.com/andhikagama/lmnlo/models/entity"
+)
+
+// Error codes of RFC 6749 and OpenID Connect
+const (
+	ErrorInvalidRequest          = `invalid_request`
+	ErrorInvalidClient           = `invalid_client`
+	ErrorInvalidGrant            = `invalid_grant`
+	ErrorUnauthorizedClient      = `unauthorized_client`
+	ErrorUnsupportedGrantType    = `unsupported_grant_type`
+	ErrorUnsupportedResponseType = `unsupported_response_type`
+	ErrorInvalidScope            = `invalid_scope`
+	ErrorInvalidToken            = `invalid_token`
+	ErrorServerError             = `server_error`
+)
+
+// Error is an OAuth error, answered to clients with its code
+type Error struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Code
+}
+
+// Metadata is the OpenID Connect discovery document
+type Metadata struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserinfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+// Repository represents database manipulation
+type Repository interface {
+	StoreClient(ctx context.Context, cl *entity.Client) error
+	GetClient(ctx context.Context, clientID string) (*entity.Client, error)
+	FetchClients(ctx context.Context) ([]*entity.Client, error)
+	DeleteClient(ctx context.Context, clientID string) (bool, error)
+	StoreCode(ctx context.Context, code *entity.AuthorizationCode) error
+	UseCode(ctx context.Context, codeHash string) (*entity.AuthorizationCode, error)
+}
+
+// Usecase represents business logic
+type Usecase interface {
+	RegisterClient(ctx context.Context, cl *entity.Client) error
+	FetchClients(ctx context.Context) ([]*entity.Client, error)
+	DeleteClient(ctx context.Context, clientID string) error
+	Authorize(ctx context.Context, req *entity.AuthorizeRequest) (string, error)
+	Approve(ctx context.Context, req *entity.AuthorizeRequest, uid int64) (string, error)
+	Token(ctx context.Context, req *entity.TokenRequest) (*entity.TokenResponse, error)
+	UserInfo(ctx context.Context, accessToken string) (map[string]interface{}, error)
+	Metadata() *Metadata
+}
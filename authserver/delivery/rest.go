@@ -0,0 +1,199 @@
+package http
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/andhikagama/lmnlo/authserver"
+	cmware "github.com/andhikagama/lmnlo/cmiddleware"
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/andhikagama/lmnlo/models/response"
+	"github.com/labstack/echo"
+	log "github.com/sirupsen/logrus"
+)
+
+// AuthServerHTTPHandler ...
+type AuthServerHTTPHandler struct {
+	Usecase authserver.Usecase
+}
+
+// NewAuthServerHTTPHandler registers discovery on e and the OAuth endpoints
+// on g
+func NewAuthServerHTTPHandler(e *echo.Echo, g *echo.Group, u authserver.Usecase, cm cmware.Usecase) {
+	handler := &AuthServerHTTPHandler{
+		Usecase: u,
+	}
+
+	e.GET(`/.well-known/openid-configuration`, handler.Metadata)
+	cm.SetPolicy(g.GET(`/oauth2/authorize`, handler.Authorize), cmware.Public)
+	g.POST(`/oauth2/authorize`, handler.Approve, cm.RequireSession, cm.DenyImpersonation)
+	cm.SetPolicy(g.POST(`/oauth2/token`, handler.Token), cmware.Public)
+	cm.SetPolicy(g.GET(`/oauth2/userinfo`, handler.UserInfo), cmware.Public)
+	cm.SetPolicy(g.POST(`/oauth2/userinfo`, handler.UserInfo), cmware.Public)
+	g.POST(`/oauth2/clients`, handler.RegisterClient, cm.RequirePermission(entity.PermissionClientManage))
+	g.GET(`/oauth2/clients`, handler.FetchClients, cm.RequirePermission(entity.PermissionClientManage))
+	g.DELETE(`/oauth2/clients/:client_id`, handler.DeleteClient, cm.RequirePermission(entity.PermissionClientManage))
+}
+
+// Metadata serves the OpenID Connect discovery document
+func (h *AuthServerHTTPHandler) Metadata(c echo.Context) error {
+	c.Response().Header().Set(`Cache-Control`, `public, max-age=300`)
+	return c.JSON(http.StatusOK, h.Usecase.Metadata())
+}
+
+// Authorize is where clients send the browser. It forwards to the login
+// page, which signs the user in and submits the request to Approve.
+func (h *AuthServerHTTPHandler) Authorize(c echo.Context) error {
+	req := &entity.AuthorizeRequest{
+		ResponseType:        c.QueryParam(`response_type`),
+		ClientID:            c.QueryParam(`client_id`),
+		RedirectURI:         c.QueryParam(`redirect_uri`),
+		Scope:               c.QueryParam(`scope`),
+		State:               c.QueryParam(`state`),
+		Nonce:               c.QueryParam(`nonce`),
+		CodeChallenge:       c.QueryParam(`code_challenge`),
+		CodeChallengeMethod: c.QueryParam(`code_challenge_method`),
+	}
+
+	redirectTo, err := h.Usecase.Authorize(c.Request().Context(), req)
+	if err != nil {
+		return oauthError(c, err)
+	}
+
+	return c.Redirect(http.StatusFound, redirectTo)
+}
+
+// Approve grants the authorization request for the signed in user and
+// return where the login page sends the browser next
+func (h *AuthServerHTTPHandler) Approve(c echo.Context) error {
+	usr, ok := c.Get(`user`).(*entity.User)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, &response.Wrapper{
+			Message: response.ErrUnAuthorized.Error(),
+		})
+	}
+
+	req := new(entity.AuthorizeRequest)
+	c.Bind(req)
+
+	redirectTo, err := h.Usecase.Approve(c.Request().Context(), req, usr.ID)
+	if err != nil {
+		return oauthError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		`redirect_to`: redirectTo,
+	})
+}
+
+// Token issues tokens to clients. Credentials are read from basic auth or
+// from the form.
+func (h *AuthServerHTTPHandler) Token(c echo.Context) error {
+	req := new(entity.TokenRequest)
+	c.Bind(req)
+
+	clientID, secret, basic := c.Request().BasicAuth()
+	if basic {
+		req.ClientID, _ = url.QueryUnescape(clientID)
+		req.ClientSecret, _ = url.QueryUnescape(secret)
+	}
+
+	c.Response().Header().Set(`Cache-Control`, `no-store`)
+	c.Response().Header().Set(`Pragma`, `no-cache`)
+
+	res, err := h.Usecase.Token(c.Request().Context(), req)
+	if err != nil {
+		if e, ok := err.(*authserver.Error); ok && e.Code == authserver.ErrorInvalidClient && basic {
+			c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Basic realm="lmnlo"`)
+		}
+		return oauthError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
+
+// UserInfo return claims about the user of an access token
+func (h *AuthServerHTTPHandler) UserInfo(c echo.Context) error {
+	token := c.FormValue(`access_token`)
+
+	temp := strings.SplitN(c.Request().Header.Get(echo.HeaderAuthorization), ` `, 2)
+	if len(temp) == 2 && strings.EqualFold(temp[0], `Bearer`) {
+		token = temp[1]
+	}
+
+	info, err := h.Usecase.UserInfo(c.Request().Context(), token)
+	if err != nil {
+		if _, ok := err.(*authserver.Error); ok {
+			c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer error="invalid_token"`)
+		}
+		return oauthError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, info)
+}
+
+// RegisterClient ...
+func (h *AuthServerHTTPHandler) RegisterClient(c echo.Context) error {
+	cl := new(entity.Client)
+	c.Bind(cl)
+
+	if err := h.Usecase.RegisterClient(c.Request().Context(), cl); err != nil {
+		if err == response.ErrBadRequest {
+			return c.JSON(http.StatusBadRequest, &response.Wrapper{
+				Message: err.Error(),
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, &response.Wrapper{
+			Message: response.ErrServer.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusCreated, cl)
+}
+
+// FetchClients ...
+func (h *AuthServerHTTPHandler) FetchClients(c echo.Context) error {
+	clients, err := h.Usecase.FetchClients(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, &response.Wrapper{
+			Message: response.ErrServer.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, clients)
+}
+
+// DeleteClient ...
+func (h *AuthServerHTTPHandler) DeleteClient(c echo.Context) error {
+	if err := h.Usecase.DeleteClient(c.Request().Context(), c.Param(`client_id`)); err != nil {
+		if err == response.ErrNotFound {
+			return c.JSON(http.StatusNotFound, &response.Wrapper{
+				Message: err.Error(),
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, &response.Wrapper{
+			Message: response.ErrServer.Error(),
+		})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// oauthError answers in the error format of RFC 6749, unexpected errors
+// are logged and hidden behind server_error
+func oauthError(c echo.Context, err error) error {
+	e, ok := err.(*authserver.Error)
+	if !ok {
+		log.Error(err)
+		return c.JSON(http.StatusInternalServerError, &authserver.Error{
+			Code: authserver.ErrorServerError,
+		})
+	}
+
+	if e.Code == authserver.ErrorInvalidClient || e.Code == authserver.ErrorInvalidToken {
+		return c.JSON(http.StatusUnauthorized, e)
+	}
+
+	return c.JSON(http.StatusBadRequest, e)
+}
@@ -0,0 +1,361 @@
+package http_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/andhikagama/lmnlo/authserver"
+	handler "github.com/andhikagama/lmnlo/authserver/delivery"
+	"github.com/andhikagama/lmnlo/authserver/mocks"
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/andhikagama/lmnlo/models/response"
+)
+
+func TestMetadata(t *testing.T) {
+	mockUCase := new(mocks.Usecase)
+	mockUCase.On("Metadata").Return(&authserver.Metadata{Issuer: `https://auth.example.com`}).Once()
+
+	e := echo.New()
+	req := httptest.NewRequest(echo.GET, "/.well-known/openid-configuration", nil)
+	rec := httptest.NewRecorder()
+
+	c := e.NewContext(req, rec)
+
+	handler := handler.AuthServerHTTPHandler{
+		Usecase: mockUCase,
+	}
+	handler.Metadata(c)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"issuer":"https://auth.example.com"`)
+	mockUCase.AssertExpectations(t)
+}
+
+func TestAuthorize(t *testing.T) {
+	cases := []struct {
+		name       string
+		redirectTo string
+		err        error
+		code       int
+	}{
+		{`success`, `https://app.example.com/login?client_id=abc`, nil, http.StatusFound},
+		{`invalid-client`, ``, &authserver.Error{Code: authserver.ErrorInvalidClient}, http.StatusUnauthorized},
+		{`invalid-redirect`, ``, &authserver.Error{Code: authserver.ErrorInvalidRequest}, http.StatusBadRequest},
+		{`error`, ``, errors.New(`error`), http.StatusInternalServerError},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockUCase := new(mocks.Usecase)
+			mockUCase.On("Authorize", mock.Anything, &entity.AuthorizeRequest{
+				ResponseType: `code`,
+				ClientID:     `abc`,
+				State:        `xyz`,
+			}).Return(tc.redirectTo, tc.err).Once()
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.GET, "/v1/oauth2/authorize?response_type=code&client_id=abc&state=xyz", nil)
+			rec := httptest.NewRecorder()
+
+			c := e.NewContext(req, rec)
+			c.SetPath("oauth2/authorize")
+
+			handler := handler.AuthServerHTTPHandler{
+				Usecase: mockUCase,
+			}
+			handler.Authorize(c)
+
+			assert.Equal(t, tc.code, rec.Code)
+			if tc.code == http.StatusFound {
+				assert.Equal(t, tc.redirectTo, rec.Header().Get(`Location`))
+			}
+			mockUCase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestApprove(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		mockUCase := new(mocks.Usecase)
+		mockUCase.On("Approve", mock.Anything, mock.MatchedBy(func(req *entity.AuthorizeRequest) bool {
+			return req.ClientID == `abc` && req.CodeChallenge == `challenge`
+		}), int64(1)).Return(`https://reports.example.com/cb?code=123&state=xyz`, nil).Once()
+
+		e := echo.New()
+		req := httptest.NewRequest(echo.POST, "/", strings.NewReader(`{"response_type":"code","client_id":"abc","code_challenge":"challenge"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+		c.SetPath("oauth2/authorize")
+		c.Set(`user`, &entity.User{ID: 1})
+
+		handler := handler.AuthServerHTTPHandler{
+			Usecase: mockUCase,
+		}
+		handler.Approve(c)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"redirect_to":"https://reports.example.com/cb?code=123\u0026state=xyz"`)
+		mockUCase.AssertExpectations(t)
+	})
+
+	t.Run("unauthorized", func(t *testing.T) {
+		mockUCase := new(mocks.Usecase)
+
+		e := echo.New()
+		req := httptest.NewRequest(echo.POST, "/", strings.NewReader(`{}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+
+		handler := handler.AuthServerHTTPHandler{
+			Usecase: mockUCase,
+		}
+		handler.Approve(c)
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		mockUCase.AssertExpectations(t)
+	})
+}
+
+func TestToken(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{`success`, nil, http.StatusOK},
+		{`invalid-client`, &authserver.Error{Code: authserver.ErrorInvalidClient}, http.StatusUnauthorized},
+		{`invalid-grant`, &authserver.Error{Code: authserver.ErrorInvalidGrant}, http.StatusBadRequest},
+		{`error`, errors.New(`error`), http.StatusInternalServerError},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var res *entity.TokenResponse
+			if tc.err == nil {
+				res = &entity.TokenResponse{AccessToken: `token`, TokenType: `Bearer`, ExpiresIn: 3600}
+			}
+
+			mockUCase := new(mocks.Usecase)
+			mockUCase.On("Token", mock.Anything, &entity.TokenRequest{
+				GrantType:    entity.GrantAuthorizationCode,
+				Code:         `123`,
+				RedirectURI:  `https://reports.example.com/cb`,
+				CodeVerifier: `verifier`,
+				ClientID:     `abc`,
+				ClientSecret: `s3cret/+`,
+			}).Return(res, tc.err).Once()
+
+			form := url.Values{
+				`grant_type`:    {entity.GrantAuthorizationCode},
+				`code`:          {`123`},
+				`redirect_uri`:  {`https://reports.example.com/cb`},
+				`code_verifier`: {`verifier`},
+			}
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.POST, "/", strings.NewReader(form.Encode()))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+			req.SetBasicAuth(`abc`, url.QueryEscape(`s3cret/+`))
+			rec := httptest.NewRecorder()
+
+			c := e.NewContext(req, rec)
+			c.SetPath("oauth2/token")
+
+			handler := handler.AuthServerHTTPHandler{
+				Usecase: mockUCase,
+			}
+			handler.Token(c)
+
+			assert.Equal(t, tc.code, rec.Code)
+			assert.Equal(t, `no-store`, rec.Header().Get(`Cache-Control`))
+			if tc.code == http.StatusUnauthorized {
+				assert.NotEmpty(t, rec.Header().Get(echo.HeaderWWWAuthenticate))
+			}
+			if tc.err != nil {
+				assert.Contains(t, rec.Body.String(), `"error":`)
+			}
+			mockUCase.AssertExpectations(t)
+		})
+	}
+
+	t.Run("client-secret-post", func(t *testing.T) {
+		mockUCase := new(mocks.Usecase)
+		mockUCase.On("Token", mock.Anything, &entity.TokenRequest{
+			GrantType:    entity.GrantClientCredentials,
+			Scope:        `reports:read`,
+			ClientID:     `abc`,
+			ClientSecret: `secret`,
+		}).Return(&entity.TokenResponse{AccessToken: `token`}, nil).Once()
+
+		form := url.Values{
+			`grant_type`:    {entity.GrantClientCredentials},
+			`scope`:         {`reports:read`},
+			`client_id`:     {`abc`},
+			`client_secret`: {`secret`},
+		}
+
+		e := echo.New()
+		req := httptest.NewRequest(echo.POST, "/", strings.NewReader(form.Encode()))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+
+		handler := handler.AuthServerHTTPHandler{
+			Usecase: mockUCase,
+		}
+		handler.Token(c)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		mockUCase.AssertExpectations(t)
+	})
+}
+
+func TestUserInfo(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{`success`, nil, http.StatusOK},
+		{`invalid-token`, &authserver.Error{Code: authserver.ErrorInvalidToken}, http.StatusUnauthorized},
+		{`error`, errors.New(`error`), http.StatusInternalServerError},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var info map[string]interface{}
+			if tc.err == nil {
+				info = map[string]interface{}{`sub`: `1`}
+			}
+
+			mockUCase := new(mocks.Usecase)
+			mockUCase.On("UserInfo", mock.Anything, `token`).Return(info, tc.err).Once()
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.GET, "/", nil)
+			req.Header.Set(echo.HeaderAuthorization, `Bearer token`)
+			rec := httptest.NewRecorder()
+
+			c := e.NewContext(req, rec)
+			c.SetPath("oauth2/userinfo")
+
+			handler := handler.AuthServerHTTPHandler{
+				Usecase: mockUCase,
+			}
+			handler.UserInfo(c)
+
+			assert.Equal(t, tc.code, rec.Code)
+			if tc.code == http.StatusUnauthorized {
+				assert.Equal(t, `Bearer error="invalid_token"`, rec.Header().Get(echo.HeaderWWWAuthenticate))
+			}
+			mockUCase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRegisterClient(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{`success`, nil, http.StatusCreated},
+		{`invalid`, response.ErrBadRequest, http.StatusBadRequest},
+		{`error`, errors.New(`error`), http.StatusInternalServerError},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockUCase := new(mocks.Usecase)
+			mockUCase.On("RegisterClient", mock.Anything, mock.MatchedBy(func(cl *entity.Client) bool {
+				return cl.Name == `Reports` && len(cl.RedirectURIs) == 1
+			})).Return(tc.err).Once()
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.POST, "/", strings.NewReader(`{"name":"Reports","redirect_uris":["https://reports.example.com/cb"]}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			c := e.NewContext(req, rec)
+			c.SetPath("oauth2/clients")
+
+			handler := handler.AuthServerHTTPHandler{
+				Usecase: mockUCase,
+			}
+			handler.RegisterClient(c)
+
+			assert.Equal(t, tc.code, rec.Code)
+			mockUCase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestFetchClients(t *testing.T) {
+	mockUCase := new(mocks.Usecase)
+	mockUCase.On("FetchClients", mock.Anything).Return([]*entity.Client{{ClientID: `abc`, SecretHash: `hash`}}, nil).Once()
+
+	e := echo.New()
+	req := httptest.NewRequest(echo.GET, "/", nil)
+	rec := httptest.NewRecorder()
+
+	c := e.NewContext(req, rec)
+	c.SetPath("oauth2/clients")
+
+	handler := handler.AuthServerHTTPHandler{
+		Usecase: mockUCase,
+	}
+	handler.FetchClients(c)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotContains(t, rec.Body.String(), `hash`)
+	mockUCase.AssertExpectations(t)
+}
+
+func TestDeleteClient(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{`success`, nil, http.StatusNoContent},
+		{`not-found`, response.ErrNotFound, http.StatusNotFound},
+		{`error`, errors.New(`error`), http.StatusInternalServerError},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockUCase := new(mocks.Usecase)
+			mockUCase.On("DeleteClient", mock.Anything, `abc`).Return(tc.err).Once()
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.DELETE, "/", nil)
+			rec := httptest.NewRecorder()
+
+			c := e.NewContext(req, rec)
+			c.SetPath("oauth2/clients/:client_id")
+			c.SetParamNames("client_id")
+			c.SetParamValues("abc")
+
+			handler := handler.AuthServerHTTPHandler{
+				Usecase: mockUCase,
+			}
+			handler.DeleteClient(c)
+
+			assert.Equal(t, tc.code, rec.Code)
+			mockUCase.AssertExpectations(t)
+		})
+	}
+}
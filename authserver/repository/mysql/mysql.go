@@ -0,0 +1,291 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/andhikagama/lmnlo/authserver"
+	"github.com/andhikagama/lmnlo/models/entity"
+	sq "github.com/elgris/sqrl"
+	"github.com/sirupsen/logrus"
+)
+
+type authServerRepository struct {
+	Conn *sql.DB
+}
+
+// NewAuthServerRepository return a repository backed by the oauth_client
+// and oauth_code tables
+func NewAuthServerRepository(Conn *sql.DB) authserver.Repository {
+	return &authServerRepository{Conn}
+}
+
+func (m *authServerRepository) StoreClient(ctx context.Context, cl *entity.Client) error {
+	cl.CreatedAt = time.Now()
+
+	trx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	query := sq.Insert(`oauth_client`)
+	query.Columns(`client_id`, `secret_hash`, `name`, `redirect_uris`, `scopes`, `grant_types`, `public`, `create_time`)
+	query.Values(
+		cl.ClientID,
+		cl.SecretHash,
+		cl.Name,
+		joinList(cl.RedirectURIs),
+		joinList(cl.Scopes),
+		joinList(cl.GrantTypes),
+		cl.Public,
+		cl.CreatedAt,
+	)
+
+	sql, args, _ := query.ToSql()
+	stmt, err := trx.PrepareContext(ctx, sql)
+	if err != nil {
+		trx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	r, err := stmt.ExecContext(ctx, args...)
+	if err != nil {
+		trx.Rollback()
+		return err
+	}
+
+	cl.ID, err = r.LastInsertId()
+	if err != nil {
+		trx.Rollback()
+		return err
+	}
+
+	return trx.Commit()
+}
+
+func (m *authServerRepository) GetClient(ctx context.Context, clientID string) (*entity.Client, error) {
+	query := sq.Select(`id, client_id, secret_hash, name, redirect_uris, scopes, grant_types, public, create_time`)
+	query.From(`oauth_client`)
+	query.Where(`client_id = ?`, clientID)
+
+	sql, args, _ := query.ToSql()
+	rows, err := m.Conn.QueryContext(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result, err := unmarshalClients(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result) == 0 {
+		return new(entity.Client), nil
+	}
+
+	return result[0], nil
+}
+
+func (m *authServerRepository) FetchClients(ctx context.Context) ([]*entity.Client, error) {
+	query := sq.Select(`id, client_id, secret_hash, name, redirect_uris, scopes, grant_types, public, create_time`)
+	query.From(`oauth_client`)
+	query.OrderBy(`id DESC`)
+
+	sql, args, _ := query.ToSql()
+	rows, err := m.Conn.QueryContext(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return unmarshalClients(rows)
+}
+
+// DeleteClient removes the client together with its unredeemed codes
+func (m *authServerRepository) DeleteClient(ctx context.Context, clientID string) (bool, error) {
+	trx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+
+	codes := sq.Delete(`oauth_code`).Where(`client_id = ?`, clientID)
+	if _, err := execTx(ctx, trx, codes); err != nil {
+		trx.Rollback()
+		return false, err
+	}
+
+	affected, err := execTx(ctx, trx, sq.Delete(`oauth_client`).Where(`client_id = ?`, clientID))
+	if err != nil {
+		trx.Rollback()
+		return false, err
+	}
+
+	if err := trx.Commit(); err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+// StoreCode saves an authorization code and drops the expired ones, which
+// are left behind by clients that never redeemed them
+func (m *authServerRepository) StoreCode(ctx context.Context, code *entity.AuthorizationCode) error {
+	trx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	cleanup := sq.Delete(`oauth_code`).Where(`expire_time < ?`, time.Now())
+	if _, err := execTx(ctx, trx, cleanup); err != nil {
+		trx.Rollback()
+		return err
+	}
+
+	query := sq.Insert(`oauth_code`)
+	query.Columns(`code_hash`, `client_id`, `user_id`, `redirect_uri`, `scopes`, `challenge`, `nonce`, `expire_time`)
+	query.Values(
+		code.CodeHash,
+		code.ClientID,
+		code.UserID,
+		code.RedirectURI,
+		joinList(code.Scopes),
+		code.Challenge,
+		code.Nonce,
+		code.ExpiresAt,
+	)
+
+	if _, err := execTx(ctx, trx, query); err != nil {
+		trx.Rollback()
+		return err
+	}
+
+	return trx.Commit()
+}
+
+// UseCode deletes the code and return it. An empty code is returned when
+// it does not exist, has expired or was redeemed by a concurrent request.
+func (m *authServerRepository) UseCode(ctx context.Context, codeHash string) (*entity.AuthorizationCode, error) {
+	query := sq.Select(`code_hash, client_id, user_id, redirect_uri, scopes, challenge, nonce, expire_time`)
+	query.From(`oauth_code`)
+	query.Where(`code_hash = ?`, codeHash)
+
+	sql, args, _ := query.ToSql()
+	rows, err := m.Conn.QueryContext(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return new(entity.AuthorizationCode), rows.Err()
+	}
+
+	code := new(entity.AuthorizationCode)
+	var scopes string
+	err = rows.Scan(
+		&code.CodeHash,
+		&code.ClientID,
+		&code.UserID,
+		&code.RedirectURI,
+		&scopes,
+		&code.Challenge,
+		&code.Nonce,
+		&code.ExpiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	rows.Close()
+	code.Scopes = splitList(scopes)
+
+	trx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	affected, err := execTx(ctx, trx, sq.Delete(`oauth_code`).Where(`code_hash = ?`, codeHash))
+	if err != nil {
+		trx.Rollback()
+		return nil, err
+	}
+
+	if err := trx.Commit(); err != nil {
+		return nil, err
+	}
+
+	if affected != 1 || !code.ExpiresAt.After(time.Now()) {
+		return new(entity.AuthorizationCode), nil
+	}
+
+	return code, nil
+}
+
+func unmarshalClients(rows *sql.Rows) ([]*entity.Client, error) {
+	results := []*entity.Client{}
+
+	for rows.Next() {
+		var cl entity.Client
+		var redirectURIs, scopes, grantTypes string
+
+		err := rows.Scan(
+			&cl.ID,
+			&cl.ClientID,
+			&cl.SecretHash,
+			&cl.Name,
+			&redirectURIs,
+			&scopes,
+			&grantTypes,
+			&cl.Public,
+			&cl.CreatedAt,
+		)
+
+		if err != nil {
+			logrus.Error(err, cl.ID)
+			return nil, err
+		}
+
+		cl.RedirectURIs = splitList(redirectURIs)
+		cl.Scopes = splitList(scopes)
+		cl.GrantTypes = splitList(grantTypes)
+
+		results = append(results, &cl)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// execTx runs a write statement inside trx and returns the number of
+// affected rows
+func execTx(ctx context.Context, trx *sql.Tx, query sq.Sqlizer) (int64, error) {
+	sql, args, _ := query.ToSql()
+	stmt, err := trx.PrepareContext(ctx, sql)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// joinList stores a list space separated as OAuth does for scopes, which
+// also suits redirect URIs since they cannot contain spaces
+func joinList(list []string) string {
+	return strings.Join(list, ` `)
+}
+
+func splitList(s string) []string {
+	return strings.Fields(s)
+}
@@ -0,0 +1,326 @@
+package mysql_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/andhikagama/lmnlo/authserver/repository/mysql"
+	"github.com/andhikagama/lmnlo/models/entity"
+)
+
+var clientColumns = []string{
+	`id`, `client_id`, `secret_hash`, `name`, `redirect_uris`, `scopes`, `grant_types`, `public`, `create_time`,
+}
+
+var codeColumns = []string{
+	`code_hash`, `client_id`, `user_id`, `redirect_uri`, `scopes`, `challenge`, `nonce`, `expire_time`,
+}
+
+func TestStoreClient(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`INSERT INTO oauth_client`).
+			ExpectExec().
+			WithArgs(`abc`, `hash`, `Reports`, `https://a.example.com/cb https://b.example.com/cb`, `openid email`, `authorization_code`, false, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(4, 1))
+		mock.ExpectCommit()
+
+		cl := &entity.Client{
+			ClientID:     `abc`,
+			SecretHash:   `hash`,
+			Name:         `Reports`,
+			RedirectURIs: []string{`https://a.example.com/cb`, `https://b.example.com/cb`},
+			Scopes:       []string{`openid`, `email`},
+			GrantTypes:   []string{`authorization_code`},
+		}
+		repo := mysql.NewAuthServerRepository(db)
+		err := repo.StoreClient(context.TODO(), cl)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(4), cl.ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("error-exec", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`INSERT INTO oauth_client`).ExpectExec().WillReturnError(fmt.Errorf("Some error"))
+		mock.ExpectRollback()
+
+		repo := mysql.NewAuthServerRepository(db)
+		err := repo.StoreClient(context.TODO(), &entity.Client{ClientID: `abc`})
+
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestGetClient(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		rows := sqlmock.NewRows(clientColumns).
+			AddRow(4, `abc`, `hash`, `Reports`, `https://a.example.com/cb`, `openid email`, `authorization_code client_credentials`, true, time.Now())
+
+		mock.ExpectQuery(`SELECT (.+) FROM oauth_client WHERE client_id = \?`).
+			WithArgs(`abc`).
+			WillReturnRows(rows)
+
+		repo := mysql.NewAuthServerRepository(db)
+		cl, err := repo.GetClient(context.TODO(), `abc`)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(4), cl.ID)
+		assert.Equal(t, []string{`https://a.example.com/cb`}, cl.RedirectURIs)
+		assert.Equal(t, []string{`openid`, `email`}, cl.Scopes)
+		assert.Equal(t, []string{`authorization_code`, `client_credentials`}, cl.GrantTypes)
+		assert.True(t, cl.Public)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("not-found", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT (.+) FROM oauth_client`).WillReturnRows(sqlmock.NewRows(clientColumns))
+
+		repo := mysql.NewAuthServerRepository(db)
+		cl, err := repo.GetClient(context.TODO(), `abc`)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), cl.ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("error", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT (.+) FROM oauth_client`).WillReturnError(fmt.Errorf("Some error"))
+
+		repo := mysql.NewAuthServerRepository(db)
+		_, err := repo.GetClient(context.TODO(), `abc`)
+
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestFetchClients(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	rows := sqlmock.NewRows(clientColumns).
+		AddRow(5, `def`, ``, `Batch`, ``, `reports:read`, `client_credentials`, false, time.Now()).
+		AddRow(4, `abc`, `hash`, `Reports`, `https://a.example.com/cb`, `openid`, `authorization_code`, true, time.Now())
+
+	mock.ExpectQuery(`SELECT (.+) FROM oauth_client ORDER BY id DESC`).WillReturnRows(rows)
+
+	repo := mysql.NewAuthServerRepository(db)
+	clients, err := repo.FetchClients(context.TODO())
+
+	assert.NoError(t, err)
+	assert.Len(t, clients, 2)
+	assert.Empty(t, clients[0].RedirectURIs)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteClient(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`DELETE FROM oauth_code WHERE client_id = \?`).
+			ExpectExec().
+			WithArgs(`abc`).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectPrepare(`DELETE FROM oauth_client WHERE client_id = \?`).
+			ExpectExec().
+			WithArgs(`abc`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		repo := mysql.NewAuthServerRepository(db)
+		ok, err := repo.DeleteClient(context.TODO(), `abc`)
+
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("not-found", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`DELETE FROM oauth_code`).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectPrepare(`DELETE FROM oauth_client`).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		repo := mysql.NewAuthServerRepository(db)
+		ok, err := repo.DeleteClient(context.TODO(), `abc`)
+
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("error-exec", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`DELETE FROM oauth_code`).ExpectExec().WillReturnError(fmt.Errorf("Some error"))
+		mock.ExpectRollback()
+
+		repo := mysql.NewAuthServerRepository(db)
+		_, err := repo.DeleteClient(context.TODO(), `abc`)
+
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestStoreCode(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		expires := time.Now().Add(time.Minute)
+
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`DELETE FROM oauth_code WHERE expire_time < \?`).
+			ExpectExec().
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectPrepare(`INSERT INTO oauth_code`).
+			ExpectExec().
+			WithArgs(`hash`, `abc`, 1, `https://a.example.com/cb`, `openid email`, `challenge`, `nonce`, expires).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		repo := mysql.NewAuthServerRepository(db)
+		err := repo.StoreCode(context.TODO(), &entity.AuthorizationCode{
+			CodeHash:    `hash`,
+			ClientID:    `abc`,
+			UserID:      1,
+			RedirectURI: `https://a.example.com/cb`,
+			Scopes:      []string{`openid`, `email`},
+			Challenge:   `challenge`,
+			Nonce:       `nonce`,
+			ExpiresAt:   expires,
+		})
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("error-exec", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`DELETE FROM oauth_code`).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectPrepare(`INSERT INTO oauth_code`).ExpectExec().WillReturnError(fmt.Errorf("Some error"))
+		mock.ExpectRollback()
+
+		repo := mysql.NewAuthServerRepository(db)
+		err := repo.StoreCode(context.TODO(), &entity.AuthorizationCode{CodeHash: `hash`})
+
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestUseCode(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT (.+) FROM oauth_code WHERE code_hash = \?`).
+			WithArgs(`hash`).
+			WillReturnRows(sqlmock.NewRows(codeColumns).
+				AddRow(`hash`, `abc`, 1, `https://a.example.com/cb`, `openid email`, `challenge`, `nonce`, time.Now().Add(time.Minute)))
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`DELETE FROM oauth_code WHERE code_hash = \?`).
+			ExpectExec().
+			WithArgs(`hash`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		repo := mysql.NewAuthServerRepository(db)
+		code, err := repo.UseCode(context.TODO(), `hash`)
+
+		assert.NoError(t, err)
+		assert.Equal(t, `abc`, code.ClientID)
+		assert.Equal(t, int64(1), code.UserID)
+		assert.Equal(t, []string{`openid`, `email`}, code.Scopes)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("not-found", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT (.+) FROM oauth_code`).WillReturnRows(sqlmock.NewRows(codeColumns))
+
+		repo := mysql.NewAuthServerRepository(db)
+		code, err := repo.UseCode(context.TODO(), `hash`)
+
+		assert.NoError(t, err)
+		assert.Equal(t, ``, code.ClientID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT (.+) FROM oauth_code`).
+			WillReturnRows(sqlmock.NewRows(codeColumns).
+				AddRow(`hash`, `abc`, 1, ``, `openid`, `challenge`, ``, time.Now().Add(-time.Second)))
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`DELETE FROM oauth_code`).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		repo := mysql.NewAuthServerRepository(db)
+		code, err := repo.UseCode(context.TODO(), `hash`)
+
+		assert.NoError(t, err)
+		assert.Equal(t, ``, code.ClientID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("redeemed-concurrently", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT (.+) FROM oauth_code`).
+			WillReturnRows(sqlmock.NewRows(codeColumns).
+				AddRow(`hash`, `abc`, 1, ``, `openid`, `challenge`, ``, time.Now().Add(time.Minute)))
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`DELETE FROM oauth_code`).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		repo := mysql.NewAuthServerRepository(db)
+		code, err := repo.UseCode(context.TODO(), `hash`)
+
+		assert.NoError(t, err)
+		assert.Equal(t, ``, code.ClientID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,577 @@
+package usecase
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+
+	"github.com/andhikagama/lmnlo/authserver"
+	"github.com/andhikagama/lmnlo/helper"
+	"github.com/andhikagama/lmnlo/keyring"
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/andhikagama/lmnlo/models/response"
+	"github.com/andhikagama/lmnlo/oidc"
+	"github.com/andhikagama/lmnlo/user"
+)
+
+const (
+	_ClientIDBytes     = 16
+	_ClientSecretBytes = 32
+	_CodeBytes         = 32
+	_TokenIDBytes      = 16
+)
+
+// Endpoints relative to the issuer, the handler registers them under /v1
+const (
+	_AuthorizePath = `/v1/oauth2/authorize`
+	_TokenPath     = `/v1/oauth2/token`
+	_UserinfoPath  = `/v1/oauth2/userinfo`
+	_JWKSPath      = `/.well-known/jwks.json`
+)
+
+// Options holds tunables of the authorization server
+type Options struct {
+	// Issuer is the public base URL of lmnlo, tokens are stamped with it
+	Issuer string
+	// LoginURL is the page that signs the user in and asks for consent,
+	// the authorization request is appended to it
+	LoginURL       string
+	CodeTTL        time.Duration
+	AccessTokenTTL time.Duration
+	IDTokenTTL     time.Duration
+}
+
+type authServerUsecase struct {
+	repo     authserver.Repository
+	userRepo user.Repository
+	keyRing  *keyring.KeyRing
+	opts     Options
+}
+
+// NewAuthServerUsecase ...
+func NewAuthServerUsecase(
+	r authserver.Repository,
+	ur user.Repository,
+	kr *keyring.KeyRing,
+	opts Options,
+) authserver.Usecase {
+	return &authServerUsecase{
+		repo:     r,
+		userRepo: ur,
+		keyRing:  kr,
+		opts:     opts,
+	}
+}
+
+// RegisterClient validates and stores cl, setting its ID and, for
+// confidential clients, the plain secret which is not retrievable later
+func (u *authServerUsecase) RegisterClient(ctx context.Context, cl *entity.Client) error {
+	if len(cl.GrantTypes) == 0 {
+		cl.GrantTypes = []string{entity.GrantAuthorizationCode}
+	}
+
+	if !validClient(cl) {
+		return response.ErrBadRequest
+	}
+
+	clientID, err := helper.GenerateRandomHex(_ClientIDBytes)
+	if err != nil {
+		return err
+	}
+	cl.ClientID = strings.ToLower(clientID)
+
+	if !cl.Public {
+		secret, err := helper.GenerateRandomHex(_ClientSecretBytes)
+		if err != nil {
+			return err
+		}
+		cl.Secret = strings.ToLower(secret)
+		cl.SecretHash = helper.HashToken(cl.Secret)
+	}
+
+	return u.repo.StoreClient(ctx, cl)
+}
+
+// FetchClients ...
+func (u *authServerUsecase) FetchClients(ctx context.Context) ([]*entity.Client, error) {
+	return u.repo.FetchClients(ctx)
+}
+
+// DeleteClient ...
+func (u *authServerUsecase) DeleteClient(ctx context.Context, clientID string) error {
+	ok, err := u.repo.DeleteClient(ctx, clientID)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return response.ErrNotFound
+	}
+
+	return nil
+}
+
+// Authorize checks an authorization request before the user signs in. It
+// return where to send the browser: the login page carrying the request,
+// or the client's redirect URI with an error. Requests whose client or
+// redirect URI can not be trusted fail with an error instead.
+func (u *authServerUsecase) Authorize(ctx context.Context, req *entity.AuthorizeRequest) (string, error) {
+	cl, redirectURI, err := u.authorizeClient(ctx, req)
+	if err != nil {
+		return ``, err
+	}
+
+	if _, err := checkAuthorize(cl, req); err != nil {
+		return errorRedirect(redirectURI, req.State, err), nil
+	}
+
+	return addQuery(u.opts.LoginURL, authorizeQuery(req)), nil
+}
+
+// Approve issues a code for the request on behalf of user uid and return
+// the client's redirect URI carrying it
+func (u *authServerUsecase) Approve(ctx context.Context, req *entity.AuthorizeRequest, uid int64) (string, error) {
+	cl, redirectURI, err := u.authorizeClient(ctx, req)
+	if err != nil {
+		return ``, err
+	}
+
+	scopes, err := checkAuthorize(cl, req)
+	if err != nil {
+		return errorRedirect(redirectURI, req.State, err), nil
+	}
+
+	code, err := helper.GenerateRandomHex(_CodeBytes)
+	if err != nil {
+		return ``, err
+	}
+
+	err = u.repo.StoreCode(ctx, &entity.AuthorizationCode{
+		CodeHash:    helper.HashToken(code),
+		ClientID:    cl.ClientID,
+		UserID:      uid,
+		RedirectURI: req.RedirectURI,
+		Scopes:      scopes,
+		Challenge:   req.CodeChallenge,
+		Nonce:       req.Nonce,
+		ExpiresAt:   time.Now().Add(u.opts.CodeTTL),
+	})
+	if err != nil {
+		return ``, err
+	}
+
+	q := url.Values{`code`: {code}}
+	if req.State != `` {
+		q.Set(`state`, req.State)
+	}
+
+	return addQuery(redirectURI, q), nil
+}
+
+// Token redeems an authorization code or client credentials for tokens
+func (u *authServerUsecase) Token(ctx context.Context, req *entity.TokenRequest) (*entity.TokenResponse, error) {
+	cl, err := u.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.GrantType == entity.GrantAuthorizationCode {
+		return u.redeemCode(ctx, cl, req)
+	}
+
+	if req.GrantType == entity.GrantClientCredentials {
+		return u.clientCredentials(cl, req)
+	}
+
+	return nil, &authserver.Error{Code: authserver.ErrorUnsupportedGrantType}
+}
+
+// UserInfo return the claims about the user an access token was issued for
+func (u *authServerUsecase) UserInfo(ctx context.Context, accessToken string) (map[string]interface{}, error) {
+	invalid := &authserver.Error{Code: authserver.ErrorInvalidToken}
+
+	// Tokens of clients are only signed with asymmetric keys, a shared
+	// secret of the ring may also sign lmnlo's own sessions
+	cc := new(entity.AccessClaims)
+	if _, err := u.keyRing.ParseAsymmetric(accessToken, cc); err != nil {
+		return nil, invalid
+	}
+
+	// Tokens of lmnlo's own sessions carry no client and client
+	// credentials never carry openid
+	scopes := strings.Fields(cc.Scope)
+	if cc.Issuer != u.opts.Issuer || cc.ClientID == `` || !contains(scopes, entity.ScopeOpenID) {
+		return nil, invalid
+	}
+
+	uid, err := strconv.ParseInt(cc.Subject, 10, 64)
+	if err != nil {
+		return nil, invalid
+	}
+
+	usr, err := u.userRepo.GetByID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	if usr.ID == 0 {
+		return nil, invalid
+	}
+
+	info := map[string]interface{}{
+		`sub`: cc.Subject,
+	}
+
+	if contains(scopes, entity.ScopeEmail) {
+		info[`email`] = usr.Email
+		info[`email_verified`] = usr.VerifiedAt != nil
+	}
+
+	return info, nil
+}
+
+// Metadata return the discovery document
+func (u *authServerUsecase) Metadata() *authserver.Metadata {
+	return &authserver.Metadata{
+		Issuer:                            u.opts.Issuer,
+		AuthorizationEndpoint:             u.opts.Issuer + _AuthorizePath,
+		TokenEndpoint:                     u.opts.Issuer + _TokenPath,
+		UserinfoEndpoint:                  u.opts.Issuer + _UserinfoPath,
+		JWKSURI:                           u.opts.Issuer + _JWKSPath,
+		ScopesSupported:                   []string{entity.ScopeOpenID, entity.ScopeEmail},
+		ResponseTypesSupported:            []string{`code`},
+		GrantTypesSupported:               []string{entity.GrantAuthorizationCode, entity.GrantClientCredentials},
+		SubjectTypesSupported:             []string{`public`},
+		IDTokenSigningAlgValuesSupported:  []string{u.keyRing.SigningAlg()},
+		TokenEndpointAuthMethodsSupported: []string{`client_secret_basic`, `client_secret_post`, `none`},
+		CodeChallengeMethodsSupported:     []string{`S256`},
+		ClaimsSupported:                   []string{`iss`, `sub`, `aud`, `exp`, `iat`, `nonce`, `email`, `email_verified`},
+	}
+}
+
+func (u *authServerUsecase) redeemCode(ctx context.Context, cl *entity.Client, req *entity.TokenRequest) (*entity.TokenResponse, error) {
+	invalid := &authserver.Error{Code: authserver.ErrorInvalidGrant}
+
+	if !contains(cl.GrantTypes, entity.GrantAuthorizationCode) {
+		return nil, &authserver.Error{Code: authserver.ErrorUnauthorizedClient}
+	}
+
+	code, err := u.repo.UseCode(ctx, helper.HashToken(req.Code))
+	if err != nil {
+		return nil, err
+	}
+
+	if code.ClientID == `` || code.ClientID != cl.ClientID || code.RedirectURI != req.RedirectURI {
+		return nil, invalid
+	}
+
+	challenge := oidc.Challenge(req.CodeVerifier)
+	if req.CodeVerifier == `` || subtle.ConstantTimeCompare([]byte(challenge), []byte(code.Challenge)) != 1 {
+		return nil, invalid
+	}
+
+	usr, err := u.userRepo.GetByID(ctx, code.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if usr.ID == 0 {
+		return nil, invalid
+	}
+
+	subject := strconv.FormatInt(usr.ID, 10)
+
+	res, err := u.accessToken(cl, subject, code.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	if contains(code.Scopes, entity.ScopeOpenID) {
+		res.IDToken, err = u.idToken(cl, usr, code)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+func (u *authServerUsecase) clientCredentials(cl *entity.Client, req *entity.TokenRequest) (*entity.TokenResponse, error) {
+	if cl.Public || !contains(cl.GrantTypes, entity.GrantClientCredentials) {
+		return nil, &authserver.Error{Code: authserver.ErrorUnauthorizedClient}
+	}
+
+	scopes, ok := grantedScopes(cl, req.Scope)
+	if !ok || contains(scopes, entity.ScopeOpenID) || contains(scopes, entity.ScopeEmail) {
+		return nil, &authserver.Error{Code: authserver.ErrorInvalidScope}
+	}
+
+	return u.accessToken(cl, cl.ClientID, scopes)
+}
+
+func (u *authServerUsecase) accessToken(cl *entity.Client, subject string, scopes []string) (*entity.TokenResponse, error) {
+	jti, err := helper.GenerateRandomHex(_TokenIDBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	scope := strings.Join(scopes, ` `)
+
+	cc := &entity.AccessClaims{
+		Scope:    scope,
+		ClientID: cl.ClientID,
+		StandardClaims: jwt.StandardClaims{
+			Id:        jti,
+			Issuer:    u.opts.Issuer,
+			Subject:   subject,
+			Audience:  cl.ClientID,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(u.opts.AccessTokenTTL).Unix(),
+		},
+	}
+
+	token, err := u.keyRing.Sign(cc)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.TokenResponse{
+		AccessToken: token,
+		TokenType:   `Bearer`,
+		ExpiresIn:   int64(u.opts.AccessTokenTTL / time.Second),
+		Scope:       scope,
+	}, nil
+}
+
+func (u *authServerUsecase) idToken(cl *entity.Client, usr *entity.User, code *entity.AuthorizationCode) (string, error) {
+	now := time.Now()
+
+	cc := &entity.IDClaims{
+		Nonce: code.Nonce,
+		StandardClaims: jwt.StandardClaims{
+			Issuer:    u.opts.Issuer,
+			Subject:   strconv.FormatInt(usr.ID, 10),
+			Audience:  cl.ClientID,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(u.opts.IDTokenTTL).Unix(),
+		},
+	}
+
+	if contains(code.Scopes, entity.ScopeEmail) {
+		verified := usr.VerifiedAt != nil
+		cc.Email = usr.Email
+		cc.EmailVerified = &verified
+	}
+
+	return u.keyRing.Sign(cc)
+}
+
+// authorizeClient return the client of an authorization request and the
+// redirect URI to answer on, which must be registered exactly. It may be
+// left out when the client registered only one.
+func (u *authServerUsecase) authorizeClient(ctx context.Context, req *entity.AuthorizeRequest) (*entity.Client, string, error) {
+	cl, err := u.repo.GetClient(ctx, req.ClientID)
+	if err != nil {
+		return nil, ``, err
+	}
+
+	if cl.ID == 0 {
+		return nil, ``, &authserver.Error{Code: authserver.ErrorInvalidClient}
+	}
+
+	if req.RedirectURI == `` && len(cl.RedirectURIs) == 1 {
+		return cl, cl.RedirectURIs[0], nil
+	}
+
+	if !contains(cl.RedirectURIs, req.RedirectURI) {
+		return nil, ``, &authserver.Error{
+			Code:        authserver.ErrorInvalidRequest,
+			Description: `redirect_uri is not registered`,
+		}
+	}
+
+	return cl, req.RedirectURI, nil
+}
+
+// authenticateClient checks the secret of confidential clients, public
+// clients are identified by their ID alone and rely on PKCE
+func (u *authServerUsecase) authenticateClient(ctx context.Context, clientID string, secret string) (*entity.Client, error) {
+	invalid := &authserver.Error{Code: authserver.ErrorInvalidClient}
+
+	if clientID == `` {
+		return nil, invalid
+	}
+
+	cl, err := u.repo.GetClient(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	if cl.ID == 0 {
+		return nil, invalid
+	}
+
+	if cl.Public {
+		return cl, nil
+	}
+
+	if secret == `` || subtle.ConstantTimeCompare([]byte(helper.HashToken(secret)), []byte(cl.SecretHash)) != 1 {
+		return nil, invalid
+	}
+
+	return cl, nil
+}
+
+// checkAuthorize validates the parameters of a request for cl and return
+// the scopes to grant. Only the code flow with an S256 challenge is
+// supported.
+func checkAuthorize(cl *entity.Client, req *entity.AuthorizeRequest) ([]string, error) {
+	if req.ResponseType != `code` {
+		return nil, &authserver.Error{Code: authserver.ErrorUnsupportedResponseType}
+	}
+
+	if !contains(cl.GrantTypes, entity.GrantAuthorizationCode) {
+		return nil, &authserver.Error{Code: authserver.ErrorUnauthorizedClient}
+	}
+
+	if req.CodeChallenge == `` || req.CodeChallengeMethod != `S256` {
+		return nil, &authserver.Error{
+			Code:        authserver.ErrorInvalidRequest,
+			Description: `code_challenge with method S256 is required`,
+		}
+	}
+
+	scopes, ok := grantedScopes(cl, req.Scope)
+	if !ok {
+		return nil, &authserver.Error{Code: authserver.ErrorInvalidScope}
+	}
+
+	return scopes, nil
+}
+
+// grantedScopes return the requested scopes, or every scope of cl when
+// none are requested. It reports false when a scope was not registered.
+func grantedScopes(cl *entity.Client, scope string) ([]string, bool) {
+	requested := strings.Fields(scope)
+	if len(requested) == 0 {
+		return cl.Scopes, true
+	}
+
+	for _, s := range requested {
+		if !contains(cl.Scopes, s) {
+			return nil, false
+		}
+	}
+
+	return requested, true
+}
+
+func validClient(cl *entity.Client) bool {
+	if strings.TrimSpace(cl.Name) == `` {
+		return false
+	}
+
+	for _, g := range cl.GrantTypes {
+		if g != entity.GrantAuthorizationCode && g != entity.GrantClientCredentials {
+			return false
+		}
+
+		// Public clients have no credentials of their own
+		if g == entity.GrantClientCredentials && cl.Public {
+			return false
+		}
+	}
+
+	if contains(cl.GrantTypes, entity.GrantAuthorizationCode) && len(cl.RedirectURIs) == 0 {
+		return false
+	}
+
+	for _, uri := range cl.RedirectURIs {
+		if !validRedirectURI(uri) {
+			return false
+		}
+	}
+
+	for _, s := range cl.Scopes {
+		if s == `` || strings.ContainsAny(s, " \t\n") {
+			return false
+		}
+	}
+
+	return true
+}
+
+// validRedirectURI accepts absolute URIs without fragment. Plain http is
+// only allowed to the loopback address, for native apps and development.
+func validRedirectURI(uri string) bool {
+	u, err := url.Parse(uri)
+	if err != nil || !u.IsAbs() || u.Fragment != `` || strings.ContainsAny(uri, " \t\n") {
+		return false
+	}
+
+	if u.Scheme == `http` {
+		host := u.Hostname()
+		return host == `localhost` || host == `127.0.0.1` || host == `::1`
+	}
+
+	return true
+}
+
+func errorRedirect(redirectURI string, state string, err error) string {
+	q := url.Values{}
+	if e, ok := err.(*authserver.Error); ok {
+		q.Set(`error`, e.Code)
+		if e.Description != `` {
+			q.Set(`error_description`, e.Description)
+		}
+	}
+
+	if state != `` {
+		q.Set(`state`, state)
+	}
+
+	return addQuery(redirectURI, q)
+}
+
+func authorizeQuery(req *entity.AuthorizeRequest) url.Values {
+	q := url.Values{}
+	set := func(key, value string) {
+		if value != `` {
+			q.Set(key, value)
+		}
+	}
+
+	set(`response_type`, req.ResponseType)
+	set(`client_id`, req.ClientID)
+	set(`redirect_uri`, req.RedirectURI)
+	set(`scope`, req.Scope)
+	set(`state`, req.State)
+	set(`nonce`, req.Nonce)
+	set(`code_challenge`, req.CodeChallenge)
+	set(`code_challenge_method`, req.CodeChallengeMethod)
+
+	return q
+}
+
+func addQuery(endpoint string, q url.Values) string {
+	if strings.Contains(endpoint, `?`) {
+		return endpoint + `&` + q.Encode()
+	}
+
+	return endpoint + `?` + q.Encode()
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
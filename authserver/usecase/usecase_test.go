@@ -0,0 +1,644 @@
+package usecase_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/andhikagama/lmnlo/authserver"
+	"github.com/andhikagama/lmnlo/authserver/mocks"
+	"github.com/andhikagama/lmnlo/authserver/usecase"
+	"github.com/andhikagama/lmnlo/helper"
+	"github.com/andhikagama/lmnlo/keyring"
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/andhikagama/lmnlo/models/response"
+	"github.com/andhikagama/lmnlo/oidc"
+	userMocks "github.com/andhikagama/lmnlo/user/mocks"
+)
+
+var mockOptions = usecase.Options{
+	Issuer:         `https://auth.example.com`,
+	LoginURL:       `https://app.example.com/login`,
+	CodeTTL:        time.Minute,
+	AccessTokenTTL: time.Hour,
+	IDTokenTTL:     time.Hour,
+}
+
+var mockClient = entity.Client{
+	ID:           4,
+	ClientID:     `abc`,
+	SecretHash:   helper.HashToken(`secret`),
+	Name:         `Reports`,
+	RedirectURIs: []string{`https://reports.example.com/cb`},
+	Scopes:       []string{`openid`, `email`, `reports:read`},
+	GrantTypes:   []string{entity.GrantAuthorizationCode, entity.GrantClientCredentials},
+}
+
+const mockVerifier = `dBjftJeZ4CVP-mB92K9uzVLIhjmJZcfORW-8VPSgw9k`
+
+func newKeyRing(t *testing.T) *keyring.KeyRing {
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	key, err := keyring.NewPrivateKey(`es`, private)
+	require.NoError(t, err)
+
+	kr, err := keyring.NewKeyRing(`es`, key)
+	require.NoError(t, err)
+
+	return kr
+}
+
+func mockRequest() *entity.AuthorizeRequest {
+	return &entity.AuthorizeRequest{
+		ResponseType:        `code`,
+		ClientID:            `abc`,
+		RedirectURI:         `https://reports.example.com/cb`,
+		Scope:               `openid email`,
+		State:               `xyz`,
+		Nonce:               `n-0S6`,
+		CodeChallenge:       oidc.Challenge(mockVerifier),
+		CodeChallengeMethod: `S256`,
+	}
+}
+
+func clientFor(cl entity.Client) *entity.Client {
+	return &cl
+}
+
+func TestRegisterClient(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		mockRepo := new(mocks.Repository)
+		mockRepo.On("StoreClient", mock.Anything, mock.AnythingOfType("*entity.Client")).Return(nil).Once()
+		u := usecase.NewAuthServerUsecase(mockRepo, new(userMocks.Repository), newKeyRing(t), mockOptions)
+
+		cl := &entity.Client{
+			Name:         `Reports`,
+			RedirectURIs: []string{`https://reports.example.com/cb`, `http://localhost:8080/cb`},
+			Scopes:       []string{`openid`},
+		}
+		err := u.RegisterClient(context.TODO(), cl)
+
+		assert.NoError(t, err)
+		assert.Len(t, cl.ClientID, 32)
+		assert.Len(t, cl.Secret, 64)
+		assert.Equal(t, helper.HashToken(cl.Secret), cl.SecretHash)
+		assert.Equal(t, []string{entity.GrantAuthorizationCode}, cl.GrantTypes)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("success-public", func(t *testing.T) {
+		mockRepo := new(mocks.Repository)
+		mockRepo.On("StoreClient", mock.Anything, mock.AnythingOfType("*entity.Client")).Return(nil).Once()
+		u := usecase.NewAuthServerUsecase(mockRepo, new(userMocks.Repository), newKeyRing(t), mockOptions)
+
+		cl := &entity.Client{Name: `Mobile`, RedirectURIs: []string{`com.example.app:/cb`}, Public: true}
+		err := u.RegisterClient(context.TODO(), cl)
+
+		assert.NoError(t, err)
+		assert.Empty(t, cl.Secret)
+		assert.Empty(t, cl.SecretHash)
+		mockRepo.AssertExpectations(t)
+	})
+
+	invalid := []struct {
+		name   string
+		client *entity.Client
+	}{
+		{`no-name`, &entity.Client{RedirectURIs: []string{`https://a.example.com/cb`}}},
+		{`no-redirect`, &entity.Client{Name: `Reports`}},
+		{`relative-redirect`, &entity.Client{Name: `Reports`, RedirectURIs: []string{`/cb`}}},
+		{`fragment-redirect`, &entity.Client{Name: `Reports`, RedirectURIs: []string{`https://a.example.com/cb#x`}}},
+		{`plain-http-redirect`, &entity.Client{Name: `Reports`, RedirectURIs: []string{`http://a.example.com/cb`}}},
+		{`unknown-grant`, &entity.Client{Name: `Reports`, GrantTypes: []string{`password`}}},
+		{`public-client-credentials`, &entity.Client{Name: `Batch`, GrantTypes: []string{entity.GrantClientCredentials}, Public: true}},
+		{`scope-with-space`, &entity.Client{Name: `Batch`, GrantTypes: []string{entity.GrantClientCredentials}, Scopes: []string{`a b`}}},
+	}
+
+	for _, tc := range invalid {
+		t.Run(tc.name, func(t *testing.T) {
+			mockRepo := new(mocks.Repository)
+			u := usecase.NewAuthServerUsecase(mockRepo, new(userMocks.Repository), newKeyRing(t), mockOptions)
+
+			err := u.RegisterClient(context.TODO(), tc.client)
+
+			assert.Equal(t, response.ErrBadRequest, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDeleteClient(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		mockRepo := new(mocks.Repository)
+		mockRepo.On("DeleteClient", mock.Anything, `abc`).Return(true, nil).Once()
+		u := usecase.NewAuthServerUsecase(mockRepo, new(userMocks.Repository), newKeyRing(t), mockOptions)
+
+		assert.NoError(t, u.DeleteClient(context.TODO(), `abc`))
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("not-found", func(t *testing.T) {
+		mockRepo := new(mocks.Repository)
+		mockRepo.On("DeleteClient", mock.Anything, `abc`).Return(false, nil).Once()
+		u := usecase.NewAuthServerUsecase(mockRepo, new(userMocks.Repository), newKeyRing(t), mockOptions)
+
+		assert.Equal(t, response.ErrNotFound, u.DeleteClient(context.TODO(), `abc`))
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestAuthorize(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		mockRepo := new(mocks.Repository)
+		mockRepo.On("GetClient", mock.Anything, `abc`).Return(clientFor(mockClient), nil).Once()
+		u := usecase.NewAuthServerUsecase(mockRepo, new(userMocks.Repository), newKeyRing(t), mockOptions)
+
+		redirectTo, err := u.Authorize(context.TODO(), mockRequest())
+
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(redirectTo, mockOptions.LoginURL+`?`))
+
+		q, _ := url.ParseQuery(strings.SplitN(redirectTo, `?`, 2)[1])
+		assert.Equal(t, `abc`, q.Get(`client_id`))
+		assert.Equal(t, `xyz`, q.Get(`state`))
+		assert.Equal(t, oidc.Challenge(mockVerifier), q.Get(`code_challenge`))
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("unknown-client", func(t *testing.T) {
+		mockRepo := new(mocks.Repository)
+		mockRepo.On("GetClient", mock.Anything, `abc`).Return(new(entity.Client), nil).Once()
+		u := usecase.NewAuthServerUsecase(mockRepo, new(userMocks.Repository), newKeyRing(t), mockOptions)
+
+		_, err := u.Authorize(context.TODO(), mockRequest())
+
+		assert.Equal(t, &authserver.Error{Code: authserver.ErrorInvalidClient}, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("unregistered-redirect", func(t *testing.T) {
+		mockRepo := new(mocks.Repository)
+		mockRepo.On("GetClient", mock.Anything, `abc`).Return(clientFor(mockClient), nil).Once()
+		u := usecase.NewAuthServerUsecase(mockRepo, new(userMocks.Repository), newKeyRing(t), mockOptions)
+
+		req := mockRequest()
+		req.RedirectURI = `https://evil.example.com/cb`
+		_, err := u.Authorize(context.TODO(), req)
+
+		assert.Equal(t, authserver.ErrorInvalidRequest, err.(*authserver.Error).Code)
+		mockRepo.AssertExpectations(t)
+	})
+
+	redirected := []struct {
+		name   string
+		modify func(req *entity.AuthorizeRequest)
+		code   string
+	}{
+		{`token-response`, func(req *entity.AuthorizeRequest) { req.ResponseType = `token` }, authserver.ErrorUnsupportedResponseType},
+		{`no-challenge`, func(req *entity.AuthorizeRequest) { req.CodeChallenge = `` }, authserver.ErrorInvalidRequest},
+		{`plain-challenge`, func(req *entity.AuthorizeRequest) { req.CodeChallengeMethod = `plain` }, authserver.ErrorInvalidRequest},
+		{`unregistered-scope`, func(req *entity.AuthorizeRequest) { req.Scope = `openid admin` }, authserver.ErrorInvalidScope},
+	}
+
+	for _, tc := range redirected {
+		t.Run(tc.name, func(t *testing.T) {
+			mockRepo := new(mocks.Repository)
+			mockRepo.On("GetClient", mock.Anything, `abc`).Return(clientFor(mockClient), nil).Once()
+			u := usecase.NewAuthServerUsecase(mockRepo, new(userMocks.Repository), newKeyRing(t), mockOptions)
+
+			req := mockRequest()
+			req.RedirectURI = ``
+			tc.modify(req)
+			redirectTo, err := u.Authorize(context.TODO(), req)
+
+			assert.NoError(t, err)
+
+			callback, _ := url.Parse(redirectTo)
+			assert.Equal(t, `reports.example.com`, callback.Host)
+			assert.Equal(t, tc.code, callback.Query().Get(`error`))
+			assert.Equal(t, `xyz`, callback.Query().Get(`state`))
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+// approve runs Approve for user 1 and return the code sent to the client
+// with the record the repository was asked to store
+func approve(t *testing.T, u authserver.Usecase, mockRepo *mocks.Repository, req *entity.AuthorizeRequest) (string, *entity.AuthorizationCode) {
+	var stored *entity.AuthorizationCode
+	mockRepo.On("GetClient", mock.Anything, req.ClientID).Return(clientFor(mockClient), nil).Once()
+	mockRepo.On("StoreCode", mock.Anything, mock.AnythingOfType("*entity.AuthorizationCode")).
+		Run(func(args mock.Arguments) { stored = args.Get(1).(*entity.AuthorizationCode) }).
+		Return(nil).Once()
+
+	redirectTo, err := u.Approve(context.TODO(), req, 1)
+	require.NoError(t, err)
+
+	callback, err := url.Parse(redirectTo)
+	require.NoError(t, err)
+	assert.Equal(t, `xyz`, callback.Query().Get(`state`))
+
+	return callback.Query().Get(`code`), stored
+}
+
+func TestApprove(t *testing.T) {
+	mockRepo := new(mocks.Repository)
+	u := usecase.NewAuthServerUsecase(mockRepo, new(userMocks.Repository), newKeyRing(t), mockOptions)
+
+	code, stored := approve(t, u, mockRepo, mockRequest())
+
+	assert.NotEmpty(t, code)
+	assert.Equal(t, helper.HashToken(code), stored.CodeHash)
+	assert.Equal(t, int64(1), stored.UserID)
+	assert.Equal(t, []string{`openid`, `email`}, stored.Scopes)
+	assert.Equal(t, `n-0S6`, stored.Nonce)
+	assert.True(t, stored.ExpiresAt.After(time.Now()))
+	mockRepo.AssertExpectations(t)
+}
+
+func TestTokenAuthorizationCode(t *testing.T) {
+	verifiedAt := time.Now()
+	kr := newKeyRing(t)
+
+	t.Run("success", func(t *testing.T) {
+		mockRepo := new(mocks.Repository)
+		mockUserRepo := new(userMocks.Repository)
+		u := usecase.NewAuthServerUsecase(mockRepo, mockUserRepo, kr, mockOptions)
+		code, stored := approve(t, u, mockRepo, mockRequest())
+
+		mockRepo.On("GetClient", mock.Anything, `abc`).Return(clientFor(mockClient), nil).Once()
+		mockRepo.On("UseCode", mock.Anything, helper.HashToken(code)).Return(stored, nil).Once()
+		mockUserRepo.On("GetByID", mock.Anything, int64(1)).Return(&entity.User{ID: 1, Email: `andhika.gama@outlook.com`, VerifiedAt: &verifiedAt}, nil).Once()
+
+		res, err := u.Token(context.TODO(), &entity.TokenRequest{
+			GrantType:    entity.GrantAuthorizationCode,
+			Code:         code,
+			RedirectURI:  `https://reports.example.com/cb`,
+			CodeVerifier: mockVerifier,
+			ClientID:     `abc`,
+			ClientSecret: `secret`,
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, `Bearer`, res.TokenType)
+		assert.Equal(t, int64(3600), res.ExpiresIn)
+		assert.Equal(t, `openid email`, res.Scope)
+
+		access := new(entity.AccessClaims)
+		_, err = kr.Parse(res.AccessToken, access)
+		assert.NoError(t, err)
+		assert.Equal(t, `1`, access.Subject)
+		assert.Equal(t, `abc`, access.ClientID)
+		assert.Equal(t, mockOptions.Issuer, access.Issuer)
+		assert.NotEmpty(t, access.Id)
+
+		id := new(entity.IDClaims)
+		_, err = kr.Parse(res.IDToken, id)
+		assert.NoError(t, err)
+		assert.Equal(t, `1`, id.Subject)
+		assert.Equal(t, `abc`, id.Audience)
+		assert.Equal(t, `n-0S6`, id.Nonce)
+		assert.Equal(t, `andhika.gama@outlook.com`, id.Email)
+		assert.True(t, *id.EmailVerified)
+
+		mockRepo.AssertExpectations(t)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("success-without-openid", func(t *testing.T) {
+		mockRepo := new(mocks.Repository)
+		mockUserRepo := new(userMocks.Repository)
+		u := usecase.NewAuthServerUsecase(mockRepo, mockUserRepo, kr, mockOptions)
+
+		req := mockRequest()
+		req.Scope = `reports:read`
+		code, stored := approve(t, u, mockRepo, req)
+
+		mockRepo.On("GetClient", mock.Anything, `abc`).Return(clientFor(mockClient), nil).Once()
+		mockRepo.On("UseCode", mock.Anything, helper.HashToken(code)).Return(stored, nil).Once()
+		mockUserRepo.On("GetByID", mock.Anything, int64(1)).Return(&entity.User{ID: 1}, nil).Once()
+
+		res, err := u.Token(context.TODO(), &entity.TokenRequest{
+			GrantType:    entity.GrantAuthorizationCode,
+			Code:         code,
+			RedirectURI:  `https://reports.example.com/cb`,
+			CodeVerifier: mockVerifier,
+			ClientID:     `abc`,
+			ClientSecret: `secret`,
+		})
+
+		assert.NoError(t, err)
+		assert.Empty(t, res.IDToken)
+		mockRepo.AssertExpectations(t)
+	})
+
+	invalid := []struct {
+		name   string
+		modify func(req *entity.TokenRequest)
+	}{
+		{`wrong-verifier`, func(req *entity.TokenRequest) { req.CodeVerifier = `other` }},
+		{`no-verifier`, func(req *entity.TokenRequest) { req.CodeVerifier = `` }},
+		{`wrong-redirect`, func(req *entity.TokenRequest) { req.RedirectURI = `https://reports.example.com/other` }},
+	}
+
+	for _, tc := range invalid {
+		t.Run(tc.name, func(t *testing.T) {
+			mockRepo := new(mocks.Repository)
+			u := usecase.NewAuthServerUsecase(mockRepo, new(userMocks.Repository), kr, mockOptions)
+			code, stored := approve(t, u, mockRepo, mockRequest())
+
+			mockRepo.On("GetClient", mock.Anything, `abc`).Return(clientFor(mockClient), nil).Once()
+			mockRepo.On("UseCode", mock.Anything, helper.HashToken(code)).Return(stored, nil).Once()
+
+			req := &entity.TokenRequest{
+				GrantType:    entity.GrantAuthorizationCode,
+				Code:         code,
+				RedirectURI:  `https://reports.example.com/cb`,
+				CodeVerifier: mockVerifier,
+				ClientID:     `abc`,
+				ClientSecret: `secret`,
+			}
+			tc.modify(req)
+			_, err := u.Token(context.TODO(), req)
+
+			assert.Equal(t, &authserver.Error{Code: authserver.ErrorInvalidGrant}, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+
+	t.Run("code-of-other-client", func(t *testing.T) {
+		mockRepo := new(mocks.Repository)
+		other := mockClient
+		other.ClientID = `def`
+		mockRepo.On("GetClient", mock.Anything, `def`).Return(&other, nil).Once()
+		mockRepo.On("UseCode", mock.Anything, helper.HashToken(`code`)).Return(&entity.AuthorizationCode{ClientID: `abc`}, nil).Once()
+		u := usecase.NewAuthServerUsecase(mockRepo, new(userMocks.Repository), kr, mockOptions)
+
+		_, err := u.Token(context.TODO(), &entity.TokenRequest{
+			GrantType:    entity.GrantAuthorizationCode,
+			Code:         `code`,
+			CodeVerifier: mockVerifier,
+			ClientID:     `def`,
+			ClientSecret: `secret`,
+		})
+
+		assert.Equal(t, &authserver.Error{Code: authserver.ErrorInvalidGrant}, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("wrong-secret", func(t *testing.T) {
+		mockRepo := new(mocks.Repository)
+		mockRepo.On("GetClient", mock.Anything, `abc`).Return(clientFor(mockClient), nil).Once()
+		u := usecase.NewAuthServerUsecase(mockRepo, new(userMocks.Repository), kr, mockOptions)
+
+		_, err := u.Token(context.TODO(), &entity.TokenRequest{
+			GrantType:    entity.GrantAuthorizationCode,
+			Code:         `code`,
+			ClientID:     `abc`,
+			ClientSecret: `wrong`,
+		})
+
+		assert.Equal(t, &authserver.Error{Code: authserver.ErrorInvalidClient}, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("unsupported-grant", func(t *testing.T) {
+		mockRepo := new(mocks.Repository)
+		mockRepo.On("GetClient", mock.Anything, `abc`).Return(clientFor(mockClient), nil).Once()
+		u := usecase.NewAuthServerUsecase(mockRepo, new(userMocks.Repository), kr, mockOptions)
+
+		_, err := u.Token(context.TODO(), &entity.TokenRequest{
+			GrantType:    `password`,
+			ClientID:     `abc`,
+			ClientSecret: `secret`,
+		})
+
+		assert.Equal(t, &authserver.Error{Code: authserver.ErrorUnsupportedGrantType}, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		mockRepo := new(mocks.Repository)
+		mockRepo.On("GetClient", mock.Anything, `abc`).Return(clientFor(mockClient), nil).Once()
+		mockRepo.On("UseCode", mock.Anything, mock.AnythingOfType("string")).Return(nil, errors.New(`error`)).Once()
+		u := usecase.NewAuthServerUsecase(mockRepo, new(userMocks.Repository), kr, mockOptions)
+
+		_, err := u.Token(context.TODO(), &entity.TokenRequest{
+			GrantType:    entity.GrantAuthorizationCode,
+			Code:         `code`,
+			ClientID:     `abc`,
+			ClientSecret: `secret`,
+		})
+
+		assert.EqualError(t, err, `error`)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestTokenClientCredentials(t *testing.T) {
+	kr := newKeyRing(t)
+
+	t.Run("success", func(t *testing.T) {
+		mockRepo := new(mocks.Repository)
+		mockRepo.On("GetClient", mock.Anything, `abc`).Return(clientFor(mockClient), nil).Once()
+		u := usecase.NewAuthServerUsecase(mockRepo, new(userMocks.Repository), kr, mockOptions)
+
+		res, err := u.Token(context.TODO(), &entity.TokenRequest{
+			GrantType:    entity.GrantClientCredentials,
+			Scope:        `reports:read`,
+			ClientID:     `abc`,
+			ClientSecret: `secret`,
+		})
+
+		require.NoError(t, err)
+		assert.Empty(t, res.IDToken)
+
+		access := new(entity.AccessClaims)
+		_, err = kr.Parse(res.AccessToken, access)
+		assert.NoError(t, err)
+		assert.Equal(t, `abc`, access.Subject)
+		assert.Equal(t, `reports:read`, access.Scope)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("openid-scope", func(t *testing.T) {
+		mockRepo := new(mocks.Repository)
+		mockRepo.On("GetClient", mock.Anything, `abc`).Return(clientFor(mockClient), nil).Once()
+		u := usecase.NewAuthServerUsecase(mockRepo, new(userMocks.Repository), kr, mockOptions)
+
+		_, err := u.Token(context.TODO(), &entity.TokenRequest{
+			GrantType:    entity.GrantClientCredentials,
+			Scope:        `openid`,
+			ClientID:     `abc`,
+			ClientSecret: `secret`,
+		})
+
+		assert.Equal(t, &authserver.Error{Code: authserver.ErrorInvalidScope}, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("public-client", func(t *testing.T) {
+		public := mockClient
+		public.Public = true
+
+		mockRepo := new(mocks.Repository)
+		mockRepo.On("GetClient", mock.Anything, `abc`).Return(&public, nil).Once()
+		u := usecase.NewAuthServerUsecase(mockRepo, new(userMocks.Repository), kr, mockOptions)
+
+		_, err := u.Token(context.TODO(), &entity.TokenRequest{
+			GrantType: entity.GrantClientCredentials,
+			ClientID:  `abc`,
+		})
+
+		assert.Equal(t, &authserver.Error{Code: authserver.ErrorUnauthorizedClient}, err)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestUserInfo(t *testing.T) {
+	kr := newKeyRing(t)
+
+	token := func(scope string) string {
+		mockRepo := new(mocks.Repository)
+		mockRepo.On("GetClient", mock.Anything, `abc`).Return(clientFor(mockClient), nil).Once()
+		mockUserRepo := new(userMocks.Repository)
+		mockUserRepo.On("GetByID", mock.Anything, int64(1)).Return(&entity.User{ID: 1}, nil).Once()
+		u := usecase.NewAuthServerUsecase(mockRepo, mockUserRepo, kr, mockOptions)
+
+		req := mockRequest()
+		req.Scope = scope
+		code, stored := approve(t, u, mockRepo, req)
+		mockRepo.On("UseCode", mock.Anything, helper.HashToken(code)).Return(stored, nil).Once()
+
+		res, err := u.Token(context.TODO(), &entity.TokenRequest{
+			GrantType:    entity.GrantAuthorizationCode,
+			Code:         code,
+			RedirectURI:  req.RedirectURI,
+			CodeVerifier: mockVerifier,
+			ClientID:     `abc`,
+			ClientSecret: `secret`,
+		})
+		require.NoError(t, err)
+
+		return res.AccessToken
+	}
+
+	t.Run("success", func(t *testing.T) {
+		mockUserRepo := new(userMocks.Repository)
+		mockUserRepo.On("GetByID", mock.Anything, int64(1)).Return(&entity.User{ID: 1, Email: `andhika.gama@outlook.com`}, nil).Once()
+		u := usecase.NewAuthServerUsecase(new(mocks.Repository), mockUserRepo, kr, mockOptions)
+
+		info, err := u.UserInfo(context.TODO(), token(`openid email`))
+
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{
+			`sub`:            `1`,
+			`email`:          `andhika.gama@outlook.com`,
+			`email_verified`: false,
+		}, info)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("without-email-scope", func(t *testing.T) {
+		mockUserRepo := new(userMocks.Repository)
+		mockUserRepo.On("GetByID", mock.Anything, int64(1)).Return(&entity.User{ID: 1, Email: `andhika.gama@outlook.com`}, nil).Once()
+		u := usecase.NewAuthServerUsecase(new(mocks.Repository), mockUserRepo, kr, mockOptions)
+
+		info, err := u.UserInfo(context.TODO(), token(`openid`))
+
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{`sub`: `1`}, info)
+	})
+
+	t.Run("without-openid-scope", func(t *testing.T) {
+		u := usecase.NewAuthServerUsecase(new(mocks.Repository), new(userMocks.Repository), kr, mockOptions)
+
+		_, err := u.UserInfo(context.TODO(), token(`reports:read`))
+
+		assert.Equal(t, &authserver.Error{Code: authserver.ErrorInvalidToken}, err)
+	})
+
+	t.Run("session-token", func(t *testing.T) {
+		u := usecase.NewAuthServerUsecase(new(mocks.Repository), new(userMocks.Repository), kr, mockOptions)
+
+		session, _ := kr.Sign(&entity.Claims{StandardClaims: jwt.StandardClaims{Subject: `1`, ExpiresAt: time.Now().Add(time.Hour).Unix()}})
+		_, err := u.UserInfo(context.TODO(), session)
+
+		assert.Equal(t, &authserver.Error{Code: authserver.ErrorInvalidToken}, err)
+	})
+
+	t.Run("rotated-key", func(t *testing.T) {
+		issued := token(`openid`)
+
+		private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		next, err := keyring.NewPrivateKey(`es-next`, private)
+		require.NoError(t, err)
+		rotated, err := keyring.NewKeyRing(`es-next`, append(kr.Keys(), next)...)
+		require.NoError(t, err)
+
+		mockUserRepo := new(userMocks.Repository)
+		mockUserRepo.On("GetByID", mock.Anything, int64(1)).Return(&entity.User{ID: 1}, nil).Once()
+		u := usecase.NewAuthServerUsecase(new(mocks.Repository), mockUserRepo, rotated, mockOptions)
+
+		info, err := u.UserInfo(context.TODO(), issued)
+
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{`sub`: `1`}, info)
+	})
+
+	t.Run("other-key", func(t *testing.T) {
+		shared := keyring.NewHMACKey(`hs`, []byte(`shared`))
+		ring, err := keyring.NewKeyRing(`es`, append(kr.Keys(), shared)...)
+		require.NoError(t, err)
+		u := usecase.NewAuthServerUsecase(new(mocks.Repository), new(userMocks.Repository), ring, mockOptions)
+
+		forger, err := keyring.NewKeyRing(`hs`, shared)
+		require.NoError(t, err)
+		forged, _ := forger.Sign(&entity.AccessClaims{
+			Scope:    `openid email`,
+			ClientID: `abc`,
+			StandardClaims: jwt.StandardClaims{
+				Issuer:    mockOptions.Issuer,
+				Subject:   `1`,
+				ExpiresAt: time.Now().Add(time.Hour).Unix(),
+			},
+		})
+
+		_, err = u.UserInfo(context.TODO(), forged)
+
+		assert.Equal(t, &authserver.Error{Code: authserver.ErrorInvalidToken}, err)
+	})
+
+	t.Run("garbage", func(t *testing.T) {
+		u := usecase.NewAuthServerUsecase(new(mocks.Repository), new(userMocks.Repository), kr, mockOptions)
+
+		_, err := u.UserInfo(context.TODO(), `garbage`)
+
+		assert.Equal(t, &authserver.Error{Code: authserver.ErrorInvalidToken}, err)
+	})
+}
+
+func TestMetadata(t *testing.T) {
+	u := usecase.NewAuthServerUsecase(new(mocks.Repository), new(userMocks.Repository), newKeyRing(t), mockOptions)
+
+	meta := u.Metadata()
+
+	assert.Equal(t, mockOptions.Issuer, meta.Issuer)
+	assert.Equal(t, `https://auth.example.com/v1/oauth2/token`, meta.TokenEndpoint)
+	assert.Equal(t, `https://auth.example.com/.well-known/jwks.json`, meta.JWKSURI)
+	assert.Equal(t, []string{`ES256`}, meta.IDTokenSigningAlgValuesSupported)
+	assert.Equal(t, []string{`S256`}, meta.CodeChallengeMethodsSupported)
+}
@@ -2,7 +2,28 @@ package cmiddleware
 
 import "github.com/labstack/echo"
 
+// HeaderAPIKey carries the API key of a machine client
+const HeaderAPIKey = `X-API-Key`
+
+// Policy is the authentication requirement of a route
+type Policy int
+
+const (
+	// Authenticated routes need a valid access token, every route is
+	// authenticated unless declared otherwise
+	Authenticated Policy = iota
+	// Public routes are served without looking at credentials
+	Public
+)
+
 // Usecase ...
 type Usecase interface {
 	CheckAuthHeader(next echo.HandlerFunc) echo.HandlerFunc
+	CheckCSRF(next echo.HandlerFunc) echo.HandlerFunc
+	SetPolicy(route *echo.Route, policy Policy) *echo.Route
+	Policy(method, path string) Policy
+	RequirePermission(permission string) echo.MiddlewareFunc
+	OwnerOrAdmin(param string) echo.MiddlewareFunc
+	RequireSession(next echo.HandlerFunc) echo.HandlerFunc
+	DenyImpersonation(next echo.HandlerFunc) echo.HandlerFunc
 }
@@ -0,0 +1,147 @@
+package cmiddleware
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo"
+
+	"github.com/andhikagama/lmnlo/helper"
+	"github.com/andhikagama/lmnlo/models/entity"
+)
+
+const _CSRFTokenBytes = 16
+
+// SessionCookies is the cookie mode of browser clients. Login answers set
+// the access and refresh token as HttpOnly cookies instead of returning
+// them, and unsafe requests authenticated by cookie have to repeat the
+// CSRF cookie in a header.
+type SessionCookies struct {
+	// Name carries the access token, RefreshName the refresh token and
+	// CSRFName the token repeated in CSRFHeader
+	Name        string
+	RefreshName string
+	CSRFName    string
+	CSRFHeader  string
+	Domain      string
+	// RefreshPath limits the refresh cookie to the refresh endpoint
+	RefreshPath string
+	Secure      bool
+	SameSite    http.SameSite
+	// MaxAge is how long the refresh and CSRF cookie live, the refresh
+	// token lifetime
+	MaxAge time.Duration
+}
+
+// NewSessionCookies fills the names left empty in sc with their defaults
+func NewSessionCookies(sc SessionCookies) *SessionCookies {
+	if sc.Name == `` {
+		sc.Name = `lmnlo_session`
+	}
+
+	if sc.RefreshName == `` {
+		sc.RefreshName = `lmnlo_refresh`
+	}
+
+	if sc.CSRFName == `` {
+		sc.CSRFName = `lmnlo_csrf`
+	}
+
+	if sc.CSRFHeader == `` {
+		sc.CSRFHeader = `X-CSRF-Token`
+	}
+
+	if sc.RefreshPath == `` {
+		sc.RefreshPath = `/`
+	}
+
+	if sc.SameSite == 0 {
+		sc.SameSite = http.SameSiteStrictMode
+	}
+
+	return &sc
+}
+
+// ParseSameSite reads the SameSite attribute as written in config.json,
+// strict unless lax or none is named
+func ParseSameSite(s string) http.SameSite {
+	if strings.EqualFold(s, `lax`) {
+		return http.SameSiteLaxMode
+	}
+
+	if strings.EqualFold(s, `none`) {
+		return http.SameSiteNoneMode
+	}
+
+	return http.SameSiteStrictMode
+}
+
+// Set moves the tokens of usr into cookies together with a new CSRF token.
+// Answers without tokens, such as a second factor challenge, are left
+// alone.
+func (sc *SessionCookies) Set(c echo.Context, usr *entity.User) error {
+	if usr == nil || usr.Token == `` {
+		return nil
+	}
+
+	csrf, err := helper.GenerateRandomHex(_CSRFTokenBytes)
+	if err != nil {
+		return err
+	}
+
+	c.SetCookie(sc.cookie(sc.Name, usr.Token, `/`, time.Duration(usr.ExpiresIn)*time.Second, true))
+	c.SetCookie(sc.cookie(sc.RefreshName, usr.RefreshToken, sc.RefreshPath, sc.MaxAge, true))
+	// The frontend reads this one to send it back in CSRFHeader
+	c.SetCookie(sc.cookie(sc.CSRFName, csrf, `/`, sc.MaxAge, false))
+
+	usr.Token = ``
+	usr.RefreshToken = ``
+	return nil
+}
+
+// Clear expires every cookie Set wrote
+func (sc *SessionCookies) Clear(c echo.Context) {
+	c.SetCookie(sc.cookie(sc.Name, ``, `/`, -1, true))
+	c.SetCookie(sc.cookie(sc.RefreshName, ``, sc.RefreshPath, -1, true))
+	c.SetCookie(sc.cookie(sc.CSRFName, ``, `/`, -1, false))
+}
+
+// RefreshToken return the refresh token cookie of req
+func (sc *SessionCookies) RefreshToken(req *http.Request) string {
+	cookie, err := req.Cookie(sc.RefreshName)
+	if err != nil {
+		return ``
+	}
+
+	return cookie.Value
+}
+
+// HasSession reports whether req carries the access or refresh cookie
+func (sc *SessionCookies) HasSession(req *http.Request) bool {
+	for _, name := range []string{sc.Name, sc.RefreshName} {
+		if cookie, err := req.Cookie(name); err == nil && cookie.Value != `` {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (sc *SessionCookies) cookie(name string, value string, path string, maxAge time.Duration, httpOnly bool) *http.Cookie {
+	age := int(maxAge.Seconds())
+	if maxAge < 0 {
+		age = -1
+	}
+
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   sc.Domain,
+		MaxAge:   age,
+		HttpOnly: httpOnly,
+		Secure:   sc.Secure,
+		SameSite: sc.SameSite,
+	}
+}
@@ -0,0 +1,221 @@
+package cmiddleware
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+var (
+	// ErrMalformedCredential is a credential that can not be parsed
+	ErrMalformedCredential = errors.New(`malformed credential`)
+	// ErrMultipleCredentials is a request that presents more than one
+	// credential, which RFC 6750 forbids
+	ErrMultipleCredentials = errors.New(`more than one credential`)
+	// ErrUnsupportedScheme is an Authorization header of a scheme that is
+	// not understood
+	ErrUnsupportedScheme = errors.New(`unsupported authorization scheme`)
+)
+
+// Scheme is how a request presents its credential
+type Scheme int
+
+const (
+	// SchemeNone is a request without credential
+	SchemeNone Scheme = iota
+	// SchemeBearer carries a session access token
+	SchemeBearer
+	// SchemeAPIKey carries an API key
+	SchemeAPIKey
+	// SchemeBasic carries an API key as user name with an empty password,
+	// for service accounts whose tools only speak basic auth
+	SchemeBasic
+)
+
+// String return the name of s as used in Authorization headers
+func (s Scheme) String() string {
+	switch s {
+	case SchemeBearer:
+		return `Bearer`
+	case SchemeAPIKey:
+		return `ApiKey`
+	case SchemeBasic:
+		return `Basic`
+	}
+
+	return ``
+}
+
+// Credential is what a request authenticates with, a token for bearer and
+// an API key otherwise
+type Credential struct {
+	Scheme Scheme
+	Value  string
+}
+
+// CredentialOptions tell where credentials are looked for besides the
+// Authorization and X-API-Key headers
+type CredentialOptions struct {
+	// Realm is announced in WWW-Authenticate challenges
+	Realm string
+	// TokenCookie and TokenQuery name a cookie and a query parameter that
+	// carry the access token of websocket handshakes, whose headers
+	// browsers can not set. Empty disables them.
+	TokenCookie string
+	TokenQuery  string
+	// Cookies enables the session cookie of browser clients, nil in
+	// header mode
+	Cookies *SessionCookies
+}
+
+// b64token is the syntax of bearer tokens in RFC 6750 section 2.1
+var b64token = regexp.MustCompile(`^[A-Za-z0-9\-._~+/]+=*$`)
+
+// ExtractCredential finds the credential of req. A request without any
+// returns SchemeNone; presenting several is refused.
+func ExtractCredential(req *http.Request, opts CredentialOptions) (*Credential, error) {
+	found := []*Credential{}
+
+	if values := req.Header[http.CanonicalHeaderKey(`Authorization`)]; len(values) > 0 {
+		if len(values) > 1 {
+			return nil, ErrMultipleCredentials
+		}
+
+		cred, err := parseAuthorization(values[0])
+		if err != nil {
+			return nil, err
+		}
+		found = append(found, cred)
+	}
+
+	if values := req.Header[http.CanonicalHeaderKey(HeaderAPIKey)]; len(values) > 0 {
+		if len(values) > 1 || strings.TrimSpace(values[0]) == `` {
+			return nil, ErrMalformedCredential
+		}
+		found = append(found, &Credential{Scheme: SchemeAPIKey, Value: strings.TrimSpace(values[0])})
+	}
+
+	if isWebSocket(req) {
+		if opts.TokenCookie != `` {
+			if cookie, err := req.Cookie(opts.TokenCookie); err == nil {
+				found = append(found, &Credential{Scheme: SchemeBearer, Value: cookie.Value})
+			}
+		}
+
+		if opts.TokenQuery != `` {
+			if values, ok := req.URL.Query()[opts.TokenQuery]; ok {
+				if len(values) > 1 {
+					return nil, ErrMultipleCredentials
+				}
+				found = append(found, &Credential{Scheme: SchemeBearer, Value: values[0]})
+			}
+		}
+	}
+
+	// The browser sends the session cookie along with everything, so it
+	// only counts when nothing else was presented
+	if len(found) == 0 && opts.Cookies != nil {
+		if cookie, err := req.Cookie(opts.Cookies.Name); err == nil && cookie.Value != `` {
+			found = append(found, &Credential{Scheme: SchemeBearer, Value: cookie.Value})
+		}
+	}
+
+	if len(found) == 0 {
+		return &Credential{Scheme: SchemeNone}, nil
+	}
+
+	if len(found) > 1 {
+		return nil, ErrMultipleCredentials
+	}
+
+	cred := found[0]
+	if cred.Scheme == SchemeBearer && !b64token.MatchString(cred.Value) {
+		return nil, ErrMalformedCredential
+	}
+
+	return cred, nil
+}
+
+// parseAuthorization reads "<scheme> <credential>", the scheme is case
+// insensitive
+func parseAuthorization(header string) (*Credential, error) {
+	header = strings.TrimSpace(header)
+	if header == `` {
+		return nil, ErrMalformedCredential
+	}
+
+	parts := strings.SplitN(header, ` `, 2)
+	if len(parts) != 2 {
+		if schemeOf(parts[0]) == SchemeNone {
+			return nil, ErrUnsupportedScheme
+		}
+		return nil, ErrMalformedCredential
+	}
+
+	value := strings.TrimLeft(parts[1], ` `)
+	if value == `` || strings.ContainsAny(value, " \t") {
+		return nil, ErrMalformedCredential
+	}
+
+	scheme := schemeOf(parts[0])
+	switch scheme {
+	case SchemeNone:
+		return nil, ErrUnsupportedScheme
+	case SchemeBasic:
+		return parseBasic(value)
+	}
+
+	return &Credential{Scheme: scheme, Value: value}, nil
+}
+
+// parseBasic decodes basic credentials whose user name is an API key and
+// whose password is empty
+func parseBasic(value string) (*Credential, error) {
+	decoded, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrMalformedCredential
+	}
+
+	parts := strings.SplitN(string(decoded), `:`, 2)
+	if len(parts) != 2 || parts[0] == `` || parts[1] != `` {
+		return nil, ErrMalformedCredential
+	}
+
+	return &Credential{Scheme: SchemeBasic, Value: parts[0]}, nil
+}
+
+func schemeOf(name string) Scheme {
+	for _, s := range []Scheme{SchemeBearer, SchemeAPIKey, SchemeBasic} {
+		if strings.EqualFold(name, s.String()) {
+			return s
+		}
+	}
+
+	return SchemeNone
+}
+
+func isWebSocket(req *http.Request) bool {
+	return req.Method == http.MethodGet && strings.EqualFold(req.Header.Get(`Upgrade`), `websocket`)
+}
+
+// Challenge return the WWW-Authenticate value of RFC 6750 section 3 for
+// scheme, with an error code and description when they are not empty
+func Challenge(scheme Scheme, realm string, code string, description string) string {
+	if scheme == SchemeNone {
+		scheme = SchemeBearer
+	}
+
+	params := []string{fmt.Sprintf(`realm=%q`, realm)}
+	if code != `` {
+		params = append(params, fmt.Sprintf(`error=%q`, code))
+	}
+
+	if description != `` {
+		params = append(params, fmt.Sprintf(`error_description=%q`, description))
+	}
+
+	return scheme.String() + ` ` + strings.Join(params, `, `)
+}
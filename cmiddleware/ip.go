@@ -0,0 +1,113 @@
+package cmiddleware
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/labstack/echo"
+)
+
+// TrustProxies return a middleware resolving the address of the client of
+// every request for ClientIP. X-Forwarded-For and X-Real-IP are set by
+// anyone, so they are only read from the proxies listed, single addresses
+// or CIDR ranges; every other peer is the client itself.
+func TrustProxies(proxies []string) (echo.MiddlewareFunc, error) {
+	trusted := make([]*net.IPNet, 0, len(proxies))
+	for _, p := range proxies {
+		if !strings.Contains(p, `/`) {
+			ip := net.ParseIP(p)
+			if ip == nil {
+				return nil, fmt.Errorf(`invalid trusted proxy %q`, p)
+			}
+
+			bits := 8 * net.IPv4len
+			if ip.To4() == nil {
+				bits = 8 * net.IPv6len
+			}
+			p = fmt.Sprintf(`%s/%d`, p, bits)
+		}
+
+		_, network, err := net.ParseCIDR(p)
+		if err != nil {
+			return nil, fmt.Errorf(`invalid trusted proxy %q`, p)
+		}
+		trusted = append(trusted, network)
+	}
+
+	isTrusted := func(addr string) bool {
+		ip := net.ParseIP(addr)
+		if ip == nil {
+			return false
+		}
+
+		for _, network := range trusted {
+			if network.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ip := remoteIP(c)
+			if isTrusted(ip) {
+				ip = forwardedIP(c, ip, isTrusted)
+			}
+
+			c.Set(`client_ip`, ip)
+			return next(c)
+		}
+	}, nil
+}
+
+// ClientIP return the address of the client of c, as resolved by
+// TrustProxies or else the peer of the connection
+func ClientIP(c echo.Context) string {
+	if ip, ok := c.Get(`client_ip`).(string); ok {
+		return ip
+	}
+
+	return remoteIP(c)
+}
+
+func remoteIP(c echo.Context) string {
+	addr := c.Request().RemoteAddr
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+
+	return host
+}
+
+// forwardedIP return the client named by the trusted proxy peer. Each
+// proxy appends the address it was reached from to X-Forwarded-For, so it
+// is read from the right until an untrusted hop, the last one that can
+// be believed.
+func forwardedIP(c echo.Context, peer string, isTrusted func(string) bool) string {
+	header := c.Request().Header
+	if xff := header.Get(echo.HeaderXForwardedFor); xff != `` {
+		hops := strings.Split(xff, `,`)
+		ip := peer
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if net.ParseIP(hop) == nil {
+				break
+			}
+
+			ip = hop
+			if !isTrusted(hop) {
+				break
+			}
+		}
+		return ip
+	}
+
+	if realIP := strings.TrimSpace(header.Get(echo.HeaderXRealIP)); net.ParseIP(realIP) != nil {
+		return realIP
+	}
+
+	return peer
+}
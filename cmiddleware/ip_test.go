@@ -0,0 +1,75 @@
+package cmiddleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	cmware "github.com/andhikagama/lmnlo/cmiddleware"
+)
+
+func TestTrustProxies(t *testing.T) {
+	trustProxies, err := cmware.TrustProxies([]string{`10.0.0.1`, `172.16.0.0/12`, `fd00::1`})
+	require.NoError(t, err)
+
+	clientIP := func(remoteAddr string, header map[string]string) string {
+		req := httptest.NewRequest(http.MethodGet, `/`, nil)
+		req.RemoteAddr = remoteAddr
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+
+		ip := ``
+		c := echo.New().NewContext(req, httptest.NewRecorder())
+		err := trustProxies(func(c echo.Context) error {
+			ip = cmware.ClientIP(c)
+			return nil
+		})(c)
+		require.NoError(t, err)
+		return ip
+	}
+
+	cases := []struct {
+		name       string
+		remoteAddr string
+		header     map[string]string
+		expected   string
+	}{
+		{`direct`, `1.2.3.4:5555`, nil, `1.2.3.4`},
+		{`spoofed-forwarded-for`, `1.2.3.4:5555`, map[string]string{echo.HeaderXForwardedFor: `5.6.7.8`}, `1.2.3.4`},
+		{`spoofed-real-ip`, `1.2.3.4:5555`, map[string]string{echo.HeaderXRealIP: `5.6.7.8`}, `1.2.3.4`},
+		{`proxy-forwarded-for`, `10.0.0.1:5555`, map[string]string{echo.HeaderXForwardedFor: `5.6.7.8`}, `5.6.7.8`},
+		{`proxy-chain`, `10.0.0.1:5555`, map[string]string{echo.HeaderXForwardedFor: `9.9.9.9, 5.6.7.8, 172.16.3.4`}, `5.6.7.8`},
+		{`proxy-garbage`, `10.0.0.1:5555`, map[string]string{echo.HeaderXForwardedFor: `garbage, 172.16.3.4`}, `172.16.3.4`},
+		{`proxy-real-ip`, `172.20.0.9:5555`, map[string]string{echo.HeaderXRealIP: `5.6.7.8`}, `5.6.7.8`},
+		{`proxy-no-header`, `10.0.0.1:5555`, nil, `10.0.0.1`},
+		{`ipv6-proxy`, `[fd00::1]:5555`, map[string]string{echo.HeaderXForwardedFor: `2001:db8::7`}, `2001:db8::7`},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, clientIP(tc.remoteAddr, tc.header))
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := cmware.TrustProxies([]string{`proxy.lmnlo.test`})
+		assert.Error(t, err)
+
+		_, err = cmware.TrustProxies([]string{`10.0.0.0/33`})
+		assert.Error(t, err)
+	})
+}
+
+func TestClientIP(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, `/`, nil)
+	req.RemoteAddr = `1.2.3.4:5555`
+	req.Header.Set(echo.HeaderXRealIP, `5.6.7.8`)
+	c := echo.New().NewContext(req, httptest.NewRecorder())
+
+	assert.Equal(t, `1.2.3.4`, cmware.ClientIP(c))
+}
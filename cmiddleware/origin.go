@@ -0,0 +1,28 @@
+package cmiddleware
+
+import (
+	"github.com/labstack/echo"
+
+	"github.com/andhikagama/lmnlo/models/entity"
+)
+
+// Origin return who makes the request of c and from where: the
+// authenticated user, the admin impersonating them, the client address and
+// the request ID
+func Origin(c echo.Context) *entity.Origin {
+	o := &entity.Origin{
+		IP:        ClientIP(c),
+		RequestID: c.Response().Header().Get(echo.HeaderXRequestID),
+	}
+
+	if o.RequestID == `` {
+		o.RequestID = c.Request().Header.Get(echo.HeaderXRequestID)
+	}
+
+	if usr, ok := c.Get(`user`).(*entity.User); ok {
+		o.ActorID = usr.ID
+		o.ImpersonatorID = usr.ActorID
+	}
+
+	return o
+}
@@ -0,0 +1,39 @@
+package cmiddleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+
+	cmware "github.com/andhikagama/lmnlo/cmiddleware"
+	"github.com/andhikagama/lmnlo/models/entity"
+)
+
+func TestOrigin(t *testing.T) {
+	t.Run("authenticated", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPut, `/`, nil)
+		req.RemoteAddr = `10.0.0.1:5555`
+		rec := httptest.NewRecorder()
+		rec.Header().Set(echo.HeaderXRequestID, `request`)
+		c := echo.New().NewContext(req, rec)
+		c.Set(`user`, &entity.User{ID: 1, ActorID: 9})
+
+		o := cmware.Origin(c)
+
+		assert.Equal(t, &entity.Origin{ActorID: 1, ImpersonatorID: 9, IP: `10.0.0.1`, RequestID: `request`}, o)
+	})
+
+	t.Run("anonymous", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, `/`, nil)
+		req.Header.Set(echo.HeaderXRequestID, `client-request`)
+		c := echo.New().NewContext(req, httptest.NewRecorder())
+
+		o := cmware.Origin(c)
+
+		assert.Equal(t, int64(0), o.ActorID)
+		assert.Equal(t, `client-request`, o.RequestID)
+	})
+}
@@ -1,79 +1,273 @@
 package usecase
 
 import (
+	"crypto/subtle"
 	"net/http"
-	"strings"
+	"strconv"
+	"sync"
 
 	cmware "github.com/andhikagama/lmnlo/cmiddleware"
 	"github.com/andhikagama/lmnlo/helper"
+	"github.com/andhikagama/lmnlo/models/entity"
 	"github.com/andhikagama/lmnlo/models/response"
 	"github.com/andhikagama/lmnlo/user"
 	"github.com/labstack/echo"
+	log "github.com/sirupsen/logrus"
 )
 
+// Error codes of RFC 6750 section 3.1
 const (
-	token = `Authorization`
+	_ErrorInvalidRequest    = `invalid_request`
+	_ErrorInvalidToken      = `invalid_token`
+	_ErrorInsufficientScope = `insufficient_scope`
 )
 
 type cmwareUsecase struct {
-	userRepo user.Repository
+	userUsecase user.Usecase
+	opts        cmware.CredentialOptions
+
+	mu       sync.RWMutex
+	policies map[string]cmware.Policy
 }
 
 // NewMiddlewareUsecase ...
 func NewMiddlewareUsecase(
-	ar user.Repository,
+	au user.Usecase,
+	opts cmware.CredentialOptions,
 ) cmware.Usecase {
+	if opts.Realm == `` {
+		opts.Realm = `lmnlo`
+	}
+
 	return &cmwareUsecase{
-		ar,
+		userUsecase: au,
+		opts:        opts,
+		policies:    make(map[string]cmware.Policy),
 	}
 }
 
-// CheckAuthHeader ...
+// CheckAuthHeader authenticates the request with its access token or API
+// key, failures are answered with a WWW-Authenticate challenge
 func (cm *cmwareUsecase) CheckAuthHeader(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		token := ``
-		if c.Request().Header.Get(`Authorization`) != `` {
-			temp := strings.Split(c.Request().Header.Get(`Authorization`), ` `)
-			token = temp[1]
+		if cm.Policy(c.Request().Method, c.Path()) == cmware.Public {
+			return next(c)
+		}
+
+		cred, err := cmware.ExtractCredential(c.Request(), cm.opts)
+		if err == cmware.ErrUnsupportedScheme {
+			return cm.unauthorized(c, cmware.SchemeNone, ``, ``)
+		}
+
+		if err != nil {
+			c.Response().Header().Set(echo.HeaderWWWAuthenticate, cmware.Challenge(cmware.SchemeBearer, cm.opts.Realm, _ErrorInvalidRequest, err.Error()))
+			return c.JSON(http.StatusBadRequest, &response.Wrapper{
+				Message: response.ErrBadRequest.Error(),
+			})
+		}
+
+		if cred.Scheme == cmware.SchemeNone {
+			return cm.unauthorized(c, cmware.SchemeNone, ``, ``)
 		}
 
-		if skipper(c) {
+		if cred.Scheme != cmware.SchemeBearer {
+			return cm.checkAPIKey(c, cred, next)
+		}
+
+		usr, err := cm.userUsecase.AuthenticateToken(c.Request().Context(), cred.Value)
+		if err != nil {
+			if err != response.ErrUnAuthorized {
+				log.Error(err)
+			}
+
+			return cm.unauthorized(c, cred.Scheme, _ErrorInvalidToken, `the access token is invalid or expired`)
+		}
+
+		c.Set(`user`, usr)
+		c.Set(`token`, cred.Value)
+		if usr.ActorID != 0 {
+			impersonationLog(c, usr).Info(`impersonated request`)
+		}
+
+		return next(c)
+	}
+
+}
+
+// CheckCSRF protects unsafe requests of browsers in cookie mode with a
+// double submit token: the CSRF cookie has to be repeated in the CSRF
+// header, which another site can neither read nor set. Requests with an
+// Authorization or API key header do not rely on cookies and pass.
+func (cm *cmwareUsecase) CheckCSRF(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		sc := cm.opts.Cookies
+		req := c.Request()
+		if sc == nil || isSafeMethod(req.Method) {
+			return next(c)
+		}
+
+		if req.Header.Get(echo.HeaderAuthorization) != `` || req.Header.Get(cmware.HeaderAPIKey) != `` {
 			return next(c)
 		}
 
-		ok, err := cm.userRepo.ValidateToken(token)
-		if err != nil || !ok {
-			return c.JSON(http.StatusUnauthorized, &response.Wrapper{
-				Message: response.ErrUnAuthorized.Error(),
+		if !sc.HasSession(req) {
+			return next(c)
+		}
+
+		header := req.Header.Get(sc.CSRFHeader)
+		cookie, err := req.Cookie(sc.CSRFName)
+		if err != nil || header == `` || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(header)) != 1 {
+			return c.JSON(http.StatusForbidden, &response.Wrapper{
+				Message: response.ErrCSRF.Error(),
 			})
 		}
 
-		if ok {
-			usr, err := helper.ClaimTokenString(token)
-			if err != nil {
+		return next(c)
+	}
+}
+
+// checkAPIKey authenticates a request made with an API key. The key's
+// prefix is kept as api_key in place of a token.
+func (cm *cmwareUsecase) checkAPIKey(c echo.Context, cred *cmware.Credential, next echo.HandlerFunc) error {
+	usr, err := cm.userUsecase.AuthenticateAPIKey(c.Request().Context(), cred.Value)
+	if err != nil {
+		if err != response.ErrUnAuthorized {
+			log.Error(err)
+		}
+
+		return cm.unauthorized(c, cred.Scheme, _ErrorInvalidToken, `the API key is invalid or expired`)
+	}
+
+	prefix, _, _ := helper.SplitAPIKey(cred.Value)
+	c.Set(`user`, usr)
+	c.Set(`api_key`, prefix)
+	return next(c)
+}
+
+// unauthorized answers 401 with a challenge for scheme, code is left out
+// when the request carried no credential
+func (cm *cmwareUsecase) unauthorized(c echo.Context, scheme cmware.Scheme, code string, description string) error {
+	c.Response().Header().Set(echo.HeaderWWWAuthenticate, cmware.Challenge(scheme, cm.opts.Realm, code, description))
+	return c.JSON(http.StatusUnauthorized, &response.Wrapper{
+		Message: response.ErrUnAuthorized.Error(),
+	})
+}
+
+// RequireSession rejects requests made with an API key, for routes that
+// manage the account and its credentials
+func (cm *cmwareUsecase) RequireSession(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if _, ok := c.Get(`api_key`).(string); ok {
+			return c.JSON(http.StatusForbidden, &response.Wrapper{
+				Message: response.ErrForbidden.Error(),
+			})
+		}
+
+		return next(c)
+	}
+}
+
+// DenyImpersonation rejects requests made with an impersonation token, for
+// routes that change credentials, roles or the account itself
+func (cm *cmwareUsecase) DenyImpersonation(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if usr, ok := c.Get(`user`).(*entity.User); ok && usr.ActorID != 0 {
+			impersonationLog(c, usr).Warn(`impersonated request denied`)
+			return c.JSON(http.StatusForbidden, &response.Wrapper{
+				Message: response.ErrImpersonation.Error(),
+			})
+		}
+
+		return next(c)
+	}
+}
+
+// SetPolicy declares the authentication requirement of route. The route is
+// matched on its method and full path, so it applies whatever group or API
+// version it was registered under.
+func (cm *cmwareUsecase) SetPolicy(route *echo.Route, policy cmware.Policy) *echo.Route {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	cm.policies[policyKey(route.Method, route.Path)] = policy
+	return route
+}
+
+// Policy return the declared policy of a route, Authenticated when none
+func (cm *cmwareUsecase) Policy(method, path string) cmware.Policy {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	return cm.policies[policyKey(method, path)]
+}
+
+// RequirePermission rejects users none of whose roles grant permission
+func (cm *cmwareUsecase) RequirePermission(permission string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			usr, ok := c.Get(`user`).(*entity.User)
+			if !ok {
 				return c.JSON(http.StatusUnauthorized, &response.Wrapper{
 					Message: response.ErrUnAuthorized.Error(),
 				})
 			}
-			c.Set(`user`, usr)
+
+			if !usr.HasPermission(permission) {
+				scheme := cmware.SchemeBearer
+				if _, ok := c.Get(`api_key`).(string); ok {
+					scheme = cmware.SchemeAPIKey
+				}
+
+				c.Response().Header().Set(echo.HeaderWWWAuthenticate, cmware.Challenge(scheme, cm.opts.Realm, _ErrorInsufficientScope, `the request needs the `+permission+` permission`))
+				return c.JSON(http.StatusForbidden, &response.Wrapper{
+					Message: response.ErrForbidden.Error(),
+				})
+			}
+
 			return next(c)
 		}
+	}
+}
+
+// OwnerOrAdmin only lets through the user whose ID is in the path param
+// and admins
+func (cm *cmwareUsecase) OwnerOrAdmin(param string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			usr, ok := c.Get(`user`).(*entity.User)
+			if !ok {
+				return c.JSON(http.StatusUnauthorized, &response.Wrapper{
+					Message: response.ErrUnAuthorized.Error(),
+				})
+			}
+
+			id, _ := strconv.ParseInt(c.Param(param), 10, 64)
+			if usr.ID != id && !usr.HasRole(entity.RoleAdmin) {
+				return c.JSON(http.StatusForbidden, &response.Wrapper{
+					Message: response.ErrForbidden.Error(),
+				})
+			}
 
-		return c.JSON(http.StatusUnauthorized, &response.Wrapper{
-			Message: response.ErrUnAuthorized.Error(),
-		})
+			return next(c)
+		}
 	}
+}
 
+// impersonationLog records what a support admin does as usr
+func impersonationLog(c echo.Context, usr *entity.User) *log.Entry {
+	return log.WithFields(log.Fields{
+		`actor_id`: usr.ActorID,
+		`user_id`:  usr.ID,
+		`method`:   c.Request().Method,
+		`path`:     c.Request().URL.Path,
+		`ip`:       cmware.ClientIP(c),
+	})
 }
 
-func skipper(c echo.Context) bool {
-	path := c.Request().URL.Path
-	ver := `v1/`
-	realPath := strings.TrimLeft(path, ver)
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
 
-	switch realPath {
-	case `ping`, `login`, `register`:
-		return true
-	}
-	return false
+func policyKey(method, path string) string {
+	return method + ` ` + path
 }
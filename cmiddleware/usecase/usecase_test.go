@@ -0,0 +1,276 @@
+package usecase_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	cmware "github.com/andhikagama/lmnlo/cmiddleware"
+	cmwareUsecase "github.com/andhikagama/lmnlo/cmiddleware/usecase"
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/andhikagama/lmnlo/models/response"
+	"github.com/andhikagama/lmnlo/user/mocks"
+)
+
+func okHandler(c echo.Context) error {
+	return c.NoContent(http.StatusOK)
+}
+
+func TestRequirePermission(t *testing.T) {
+	cases := []struct {
+		name string
+		user *entity.User
+		code int
+	}{
+		{`granted`, &entity.User{ID: 1, Permissions: []string{entity.PermissionUserDelete}}, http.StatusOK},
+		{`denied`, &entity.User{ID: 1, Permissions: []string{entity.PermissionUserRead}}, http.StatusForbidden},
+		{`anonymous`, nil, http.StatusUnauthorized},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cm := cmwareUsecase.NewMiddlewareUsecase(new(mocks.Usecase), cmware.CredentialOptions{})
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.DELETE, "/", strings.NewReader(""))
+			rec := httptest.NewRecorder()
+
+			c := e.NewContext(req, rec)
+			if tc.user != nil {
+				c.Set(`user`, tc.user)
+			}
+
+			cm.RequirePermission(entity.PermissionUserDelete)(okHandler)(c)
+
+			assert.Equal(t, tc.code, rec.Code)
+			if tc.code == http.StatusForbidden {
+				assert.Contains(t, rec.Header().Get(echo.HeaderWWWAuthenticate), `error="insufficient_scope"`)
+			}
+		})
+	}
+}
+
+func TestOwnerOrAdmin(t *testing.T) {
+	cases := []struct {
+		name string
+		user *entity.User
+		id   string
+		code int
+	}{
+		{`owner`, &entity.User{ID: 1}, `1`, http.StatusOK},
+		{`admin`, &entity.User{ID: 2, Roles: []string{entity.RoleAdmin}}, `1`, http.StatusOK},
+		{`other`, &entity.User{ID: 2, Roles: []string{entity.RoleUser}}, `1`, http.StatusForbidden},
+		{`bad-param`, &entity.User{ID: 2}, `a`, http.StatusForbidden},
+		{`anonymous`, nil, `1`, http.StatusUnauthorized},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cm := cmwareUsecase.NewMiddlewareUsecase(new(mocks.Usecase), cmware.CredentialOptions{})
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.PUT, "/", strings.NewReader(""))
+			rec := httptest.NewRecorder()
+
+			c := e.NewContext(req, rec)
+			c.SetParamNames(`id`)
+			c.SetParamValues(tc.id)
+			if tc.user != nil {
+				c.Set(`user`, tc.user)
+			}
+
+			cm.OwnerOrAdmin(`id`)(okHandler)(c)
+
+			assert.Equal(t, tc.code, rec.Code)
+		})
+	}
+}
+
+func TestCheckAuthHeader(t *testing.T) {
+	mockUserUcase := new(mocks.Usecase)
+	cm := cmwareUsecase.NewMiddlewareUsecase(mockUserUcase, cmware.CredentialOptions{TokenQuery: `access_token`})
+
+	e := echo.New()
+	for _, prefix := range []string{`/v1`, `/v2`} {
+		g := e.Group(prefix)
+		g.Use(cm.CheckAuthHeader)
+
+		cm.SetPolicy(g.POST(`/login`, okHandler), cmware.Public)
+		g.GET(`/user/:id`, okHandler)
+		g.GET(`/vlogin`, okHandler)
+	}
+
+	mockUserUcase.On("AuthenticateToken", mock.Anything, `signed`).Return(&entity.User{ID: 1}, nil)
+	mockUserUcase.On("AuthenticateToken", mock.Anything, `deleted`).Return(nil, response.ErrUnAuthorized)
+	mockUserUcase.On("AuthenticateAPIKey", mock.Anything, `lmnlo_ABCD_SECRET`).Return(&entity.User{ID: 1}, nil)
+	mockUserUcase.On("AuthenticateAPIKey", mock.Anything, `lmnlo_ABCD_WRONG`).Return(nil, response.ErrUnAuthorized)
+
+	cases := []struct {
+		name      string
+		method    string
+		path      string
+		header    string
+		code      int
+		challenge string
+	}{
+		{`public`, echo.POST, `/v1/login`, ``, http.StatusOK, ``},
+		{`public-other-version`, echo.POST, `/v2/login`, ``, http.StatusOK, ``},
+		{`prefix-lookalike`, echo.GET, `/v1/vlogin`, ``, http.StatusUnauthorized, `Bearer realm="lmnlo"`},
+		{`authenticated`, echo.GET, `/v1/user/1`, ``, http.StatusUnauthorized, `Bearer realm="lmnlo"`},
+		{`malformed-header`, echo.GET, `/v1/user/1`, `Bearer`, http.StatusBadRequest, `Bearer realm="lmnlo", error="invalid_request", error_description="malformed credential"`},
+		{`unsupported-scheme`, echo.GET, `/v1/user/1`, `Digest username="a"`, http.StatusUnauthorized, `Bearer realm="lmnlo"`},
+		{`authenticated-with-token`, echo.GET, `/v1/user/1`, `Bearer signed`, http.StatusOK, ``},
+		{`token-in-query`, echo.GET, `/v1/user/1?access_token=signed`, ``, http.StatusUnauthorized, `Bearer realm="lmnlo"`},
+		{`user-gone`, echo.GET, `/v1/user/1`, `Bearer deleted`, http.StatusUnauthorized, `Bearer realm="lmnlo", error="invalid_token", error_description="the access token is invalid or expired"`},
+		{`unknown-route`, echo.GET, `/v1/nothing`, ``, http.StatusUnauthorized, `Bearer realm="lmnlo"`},
+		{`api-key-authorization`, echo.GET, `/v1/user/1`, `ApiKey lmnlo_ABCD_SECRET`, http.StatusOK, ``},
+		{`api-key-header`, echo.GET, `/v1/user/1`, `X-API-Key lmnlo_ABCD_SECRET`, http.StatusOK, ``},
+		{`api-key-basic`, echo.GET, `/v1/user/1`, `Basic bG1ubG9fQUJDRF9TRUNSRVQ6`, http.StatusOK, ``},
+		{`api-key-wrong`, echo.GET, `/v1/user/1`, `ApiKey lmnlo_ABCD_WRONG`, http.StatusUnauthorized, `ApiKey realm="lmnlo", error="invalid_token", error_description="the API key is invalid or expired"`},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(""))
+			if strings.HasPrefix(tc.header, `X-API-Key `) {
+				req.Header.Set(cmware.HeaderAPIKey, strings.TrimPrefix(tc.header, `X-API-Key `))
+			} else if tc.header != `` {
+				req.Header.Set(echo.HeaderAuthorization, tc.header)
+			}
+			rec := httptest.NewRecorder()
+
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.code, rec.Code)
+			assert.Equal(t, tc.challenge, rec.Header().Get(echo.HeaderWWWAuthenticate))
+		})
+	}
+
+	t.Run("websocket-query", func(t *testing.T) {
+		req := httptest.NewRequest(echo.GET, `/v1/user/1?access_token=signed`, nil)
+		req.Header.Set(`Connection`, `Upgrade`)
+		req.Header.Set(`Upgrade`, `websocket`)
+		rec := httptest.NewRecorder()
+
+		e.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+}
+
+func TestRequireSession(t *testing.T) {
+	cases := []struct {
+		name   string
+		apiKey interface{}
+		code   int
+	}{
+		{`session`, nil, http.StatusOK},
+		{`api-key`, `ABCD`, http.StatusForbidden},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cm := cmwareUsecase.NewMiddlewareUsecase(new(mocks.Usecase), cmware.CredentialOptions{})
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.POST, "/", strings.NewReader(""))
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.Set(`user`, &entity.User{ID: 1})
+			if tc.apiKey != nil {
+				c.Set(`api_key`, tc.apiKey)
+			}
+
+			cm.RequireSession(okHandler)(c)
+
+			assert.Equal(t, tc.code, rec.Code)
+		})
+	}
+}
+
+func TestCheckCSRF(t *testing.T) {
+	cases := []struct {
+		name    string
+		method  string
+		cookies bool
+		session bool
+		csrf    string
+		header  string
+		auth    string
+		code    int
+	}{
+		{`header-mode`, echo.POST, false, true, `abc`, ``, ``, http.StatusOK},
+		{`safe-method`, echo.GET, true, true, `abc`, ``, ``, http.StatusOK},
+		{`no-session`, echo.POST, true, false, ``, ``, ``, http.StatusOK},
+		{`authorization-header`, echo.POST, true, true, `abc`, ``, `Bearer signed`, http.StatusOK},
+		{`matching`, echo.POST, true, true, `abc`, `abc`, ``, http.StatusOK},
+		{`missing-header`, echo.DELETE, true, true, `abc`, ``, ``, http.StatusForbidden},
+		{`mismatch`, echo.POST, true, true, `abc`, `abd`, ``, http.StatusForbidden},
+		{`missing-cookie`, echo.POST, true, true, ``, `abc`, ``, http.StatusForbidden},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := cmware.CredentialOptions{}
+			if tc.cookies {
+				opts.Cookies = cmware.NewSessionCookies(cmware.SessionCookies{})
+			}
+			cm := cmwareUsecase.NewMiddlewareUsecase(new(mocks.Usecase), opts)
+
+			e := echo.New()
+			req := httptest.NewRequest(tc.method, "/", strings.NewReader(""))
+			if tc.session {
+				req.AddCookie(&http.Cookie{Name: `lmnlo_session`, Value: `signed`})
+			}
+
+			if tc.csrf != `` {
+				req.AddCookie(&http.Cookie{Name: `lmnlo_csrf`, Value: tc.csrf})
+			}
+
+			if tc.header != `` {
+				req.Header.Set(`X-CSRF-Token`, tc.header)
+			}
+
+			if tc.auth != `` {
+				req.Header.Set(echo.HeaderAuthorization, tc.auth)
+			}
+			rec := httptest.NewRecorder()
+
+			cm.CheckCSRF(okHandler)(e.NewContext(req, rec))
+
+			assert.Equal(t, tc.code, rec.Code)
+		})
+	}
+}
+
+func TestDenyImpersonation(t *testing.T) {
+	cases := []struct {
+		name string
+		user *entity.User
+		code int
+	}{
+		{`own-session`, &entity.User{ID: 1}, http.StatusOK},
+		{`impersonated`, &entity.User{ID: 1, ActorID: 9}, http.StatusForbidden},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cm := cmwareUsecase.NewMiddlewareUsecase(new(mocks.Usecase), cmware.CredentialOptions{})
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.POST, "/", strings.NewReader(""))
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.Set(`user`, tc.user)
+
+			cm.DenyImpersonation(okHandler)(c)
+
+			assert.Equal(t, tc.code, rec.Code)
+		})
+	}
+}
@@ -1,7 +1,9 @@
 package config
 
 import (
+	"os"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -12,6 +14,9 @@ type Config interface {
 	GetString(key string) string
 	GetInt(key string) int
 	GetBool(key string) bool
+	GetStringSlice(key string) []string
+	GetDuration(key string) time.Duration
+	UnmarshalKey(key string, rawVal interface{}) error
 }
 
 type viperConfig struct{}
@@ -23,8 +28,14 @@ func (v *viperConfig) Init() {
 	replacer := strings.NewReplacer(".", "_")
 	viper.SetEnvKeyReplacer(replacer)
 
+	// LMNLO_CONFIG picks another file, such as config.local.json
+	file := os.Getenv("LMNLO_CONFIG")
+	if file == "" {
+		file = "config.json"
+	}
+
 	viper.SetConfigType("json")
-	viper.SetConfigFile("config.json")
+	viper.SetConfigFile(file)
 
 	err := viper.ReadInConfig()
 	if err != nil {
@@ -44,6 +55,18 @@ func (v *viperConfig) GetBool(key string) bool {
 	return viper.GetBool(key)
 }
 
+func (v *viperConfig) GetStringSlice(key string) []string {
+	return viper.GetStringSlice(key)
+}
+
+func (v *viperConfig) GetDuration(key string) time.Duration {
+	return viper.GetDuration(key)
+}
+
+func (v *viperConfig) UnmarshalKey(key string, rawVal interface{}) error {
+	return viper.UnmarshalKey(key, rawVal)
+}
+
 // NewViperConfig return new viper config instance
 func NewViperConfig() Config {
 	v := &viperConfig{}
@@ -0,0 +1,38 @@
+package helper
+
+import "strings"
+
+const (
+	// APIKeyTag starts every API key so that leaked keys are easy to spot
+	APIKeyTag = `lmnlo`
+
+	_APIKeyPrefixBytes = 8
+	_APIKeySecretBytes = 32
+)
+
+// GenerateAPIKey return a new API key together with its parts. The prefix
+// is stored in clear to find the key, the secret only as a hash.
+func GenerateAPIKey() (key string, prefix string, secret string, err error) {
+	prefix, err = GenerateRandomHex(_APIKeyPrefixBytes)
+	if err != nil {
+		return ``, ``, ``, err
+	}
+
+	secret, err = GenerateRandomHex(_APIKeySecretBytes)
+	if err != nil {
+		return ``, ``, ``, err
+	}
+
+	return APIKeyTag + `_` + prefix + `_` + secret, prefix, secret, nil
+}
+
+// SplitAPIKey return the prefix and the secret of key, ok is false when key
+// is not shaped like an API key
+func SplitAPIKey(key string) (prefix string, secret string, ok bool) {
+	parts := strings.Split(key, `_`)
+	if len(parts) != 3 || parts[0] != APIKeyTag || parts[1] == `` || parts[2] == `` {
+		return ``, ``, false
+	}
+
+	return parts[1], parts[2], true
+}
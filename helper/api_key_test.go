@@ -0,0 +1,26 @@
+package helper_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/andhikagama/lmnlo/helper"
+)
+
+func TestAPIKey(t *testing.T) {
+	key, prefix, secret, err := helper.GenerateAPIKey()
+	assert.NoError(t, err)
+	assert.Len(t, prefix, 16)
+	assert.Len(t, secret, 64)
+
+	p, s, ok := helper.SplitAPIKey(key)
+	assert.True(t, ok)
+	assert.Equal(t, prefix, p)
+	assert.Equal(t, secret, s)
+
+	for _, bad := range []string{``, `lmnlo`, `lmnlo__secret`, `other_prefix_secret`, `lmnlo_a_b_c`} {
+		_, _, ok := helper.SplitAPIKey(bad)
+		assert.False(t, ok, bad)
+	}
+}
@@ -7,9 +7,6 @@ import (
 	"encoding/hex"
 	"strings"
 
-	"github.com/andhikagama/lmnlo/models/entity"
-	"github.com/dgrijalva/jwt-go"
-	"github.com/labstack/gommon/log"
 	"golang.org/x/crypto/blowfish"
 )
 
@@ -119,30 +116,3 @@ func GenerateRandomHex(n int) (string, error) {
 
 	return rndStr, nil
 }
-
-func GenerateTokenString(cc *entity.Claims) string {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, cc)
-	tokenstring, err := token.SignedString([]byte(_CipherKey))
-	if err != nil {
-		log.Error(err)
-	}
-	return tokenstring
-}
-
-func ClaimTokenString(tokenString string) (*entity.User, error) {
-	var usr *entity.User
-
-	token, err := jwt.ParseWithClaims(tokenString, &entity.Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(_CipherKey), nil
-	})
-
-	if err != nil {
-		return nil, err
-	}
-
-	if claims, ok := token.Claims.(*entity.Claims); ok && token.Valid {
-		usr = claims.User
-	}
-
-	return usr, nil
-}
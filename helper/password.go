@@ -0,0 +1,177 @@
+package helper
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	_Argon2idPrefix = `$argon2id$`
+	_BcryptPrefix   = `$2`
+	_SaltLength     = 16
+)
+
+// ErrInvalidHash is returned when a stored hash cannot be decoded
+var ErrInvalidHash = errors.New(`invalid password hash`)
+
+// PasswordHasher hashes and verifies user passwords
+type PasswordHasher interface {
+	// Hash returns an encoded hash of password
+	Hash(password string) (string, error)
+	// Verify reports whether password matches hashed. Any supported encoding
+	// is accepted, including legacy Blowfish ciphertexts.
+	Verify(hashed, password string) bool
+	// NeedsRehash reports whether hashed was produced by another algorithm
+	// or with different parameters than this hasher uses
+	NeedsRehash(hashed string) bool
+}
+
+// Argon2idParams tunes the argon2id key derivation
+type Argon2idParams struct {
+	Time      uint32
+	Memory    uint32
+	Threads   uint8
+	KeyLength uint32
+}
+
+type argon2idHasher struct {
+	params Argon2idParams
+}
+
+// NewArgon2idHasher return password hasher using argon2id
+func NewArgon2idHasher(p Argon2idParams) PasswordHasher {
+	return &argon2idHasher{p}
+}
+
+func (h *argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, _SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return ``, err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.params.Time, h.params.Memory, h.params.Threads, h.params.KeyLength)
+
+	return fmt.Sprintf(`%sv=%d$m=%d,t=%d,p=%d$%s$%s`,
+		_Argon2idPrefix,
+		argon2.Version,
+		h.params.Memory,
+		h.params.Time,
+		h.params.Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (h *argon2idHasher) Verify(hashed, password string) bool {
+	return verifyPassword(hashed, password)
+}
+
+func (h *argon2idHasher) NeedsRehash(hashed string) bool {
+	p, _, key, err := decodeArgon2id(hashed)
+	if err != nil {
+		return true
+	}
+
+	return p.Time != h.params.Time ||
+		p.Memory != h.params.Memory ||
+		p.Threads != h.params.Threads ||
+		uint32(len(key)) != h.params.KeyLength
+}
+
+type bcryptHasher struct {
+	cost int
+}
+
+// NewBcryptHasher return password hasher using bcrypt
+func NewBcryptHasher(cost int) PasswordHasher {
+	if cost < bcrypt.MinCost {
+		cost = bcrypt.DefaultCost
+	}
+
+	return &bcryptHasher{cost}
+}
+
+func (h *bcryptHasher) Hash(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	if err != nil {
+		return ``, err
+	}
+
+	return string(hashed), nil
+}
+
+func (h *bcryptHasher) Verify(hashed, password string) bool {
+	return verifyPassword(hashed, password)
+}
+
+func (h *bcryptHasher) NeedsRehash(hashed string) bool {
+	cost, err := bcrypt.Cost([]byte(hashed))
+	if err != nil {
+		return true
+	}
+
+	return cost != h.cost
+}
+
+func verifyPassword(hashed, password string) bool {
+	switch {
+	case strings.HasPrefix(hashed, _Argon2idPrefix):
+		p, salt, key, err := decodeArgon2id(hashed)
+		if err != nil {
+			return false
+		}
+
+		other := argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, uint32(len(key)))
+		return subtle.ConstantTimeCompare(key, other) == 1
+
+	case strings.HasPrefix(hashed, _BcryptPrefix):
+		return bcrypt.CompareHashAndPassword([]byte(hashed), []byte(password)) == nil
+
+	default:
+		// Legacy passwords were stored as Blowfish ciphertexts with a zero IV,
+		// so encrypting the candidate yields the same string on a match.
+		encrypted, err := EncryptToString(password)
+		if err != nil {
+			return false
+		}
+
+		return subtle.ConstantTimeCompare([]byte(hashed), []byte(encrypted)) == 1
+	}
+}
+
+func decodeArgon2id(hashed string) (*Argon2idParams, []byte, []byte, error) {
+	parts := strings.Split(hashed, `$`)
+	if len(parts) != 6 || parts[1] != `argon2id` {
+		return nil, nil, nil, ErrInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], `v=%d`, &version); err != nil || version != argon2.Version {
+		return nil, nil, nil, ErrInvalidHash
+	}
+
+	p := new(Argon2idParams)
+	if _, err := fmt.Sscanf(parts[3], `m=%d,t=%d,p=%d`, &p.Memory, &p.Time, &p.Threads); err != nil {
+		return nil, nil, nil, ErrInvalidHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, nil, nil, ErrInvalidHash
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return nil, nil, nil, ErrInvalidHash
+	}
+	p.KeyLength = uint32(len(key))
+
+	return p, salt, key, nil
+}
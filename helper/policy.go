@@ -0,0 +1,43 @@
+package helper
+
+import "unicode"
+
+// PasswordPolicy describes which passwords users may choose. The zero value
+// accepts any non-empty password.
+type PasswordPolicy struct {
+	MinLength     int  `mapstructure:"min_length"`
+	RequireUpper  bool `mapstructure:"require_upper"`
+	RequireLower  bool `mapstructure:"require_lower"`
+	RequireDigit  bool `mapstructure:"require_digit"`
+	RequireSymbol bool `mapstructure:"require_symbol"`
+}
+
+// Allows reports whether password satisfies the policy
+func (p PasswordPolicy) Allows(password string) bool {
+	if password == `` {
+		return false
+	}
+
+	var length int
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		length++
+
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+
+	return length >= p.MinLength &&
+		(upper || !p.RequireUpper) &&
+		(lower || !p.RequireLower) &&
+		(digit || !p.RequireDigit) &&
+		(symbol || !p.RequireSymbol)
+}
@@ -0,0 +1,13 @@
+package helper
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// HashToken return hex encoded SHA-256 digest of an opaque token, which is
+// what gets persisted instead of the token itself
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,97 @@
+package helper
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP parameters of RFC 6238 as understood by common authenticator apps
+const (
+	TOTPDigits = 6
+	TOTPPeriod = 30
+)
+
+const _TOTPSecretBytes = 20
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTPSecret return a random base32 encoded TOTP secret
+func GenerateTOTPSecret() (string, error) {
+	b := make([]byte, _TOTPSecretBytes)
+	if _, err := rand.Read(b); err != nil {
+		return ``, err
+	}
+
+	return totpEncoding.EncodeToString(b), nil
+}
+
+// TOTPStep return the time step t falls in
+func TOTPStep(t time.Time) int64 {
+	return t.Unix() / TOTPPeriod
+}
+
+// TOTPCode return the code of secret for time step
+func TOTPCode(secret string, step int64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return ``, err
+	}
+
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf(`%06d`, value%1000000), nil
+}
+
+// ValidateTOTP checks code against the time step of t and skew steps on
+// either side to allow for clock drift. It return the matching step so
+// callers can refuse a code that was already used.
+func ValidateTOTP(secret string, code string, t time.Time, skew int) (int64, bool) {
+	if len(code) != TOTPDigits {
+		return 0, false
+	}
+
+	now := TOTPStep(t)
+	for i := -skew; i <= skew; i++ {
+		step := now + int64(i)
+
+		expected, err := TOTPCode(secret, step)
+		if err != nil {
+			return 0, false
+		}
+
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+// TOTPURI return the otpauth URI authenticator apps read from a QR code
+func TOTPURI(issuer string, account string, secret string) string {
+	v := url.Values{}
+	v.Set(`secret`, secret)
+	v.Set(`issuer`, issuer)
+	v.Set(`algorithm`, `SHA1`)
+	v.Set(`digits`, fmt.Sprint(TOTPDigits))
+	v.Set(`period`, fmt.Sprint(TOTPPeriod))
+
+	label := url.PathEscape(issuer + `:` + account)
+	return `otpauth://totp/` + label + `?` + v.Encode()
+}
@@ -0,0 +1,86 @@
+package helper_test
+
+import (
+	"encoding/base32"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/andhikagama/lmnlo/helper"
+)
+
+// rfcSecret is the SHA1 seed of the RFC 6238 test vectors
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte(`12345678901234567890`))
+
+func TestTOTPCode(t *testing.T) {
+	// The RFC lists 8 digit codes, the last 6 digits are the 6 digit code
+	cases := []struct {
+		unix int64
+		code string
+	}{
+		{59, `287082`},
+		{1111111109, `081804`},
+		{1111111111, `050471`},
+		{1234567890, `005924`},
+		{2000000000, `279037`},
+		{20000000000, `353130`},
+	}
+
+	for _, tc := range cases {
+		code, err := helper.TOTPCode(rfcSecret, helper.TOTPStep(time.Unix(tc.unix, 0)))
+
+		assert.NoError(t, err)
+		assert.Equal(t, tc.code, code)
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	step := helper.TOTPStep(now)
+
+	previous, _ := helper.TOTPCode(rfcSecret, step-1)
+	stale, _ := helper.TOTPCode(rfcSecret, step-2)
+
+	t.Run("current", func(t *testing.T) {
+		matched, ok := helper.ValidateTOTP(rfcSecret, `081804`, now, 1)
+
+		assert.True(t, ok)
+		assert.Equal(t, step, matched)
+	})
+
+	t.Run("skew", func(t *testing.T) {
+		matched, ok := helper.ValidateTOTP(rfcSecret, previous, now, 1)
+
+		assert.True(t, ok)
+		assert.Equal(t, step-1, matched)
+	})
+
+	t.Run("stale", func(t *testing.T) {
+		_, ok := helper.ValidateTOTP(rfcSecret, stale, now, 1)
+
+		assert.False(t, ok)
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		_, ok := helper.ValidateTOTP(rfcSecret, `81804`, now, 1)
+
+		assert.False(t, ok)
+	})
+}
+
+func TestTOTPURI(t *testing.T) {
+	secret, err := helper.GenerateTOTPSecret()
+	assert.NoError(t, err)
+
+	uri, err := url.Parse(helper.TOTPURI(`lmnlo`, `andhika.gama@outlook.com`, secret))
+	assert.NoError(t, err)
+
+	assert.Equal(t, `otpauth`, uri.Scheme)
+	assert.Equal(t, `totp`, uri.Host)
+	assert.True(t, strings.HasPrefix(uri.Path, `/lmnlo:andhika.gama@outlook.com`))
+	assert.Equal(t, secret, uri.Query().Get(`secret`))
+	assert.Equal(t, `lmnlo`, uri.Query().Get(`issuer`))
+}
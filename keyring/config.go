@@ -0,0 +1,98 @@
+package keyring
+
+import (
+	"io/ioutil"
+
+	"github.com/andhikagama/lmnlo/config"
+)
+
+// KeyConfig describes one key of the ring in config.json
+type KeyConfig struct {
+	ID             string `mapstructure:"kid"`
+	Algorithm      string `mapstructure:"alg"`
+	Secret         string `mapstructure:"secret"`
+	PrivateKeyFile string `mapstructure:"private_key_file"`
+	PublicKeyFile  string `mapstructure:"public_key_file"`
+}
+
+// NewKeyRingFromConfig return key ring described by `jwt.signing_key` and
+// `jwt.keys`
+func NewKeyRingFromConfig(cfg config.Config) (*KeyRing, error) {
+	var keyConfigs []*KeyConfig
+	if err := cfg.UnmarshalKey(`jwt.keys`, &keyConfigs); err != nil {
+		return nil, err
+	}
+
+	keys := make([]*Key, 0, len(keyConfigs))
+	for _, kc := range keyConfigs {
+		k, err := kc.Key()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+
+	return NewKeyRing(cfg.GetString(`jwt.signing_key`), keys...)
+}
+
+// Key loads the key material referenced by the config
+func (kc *KeyConfig) Key() (*Key, error) {
+	if kc.Algorithm == `HS256` {
+		if kc.Secret == `` {
+			return nil, ErrInvalidKeyFormat
+		}
+		return NewHMACKey(kc.ID, []byte(kc.Secret)), nil
+	}
+
+	var (
+		k   *Key
+		err error
+	)
+
+	switch {
+	case kc.PrivateKeyFile != ``:
+		k, err = loadPrivateKey(kc.ID, kc.PrivateKeyFile)
+	case kc.PublicKeyFile != ``:
+		k, err = loadPublicKey(kc.ID, kc.PublicKeyFile)
+	default:
+		err = ErrInvalidKeyFormat
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if k.Method.Alg() != kc.Algorithm {
+		return nil, ErrUnsupportedAlg
+	}
+
+	return k, nil
+}
+
+func loadPrivateKey(kid, path string) (*Key, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	private, err := ParsePrivateKeyPEM(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewPrivateKey(kid, private)
+}
+
+func loadPublicKey(kid, path string) (*Key, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	public, err := ParsePublicKeyPEM(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewPublicKey(kid, public)
+}
@@ -0,0 +1,29 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/andhikagama/lmnlo/keyring"
+	"github.com/labstack/echo"
+)
+
+// KeyRingHTTPHandler ...
+type KeyRingHTTPHandler struct {
+	KeyRing *keyring.KeyRing
+}
+
+// NewKeyRingHTTPHandler ...
+func NewKeyRingHTTPHandler(e *echo.Echo, kr *keyring.KeyRing) {
+	handler := &KeyRingHTTPHandler{
+		KeyRing: kr,
+	}
+
+	e.GET(`/.well-known/jwks.json`, handler.JWKS)
+}
+
+// JWKS publishes the public signing keys so other services can verify
+// tokens without calling back to lmnlo
+func (h *KeyRingHTTPHandler) JWKS(c echo.Context) error {
+	c.Response().Header().Set(`Cache-Control`, `public, max-age=300`)
+	return c.JSON(http.StatusOK, h.KeyRing.JWKS())
+}
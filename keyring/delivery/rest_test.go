@@ -0,0 +1,51 @@
+package http_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/andhikagama/lmnlo/keyring"
+	handler "github.com/andhikagama/lmnlo/keyring/delivery"
+)
+
+func TestJWKS(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		_, edKey, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		eddsa, err := keyring.NewPrivateKey(`ed`, edKey)
+		require.NoError(t, err)
+
+		kr, err := keyring.NewKeyRing(`ed`, eddsa, keyring.NewHMACKey(`hs`, []byte(`secret`)))
+		require.NoError(t, err)
+
+		e := echo.New()
+		req := httptest.NewRequest(echo.GET, "/.well-known/jwks.json", strings.NewReader(""))
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+
+		handler := handler.KeyRingHTTPHandler{
+			KeyRing: kr,
+		}
+		handler.JWKS(c)
+
+		set := new(keyring.JWKS)
+		err = json.Unmarshal(rec.Body.Bytes(), set)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		require.Len(t, set.Keys, 1)
+		assert.Equal(t, `OKP`, set.Keys[0].KeyType)
+		assert.Equal(t, `EdDSA`, set.Keys[0].Algorithm)
+	})
+}
@@ -0,0 +1,107 @@
+package keyring
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// NewHMACKey return HS256 key using a shared secret
+func NewHMACKey(kid string, secret []byte) *Key {
+	return &Key{
+		ID:        kid,
+		Method:    jwt.SigningMethodHS256,
+		signKey:   secret,
+		verifyKey: secret,
+	}
+}
+
+// NewPrivateKey return signing key for an RSA (RS256), P-256 (ES256) or
+// Ed25519 (EdDSA) private key
+func NewPrivateKey(kid string, private crypto.Signer) (*Key, error) {
+	k, err := NewPublicKey(kid, private.Public())
+	if err != nil {
+		return nil, err
+	}
+	k.signKey = private
+
+	return k, nil
+}
+
+// NewPublicKey return verify-only key, used for keys that are retired from
+// signing but may still appear on outstanding tokens
+func NewPublicKey(kid string, public crypto.PublicKey) (*Key, error) {
+	k := &Key{
+		ID:        kid,
+		verifyKey: public,
+	}
+
+	switch pub := public.(type) {
+	case *rsa.PublicKey:
+		k.Method = jwt.SigningMethodRS256
+	case *ecdsa.PublicKey:
+		if pub.Curve != elliptic.P256() {
+			return nil, ErrUnsupportedAlg
+		}
+		k.Method = jwt.SigningMethodES256
+	case ed25519.PublicKey:
+		k.Method = SigningMethodEdDSA
+	default:
+		return nil, ErrUnsupportedAlg
+	}
+
+	return k, nil
+}
+
+// ParsePrivateKeyPEM decodes a PKCS#1, PKCS#8 or SEC 1 private key
+func ParsePrivateKeyPEM(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrInvalidKeyFormat
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		if signer, ok := key.(crypto.Signer); ok {
+			return signer, nil
+		}
+		return nil, ErrUnsupportedAlg
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	return nil, ErrInvalidKeyFormat
+}
+
+// ParsePublicKeyPEM decodes a PKIX or PKCS#1 public key or a certificate
+func ParsePublicKeyPEM(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrInvalidKeyFormat
+	}
+
+	if key, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+		return cert.PublicKey, nil
+	}
+
+	return nil, ErrInvalidKeyFormat
+}
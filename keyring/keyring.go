@@ -0,0 +1,148 @@
+package keyring
+
+import (
+	"errors"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+var (
+	ErrUnknownKey       = errors.New(`unknown signing key`)
+	ErrUnsupportedAlg   = errors.New(`unsupported signing algorithm`)
+	ErrNoSigningKey     = errors.New(`no signing key configured`)
+	ErrVerifyOnlyKey    = errors.New(`signing key has no private part`)
+	ErrInvalidKeyFormat = errors.New(`invalid key format`)
+)
+
+// Key is a single JWT signing or verification key
+type Key struct {
+	ID        string
+	Method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// CanSign reports whether the key holds private material
+func (k *Key) CanSign() bool {
+	return k.signKey != nil
+}
+
+// KeyRing signs tokens with one key and verifies them against all keys,
+// which lets a new key take over signing while tokens issued with the
+// previous one stay valid until they expire
+type KeyRing struct {
+	signing *Key
+	keys    map[string]*Key
+	order   []*Key
+}
+
+// NewKeyRing return key ring signing with the key identified by signingKID
+func NewKeyRing(signingKID string, keys ...*Key) (*KeyRing, error) {
+	kr := &KeyRing{
+		keys: make(map[string]*Key, len(keys)),
+	}
+
+	for _, k := range keys {
+		kr.keys[k.ID] = k
+		kr.order = append(kr.order, k)
+	}
+
+	signing, ok := kr.keys[signingKID]
+	if !ok {
+		return nil, ErrNoSigningKey
+	}
+
+	if !signing.CanSign() {
+		return nil, ErrVerifyOnlyKey
+	}
+	kr.signing = signing
+
+	return kr, nil
+}
+
+// Sign return signed token string stamped with the signing key ID
+func (kr *KeyRing) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(kr.signing.Method, claims)
+	token.Header[`kid`] = kr.signing.ID
+
+	return token.SignedString(kr.signing.signKey)
+}
+
+// SigningAlg return the algorithm new tokens are signed with
+func (kr *KeyRing) SigningAlg() string {
+	return kr.signing.Method.Alg()
+}
+
+// Parse verifies tokenString against the key named by its kid header and
+// decodes it into claims. Tokens without kid are tried against every key
+// of the same algorithm.
+func (kr *KeyRing) Parse(tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	return kr.parse(tokenString, claims, func(k *Key) bool { return true })
+}
+
+// ParseAsymmetric is Parse restricted to the keys of public key algorithms,
+// for tokens that others verify with the published keys. Keys retired from
+// signing still verify, tokens of shared secrets are refused.
+func (kr *KeyRing) ParseAsymmetric(tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	return kr.parse(tokenString, claims, func(k *Key) bool {
+		_, hmac := k.Method.(*jwt.SigningMethodHMAC)
+		return !hmac
+	})
+}
+
+func (kr *KeyRing) parse(tokenString string, claims jwt.Claims, allow func(k *Key) bool) (*jwt.Token, error) {
+	kid, alg, err := peekHeader(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := kr.order
+	if kid != `` {
+		k, ok := kr.keys[kid]
+		if !ok {
+			return nil, ErrUnknownKey
+		}
+		candidates = []*Key{k}
+	}
+
+	err = ErrUnknownKey
+	for _, k := range candidates {
+		if k.Method.Alg() != alg || !allow(k) {
+			continue
+		}
+
+		var token *jwt.Token
+		token, err = jwt.ParseWithClaims(tokenString, claims, keyFunc(k))
+		if err == nil && token.Valid {
+			return token, nil
+		}
+	}
+
+	return nil, err
+}
+
+// Keys return every key in the ring in configuration order
+func (kr *KeyRing) Keys() []*Key {
+	return kr.order
+}
+
+func keyFunc(k *Key) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		if token.Method.Alg() != k.Method.Alg() {
+			return nil, ErrUnsupportedAlg
+		}
+		return k.verifyKey, nil
+	}
+}
+
+func peekHeader(tokenString string) (string, string, error) {
+	token, _, err := new(jwt.Parser).ParseUnverified(tokenString, jwt.MapClaims{})
+	if err != nil {
+		return ``, ``, err
+	}
+
+	kid, _ := token.Header[`kid`].(string)
+	alg, _ := token.Header[`alg`].(string)
+
+	return kid, alg, nil
+}
@@ -0,0 +1,236 @@
+package keyring_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/andhikagama/lmnlo/keyring"
+)
+
+func mockClaims() *jwt.StandardClaims {
+	return &jwt.StandardClaims{
+		Subject:   `1`,
+		IssuedAt:  time.Now().Unix(),
+		ExpiresAt: time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func TestSignAndParse(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	rs256, err := keyring.NewPrivateKey(`rs`, rsaKey)
+	require.NoError(t, err)
+
+	es256, err := keyring.NewPrivateKey(`es`, ecKey)
+	require.NoError(t, err)
+
+	eddsa, err := keyring.NewPrivateKey(`ed`, edKey)
+	require.NoError(t, err)
+
+	keys := []*keyring.Key{
+		keyring.NewHMACKey(`hs`, []byte(`secret`)),
+		rs256,
+		es256,
+		eddsa,
+	}
+
+	for _, k := range keys {
+		t.Run(k.Method.Alg(), func(t *testing.T) {
+			kr, err := keyring.NewKeyRing(k.ID, keys...)
+			require.NoError(t, err)
+
+			token, err := kr.Sign(mockClaims())
+			require.NoError(t, err)
+
+			claims := new(jwt.StandardClaims)
+			parsed, err := kr.Parse(token, claims)
+
+			assert.NoError(t, err)
+			assert.Equal(t, k.ID, parsed.Header[`kid`])
+			assert.Equal(t, `1`, claims.Subject)
+		})
+	}
+}
+
+func TestRotation(t *testing.T) {
+	oldKey := keyring.NewHMACKey(`old`, []byte(`old-secret`))
+	newKey := keyring.NewHMACKey(`new`, []byte(`new-secret`))
+
+	before, err := keyring.NewKeyRing(`old`, oldKey)
+	require.NoError(t, err)
+
+	token, err := before.Sign(mockClaims())
+	require.NoError(t, err)
+
+	t.Run("old-token-still-valid", func(t *testing.T) {
+		after, err := keyring.NewKeyRing(`new`, newKey, oldKey)
+		require.NoError(t, err)
+
+		_, err = after.Parse(token, new(jwt.StandardClaims))
+		assert.NoError(t, err)
+	})
+
+	t.Run("removed-key", func(t *testing.T) {
+		after, err := keyring.NewKeyRing(`new`, newKey)
+		require.NoError(t, err)
+
+		_, err = after.Parse(token, new(jwt.StandardClaims))
+		assert.Equal(t, keyring.ErrUnknownKey, err)
+	})
+
+	t.Run("no-kid", func(t *testing.T) {
+		legacy, err := jwt.NewWithClaims(jwt.SigningMethodHS256, mockClaims()).SignedString([]byte(`old-secret`))
+		require.NoError(t, err)
+
+		after, err := keyring.NewKeyRing(`new`, newKey, oldKey)
+		require.NoError(t, err)
+
+		_, err = after.Parse(legacy, new(jwt.StandardClaims))
+		assert.NoError(t, err)
+	})
+}
+
+func TestParseAsymmetric(t *testing.T) {
+	oldEC, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	newEC, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	oldKey, err := keyring.NewPrivateKey(`old`, oldEC)
+	require.NoError(t, err)
+
+	newKey, err := keyring.NewPrivateKey(`new`, newEC)
+	require.NoError(t, err)
+
+	shared := keyring.NewHMACKey(`hs`, []byte(`shared`))
+
+	kr, err := keyring.NewKeyRing(`new`, newKey, oldKey, shared)
+	require.NoError(t, err)
+
+	t.Run("success", func(t *testing.T) {
+		token, err := kr.Sign(mockClaims())
+		require.NoError(t, err)
+
+		claims := new(jwt.StandardClaims)
+		_, err = kr.ParseAsymmetric(token, claims)
+
+		assert.NoError(t, err)
+		assert.Equal(t, `1`, claims.Subject)
+	})
+
+	t.Run("retired-key", func(t *testing.T) {
+		before, err := keyring.NewKeyRing(`old`, oldKey)
+		require.NoError(t, err)
+
+		token, err := before.Sign(mockClaims())
+		require.NoError(t, err)
+
+		_, err = kr.ParseAsymmetric(token, new(jwt.StandardClaims))
+		assert.NoError(t, err)
+	})
+
+	t.Run("shared-secret", func(t *testing.T) {
+		hs, err := keyring.NewKeyRing(`hs`, shared)
+		require.NoError(t, err)
+
+		token, err := hs.Sign(mockClaims())
+		require.NoError(t, err)
+
+		_, err = kr.ParseAsymmetric(token, new(jwt.StandardClaims))
+		assert.Equal(t, keyring.ErrUnknownKey, err)
+	})
+
+	t.Run("shared-secret-no-kid", func(t *testing.T) {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, mockClaims()).SignedString([]byte(`shared`))
+		require.NoError(t, err)
+
+		_, err = kr.ParseAsymmetric(token, new(jwt.StandardClaims))
+		assert.Equal(t, keyring.ErrUnknownKey, err)
+	})
+}
+
+func TestParseRejectsAlgorithmMismatch(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	rs256, err := keyring.NewPrivateKey(`rs`, rsaKey)
+	require.NoError(t, err)
+
+	kr, err := keyring.NewKeyRing(`rs`, rs256)
+	require.NoError(t, err)
+
+	// An HS256 token keyed with the public modulus must not verify
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, mockClaims())
+	forged.Header[`kid`] = `rs`
+	token, err := forged.SignedString(rsaKey.PublicKey.N.Bytes())
+	require.NoError(t, err)
+
+	_, err = kr.Parse(token, new(jwt.StandardClaims))
+	assert.Error(t, err)
+}
+
+func TestNewKeyRing(t *testing.T) {
+	t.Run("unknown-signing-key", func(t *testing.T) {
+		_, err := keyring.NewKeyRing(`missing`, keyring.NewHMACKey(`hs`, []byte(`secret`)))
+		assert.Equal(t, keyring.ErrNoSigningKey, err)
+	})
+
+	t.Run("verify-only-signing-key", func(t *testing.T) {
+		pub, _, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		k, err := keyring.NewPublicKey(`ed`, pub)
+		require.NoError(t, err)
+
+		_, err = keyring.NewKeyRing(`ed`, k)
+		assert.Equal(t, keyring.ErrVerifyOnlyKey, err)
+	})
+
+	t.Run("signing-alg", func(t *testing.T) {
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		k, err := keyring.NewPrivateKey(`ed`, private)
+		require.NoError(t, err)
+
+		kr, err := keyring.NewKeyRing(`ed`, k, keyring.NewHMACKey(`hs`, []byte(`secret`)))
+		require.NoError(t, err)
+		assert.Equal(t, `EdDSA`, kr.SigningAlg())
+	})
+}
+
+func TestJWKS(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	es256, err := keyring.NewPrivateKey(`es`, ecKey)
+	require.NoError(t, err)
+
+	kr, err := keyring.NewKeyRing(`hs`, keyring.NewHMACKey(`hs`, []byte(`secret`)), es256)
+	require.NoError(t, err)
+
+	set := kr.JWKS()
+
+	require.Len(t, set.Keys, 1)
+	assert.Equal(t, `es`, set.Keys[0].KeyID)
+	assert.Equal(t, `EC`, set.Keys[0].KeyType)
+	assert.Equal(t, `P-256`, set.Keys[0].Curve)
+	assert.Equal(t, `ES256`, set.Keys[0].Algorithm)
+}
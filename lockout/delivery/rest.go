@@ -0,0 +1,48 @@
+package http
+
+import (
+	"net/http"
+
+	cmware "github.com/andhikagama/lmnlo/cmiddleware"
+	"github.com/andhikagama/lmnlo/lockout"
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/andhikagama/lmnlo/models/response"
+	"github.com/labstack/echo"
+)
+
+// LockoutHTTPHandler ...
+type LockoutHTTPHandler struct {
+	Usecase lockout.Usecase
+}
+
+// NewLockoutHTTPHandler ...
+func NewLockoutHTTPHandler(g *echo.Group, u lockout.Usecase, cm cmware.Usecase) {
+	handler := &LockoutHTTPHandler{
+		Usecase: u,
+	}
+
+	g.POST(`/lockout/unlock`, handler.Unlock, cm.RequirePermission(entity.PermissionLockoutUnlock))
+}
+
+// Unlock clears the failed attempts of an account, an address or both
+func (h *LockoutHTTPHandler) Unlock(c echo.Context) error {
+	req := new(struct {
+		Email string `json:"email"`
+		IP    string `json:"ip"`
+	})
+	c.Bind(req)
+
+	if req.Email == `` && req.IP == `` {
+		return c.JSON(http.StatusBadRequest, &response.Wrapper{
+			Message: response.ErrBadRequest.Error(),
+		})
+	}
+
+	if err := h.Usecase.Unlock(c.Request().Context(), req.Email, req.IP); err != nil {
+		return c.JSON(http.StatusInternalServerError, &response.Wrapper{
+			Message: response.ErrServer.Error(),
+		})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,57 @@
+package http_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	handler "github.com/andhikagama/lmnlo/lockout/delivery"
+	"github.com/andhikagama/lmnlo/lockout/mocks"
+)
+
+func TestUnlock(t *testing.T) {
+	cases := []struct {
+		name  string
+		body  string
+		email string
+		ip    string
+		err   error
+		code  int
+	}{
+		{`success`, `{"email":"andhika.gama@outlook.com","ip":"1.2.3.4"}`, `andhika.gama@outlook.com`, `1.2.3.4`, nil, http.StatusNoContent},
+		{`success-ip`, `{"ip":"1.2.3.4"}`, ``, `1.2.3.4`, nil, http.StatusNoContent},
+		{`empty`, `{}`, ``, ``, nil, http.StatusBadRequest},
+		{`error`, `{"email":"andhika.gama@outlook.com"}`, `andhika.gama@outlook.com`, ``, errors.New(`error`), http.StatusInternalServerError},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockUCase := new(mocks.Usecase)
+			if tc.code != http.StatusBadRequest {
+				mockUCase.On("Unlock", mock.Anything, tc.email, tc.ip).Return(tc.err).Once()
+			}
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.POST, "/", strings.NewReader(tc.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			c := e.NewContext(req, rec)
+			c.SetPath("lockout/unlock")
+
+			handler := handler.LockoutHTTPHandler{
+				Usecase: mockUCase,
+			}
+			handler.Unlock(c)
+
+			assert.Equal(t, tc.code, rec.Code)
+			mockUCase.AssertExpectations(t)
+		})
+	}
+}
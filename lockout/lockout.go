@@ -0,0 +1,26 @@
+package lockout
+
+import (
+	"context"
+	"time"
+
+	"github.com/andhikagama/lmnlo/models/entity"
+)
+
+// Repository keeps failed login attempts by key
+type Repository interface {
+	Get(ctx context.Context, key string) (*entity.LoginAttempt, error)
+	// AddFailure counts a failure at t, the count starts over when the
+	// previous failure is older than window
+	AddFailure(ctx context.Context, key string, t time.Time, window time.Duration) (*entity.LoginAttempt, error)
+	Lock(ctx context.Context, key string, until time.Time) error
+	Delete(ctx context.Context, key string) error
+}
+
+// Usecase represents business logic
+type Usecase interface {
+	Check(ctx context.Context, email string, ip string) (time.Duration, error)
+	Fail(ctx context.Context, email string, ip string) error
+	Succeed(ctx context.Context, email string) error
+	Unlock(ctx context.Context, email string, ip string) error
+}
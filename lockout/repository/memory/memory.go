@@ -0,0 +1,103 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/andhikagama/lmnlo/lockout"
+	"github.com/andhikagama/lmnlo/models/entity"
+)
+
+// _MinSweep is the size the attempt map may reach before stale entries are
+// swept for the first time
+const _MinSweep = 1024
+
+type lockoutRepository struct {
+	mu       sync.Mutex
+	attempts map[string]*entity.LoginAttempt
+	sweepAt  int
+}
+
+// NewLockoutRepository return a repository that keeps attempts in the
+// process, counters are not shared between replicas
+func NewLockoutRepository() lockout.Repository {
+	return &lockoutRepository{
+		attempts: make(map[string]*entity.LoginAttempt),
+		sweepAt:  _MinSweep,
+	}
+}
+
+func (m *lockoutRepository) Get(ctx context.Context, key string) (*entity.LoginAttempt, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	attempt, ok := m.attempts[key]
+	if !ok {
+		return new(entity.LoginAttempt), nil
+	}
+
+	res := *attempt
+	return &res, nil
+}
+
+func (m *lockoutRepository) AddFailure(ctx context.Context, key string, t time.Time, window time.Duration) (*entity.LoginAttempt, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	attempt, ok := m.attempts[key]
+	if !ok {
+		m.sweep(t, window)
+
+		attempt = &entity.LoginAttempt{Key: key}
+		m.attempts[key] = attempt
+	}
+
+	if attempt.LastFailureAt.Before(t.Add(-window)) {
+		attempt.Failures = 0
+	}
+
+	attempt.Failures++
+	attempt.LastFailureAt = t
+
+	res := *attempt
+	return &res, nil
+}
+
+func (m *lockoutRepository) Lock(ctx context.Context, key string, until time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if attempt, ok := m.attempts[key]; ok {
+		attempt.LockedUntil = &until
+	}
+
+	return nil
+}
+
+func (m *lockoutRepository) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.attempts, key)
+	return nil
+}
+
+// sweep drops attempts that neither count nor lock anymore once the map
+// has grown, so that sprayed keys do not pile up
+func (m *lockoutRepository) sweep(t time.Time, window time.Duration) {
+	if len(m.attempts) < m.sweepAt {
+		return
+	}
+
+	for key, attempt := range m.attempts {
+		if attempt.LastFailureAt.Before(t.Add(-window)) && attempt.LockedAt(t) == 0 {
+			delete(m.attempts, key)
+		}
+	}
+
+	m.sweepAt = 2 * len(m.attempts)
+	if m.sweepAt < _MinSweep {
+		m.sweepAt = _MinSweep
+	}
+}
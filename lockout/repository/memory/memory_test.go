@@ -0,0 +1,54 @@
+package memory_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/andhikagama/lmnlo/lockout/repository/memory"
+)
+
+func TestAddFailure(t *testing.T) {
+	now := time.Now()
+
+	t.Run("counts-within-window", func(t *testing.T) {
+		repo := memory.NewLockoutRepository()
+
+		repo.AddFailure(context.TODO(), `ip:1.2.3.4`, now, time.Minute)
+		res, err := repo.AddFailure(context.TODO(), `ip:1.2.3.4`, now.Add(30*time.Second), time.Minute)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), res.Failures)
+	})
+
+	t.Run("starts-over-after-window", func(t *testing.T) {
+		repo := memory.NewLockoutRepository()
+
+		repo.AddFailure(context.TODO(), `ip:1.2.3.4`, now, time.Minute)
+		res, err := repo.AddFailure(context.TODO(), `ip:1.2.3.4`, now.Add(2*time.Minute), time.Minute)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), res.Failures)
+	})
+}
+
+func TestLock(t *testing.T) {
+	now := time.Now()
+	repo := memory.NewLockoutRepository()
+
+	repo.AddFailure(context.TODO(), `ip:1.2.3.4`, now, time.Minute)
+	assert.NoError(t, repo.Lock(context.TODO(), `ip:1.2.3.4`, now.Add(time.Minute)))
+
+	res, err := repo.Get(context.TODO(), `ip:1.2.3.4`)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Minute, res.LockedAt(now))
+
+	assert.NoError(t, repo.Delete(context.TODO(), `ip:1.2.3.4`))
+
+	res, err = repo.Get(context.TODO(), `ip:1.2.3.4`)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), res.Failures)
+	assert.Equal(t, time.Duration(0), res.LockedAt(now))
+}
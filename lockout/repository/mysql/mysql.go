@@ -0,0 +1,144 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/andhikagama/lmnlo/lockout"
+	"github.com/andhikagama/lmnlo/models/entity"
+	sq "github.com/elgris/sqrl"
+	"github.com/sirupsen/logrus"
+)
+
+type lockoutRepository struct {
+	Conn *sql.DB
+}
+
+// NewLockoutRepository return a repository backed by the login_attempt
+// table, counters are shared by every replica using the database
+func NewLockoutRepository(Conn *sql.DB) lockout.Repository {
+	return &lockoutRepository{Conn}
+}
+
+func (m *lockoutRepository) Get(ctx context.Context, key string) (*entity.LoginAttempt, error) {
+	query := sq.Select(`attempt_key, failures, last_failure_time, lock_time`)
+	query.From(`login_attempt`)
+	query.Where(`attempt_key = ?`, key)
+
+	sql, args, _ := query.ToSql()
+	rows, err := m.Conn.QueryContext(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return unmarshalAttempt(rows)
+}
+
+func (m *lockoutRepository) AddFailure(ctx context.Context, key string, t time.Time, window time.Duration) (*entity.LoginAttempt, error) {
+	trx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Assignments run left to right, failures still sees the previous
+	// last_failure_time
+	query := sq.Insert(`login_attempt`).
+		Columns(`attempt_key`, `failures`, `last_failure_time`).
+		Values(key, 1, t).
+		Suffix(`ON DUPLICATE KEY UPDATE failures = IF(last_failure_time < ?, 1, failures + 1), last_failure_time = VALUES(last_failure_time)`, t.Add(-window))
+
+	sql, args, _ := query.ToSql()
+	stmt, err := trx.PrepareContext(ctx, sql)
+	if err != nil {
+		trx.Rollback()
+		return nil, err
+	}
+	defer stmt.Close()
+
+	if _, err := stmt.ExecContext(ctx, args...); err != nil {
+		trx.Rollback()
+		return nil, err
+	}
+
+	sel := sq.Select(`attempt_key, failures, last_failure_time, lock_time`).
+		From(`login_attempt`).
+		Where(`attempt_key = ?`, key)
+
+	sql, args, _ = sel.ToSql()
+	rows, err := trx.QueryContext(ctx, sql, args...)
+	if err != nil {
+		trx.Rollback()
+		return nil, err
+	}
+
+	attempt, err := unmarshalAttempt(rows)
+	rows.Close()
+	if err != nil {
+		trx.Rollback()
+		return nil, err
+	}
+
+	return attempt, trx.Commit()
+}
+
+func (m *lockoutRepository) Lock(ctx context.Context, key string, until time.Time) error {
+	query := sq.Update(`login_attempt`).
+		Set(`lock_time`, until).
+		Where(`attempt_key = ?`, key)
+
+	return m.exec(ctx, query)
+}
+
+func (m *lockoutRepository) Delete(ctx context.Context, key string) error {
+	query := sq.Delete(`login_attempt`).
+		Where(`attempt_key = ?`, key)
+
+	return m.exec(ctx, query)
+}
+
+func unmarshalAttempt(rows *sql.Rows) (*entity.LoginAttempt, error) {
+	attempt := new(entity.LoginAttempt)
+	for rows.Next() {
+		err := rows.Scan(
+			&attempt.Key,
+			&attempt.Failures,
+			&attempt.LastFailureAt,
+			&attempt.LockedUntil,
+		)
+
+		if err != nil {
+			logrus.Error(err, attempt.Key)
+			return nil, err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return attempt, nil
+}
+
+func (m *lockoutRepository) exec(ctx context.Context, query sq.Sqlizer) error {
+	trx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	sql, args, _ := query.ToSql()
+	stmt, err := trx.PrepareContext(ctx, sql)
+	if err != nil {
+		trx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	if _, err := stmt.ExecContext(ctx, args...); err != nil {
+		trx.Rollback()
+		return err
+	}
+
+	return trx.Commit()
+}
@@ -0,0 +1,134 @@
+package mysql_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/andhikagama/lmnlo/lockout/repository/mysql"
+)
+
+var columns = []string{`attempt_key`, `failures`, `last_failure_time`, `lock_time`}
+
+func TestGet(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		until := time.Now().Add(time.Minute)
+		rows := sqlmock.NewRows(columns).AddRow(`ip:1.2.3.4`, 5, time.Now(), until)
+
+		mock.ExpectQuery(`SELECT (.+) FROM login_attempt WHERE attempt_key = \?`).WithArgs(`ip:1.2.3.4`).WillReturnRows(rows)
+
+		repo := mysql.NewLockoutRepository(db)
+		res, err := repo.Get(context.TODO(), `ip:1.2.3.4`)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(5), res.Failures)
+		assert.Equal(t, until, *res.LockedUntil)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("success-no-data", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT (.+) FROM login_attempt`).WillReturnRows(sqlmock.NewRows(columns))
+
+		repo := mysql.NewLockoutRepository(db)
+		res, err := repo.Get(context.TODO(), `ip:1.2.3.4`)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), res.Failures)
+		assert.Nil(t, res.LockedUntil)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestAddFailure(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		now := time.Now()
+
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`INSERT INTO login_attempt (.+) ON DUPLICATE KEY UPDATE failures = IF\(last_failure_time < \?, 1, failures \+ 1\)`).
+			ExpectExec().
+			WithArgs(`ip:1.2.3.4`, 1, now, now.Add(-time.Minute)).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectQuery(`SELECT (.+) FROM login_attempt WHERE attempt_key = \?`).
+			WithArgs(`ip:1.2.3.4`).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(`ip:1.2.3.4`, 3, now, nil))
+		mock.ExpectCommit()
+
+		repo := mysql.NewLockoutRepository(db)
+		res, err := repo.AddFailure(context.TODO(), `ip:1.2.3.4`, now, time.Minute)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), res.Failures)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestLock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		until := time.Now().Add(time.Minute)
+
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`UPDATE login_attempt SET lock_time = \? WHERE attempt_key = \?`).
+			ExpectExec().
+			WithArgs(until, `ip:1.2.3.4`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		repo := mysql.NewLockoutRepository(db)
+		err := repo.Lock(context.TODO(), `ip:1.2.3.4`, until)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestDelete(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`DELETE FROM login_attempt WHERE attempt_key = \?`).
+			ExpectExec().
+			WithArgs(`ip:1.2.3.4`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		repo := mysql.NewLockoutRepository(db)
+		err := repo.Delete(context.TODO(), `ip:1.2.3.4`)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
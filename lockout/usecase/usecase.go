@@ -0,0 +1,168 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/andhikagama/lmnlo/lockout"
+)
+
+// _MaxShift bounds the backoff exponent so that the delay cannot overflow
+const _MaxShift = 30
+
+// Options holds tunables of the lockout usecase. A zero threshold turns
+// tracking of that kind of key off.
+type Options struct {
+	// AccountThreshold is how many failures lock an account
+	AccountThreshold int64
+	// IPThreshold is how many failures lock an address, usually higher
+	// than AccountThreshold since addresses are shared behind NAT
+	IPThreshold int64
+	// Window is how long a failure counts
+	Window time.Duration
+	// BaseDelay is the wait after the first failure, doubled after each
+	// following one until the threshold is reached
+	BaseDelay    time.Duration
+	LockDuration time.Duration
+}
+
+type lockoutUsecase struct {
+	repo lockout.Repository
+	opts Options
+}
+
+// NewLockoutUsecase ...
+func NewLockoutUsecase(r lockout.Repository, opts Options) lockout.Usecase {
+	return &lockoutUsecase{
+		repo: r,
+		opts: opts,
+	}
+}
+
+// Check return how long attempts for email from ip stay refused, zero when
+// an attempt is allowed
+func (l *lockoutUsecase) Check(ctx context.Context, email string, ip string) (time.Duration, error) {
+	now := time.Now()
+
+	var wait time.Duration
+	for _, key := range l.keys(email, ip) {
+		attempt, err := l.repo.Get(ctx, key)
+		if err != nil {
+			return 0, err
+		}
+
+		if d := attempt.LockedAt(now); d > wait {
+			wait = d
+		}
+	}
+
+	return wait, nil
+}
+
+// Fail counts a failed attempt against the account and the address
+func (l *lockoutUsecase) Fail(ctx context.Context, email string, ip string) error {
+	if err := l.fail(ctx, accountKey(email), l.opts.AccountThreshold); err != nil {
+		return err
+	}
+
+	return l.fail(ctx, ipKey(ip), l.opts.IPThreshold)
+}
+
+// Succeed clears the failures of the account. Failures of the address are
+// kept, one valid login must not hide a spray from the same address.
+func (l *lockoutUsecase) Succeed(ctx context.Context, email string) error {
+	if email == `` || l.opts.AccountThreshold <= 0 {
+		return nil
+	}
+
+	return l.repo.Delete(ctx, accountKey(email))
+}
+
+// Unlock clears the failures and locks of email and ip, either may be empty
+func (l *lockoutUsecase) Unlock(ctx context.Context, email string, ip string) error {
+	if email != `` {
+		if err := l.repo.Delete(ctx, accountKey(email)); err != nil {
+			return err
+		}
+	}
+
+	if ip != `` {
+		return l.repo.Delete(ctx, ipKey(ip))
+	}
+
+	return nil
+}
+
+func (l *lockoutUsecase) fail(ctx context.Context, key string, threshold int64) error {
+	if key == `` || threshold <= 0 {
+		return nil
+	}
+
+	now := time.Now()
+	attempt, err := l.repo.AddFailure(ctx, key, now, l.opts.Window)
+	if err != nil {
+		return err
+	}
+
+	delay := l.delay(attempt.Failures, threshold)
+	if delay <= 0 {
+		return nil
+	}
+
+	return l.repo.Lock(ctx, key, now.Add(delay))
+}
+
+// delay is the wait imposed after the given number of failures
+func (l *lockoutUsecase) delay(failures int64, threshold int64) time.Duration {
+	if failures >= threshold {
+		return l.opts.LockDuration
+	}
+
+	if l.opts.BaseDelay <= 0 {
+		return 0
+	}
+
+	shift := failures - 1
+	if shift > _MaxShift {
+		shift = _MaxShift
+	}
+
+	delay := l.opts.BaseDelay << uint(shift)
+	if delay > l.opts.LockDuration {
+		return l.opts.LockDuration
+	}
+
+	return delay
+}
+
+// keys are the tracked keys of an attempt
+func (l *lockoutUsecase) keys(email string, ip string) []string {
+	keys := make([]string, 0, 2)
+	if key := accountKey(email); key != `` {
+		keys = append(keys, key)
+	}
+
+	if key := ipKey(ip); key != `` {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+func accountKey(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == `` {
+		return ``
+	}
+
+	return `account:` + email
+}
+
+func ipKey(ip string) string {
+	if ip == `` {
+		return ``
+	}
+
+	return `ip:` + ip
+}
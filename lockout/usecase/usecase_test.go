@@ -0,0 +1,128 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/andhikagama/lmnlo/lockout/mocks"
+	"github.com/andhikagama/lmnlo/lockout/repository/memory"
+	"github.com/andhikagama/lmnlo/lockout/usecase"
+)
+
+var mockOptions = usecase.Options{
+	AccountThreshold: 3,
+	IPThreshold:      5,
+	Window:           time.Hour,
+	BaseDelay:        time.Second,
+	LockDuration:     time.Hour,
+}
+
+func TestFail(t *testing.T) {
+	t.Run("backoff", func(t *testing.T) {
+		u := usecase.NewLockoutUsecase(memory.NewLockoutRepository(), mockOptions)
+
+		assert.NoError(t, u.Fail(context.TODO(), `andhika.gama@outlook.com`, `1.2.3.4`))
+		wait, err := u.Check(context.TODO(), `andhika.gama@outlook.com`, `1.2.3.4`)
+		assert.NoError(t, err)
+		assert.InDelta(t, float64(time.Second), float64(wait), float64(100*time.Millisecond))
+
+		assert.NoError(t, u.Fail(context.TODO(), `andhika.gama@outlook.com`, `1.2.3.4`))
+		wait, err = u.Check(context.TODO(), `andhika.gama@outlook.com`, `1.2.3.4`)
+		assert.NoError(t, err)
+		assert.InDelta(t, float64(2*time.Second), float64(wait), float64(100*time.Millisecond))
+	})
+
+	t.Run("account-locked", func(t *testing.T) {
+		u := usecase.NewLockoutUsecase(memory.NewLockoutRepository(), mockOptions)
+
+		for i := 0; i < 3; i++ {
+			assert.NoError(t, u.Fail(context.TODO(), `andhika.gama@outlook.com`, `1.2.3.4`))
+		}
+
+		wait, err := u.Check(context.TODO(), `Andhika.Gama@outlook.com`, `5.6.7.8`)
+		assert.NoError(t, err)
+		assert.InDelta(t, float64(time.Hour), float64(wait), float64(time.Second))
+
+		wait, err = u.Check(context.TODO(), `other@outlook.com`, `5.6.7.8`)
+		assert.NoError(t, err)
+		assert.Equal(t, time.Duration(0), wait)
+	})
+
+	t.Run("ip-locked", func(t *testing.T) {
+		u := usecase.NewLockoutUsecase(memory.NewLockoutRepository(), mockOptions)
+
+		emails := []string{`a@outlook.com`, `b@outlook.com`, `c@outlook.com`, `d@outlook.com`, `e@outlook.com`}
+		for _, email := range emails {
+			assert.NoError(t, u.Fail(context.TODO(), email, `1.2.3.4`))
+		}
+
+		wait, err := u.Check(context.TODO(), `f@outlook.com`, `1.2.3.4`)
+		assert.NoError(t, err)
+		assert.InDelta(t, float64(time.Hour), float64(wait), float64(time.Second))
+
+		wait, err = u.Check(context.TODO(), `f@outlook.com`, `5.6.7.8`)
+		assert.NoError(t, err)
+		assert.Equal(t, time.Duration(0), wait)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		u := usecase.NewLockoutUsecase(memory.NewLockoutRepository(), usecase.Options{})
+
+		for i := 0; i < 10; i++ {
+			assert.NoError(t, u.Fail(context.TODO(), `andhika.gama@outlook.com`, `1.2.3.4`))
+		}
+
+		wait, err := u.Check(context.TODO(), `andhika.gama@outlook.com`, `1.2.3.4`)
+		assert.NoError(t, err)
+		assert.Equal(t, time.Duration(0), wait)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		mockRepo := new(mocks.Repository)
+		mockRepo.On("AddFailure", mock.Anything, `account:andhika.gama@outlook.com`, mock.AnythingOfType("time.Time"), time.Hour).Return(nil, errors.New(`Unexpected Error`)).Once()
+		u := usecase.NewLockoutUsecase(mockRepo, mockOptions)
+
+		err := u.Fail(context.TODO(), `andhika.gama@outlook.com`, `1.2.3.4`)
+
+		assert.Error(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestSucceed(t *testing.T) {
+	u := usecase.NewLockoutUsecase(memory.NewLockoutRepository(), mockOptions)
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, u.Fail(context.TODO(), `andhika.gama@outlook.com`, `1.2.3.4`))
+	}
+
+	assert.NoError(t, u.Succeed(context.TODO(), `andhika.gama@outlook.com`))
+
+	wait, err := u.Check(context.TODO(), `andhika.gama@outlook.com`, ``)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), wait)
+
+	// The address keeps its failures
+	wait, err = u.Check(context.TODO(), ``, `1.2.3.4`)
+	assert.NoError(t, err)
+	assert.True(t, wait > 0)
+}
+
+func TestUnlock(t *testing.T) {
+	u := usecase.NewLockoutUsecase(memory.NewLockoutRepository(), mockOptions)
+
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, u.Fail(context.TODO(), `andhika.gama@outlook.com`, `1.2.3.4`))
+	}
+
+	assert.NoError(t, u.Unlock(context.TODO(), `andhika.gama@outlook.com`, `1.2.3.4`))
+
+	wait, err := u.Check(context.TODO(), `andhika.gama@outlook.com`, `1.2.3.4`)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), wait)
+}
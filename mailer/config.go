@@ -0,0 +1,32 @@
+package mailer
+
+import (
+	"io"
+	"os"
+
+	"github.com/andhikagama/lmnlo/config"
+)
+
+// NewMailerFromConfig return mailer selected by `mail.driver`: `smtp`
+// relays through `mail.smtp`, anything else writes messages to
+// `mail.log_file`, or stdout when it is empty
+func NewMailerFromConfig(cfg config.Config) (Mailer, error) {
+	if cfg.GetString(`mail.driver`) == `smtp` {
+		smtpCfg := SMTPConfig{}
+		if err := cfg.UnmarshalKey(`mail.smtp`, &smtpCfg); err != nil {
+			return nil, err
+		}
+		return NewSMTPMailer(smtpCfg), nil
+	}
+
+	var w io.Writer = os.Stdout
+	if path := cfg.GetString(`mail.log_file`); path != `` {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			return nil, err
+		}
+		w = f
+	}
+
+	return NewLogMailer(w, cfg.GetString(`mail.from`)), nil
+}
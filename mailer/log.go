@@ -0,0 +1,30 @@
+package mailer
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+type logMailer struct {
+	mu   sync.Mutex
+	w    io.Writer
+	from string
+}
+
+// NewLogMailer return mailer writing every message to w instead of
+// delivering it, a stand-in for local development
+func NewLogMailer(w io.Writer, from string) Mailer {
+	return &logMailer{
+		w:    w,
+		from: from,
+	}
+}
+
+func (m *logMailer) Send(msg *Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, err := fmt.Fprintf(m.w, "%s\r\n\r\n", msg.Bytes(m.from))
+	return err
+}
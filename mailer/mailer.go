@@ -0,0 +1,42 @@
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers outbound email
+type Mailer interface {
+	Send(msg *Message) error
+}
+
+// Bytes return msg as an RFC 5322 message sent by from
+func (msg *Message) Bytes(from string) []byte {
+	buf := new(bytes.Buffer)
+
+	fmt.Fprintf(buf, "From: %s\r\n", headerValue(from))
+	fmt.Fprintf(buf, "To: %s\r\n", headerValue(msg.To))
+	fmt.Fprintf(buf, "Subject: %s\r\n", headerValue(msg.Subject))
+	fmt.Fprintf(buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(msg.Body)
+
+	return buf.Bytes()
+}
+
+// headerValue drops line breaks so user supplied values such as the
+// recipient address cannot inject extra headers
+func headerValue(s string) string {
+	return strings.NewReplacer("\r", ``, "\n", ``).Replace(s)
+}
@@ -0,0 +1,43 @@
+package mailer_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/andhikagama/lmnlo/mailer"
+)
+
+func TestLogMailer(t *testing.T) {
+	buf := new(bytes.Buffer)
+	m := mailer.NewLogMailer(buf, `no-reply@lmnlo.local`)
+
+	err := m.Send(&mailer.Message{
+		To:      `andhika.gama@outlook.com`,
+		Subject: `Verify your email`,
+		Body:    `token: abc`,
+	})
+
+	assert.NoError(t, err)
+
+	out := buf.String()
+	assert.Contains(t, out, "From: no-reply@lmnlo.local\r\n")
+	assert.Contains(t, out, "To: andhika.gama@outlook.com\r\n")
+	assert.Contains(t, out, "Subject: Verify your email\r\n")
+	assert.True(t, strings.Contains(out, "\r\n\r\ntoken: abc"))
+}
+
+func TestLogMailerHeaderInjection(t *testing.T) {
+	buf := new(bytes.Buffer)
+	m := mailer.NewLogMailer(buf, `no-reply@lmnlo.local`)
+
+	err := m.Send(&mailer.Message{
+		To:      "victim@lmnlo.local\r\nBcc: attacker@lmnlo.local",
+		Subject: `Verify your email`,
+	})
+
+	assert.NoError(t, err)
+	assert.NotContains(t, buf.String(), "\r\nBcc:")
+}
@@ -1,19 +1,52 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"text/tabwriter"
+	"time"
 
+	"github.com/andhikagama/lmnlo/audit"
+	auditHandler "github.com/andhikagama/lmnlo/audit/delivery"
+	_auditFileRepository "github.com/andhikagama/lmnlo/audit/repository/file"
+	_auditMySQLRepository "github.com/andhikagama/lmnlo/audit/repository/mysql"
+	_auditUsecase "github.com/andhikagama/lmnlo/audit/usecase"
+	authServerHandler "github.com/andhikagama/lmnlo/authserver/delivery"
+	_authServerRepository "github.com/andhikagama/lmnlo/authserver/repository/mysql"
+	_authServerUsecase "github.com/andhikagama/lmnlo/authserver/usecase"
+	cmware "github.com/andhikagama/lmnlo/cmiddleware"
 	_customMiddleware "github.com/andhikagama/lmnlo/cmiddleware/usecase"
 	cfg "github.com/andhikagama/lmnlo/config"
+	"github.com/andhikagama/lmnlo/helper"
+	"github.com/andhikagama/lmnlo/keyring"
+	keyRingHandler "github.com/andhikagama/lmnlo/keyring/delivery"
+	"github.com/andhikagama/lmnlo/lockout"
+	lockoutHandler "github.com/andhikagama/lmnlo/lockout/delivery"
+	_lockoutMemoryRepository "github.com/andhikagama/lmnlo/lockout/repository/memory"
+	_lockoutMySQLRepository "github.com/andhikagama/lmnlo/lockout/repository/mysql"
+	_lockoutUsecase "github.com/andhikagama/lmnlo/lockout/usecase"
+	"github.com/andhikagama/lmnlo/mailer"
+	"github.com/andhikagama/lmnlo/migration"
+	"github.com/andhikagama/lmnlo/oidc"
+	"github.com/andhikagama/lmnlo/ratelimit"
+	_rateLimitMemoryRepository "github.com/andhikagama/lmnlo/ratelimit/repository/memory"
+	_rateLimitMySQLRepository "github.com/andhikagama/lmnlo/ratelimit/repository/mysql"
+	_rateLimitUsecase "github.com/andhikagama/lmnlo/ratelimit/usecase"
+	"github.com/andhikagama/lmnlo/user"
 	userHandler "github.com/andhikagama/lmnlo/user/delivery"
 	_userRepository "github.com/andhikagama/lmnlo/user/repository"
+	_userPostgresRepository "github.com/andhikagama/lmnlo/user/repository/postgres"
+	_userSQLiteRepository "github.com/andhikagama/lmnlo/user/repository/sqlite"
 	_userUsecase "github.com/andhikagama/lmnlo/user/usecase"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/labstack/echo"
 	"github.com/labstack/echo/middleware"
+	_ "github.com/lib/pq"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -32,15 +65,9 @@ func init() {
 
 func main() {
 	//Setup Database Connection
-	dbHost := config.GetString(`database.host`)
-	dbPort := config.GetString(`database.port`)
-	dbUser := config.GetString(`database.user`)
-	dbPass := config.GetString(`database.pass`)
-	dbName := config.GetString(`database.name`)
-
-	dsn := dbUser + `:` + dbPass + `@tcp(` + dbHost + `:` + dbPort + `)/` + dbName + `?parseTime=1`
+	dbDriver := databaseDriver()
 	log.Info("connecting to database")
-	db, err := sql.Open(`mysql`, dsn)
+	db, err := sql.Open(dbDriver, newDSN(dbDriver))
 	if err != nil {
 		log.Error(fmt.Sprintf("database connection failed. Err: %v", err.Error()))
 		os.Exit(1)
@@ -48,7 +75,47 @@ func main() {
 
 	defer db.Close()
 
+	// SQLite takes one writer at a time, and every connection to :memory:
+	// opens a database of its own
+	if dbDriver == _userSQLiteRepository.DriverName {
+		db.SetMaxOpenConns(1)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == `migrate` {
+		os.Exit(migrate(db, os.Args[2:]))
+	}
+
+	if config.GetBool(`database.migrate_on_start`) {
+		migrateOnStart(db)
+	}
+
+	keyRing, err := keyring.NewKeyRingFromConfig(config)
+	if err != nil {
+		log.Error(fmt.Sprintf("loading jwt keys failed. Err: %v", err.Error()))
+		os.Exit(1)
+	}
+
+	mail, err := mailer.NewMailerFromConfig(config)
+	if err != nil {
+		log.Error(fmt.Sprintf("initiating mailer failed. Err: %v", err.Error()))
+		os.Exit(1)
+	}
+
+	oauthProviders, err := oidc.NewProvidersFromConfig(config)
+	if err != nil {
+		log.Error(fmt.Sprintf("loading oauth providers failed. Err: %v", err.Error()))
+		os.Exit(1)
+	}
+
+	trustProxies, err := cmware.TrustProxies(config.GetStringSlice(`server.trusted_proxies`))
+	if err != nil {
+		log.Error(fmt.Sprintf("loading trusted proxies failed. Err: %v", err.Error()))
+		os.Exit(1)
+	}
+
 	e := echo.New()
+	e.Use(middleware.RequestID())
+	e.Use(trustProxies)
 
 	// For Health Check
 	e.GET("/ping", func(c echo.Context) error {
@@ -68,21 +135,414 @@ func main() {
 		ExposeHeaders: []string{`X-Cursor`},
 	}))
 
+	// Address quotas run before anything else touches the request, user and
+	// API key quotas once CheckAuthHeader told who is calling
+	rateLimitUsecase := _rateLimitUsecase.NewRateLimitUsecase(newRateLimitRepository(db), newRateLimitOptions())
+	gv1.Use(rateLimitUsecase.Limit)
+	go sweepRateLimits(rateLimitUsecase, config.GetDuration(`rate_limit.sweep_interval`))
+
 	//Initiate Repository for each entity
-	userRepository := _userRepository.NewUserRepository(db)
+	userRepository := newUserRepository(db)
+	lockoutRepository := newLockoutRepository(db)
+	auditRepositories := newAuditRepositories(db)
+
+	//Initiate Usecase for each entity
+	lockoutUsecase := _lockoutUsecase.NewLockoutUsecase(lockoutRepository, _lockoutUsecase.Options{
+		AccountThreshold: int64(config.GetInt(`auth.lockout.account_threshold`)),
+		IPThreshold:      int64(config.GetInt(`auth.lockout.ip_threshold`)),
+		Window:           config.GetDuration(`auth.lockout.window`),
+		BaseDelay:        config.GetDuration(`auth.lockout.base_delay`),
+		LockDuration:     config.GetDuration(`auth.lockout.lock_duration`),
+	})
+	auditUsecase := _auditUsecase.NewAuditUsecase(auditRepositories...)
+	userUsecase := _userUsecase.NewUserUsecase(userRepository, newPasswordHasher(), keyRing, mail, lockoutUsecase, auditUsecase, _userUsecase.Options{
+		AccessTokenTTL:       config.GetDuration(`auth.access_token_ttl`),
+		RefreshTokenTTL:      config.GetDuration(`auth.refresh_token_ttl`),
+		VerifyEmailTTL:       config.GetDuration(`auth.verify_email_ttl`),
+		VerifyEmailURL:       config.GetString(`auth.verify_email_url`),
+		RequireVerifiedEmail: config.GetBool(`auth.require_verified_email`),
+		PasswordResetTTL:     config.GetDuration(`auth.password_reset_ttl`),
+		PasswordResetURL:     config.GetString(`auth.password_reset_url`),
+		PasswordResetLimit:   config.GetInt(`auth.password_reset_limit`),
+		PasswordResetWindow:  config.GetDuration(`auth.password_reset_window`),
+		PasswordPolicy:       newPasswordPolicy(),
+		MFAChallengeTTL:      config.GetDuration(`auth.mfa.challenge_ttl`),
+		MFAIssuer:            config.GetString(`auth.mfa.issuer`),
+		MFARoles:             config.GetStringSlice(`auth.mfa.required_roles`),
+		OAuthProviders:       oauthProviders,
+		OAuthStateTTL:        config.GetDuration(`oauth.state_ttl`),
+		TokenIssuer:          config.GetString(`auth.token.issuer`),
+		TokenAudience:        config.GetString(`auth.token.audience`),
+		TokenLeeway:          config.GetDuration(`auth.token.leeway`),
+		UserCacheTTL:         config.GetDuration(`auth.token.user_cache_ttl`),
+		SessionRetention:     config.GetDuration(`auth.token.session_retention`),
+		ImpersonationTTL:     config.GetDuration(`auth.impersonation_ttl`),
+		ContextTimeout:       config.GetDuration(`server.request_timeout`),
+	})
+	go sweepSessions(userUsecase, config.GetDuration(`auth.token.sweep_interval`))
 
 	// Initiate Custom Middleware
-	customMiddleware := _customMiddleware.NewMiddlewareUsecase(userRepository)
+	sessionCookies := newSessionCookies()
+	customMiddleware := _customMiddleware.NewMiddlewareUsecase(userUsecase, cmware.CredentialOptions{
+		Realm:       config.GetString(`auth.credentials.realm`),
+		TokenCookie: config.GetString(`auth.credentials.token_cookie`),
+		TokenQuery:  config.GetString(`auth.credentials.token_query`),
+		Cookies:     sessionCookies,
+	})
+	gv1.Use(customMiddleware.CheckCSRF)
 	gv1.Use(customMiddleware.CheckAuthHeader)
-
-	//Initiate Usecase for each entity
-	userUsecase := _userUsecase.NewUserUsecase(userRepository)
+	gv1.Use(rateLimitUsecase.LimitClient)
 
 	//Initiate Handler for each entity
-	userHandler.NewUserHTTPHandler(gv1, userUsecase)
+	userHandler.NewUserHTTPHandler(gv1, userUsecase, customMiddleware, sessionCookies)
+	lockoutHandler.NewLockoutHTTPHandler(gv1, lockoutUsecase, customMiddleware)
+	auditHandler.NewAuditHTTPHandler(gv1, auditUsecase, customMiddleware)
+	keyRingHandler.NewKeyRingHTTPHandler(e, keyRing)
+
+	if config.GetBool(`authserver.enabled`) {
+		requireMySQL(`the authorization server`)
+
+		// Clients verify ID and access tokens with the published keys, which
+		// a shared secret can not be
+		if keyRing.SigningAlg() == `HS256` {
+			log.Error(`the authorization server needs an asymmetric jwt.signing_key`)
+			os.Exit(1)
+		}
+
+		authServerUsecase := _authServerUsecase.NewAuthServerUsecase(_authServerRepository.NewAuthServerRepository(db), userRepository, keyRing, _authServerUsecase.Options{
+			Issuer:         config.GetString(`authserver.issuer`),
+			LoginURL:       config.GetString(`authserver.login_url`),
+			CodeTTL:        config.GetDuration(`authserver.code_ttl`),
+			AccessTokenTTL: config.GetDuration(`authserver.access_token_ttl`),
+			IDTokenTTL:     config.GetDuration(`authserver.id_token_ttl`),
+		})
+		authServerHandler.NewAuthServerHTTPHandler(e, gv1, authServerUsecase, customMiddleware)
+	}
 
 	log.Infof(`Connected to database : %v on %v`, config.GetString(`database.name`), config.GetString(`database.host`))
 	log.Infof(`Lmnlo server running at address : %v`, config.GetString(`server.address`))
 	e.Start(config.GetString("server.address"))
 
 }
+
+// databaseDriver is database.driver, mysql unless set
+func databaseDriver() string {
+	if driver := config.GetString(`database.driver`); driver != `` {
+		return driver
+	}
+
+	return `mysql`
+}
+
+// newDSN return the data source name of the database for driver
+func newDSN(driver string) string {
+	if driver == _userSQLiteRepository.DriverName {
+		return config.GetString(`database.file`) + `?_busy_timeout=5000`
+	}
+
+	host := config.GetString(`database.host`)
+	port := config.GetString(`database.port`)
+	user := config.GetString(`database.user`)
+	pass := config.GetString(`database.pass`)
+	name := config.GetString(`database.name`)
+
+	if driver == `postgres` {
+		dsn := url.URL{
+			Scheme: `postgres`,
+			User:   url.UserPassword(user, pass),
+			Host:   net.JoinHostPort(host, port),
+			Path:   `/` + name,
+		}
+
+		if sslmode := config.GetString(`database.sslmode`); sslmode != `` {
+			dsn.RawQuery = url.Values{`sslmode`: {sslmode}}.Encode()
+		}
+
+		return dsn.String()
+	}
+
+	return user + `:` + pass + `@tcp(` + host + `:` + port + `)/` + name + `?parseTime=1`
+}
+
+// newUserRepository picks the user repository of database.driver
+func newUserRepository(db *sql.DB) user.Repository {
+	switch driver := databaseDriver(); driver {
+	case `mysql`:
+		return _userRepository.NewUserRepository(db)
+	case `postgres`:
+		return _userPostgresRepository.NewUserRepository(db)
+	case _userSQLiteRepository.DriverName:
+		repo, err := _userSQLiteRepository.NewUserRepository(db)
+		if err != nil {
+			log.Error(fmt.Sprintf("creating sqlite schema failed. Err: %v", err.Error()))
+			os.Exit(1)
+		}
+		return repo
+	default:
+		log.Error(fmt.Sprintf("unknown database driver %q", driver))
+		os.Exit(1)
+	}
+
+	return nil
+}
+
+// requireMySQL stops the server when component, which only has a MySQL
+// repository so far, is configured on another database
+func requireMySQL(component string) {
+	if driver := databaseDriver(); driver != `mysql` {
+		log.Error(fmt.Sprintf("%s needs the mysql database driver, database.driver is %q", component, driver))
+		os.Exit(1)
+	}
+}
+
+// onMySQL reports whether component, which only has a MySQL repository so
+// far, can use the database, warning that fallback is used instead
+func onMySQL(component string, fallback string) bool {
+	if driver := databaseDriver(); driver != `mysql` {
+		log.Warn(fmt.Sprintf("%s needs the mysql database driver, database.driver is %q: %s", component, driver, fallback))
+		return false
+	}
+
+	return true
+}
+
+// migrate runs `lmnlo migrate up|down|status` and return the exit code
+func migrate(db *sql.DB, args []string) int {
+	migrator, err := migration.NewMigrator(db, databaseDriver())
+	if err != nil {
+		log.Error(fmt.Sprintf("loading migrations failed. Err: %v", err.Error()))
+		return 1
+	}
+
+	cmd := ``
+	if len(args) > 0 {
+		cmd = args[0]
+	}
+
+	ctx := context.Background()
+	switch cmd {
+	case `up`:
+		done, err := migrator.Up(ctx)
+		for _, mig := range done {
+			fmt.Printf("applied %d_%s\n", mig.Version, mig.Name)
+		}
+
+		if err != nil {
+			log.Error(fmt.Sprintf("migrating up failed. Err: %v", err.Error()))
+			return 1
+		}
+
+		if len(done) == 0 {
+			fmt.Println(`schema is up to date`)
+		}
+	case `down`:
+		mig, err := migrator.Down(ctx)
+		if err != nil {
+			log.Error(fmt.Sprintf("migrating down failed. Err: %v", err.Error()))
+			return 1
+		}
+
+		if mig == nil {
+			fmt.Println(`no migration to revert`)
+			return 0
+		}
+
+		fmt.Printf("reverted %d_%s\n", mig.Version, mig.Name)
+	case `status`:
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.Error(fmt.Sprintf("reading migrations failed. Err: %v", err.Error()))
+			return 1
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+		for _, st := range statuses {
+			applied := `pending`
+			if st.AppliedAt != nil {
+				applied = st.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", st.Version, st.Name, applied)
+		}
+		w.Flush()
+	default:
+		fmt.Fprintln(os.Stderr, `usage: lmnlo migrate up|down|status`)
+		return 2
+	}
+
+	return 0
+}
+
+// migrateOnStart applies pending migrations before serving, replicas
+// starting together wait for each other
+func migrateOnStart(db *sql.DB) {
+	migrator, err := migration.NewMigrator(db, databaseDriver())
+	if err != nil {
+		log.Error(fmt.Sprintf("loading migrations failed. Err: %v", err.Error()))
+		os.Exit(1)
+	}
+
+	done, err := migrator.Up(context.Background())
+	for _, mig := range done {
+		log.Infof(`applied migration %d_%s`, mig.Version, mig.Name)
+	}
+
+	if err != nil {
+		log.Error(fmt.Sprintf("migrating up failed. Err: %v", err.Error()))
+		os.Exit(1)
+	}
+}
+
+func newPasswordHasher() helper.PasswordHasher {
+	if config.GetString(`password.algorithm`) == `bcrypt` {
+		return helper.NewBcryptHasher(config.GetInt(`password.bcrypt.cost`))
+	}
+
+	return helper.NewArgon2idHasher(helper.Argon2idParams{
+		Time:      uint32(config.GetInt(`password.argon2id.time`)),
+		Memory:    uint32(config.GetInt(`password.argon2id.memory`)),
+		Threads:   uint8(config.GetInt(`password.argon2id.threads`)),
+		KeyLength: uint32(config.GetInt(`password.argon2id.key_length`)),
+	})
+}
+
+// sweepSessions purges expired and revoked sessions every interval, so that
+// the token table does not grow forever
+func sweepSessions(u user.Usecase, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		n, err := u.SweepSessions(context.Background())
+		if err != nil {
+			log.Error(fmt.Sprintf("sweeping sessions failed. Err: %v", err.Error()))
+			continue
+		}
+
+		log.Debugf(`purged %d sessions`, n)
+	}
+}
+
+// sweepRateLimits purges counters of past windows every interval, so that
+// one-off clients do not pile up
+func sweepRateLimits(rl ratelimit.Usecase, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		n, err := rl.Sweep(context.Background())
+		if err != nil {
+			log.Error(fmt.Sprintf("sweeping rate limits failed. Err: %v", err.Error()))
+			continue
+		}
+
+		log.Debugf(`purged %d rate limit counters`, n)
+	}
+}
+
+// newSessionCookies return the cookie mode of browser clients, nil when
+// tokens are returned in the body
+func newSessionCookies() *cmware.SessionCookies {
+	if !config.GetBool(`auth.cookie.enabled`) {
+		return nil
+	}
+
+	return cmware.NewSessionCookies(cmware.SessionCookies{
+		Name:        config.GetString(`auth.cookie.name`),
+		RefreshName: config.GetString(`auth.cookie.refresh_name`),
+		CSRFName:    config.GetString(`auth.cookie.csrf_name`),
+		CSRFHeader:  config.GetString(`auth.cookie.csrf_header`),
+		Domain:      config.GetString(`auth.cookie.domain`),
+		RefreshPath: config.GetString(`auth.cookie.refresh_path`),
+		Secure:      config.GetBool(`auth.cookie.secure`),
+		SameSite:    cmware.ParseSameSite(config.GetString(`auth.cookie.same_site`)),
+		MaxAge:      config.GetDuration(`auth.refresh_token_ttl`),
+	})
+}
+
+// newAuditRepositories return the sinks listed in audit.sinks, the first
+// one answers queries
+func newAuditRepositories(db *sql.DB) []audit.Repository {
+	repos := []audit.Repository{}
+	for _, sink := range config.GetStringSlice(`audit.sinks`) {
+		switch sink {
+		case `mysql`:
+			if onMySQL(`the mysql audit sink`, `events are not written to it`) {
+				repos = append(repos, _auditMySQLRepository.NewAuditRepository(db))
+			}
+		case `file`:
+			repo, err := _auditFileRepository.NewAuditRepository(config.GetString(`audit.file`))
+			if err != nil {
+				log.Error(fmt.Sprintf("opening audit file failed. Err: %v", err.Error()))
+				os.Exit(1)
+			}
+			repos = append(repos, repo)
+		default:
+			log.Error(fmt.Sprintf("unknown audit sink %q", sink))
+			os.Exit(1)
+		}
+	}
+
+	return repos
+}
+
+// newLockoutRepository keeps attempts in the database unless the memory
+// driver is chosen, which only suits a single replica
+func newLockoutRepository(db *sql.DB) lockout.Repository {
+	if config.GetString(`auth.lockout.driver`) == `memory` {
+		return _lockoutMemoryRepository.NewLockoutRepository()
+	}
+
+	if !onMySQL(`the mysql lockout driver`, `counting in memory`) {
+		return _lockoutMemoryRepository.NewLockoutRepository()
+	}
+
+	return _lockoutMySQLRepository.NewLockoutRepository(db)
+}
+
+// newRateLimitRepository counts in the database unless the memory driver is
+// chosen, which only suits a single replica
+func newRateLimitRepository(db *sql.DB) ratelimit.Repository {
+	if config.GetString(`rate_limit.driver`) == `memory` {
+		return _rateLimitMemoryRepository.NewRateLimitRepository()
+	}
+
+	if !onMySQL(`the mysql rate limit driver`, `counting in memory`) {
+		return _rateLimitMemoryRepository.NewRateLimitRepository()
+	}
+
+	return _rateLimitMySQLRepository.NewRateLimitRepository(db)
+}
+
+func newRateLimitOptions() _rateLimitUsecase.Options {
+	opts := _rateLimitUsecase.Options{}
+	if err := config.UnmarshalKey(`rate_limit.default`, &opts.Default); err != nil {
+		log.Error(fmt.Sprintf("loading rate limit failed. Err: %v", err.Error()))
+		os.Exit(1)
+	}
+
+	if err := config.UnmarshalKey(`rate_limit.routes`, &opts.Rules); err != nil {
+		log.Error(fmt.Sprintf("loading rate limit failed. Err: %v", err.Error()))
+		os.Exit(1)
+	}
+
+	return opts
+}
+
+func newPasswordPolicy() helper.PasswordPolicy {
+	policy := helper.PasswordPolicy{}
+	if err := config.UnmarshalKey(`password.policy`, &policy); err != nil {
+		log.Error(fmt.Sprintf("loading password policy failed. Err: %v", err.Error()))
+		os.Exit(1)
+	}
+
+	return policy
+}
@@ -0,0 +1,422 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	sq "github.com/elgris/sqrl"
+)
+
+// _LockName serialises migrations of replicas starting at the same time
+const _LockName = `lmnlo_schema_migrations`
+
+// _LockTimeout is how many seconds a migration waits for another to finish
+const _LockTimeout = 60
+
+//go:embed sql
+var files embed.FS
+
+// ErrLocked is returned while another process holds the migration lock
+var ErrLocked = errors.New(`another migration is running`)
+
+// fileName is <version>_<name>.<up|down>.sql
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// dialect is what differs between the databases migrations are shipped for
+type dialect struct {
+	builder sq.StatementBuilderType
+	// createTable creates schema_migrations
+	createTable string
+	lock        func(ctx context.Context, conn *sql.Conn) error
+	// unlock is run with the lock name unless empty
+	unlock string
+}
+
+// dialects by database.driver, their migrations are in sql/<driver>
+var dialects = map[string]*dialect{
+	`mysql`: {
+		builder: sq.StatementBuilder,
+		createTable: "CREATE TABLE IF NOT EXISTS `schema_migrations` (" +
+			"`version` BIGINT NOT NULL, " +
+			"`name` VARCHAR(255) NOT NULL, " +
+			"`apply_time` DATETIME NOT NULL, " +
+			"PRIMARY KEY (`version`)" +
+			") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4",
+		lock: func(ctx context.Context, conn *sql.Conn) error {
+			var ok sql.NullInt64
+			if err := conn.QueryRowContext(ctx, `SELECT GET_LOCK(?, ?)`, _LockName, _LockTimeout).Scan(&ok); err != nil {
+				return err
+			}
+
+			if ok.Int64 != 1 {
+				return ErrLocked
+			}
+
+			return nil
+		},
+		unlock: `SELECT RELEASE_LOCK(?)`,
+	},
+	`postgres`: {
+		builder: sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
+		createTable: `CREATE TABLE IF NOT EXISTS schema_migrations (` +
+			`version BIGINT PRIMARY KEY, ` +
+			`name VARCHAR(255) NOT NULL, ` +
+			`apply_time TIMESTAMPTZ NOT NULL` +
+			`)`,
+		// Waits as long as ctx allows, advisory locks take no timeout
+		lock: func(ctx context.Context, conn *sql.Conn) error {
+			_, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock(hashtext($1))`, _LockName)
+			return err
+		},
+		unlock: `SELECT pg_advisory_unlock(hashtext($1))`,
+	},
+	// A SQLite file is served by a single process, there is nobody to wait for
+	`sqlite`: {
+		builder: sq.StatementBuilder,
+		createTable: `CREATE TABLE IF NOT EXISTS schema_migrations (` +
+			`version INTEGER PRIMARY KEY, ` +
+			`name VARCHAR(255) NOT NULL, ` +
+			`apply_time DATETIME NOT NULL` +
+			`)`,
+		lock: func(ctx context.Context, conn *sql.Conn) error {
+			return nil
+		},
+	},
+}
+
+// Migration is one version of the schema, Down reverts what Up did
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status is a migration and when it was applied, AppliedAt is nil while it
+// is pending
+type Status struct {
+	*Migration
+	AppliedAt *time.Time
+}
+
+// Migrator applies migrations in version order and records them in the
+// schema_migrations table.
+//
+// MySQL commits schema changes as they run, so a migration that fails
+// halfway keeps its earlier statements. Migrations are therefore written
+// to be run again, with IF NOT EXISTS and INSERT IGNORE or ON CONFLICT.
+type Migrator struct {
+	Conn       *sql.DB
+	dialect    *dialect
+	migrations []*Migration
+}
+
+// NewMigrator return a migrator of the migrations embedded in the binary
+// for driver, mysql, postgres or sqlite
+func NewMigrator(Conn *sql.DB, driver string) (*Migrator, error) {
+	sub, err := fs.Sub(files, `sql/`+driver)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewMigratorFS(Conn, driver, sub)
+}
+
+// NewMigratorFS return a migrator of the .sql files at the root of fsys.
+// Every version needs an up and a down file.
+func NewMigratorFS(Conn *sql.DB, driver string, fsys fs.FS) (*Migrator, error) {
+	d, ok := dialects[driver]
+	if !ok {
+		return nil, fmt.Errorf(`no migrations for database driver %q`, driver)
+	}
+
+	migrations, err := load(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{Conn, d, migrations}, nil
+}
+
+// Migrations return every known migration in version order
+func (m *Migrator) Migrations() []*Migration {
+	return m.migrations
+}
+
+// Up applies every pending migration and return those it applied
+func (m *Migrator) Up(ctx context.Context) ([]*Migration, error) {
+	conn, err := m.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer m.unlock(conn)
+
+	applied, err := m.applied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	done := []*Migration{}
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.Version]; ok {
+			continue
+		}
+
+		if err := execScript(ctx, conn, mig.Up); err != nil {
+			return done, fmt.Errorf(`migration %d_%s: %v`, mig.Version, mig.Name, err)
+		}
+
+		query := m.dialect.builder.Insert(`schema_migrations`).
+			Columns(`version`, `name`, `apply_time`).
+			Values(mig.Version, mig.Name, time.Now())
+
+		if err := execQuery(ctx, conn, query); err != nil {
+			return done, err
+		}
+
+		done = append(done, mig)
+	}
+
+	return done, nil
+}
+
+// Down reverts the latest applied migration and return it, nil when none
+// was applied
+func (m *Migrator) Down(ctx context.Context) (*Migration, error) {
+	conn, err := m.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer m.unlock(conn)
+
+	applied, err := m.applied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	var latest int64
+	for version := range applied {
+		if version > latest {
+			latest = version
+		}
+	}
+
+	if latest == 0 {
+		return nil, nil
+	}
+
+	mig := m.find(latest)
+	if mig == nil {
+		return nil, fmt.Errorf(`migration %d is applied but unknown to this binary`, latest)
+	}
+
+	if err := execScript(ctx, conn, mig.Down); err != nil {
+		return nil, fmt.Errorf(`migration %d_%s: %v`, mig.Version, mig.Name, err)
+	}
+
+	query := m.dialect.builder.Delete(`schema_migrations`).
+		Where(`version = ?`, mig.Version)
+
+	if err := execQuery(ctx, conn, query); err != nil {
+		return nil, err
+	}
+
+	return mig, nil
+}
+
+// Status lists every known migration with when it was applied
+func (m *Migrator) Status(ctx context.Context) ([]*Status, error) {
+	conn, err := m.Conn.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	applied, err := m.applied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	res := []*Status{}
+	for _, mig := range m.migrations {
+		st := &Status{Migration: mig}
+		if t, ok := applied[mig.Version]; ok {
+			st.AppliedAt = &t
+		}
+		res = append(res, st)
+	}
+
+	return res, nil
+}
+
+// lock takes the named lock on a connection of its own, the lock belongs
+// to the connection and is gone once it closes
+func (m *Migrator) lock(ctx context.Context) (*sql.Conn, error) {
+	conn, err := m.Conn.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.dialect.lock(ctx, conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+func (m *Migrator) unlock(conn *sql.Conn) {
+	if m.dialect.unlock != `` {
+		conn.ExecContext(context.Background(), m.dialect.unlock, _LockName)
+	}
+	conn.Close()
+}
+
+// applied return the apply time of every version in schema_migrations,
+// creating the table on first use
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn) (map[int64]time.Time, error) {
+	if _, err := conn.ExecContext(ctx, m.dialect.createTable); err != nil {
+		return nil, err
+	}
+
+	query := m.dialect.builder.Select(`version, apply_time`).
+		From(`schema_migrations`)
+
+	sql, args, _ := query.ToSql()
+	rows, err := conn.QueryContext(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := map[int64]time.Time{}
+	for rows.Next() {
+		var version int64
+		var t time.Time
+		if err := rows.Scan(&version, &t); err != nil {
+			return nil, err
+		}
+		res[version] = t
+	}
+
+	return res, rows.Err()
+}
+
+func (m *Migrator) find(version int64) *Migration {
+	for _, mig := range m.migrations {
+		if mig.Version == version {
+			return mig
+		}
+	}
+
+	return nil
+}
+
+func execQuery(ctx context.Context, conn *sql.Conn, query sq.Sqlizer) error {
+	sql, args, _ := query.ToSql()
+	_, err := conn.ExecContext(ctx, sql, args...)
+	return err
+}
+
+// execScript runs the statements of script one by one, the driver does not
+// take several at once
+func execScript(ctx context.Context, conn *sql.Conn, script string) error {
+	for _, stmt := range Statements(script) {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Statements splits script at semicolons ending a line, dropping comment
+// lines and empty statements
+func Statements(script string) []string {
+	res := []string{}
+	cur := []string{}
+
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == `` || strings.HasPrefix(trimmed, `--`) {
+			continue
+		}
+
+		cur = append(cur, line)
+		if strings.HasSuffix(trimmed, `;`) {
+			res = append(res, strings.TrimSuffix(strings.TrimSpace(strings.Join(cur, "\n")), `;`))
+			cur = []string{}
+		}
+	}
+
+	if stmt := strings.TrimSpace(strings.Join(cur, "\n")); stmt != `` {
+		res = append(res, stmt)
+	}
+
+	return res
+}
+
+// load reads the migrations of fsys in version order
+func load(fsys fs.FS) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, `.`)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+		if version <= 0 {
+			return nil, fmt.Errorf(`migration %s: version must be positive`, entry.Name())
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = mig
+		}
+
+		if mig.Name != match[2] {
+			return nil, fmt.Errorf(`migration %d is named both %s and %s`, version, mig.Name, match[2])
+		}
+
+		body, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		if match[3] == `up` {
+			mig.Up = string(body)
+		} else {
+			mig.Down = string(body)
+		}
+	}
+
+	res := []*Migration{}
+	for _, mig := range byVersion {
+		if mig.Up == `` || mig.Down == `` {
+			return nil, fmt.Errorf(`migration %d_%s needs both an up and a down file`, mig.Version, mig.Name)
+		}
+		res = append(res, mig)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Version < res[j].Version
+	})
+
+	return res, nil
+}
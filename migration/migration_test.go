@@ -0,0 +1,277 @@
+package migration_test
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/andhikagama/lmnlo/migration"
+	"github.com/andhikagama/lmnlo/models/entity"
+)
+
+var mockFS = fstest.MapFS{
+	`0001_create_a.up.sql`:   {Data: []byte("-- first table\nCREATE TABLE a (\n  id BIGINT\n);\n")},
+	`0001_create_a.down.sql`: {Data: []byte("DROP TABLE a;\n")},
+	`0002_create_b.up.sql`:   {Data: []byte("CREATE TABLE b (id BIGINT);\n\nINSERT INTO b VALUES (1);\n")},
+	`0002_create_b.down.sql`: {Data: []byte("DROP TABLE b;\n")},
+	`README.md`:              {Data: []byte("not a migration")},
+}
+
+func expectLock(mock sqlmock.Sqlmock, ok int64) {
+	mock.ExpectQuery(`SELECT GET_LOCK`).WillReturnRows(sqlmock.NewRows([]string{`ok`}).AddRow(ok))
+}
+
+func expectApplied(mock sqlmock.Sqlmock, versions ...int64) {
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS `schema_migrations`").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	rows := sqlmock.NewRows([]string{`version`, `apply_time`})
+	for _, v := range versions {
+		rows.AddRow(v, time.Now())
+	}
+	mock.ExpectQuery(`SELECT (.+) FROM schema_migrations`).WillReturnRows(rows)
+}
+
+func TestNewMigrator(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	for _, driver := range []string{`mysql`, `postgres`, `sqlite`} {
+		t.Run("embedded-"+driver, func(t *testing.T) {
+			m, err := migration.NewMigrator(db, driver)
+			assert.NoError(t, err)
+
+			migrations := m.Migrations()
+			assert.NotEmpty(t, migrations)
+			for i, mig := range migrations {
+				assert.Equal(t, int64(i+1), mig.Version)
+				assert.NotEmpty(t, migration.Statements(mig.Up))
+				assert.NotEmpty(t, migration.Statements(mig.Down))
+			}
+
+			schema := ``
+			for _, mig := range migrations {
+				schema += mig.Up
+			}
+
+			permissions := []string{
+				entity.PermissionUserRead,
+				entity.PermissionUserUpdate,
+				entity.PermissionUserList,
+				entity.PermissionUserDelete,
+				entity.PermissionRoleAssign,
+				entity.PermissionLockoutUnlock,
+				entity.PermissionClientManage,
+				entity.PermissionUserImpersonate,
+				entity.PermissionAuditRead,
+			}
+			for _, p := range permissions {
+				assert.Contains(t, schema, `('admin', '`+p+`')`)
+			}
+		})
+	}
+
+	t.Run("error-driver", func(t *testing.T) {
+		_, err := migration.NewMigrator(db, `oracle`)
+		assert.Error(t, err)
+	})
+
+	t.Run("fs", func(t *testing.T) {
+		m, err := migration.NewMigratorFS(db, `mysql`, mockFS)
+		assert.NoError(t, err)
+		assert.Len(t, m.Migrations(), 2)
+		assert.Equal(t, `create_a`, m.Migrations()[0].Name)
+		assert.Equal(t, `create_b`, m.Migrations()[1].Name)
+	})
+
+	t.Run("error-missing-down", func(t *testing.T) {
+		_, err := migration.NewMigratorFS(db, `mysql`, fstest.MapFS{
+			`0001_create_a.up.sql`: {Data: []byte("CREATE TABLE a (id BIGINT);")},
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("error-name", func(t *testing.T) {
+		_, err := migration.NewMigratorFS(db, `mysql`, fstest.MapFS{
+			`0001_create_a.up.sql`:   {Data: []byte("CREATE TABLE a (id BIGINT);")},
+			`0001_create_b.down.sql`: {Data: []byte("DROP TABLE b;")},
+		})
+		assert.Error(t, err)
+	})
+}
+
+func TestStatements(t *testing.T) {
+	script := "-- comment\nCREATE TABLE a (\n  id BIGINT\n);\n\nINSERT INTO a VALUES (1);\nDROP TABLE b"
+
+	assert.Equal(t, []string{
+		"CREATE TABLE a (\n  id BIGINT\n)",
+		`INSERT INTO a VALUES (1)`,
+		`DROP TABLE b`,
+	}, migration.Statements(script))
+	assert.Empty(t, migration.Statements("-- nothing\n\n"))
+}
+
+func TestUp(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	m, err := migration.NewMigratorFS(db, `mysql`, mockFS)
+	assert.NoError(t, err)
+
+	t.Run("success", func(t *testing.T) {
+		expectLock(mock, 1)
+		expectApplied(mock, 1)
+		mock.ExpectExec(`CREATE TABLE b`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`INSERT INTO b`).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(`INSERT INTO schema_migrations`).WithArgs(int64(2), `create_b`, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectExec(`SELECT RELEASE_LOCK`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		done, err := m.Up(context.TODO())
+
+		assert.NoError(t, err)
+		assert.Len(t, done, 1)
+		assert.Equal(t, int64(2), done[0].Version)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("success-up-to-date", func(t *testing.T) {
+		expectLock(mock, 1)
+		expectApplied(mock, 1, 2)
+		mock.ExpectExec(`SELECT RELEASE_LOCK`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		done, err := m.Up(context.TODO())
+
+		assert.NoError(t, err)
+		assert.Len(t, done, 0)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("error-locked", func(t *testing.T) {
+		expectLock(mock, 0)
+
+		done, err := m.Up(context.TODO())
+
+		assert.Equal(t, migration.ErrLocked, err)
+		assert.Nil(t, done)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("error-statement", func(t *testing.T) {
+		expectLock(mock, 1)
+		expectApplied(mock)
+		mock.ExpectExec(`CREATE TABLE a`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`INSERT INTO schema_migrations`).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(`CREATE TABLE b`).WillReturnError(assert.AnError)
+		mock.ExpectExec(`SELECT RELEASE_LOCK`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		done, err := m.Up(context.TODO())
+
+		assert.Error(t, err)
+		assert.Len(t, done, 1)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestUpPostgres(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	m, err := migration.NewMigratorFS(db, `postgres`, mockFS)
+	assert.NoError(t, err)
+
+	mock.ExpectExec(`SELECT pg_advisory_lock(hashtext($1))`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT PRIMARY KEY, name VARCHAR(255) NOT NULL, apply_time TIMESTAMPTZ NOT NULL)`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT version, apply_time FROM schema_migrations`).WillReturnRows(sqlmock.NewRows([]string{`version`, `apply_time`}).AddRow(1, time.Now()))
+	mock.ExpectExec(`CREATE TABLE b (id BIGINT)`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO b VALUES (1)`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO schema_migrations (version,name,apply_time) VALUES ($1,$2,$3)`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`SELECT pg_advisory_unlock(hashtext($1))`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	done, err := m.Up(context.TODO())
+
+	assert.NoError(t, err)
+	assert.Len(t, done, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDown(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	m, err := migration.NewMigratorFS(db, `mysql`, mockFS)
+	assert.NoError(t, err)
+
+	t.Run("success", func(t *testing.T) {
+		expectLock(mock, 1)
+		expectApplied(mock, 1, 2)
+		mock.ExpectExec(`DROP TABLE b`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`DELETE FROM schema_migrations`).WithArgs(int64(2)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`SELECT RELEASE_LOCK`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		mig, err := m.Down(context.TODO())
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), mig.Version)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("success-nothing-applied", func(t *testing.T) {
+		expectLock(mock, 1)
+		expectApplied(mock)
+		mock.ExpectExec(`SELECT RELEASE_LOCK`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		mig, err := m.Down(context.TODO())
+
+		assert.NoError(t, err)
+		assert.Nil(t, mig)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("error-unknown", func(t *testing.T) {
+		expectLock(mock, 1)
+		expectApplied(mock, 1, 2, 3)
+		mock.ExpectExec(`SELECT RELEASE_LOCK`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		mig, err := m.Down(context.TODO())
+
+		assert.Error(t, err)
+		assert.Nil(t, mig)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestStatus(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	m, err := migration.NewMigratorFS(db, `mysql`, mockFS)
+	assert.NoError(t, err)
+
+	expectApplied(mock, 1)
+
+	res, err := m.Status(context.TODO())
+
+	assert.NoError(t, err)
+	assert.Len(t, res, 2)
+	assert.NotNil(t, res[0].AppliedAt)
+	assert.Nil(t, res[1].AppliedAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS `user` (
+  `id` BIGINT NOT NULL AUTO_INCREMENT,
+  `email` VARCHAR(255) NOT NULL,
+  -- Empty for accounts created through social login
+  `password` VARCHAR(255) NOT NULL DEFAULT '',
+  `address` VARCHAR(255) NOT NULL DEFAULT '',
+  `verified_at` DATETIME NULL,
+  `create_time` DATETIME NOT NULL,
+  `update_time` DATETIME NULL,
+  `delete_time` DATETIME NULL,
+  PRIMARY KEY (`id`),
+  -- Not unique, deleted accounts keep their email
+  KEY `idx_user_email` (`email`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `user_token` (
+  `id` BIGINT NOT NULL AUTO_INCREMENT,
+  `user_id` BIGINT NOT NULL,
+  `purpose` VARCHAR(32) NOT NULL,
+  `token_hash` CHAR(64) NOT NULL,
+  `expire_time` DATETIME NOT NULL,
+  `use_time` DATETIME NULL,
+  `create_time` DATETIME NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_user_token_hash` (`token_hash`),
+  KEY `idx_user_token_user` (`user_id`, `purpose`, `create_time`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS `role` (
+  `name` VARCHAR(64) NOT NULL,
+  PRIMARY KEY (`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `role_permission` (
+  `role` VARCHAR(64) NOT NULL,
+  `permission` VARCHAR(64) NOT NULL,
+  PRIMARY KEY (`role`, `permission`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `user_role` (
+  `user_id` BIGINT NOT NULL,
+  `role` VARCHAR(64) NOT NULL,
+  PRIMARY KEY (`user_id`, `role`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+INSERT IGNORE INTO `role` (`name`) VALUES ('admin'), ('user');
+
+INSERT IGNORE INTO `role_permission` (`role`, `permission`) VALUES
+  ('admin', 'user:read'),
+  ('admin', 'user:update'),
+  ('admin', 'user:delete'),
+  ('admin', 'role:assign'),
+  ('admin', 'lockout:unlock'),
+  ('admin', 'client:manage'),
+  ('admin', 'user:impersonate'),
+  ('admin', 'audit:read');
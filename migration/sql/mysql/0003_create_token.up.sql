@@ -0,0 +1,34 @@
+-- One row per session, the access token itself is never stored
+CREATE TABLE IF NOT EXISTS `token` (
+  `id` BIGINT NOT NULL AUTO_INCREMENT,
+  `user_id` BIGINT NOT NULL,
+  `family_id` VARCHAR(64) NOT NULL DEFAULT '',
+  `jti` VARCHAR(64) NOT NULL,
+  `token_hash` CHAR(64) NOT NULL,
+  `user_agent` VARCHAR(255) NOT NULL DEFAULT '',
+  `ip` VARCHAR(45) NOT NULL DEFAULT '',
+  `create_time` DATETIME NOT NULL,
+  `expire_time` DATETIME NOT NULL,
+  `last_used_time` DATETIME NULL,
+  `revoke_time` DATETIME NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_token_jti` (`jti`),
+  KEY `idx_token_hash` (`token_hash`),
+  KEY `idx_token_family` (`family_id`),
+  KEY `idx_token_user` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `refresh_token` (
+  `id` BIGINT NOT NULL AUTO_INCREMENT,
+  `user_id` BIGINT NOT NULL,
+  `family_id` VARCHAR(64) NOT NULL,
+  `token_hash` CHAR(64) NOT NULL,
+  `expire_time` DATETIME NOT NULL,
+  `use_time` DATETIME NULL,
+  `revoke_time` DATETIME NULL,
+  `create_time` DATETIME NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uniq_refresh_token_hash` (`token_hash`),
+  KEY `idx_refresh_token_family` (`family_id`),
+  KEY `idx_refresh_token_user` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
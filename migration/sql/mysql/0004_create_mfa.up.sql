@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS `user_mfa` (
+  `user_id` BIGINT NOT NULL,
+  `secret` VARCHAR(255) NOT NULL,
+  `last_used_step` BIGINT NOT NULL DEFAULT 0,
+  `confirm_time` DATETIME NULL,
+  `create_time` DATETIME NOT NULL,
+  PRIMARY KEY (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `user_recovery_code` (
+  `id` BIGINT NOT NULL AUTO_INCREMENT,
+  `user_id` BIGINT NOT NULL,
+  `code_hash` CHAR(64) NOT NULL,
+  `use_time` DATETIME NULL,
+  `create_time` DATETIME NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_user_recovery_code_user` (`user_id`, `code_hash`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
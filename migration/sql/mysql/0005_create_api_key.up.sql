@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS `api_key` (
+  `id` BIGINT NOT NULL AUTO_INCREMENT,
+  `user_id` BIGINT NOT NULL,
+  `name` VARCHAR(255) NOT NULL,
+  `prefix` VARCHAR(32) NOT NULL,
+  `secret_hash` CHAR(64) NOT NULL,
+  -- Comma separated permissions
+  `scopes` VARCHAR(1024) NOT NULL DEFAULT '',
+  `expire_time` DATETIME NULL,
+  `last_used_time` DATETIME NULL,
+  `create_time` DATETIME NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uniq_api_key_prefix` (`prefix`),
+  KEY `idx_api_key_user` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS `user_identity` (
+  `id` BIGINT NOT NULL AUTO_INCREMENT,
+  `user_id` BIGINT NOT NULL,
+  `provider` VARCHAR(64) NOT NULL,
+  `subject` VARCHAR(255) NOT NULL,
+  `email` VARCHAR(255) NOT NULL DEFAULT '',
+  `create_time` DATETIME NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uniq_user_identity_subject` (`provider`, `subject`),
+  KEY `idx_user_identity_user` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `oauth_state` (
+  `state_hash` CHAR(64) NOT NULL,
+  `provider` VARCHAR(64) NOT NULL,
+  `verifier` VARCHAR(128) NOT NULL,
+  `nonce` VARCHAR(128) NOT NULL,
+  `expire_time` DATETIME NOT NULL,
+  PRIMARY KEY (`state_hash`),
+  KEY `idx_oauth_state_expire` (`expire_time`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
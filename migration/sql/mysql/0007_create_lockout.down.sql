@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS `rate_limit`;
+
+DROP TABLE IF EXISTS `login_attempt`;
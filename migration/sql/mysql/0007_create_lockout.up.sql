@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS `login_attempt` (
+  `attempt_key` VARCHAR(255) NOT NULL,
+  `failures` BIGINT NOT NULL DEFAULT 0,
+  `last_failure_time` DATETIME NOT NULL,
+  `lock_time` DATETIME NULL,
+  PRIMARY KEY (`attempt_key`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `rate_limit` (
+  `bucket_key` VARCHAR(255) NOT NULL,
+  `window_start` DATETIME NOT NULL,
+  `hits` BIGINT NOT NULL DEFAULT 0,
+  `previous_hits` BIGINT NOT NULL DEFAULT 0,
+  PRIMARY KEY (`bucket_key`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS `oauth_client` (
+  `id` BIGINT NOT NULL AUTO_INCREMENT,
+  `client_id` VARCHAR(64) NOT NULL,
+  `secret_hash` VARCHAR(255) NOT NULL DEFAULT '',
+  `name` VARCHAR(255) NOT NULL,
+  `redirect_uris` TEXT NOT NULL,
+  `scopes` VARCHAR(1024) NOT NULL DEFAULT '',
+  `grant_types` VARCHAR(255) NOT NULL DEFAULT '',
+  `public` TINYINT(1) NOT NULL DEFAULT 0,
+  `create_time` DATETIME NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uniq_oauth_client_client_id` (`client_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `oauth_code` (
+  `code_hash` CHAR(64) NOT NULL,
+  `client_id` VARCHAR(64) NOT NULL,
+  `user_id` BIGINT NOT NULL,
+  `redirect_uri` VARCHAR(2048) NOT NULL,
+  `scopes` VARCHAR(1024) NOT NULL DEFAULT '',
+  `challenge` VARCHAR(128) NOT NULL DEFAULT '',
+  `nonce` VARCHAR(255) NOT NULL DEFAULT '',
+  `expire_time` DATETIME NOT NULL,
+  PRIMARY KEY (`code_hash`),
+  KEY `idx_oauth_code_client` (`client_id`),
+  KEY `idx_oauth_code_expire` (`expire_time`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
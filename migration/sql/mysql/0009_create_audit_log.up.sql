@@ -0,0 +1,16 @@
+-- Append-only, rows are never updated or deleted
+CREATE TABLE IF NOT EXISTS `audit_log` (
+  `id` BIGINT NOT NULL AUTO_INCREMENT,
+  `actor_id` BIGINT NOT NULL DEFAULT 0,
+  `impersonator_id` BIGINT NOT NULL DEFAULT 0,
+  `action` VARCHAR(64) NOT NULL,
+  `target_id` BIGINT NOT NULL DEFAULT 0,
+  `diff` JSON NULL,
+  `ip` VARCHAR(45) NOT NULL DEFAULT '',
+  `request_id` VARCHAR(64) NOT NULL DEFAULT '',
+  `create_time` DATETIME NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_audit_log_actor` (`actor_id`),
+  KEY `idx_audit_log_target` (`target_id`),
+  KEY `idx_audit_log_create` (`create_time`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,3 @@
+DELETE FROM `role_permission` WHERE `role` = 'user' AND `permission` IN ('user:read', 'user:update');
+
+DELETE FROM `role_permission` WHERE `permission` = 'user:list';
@@ -0,0 +1,5 @@
+-- Users read and update their own account, listing everyone is for admins
+INSERT IGNORE INTO `role_permission` (`role`, `permission`) VALUES
+  ('admin', 'user:list'),
+  ('user', 'user:read'),
+  ('user', 'user:update');
@@ -0,0 +1,10 @@
+-- Dropping the column drops its unique key along
+SET @ddl = IF(
+  (SELECT COUNT(*) FROM information_schema.columns
+    WHERE table_schema = DATABASE() AND table_name = 'user' AND column_name = 'live_email') > 0,
+  'ALTER TABLE `user` DROP COLUMN `live_email`',
+  'DO 0'
+);
+PREPARE stmt FROM @ddl;
+EXECUTE stmt;
+DEALLOCATE PREPARE stmt;
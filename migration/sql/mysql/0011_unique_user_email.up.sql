@@ -0,0 +1,25 @@
+-- One live account per email. Deleted accounts keep their email but leave
+-- live_email NULL, which a unique key does not compare, so the email can
+-- register again. Live accounts sharing an email have to be merged or
+-- deleted before this runs, the unique key is refused otherwise.
+-- MySQL has no IF NOT EXISTS for columns and indexes, each step is skipped
+-- through information_schema when a previous run already made it.
+SET @ddl = IF(
+  (SELECT COUNT(*) FROM information_schema.columns
+    WHERE table_schema = DATABASE() AND table_name = 'user' AND column_name = 'live_email') = 0,
+  'ALTER TABLE `user` ADD COLUMN `live_email` VARCHAR(255) AS (IF(`delete_time` IS NULL, `email`, NULL)) VIRTUAL',
+  'DO 0'
+);
+PREPARE stmt FROM @ddl;
+EXECUTE stmt;
+DEALLOCATE PREPARE stmt;
+
+SET @ddl = IF(
+  (SELECT COUNT(*) FROM information_schema.statistics
+    WHERE table_schema = DATABASE() AND table_name = 'user' AND index_name = 'uniq_user_live_email') = 0,
+  'ALTER TABLE `user` ADD UNIQUE KEY `uniq_user_live_email` (`live_email`)',
+  'DO 0'
+);
+PREPARE stmt FROM @ddl;
+EXECUTE stmt;
+DEALLOCATE PREPARE stmt;
@@ -0,0 +1,29 @@
+-- user is a reserved word and always quoted
+CREATE TABLE IF NOT EXISTS "user" (
+  id BIGSERIAL PRIMARY KEY,
+  email VARCHAR(255) NOT NULL,
+  -- Empty for accounts created through social login
+  password VARCHAR(255) NOT NULL DEFAULT '',
+  address VARCHAR(255) NOT NULL DEFAULT '',
+  verified_at TIMESTAMPTZ NULL,
+  create_time TIMESTAMPTZ NOT NULL,
+  update_time TIMESTAMPTZ NULL,
+  delete_time TIMESTAMPTZ NULL
+);
+
+-- Not unique, deleted accounts keep their email
+CREATE INDEX IF NOT EXISTS idx_user_email ON "user" (email);
+
+CREATE TABLE IF NOT EXISTS user_token (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL,
+  purpose VARCHAR(32) NOT NULL,
+  token_hash CHAR(64) NOT NULL,
+  expire_time TIMESTAMPTZ NOT NULL,
+  use_time TIMESTAMPTZ NULL,
+  create_time TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_token_hash ON user_token (token_hash);
+
+CREATE INDEX IF NOT EXISTS idx_user_token_user ON user_token (user_id, purpose, create_time);
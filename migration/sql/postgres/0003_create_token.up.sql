@@ -0,0 +1,37 @@
+-- One row per session, the access token itself is never stored
+CREATE TABLE IF NOT EXISTS token (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL,
+  family_id VARCHAR(64) NOT NULL DEFAULT '',
+  jti VARCHAR(64) NOT NULL,
+  token_hash CHAR(64) NOT NULL,
+  user_agent VARCHAR(255) NOT NULL DEFAULT '',
+  ip VARCHAR(45) NOT NULL DEFAULT '',
+  create_time TIMESTAMPTZ NOT NULL,
+  expire_time TIMESTAMPTZ NOT NULL,
+  last_used_time TIMESTAMPTZ NULL,
+  revoke_time TIMESTAMPTZ NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_token_jti ON token (jti);
+
+CREATE INDEX IF NOT EXISTS idx_token_hash ON token (token_hash);
+
+CREATE INDEX IF NOT EXISTS idx_token_family ON token (family_id);
+
+CREATE INDEX IF NOT EXISTS idx_token_user ON token (user_id);
+
+CREATE TABLE IF NOT EXISTS refresh_token (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL,
+  family_id VARCHAR(64) NOT NULL,
+  token_hash CHAR(64) NOT NULL UNIQUE,
+  expire_time TIMESTAMPTZ NOT NULL,
+  use_time TIMESTAMPTZ NULL,
+  revoke_time TIMESTAMPTZ NULL,
+  create_time TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_token_family ON refresh_token (family_id);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_token_user ON refresh_token (user_id);
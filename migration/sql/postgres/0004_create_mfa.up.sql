@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS user_mfa (
+  user_id BIGINT PRIMARY KEY,
+  secret VARCHAR(255) NOT NULL,
+  last_used_step BIGINT NOT NULL DEFAULT 0,
+  confirm_time TIMESTAMPTZ NULL,
+  create_time TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS user_recovery_code (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL,
+  code_hash CHAR(64) NOT NULL,
+  use_time TIMESTAMPTZ NULL,
+  create_time TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_recovery_code_user ON user_recovery_code (user_id, code_hash);
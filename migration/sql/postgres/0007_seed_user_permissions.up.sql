@@ -0,0 +1,6 @@
+-- Users read and update their own account, listing everyone is for admins
+INSERT INTO role_permission (role, permission) VALUES
+  ('admin', 'user:list'),
+  ('user', 'user:read'),
+  ('user', 'user:update')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,3 @@
+-- One live account per email, deleted accounts keep their email and leave
+-- it free to register again
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_user_live_email ON "user" (email) WHERE delete_time IS NULL;
@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS "user" (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  email VARCHAR(255) NOT NULL,
+  -- Empty for accounts created through social login
+  password VARCHAR(255) NOT NULL DEFAULT '',
+  address VARCHAR(255) NOT NULL DEFAULT '',
+  verified_at DATETIME NULL,
+  create_time DATETIME NOT NULL,
+  update_time DATETIME NULL,
+  delete_time DATETIME NULL
+);
+
+-- Not unique, deleted accounts keep their email
+CREATE INDEX IF NOT EXISTS idx_user_email ON "user" (email);
+
+CREATE TABLE IF NOT EXISTS user_token (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_id BIGINT NOT NULL,
+  purpose VARCHAR(32) NOT NULL,
+  token_hash CHAR(64) NOT NULL,
+  expire_time DATETIME NOT NULL,
+  use_time DATETIME NULL,
+  create_time DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_token_hash ON user_token (token_hash);
+
+CREATE INDEX IF NOT EXISTS idx_user_token_user ON user_token (user_id, purpose, create_time);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS api_key (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_id BIGINT NOT NULL,
+  name VARCHAR(255) NOT NULL,
+  prefix VARCHAR(32) NOT NULL UNIQUE,
+  secret_hash CHAR(64) NOT NULL,
+  -- Comma separated permissions
+  scopes VARCHAR(1024) NOT NULL DEFAULT '',
+  expire_time DATETIME NULL,
+  last_used_time DATETIME NULL,
+  create_time DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_key_user ON api_key (user_id);
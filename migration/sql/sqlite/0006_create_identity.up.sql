@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS user_identity (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_id BIGINT NOT NULL,
+  provider VARCHAR(64) NOT NULL,
+  subject VARCHAR(255) NOT NULL,
+  email VARCHAR(255) NOT NULL DEFAULT '',
+  create_time DATETIME NOT NULL,
+  UNIQUE (provider, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_identity_user ON user_identity (user_id);
+
+CREATE TABLE IF NOT EXISTS oauth_state (
+  state_hash CHAR(64) PRIMARY KEY,
+  provider VARCHAR(64) NOT NULL,
+  verifier VARCHAR(128) NOT NULL,
+  nonce VARCHAR(128) NOT NULL,
+  expire_time DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_oauth_state_expire ON oauth_state (expire_time);
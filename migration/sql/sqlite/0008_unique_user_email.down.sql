@@ -0,0 +1 @@
+DROP INDEX IF EXISTS uniq_user_live_email;
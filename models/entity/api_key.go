@@ -0,0 +1,24 @@
+package entity
+
+import "time"
+
+// APIKey lets a machine client act as its owner, limited to Scopes. Only
+// the hash of the secret is stored, the full key is returned once when it
+// is created.
+type APIKey struct {
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"-"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	SecretHash string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Key        string     `json:"key,omitempty"`
+}
+
+// Expired reports whether the key stopped being valid at t
+func (k *APIKey) Expired(t time.Time) bool {
+	return k.ExpiresAt != nil && !k.ExpiresAt.After(t)
+}
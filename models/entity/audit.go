@@ -0,0 +1,50 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Audited actions
+const (
+	AuditUserRegister          = `user.register`
+	AuditUserUpdate            = `user.update`
+	AuditUserDelete            = `user.delete`
+	AuditUserLogin             = `user.login`
+	AuditUserLoginFailed       = `user.login_failed`
+	AuditUserImpersonate       = `user.impersonate`
+	AuditUserLogout            = `user.logout`
+	AuditUserAssignRoles       = `user.assign_roles`
+	AuditUserRevokeTokenFamily = `user.revoke_token_family`
+	AuditUserChangePassword    = `user.change_password`
+	AuditUserResetPassword     = `user.reset_password`
+	AuditUserEnableMFA         = `user.enable_mfa`
+	AuditUserDisableMFA        = `user.disable_mfa`
+	AuditUserCreateAPIKey      = `user.create_api_key`
+	AuditUserRevokeAPIKey      = `user.revoke_api_key`
+)
+
+// AuditEvent records who did what to whom. ActorID is zero for anonymous
+// requests such as registration or a failed login.
+type AuditEvent struct {
+	ID      int64 `json:"id"`
+	ActorID int64 `json:"actor_id"`
+	// ImpersonatorID is the support admin acting as ActorID
+	ImpersonatorID int64  `json:"impersonator_id,omitempty"`
+	Action         string `json:"action"`
+	TargetID       int64  `json:"target_id"`
+	// Diff holds the changed fields as {"field": {"before": .., "after": ..}}
+	Diff      json.RawMessage `json:"diff,omitempty"`
+	IP        string          `json:"ip"`
+	RequestID string          `json:"request_id,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Origin is who makes a request and from where, as recorded in audit
+// events
+type Origin struct {
+	ActorID        int64
+	ImpersonatorID int64
+	IP             string
+	RequestID      string
+}
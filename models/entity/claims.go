@@ -1,11 +1,69 @@
 package entity
 
 import (
+	"time"
+
 	"github.com/dgrijalva/jwt-go"
 )
 
-// Claims ...
+// Claims are carried by session access tokens. The subject is the user ID;
+// the user itself is loaded on every request, roles are informational only.
 type Claims struct {
-	User *User `json:"user"`
+	Roles []string `json:"roles,omitempty"`
+	// Act names the support admin who impersonates the subject
+	Act *Actor `json:"act,omitempty"`
+	// Leeway tolerates clock skew between servers when exp, iat and nbf
+	// are checked, it is not part of the token
+	Leeway time.Duration `json:"-"`
+	jwt.StandardClaims
+}
+
+// Valid checks the time based claims allowing for Leeway. Unlike
+// StandardClaims an expiry is required.
+func (c *Claims) Valid() error {
+	now := time.Now().Unix()
+	skew := int64(c.Leeway / time.Second)
+
+	if !c.VerifyExpiresAt(now-skew, true) {
+		return jwt.NewValidationError(`token is expired`, jwt.ValidationErrorExpired)
+	}
+
+	if !c.VerifyIssuedAt(now+skew, false) {
+		return jwt.NewValidationError(`token used before issued`, jwt.ValidationErrorIssuedAt)
+	}
+
+	if !c.VerifyNotBefore(now+skew, false) {
+		return jwt.NewValidationError(`token is not valid yet`, jwt.ValidationErrorNotValidYet)
+	}
+
+	return nil
+}
+
+// Actor is the act claim of RFC 8693, the party acting as the subject
+type Actor struct {
+	Subject string `json:"sub"`
+}
+
+// PurposeClaims are carried by signed single-use tokens, the subject is the
+// user ID and Email the address the user had when the token was issued
+type PurposeClaims struct {
+	Purpose string `json:"purpose"`
+	Email   string `json:"email,omitempty"`
+	jwt.StandardClaims
+}
+
+// AccessClaims are carried by access tokens issued to OAuth clients. The
+// subject is the user ID, or the client ID for client credentials.
+type AccessClaims struct {
+	Scope    string `json:"scope"`
+	ClientID string `json:"client_id"`
+	jwt.StandardClaims
+}
+
+// IDClaims are carried by OpenID Connect ID tokens
+type IDClaims struct {
+	Nonce         string `json:"nonce,omitempty"`
+	Email         string `json:"email,omitempty"`
+	EmailVerified *bool  `json:"email_verified,omitempty"`
 	jwt.StandardClaims
 }
@@ -0,0 +1,79 @@
+package entity
+
+import "time"
+
+// Grant types an OAuth client may be registered for
+const (
+	GrantAuthorizationCode = `authorization_code`
+	GrantClientCredentials = `client_credentials`
+)
+
+// Scopes of OpenID Connect, which only apply to tokens issued for a user
+const (
+	ScopeOpenID = `openid`
+	ScopeEmail  = `email`
+)
+
+// Client is an application that signs its users in through lmnlo or calls
+// other services as itself. Public clients, such as single page and mobile
+// apps, cannot keep a secret and only use the authorization code grant.
+// Only the hash of the secret is stored, it is returned once on
+// registration.
+type Client struct {
+	ID           int64     `json:"-"`
+	ClientID     string    `json:"client_id"`
+	SecretHash   string    `json:"-"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Scopes       []string  `json:"scopes"`
+	GrantTypes   []string  `json:"grant_types"`
+	Public       bool      `json:"public"`
+	CreatedAt    time.Time `json:"created_at"`
+	Secret       string    `json:"client_secret,omitempty"`
+}
+
+// AuthorizationCode is issued to a client once the user approved its
+// request. Only the SHA-256 digest of the code is stored.
+type AuthorizationCode struct {
+	CodeHash    string
+	ClientID    string
+	UserID      int64
+	RedirectURI string
+	Scopes      []string
+	Challenge   string
+	Nonce       string
+	ExpiresAt   time.Time
+}
+
+// AuthorizeRequest are the parameters of an authorization request
+type AuthorizeRequest struct {
+	ResponseType        string `json:"response_type" form:"response_type"`
+	ClientID            string `json:"client_id" form:"client_id"`
+	RedirectURI         string `json:"redirect_uri" form:"redirect_uri"`
+	Scope               string `json:"scope" form:"scope"`
+	State               string `json:"state" form:"state"`
+	Nonce               string `json:"nonce" form:"nonce"`
+	CodeChallenge       string `json:"code_challenge" form:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method" form:"code_challenge_method"`
+}
+
+// TokenRequest are the parameters of a token request, the client
+// credentials are taken from basic auth when present
+type TokenRequest struct {
+	GrantType    string `form:"grant_type"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	Scope        string `form:"scope"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
+
+// TokenResponse is the successful answer of the token endpoint
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Scope       string `json:"scope,omitempty"`
+	IDToken     string `json:"id_token,omitempty"`
+}
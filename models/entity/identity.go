@@ -0,0 +1,24 @@
+package entity
+
+import "time"
+
+// Identity links an account at an external identity provider to a user.
+// Subject is the provider's stable ID of the account, emails can change.
+type Identity struct {
+	ID        int64
+	UserID    int64
+	Provider  string
+	Subject   string
+	Email     string
+	CreatedAt time.Time
+}
+
+// OAuthState is a pending external sign-in. Only the SHA-256 digest of the
+// state is stored, Verifier and Nonce are redeemed with it on the callback.
+type OAuthState struct {
+	StateHash string
+	Provider  string
+	Verifier  string
+	Nonce     string
+	ExpiresAt time.Time
+}
@@ -0,0 +1,21 @@
+package entity
+
+import "time"
+
+// LoginAttempt counts the recent failed logins of one account or address
+type LoginAttempt struct {
+	Key           string
+	Failures      int64
+	LastFailureAt time.Time
+	LockedUntil   *time.Time
+}
+
+// LockedAt reports how long attempts stay refused at t, zero when they are
+// allowed
+func (a *LoginAttempt) LockedAt(t time.Time) time.Duration {
+	if a.LockedUntil == nil || !a.LockedUntil.After(t) {
+		return 0
+	}
+
+	return a.LockedUntil.Sub(t)
+}
@@ -0,0 +1,24 @@
+package entity
+
+import "time"
+
+// MFA is the TOTP second factor of a user. It only guards logins once the
+// user has confirmed it with a code from the authenticator app.
+type MFA struct {
+	UserID       int64
+	Secret       string
+	LastUsedStep int64
+	ConfirmedAt  *time.Time
+	CreatedAt    time.Time
+}
+
+// Confirmed reports whether the second factor is active
+func (m *MFA) Confirmed() bool {
+	return m.ConfirmedAt != nil
+}
+
+// MFAEnrollment is handed to the user to set up an authenticator app
+type MFAEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
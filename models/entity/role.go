@@ -0,0 +1,47 @@
+package entity
+
+// Built-in roles
+const (
+	RoleAdmin = `admin`
+	RoleUser  = `user`
+)
+
+// Built-in permissions, granted to roles through the role_permission table
+const (
+	// PermissionUserRead and PermissionUserUpdate apply to the own account
+	// unless the user is an admin
+	PermissionUserRead   = `user:read`
+	PermissionUserUpdate = `user:update`
+	// PermissionUserList lists every user
+	PermissionUserList   = `user:list`
+	PermissionUserDelete = `user:delete`
+	PermissionRoleAssign = `role:assign`
+	// PermissionLockoutUnlock clears failed login attempts
+	PermissionLockoutUnlock = `lockout:unlock`
+	// PermissionClientManage registers and removes OAuth clients
+	PermissionClientManage = `client:manage`
+	// PermissionUserImpersonate logs in as another user for support
+	PermissionUserImpersonate = `user:impersonate`
+	// PermissionAuditRead queries the audit log
+	PermissionAuditRead = `audit:read`
+)
+
+// HasRole reports whether the user was granted role
+func (u *User) HasRole(role string) bool {
+	for _, r := range u.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// HasPermission reports whether any role of the user grants permission
+func (u *User) HasPermission(permission string) bool {
+	for _, p := range u.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,22 @@
+package entity
+
+import "time"
+
+// Session represents a login, backed by a row of the token table. The row
+// keeps its identity across refreshes, only the access token is swapped.
+// The token itself is not stored, only its jti and digest.
+type Session struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"-"`
+	FamilyID  string    `json:"-"`
+	TokenID   string    `json:"-"`
+	TokenHash string    `json:"-"`
+	UserAgent string    `json:"user_agent"`
+	IP        string    `json:"ip"`
+	CreatedAt time.Time `json:"created_at"`
+	// ExpiresAt is when the session ends unless it is refreshed
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"-"`
+	Current    bool       `json:"current"`
+}
@@ -0,0 +1,36 @@
+package entity
+
+import "time"
+
+// RefreshToken represents an opaque refresh token. Only the SHA-256 digest
+// of the token is stored; every rotation stays in the same family so reuse
+// of a rotated token can revoke all of its descendants.
+type RefreshToken struct {
+	ID        int64
+	UserID    int64
+	FamilyID  string
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+// Purposes of single-use user tokens
+const (
+	TokenPurposeVerifyEmail   = `verify_email`
+	TokenPurposeResetPassword = `reset_password`
+	TokenPurposeMFAChallenge  = `mfa_challenge`
+)
+
+// UserToken is a single-use token mailed to the user, such as an email
+// verification link. Only the SHA-256 digest of the token is stored.
+type UserToken struct {
+	ID        int64
+	UserID    int64
+	Purpose   string
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
@@ -1,10 +1,21 @@
 package entity
 
+import "time"
+
 // User represents object user
 type User struct {
-	ID       int64  `json:"id"`
-	Email    string `json:"email"`
-	Password string `json:"password,omitempty"`
-	Address  string `json:"address"`
-	Token    string `json:"token,omitempty"`
+	ID             int64      `json:"id"`
+	Email          string     `json:"email"`
+	Password       string     `json:"password,omitempty"`
+	Address        string     `json:"address"`
+	VerifiedAt     *time.Time `json:"verified_at,omitempty"`
+	Roles          []string   `json:"roles,omitempty"`
+	Permissions    []string   `json:"permissions,omitempty"`
+	Token          string     `json:"token,omitempty"`
+	RefreshToken   string     `json:"refresh_token,omitempty"`
+	ExpiresIn      int64      `json:"expires_in,omitempty"`
+	ChallengeToken string     `json:"challenge_token,omitempty"`
+	// ActorID is the support admin acting as the user, zero unless the
+	// request was made with an impersonation token
+	ActorID int64 `json:"actor_id,omitempty"`
 }
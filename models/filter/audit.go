@@ -0,0 +1,13 @@
+package filter
+
+import "time"
+
+// Audit represents object audit event, zero fields do not filter
+type Audit struct {
+	ActorID  int64
+	TargetID int64
+	From     time.Time
+	To       time.Time
+	Num      int64
+	Cursor   int64
+}
@@ -2,9 +2,8 @@ package filter
 
 // User represents object user
 type User struct {
-	Email    string
-	Password string
-	Address  string
-	Num      int64
-	Cursor   int64
+	Email   string
+	Address string
+	Num     int64
+	Cursor  int64
 }
@@ -1,6 +1,9 @@
 package response
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 var (
 	ErrNotFound        = errors.New(`Not Found`)
@@ -12,4 +15,23 @@ var (
 	ErrNoDeviceForRoom = errors.New(`No Device Set Up for This Room`)
 	ErrLogin           = errors.New(`Invalid Email or Password`)
 	ErrInterface       = errors.New(`Service Unavailable`)
+	ErrInvalidToken    = errors.New(`Invalid or Expired Token`)
+	ErrUnverified      = errors.New(`Email Not Verified`)
+	ErrWeakPassword    = errors.New(`Password Does Not Meet Policy`)
+	ErrWrongPassword   = errors.New(`Current Password Is Incorrect`)
+	ErrInvalidCode     = errors.New(`Invalid Verification Code`)
+	ErrLocked          = errors.New(`Too Many Failed Attempts`)
+	ErrRateLimited     = errors.New(`Too Many Requests`)
+	ErrCSRF            = errors.New(`Invalid CSRF Token`)
+	ErrImpersonation   = errors.New(`Not Allowed While Impersonating`)
 )
+
+// LockedError is returned while login attempts are refused, RetryAfter is
+// how long until the next attempt is accepted
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return ErrLocked.Error()
+}
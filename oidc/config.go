@@ -0,0 +1,30 @@
+package oidc
+
+import (
+	"github.com/andhikagama/lmnlo/config"
+)
+
+// NewProvidersFromConfig return providers described by `oauth.providers`
+// by name. Providers without a client ID are left out, so they stay off
+// until the application is registered with them.
+func NewProvidersFromConfig(cfg config.Config) (map[string]Provider, error) {
+	configs := make(map[string]ProviderConfig)
+	if err := cfg.UnmarshalKey(`oauth.providers`, &configs); err != nil {
+		return nil, err
+	}
+
+	providers := make(map[string]Provider, len(configs))
+	for name, pc := range configs {
+		if pc.ClientID == `` {
+			continue
+		}
+
+		p, err := NewProvider(name, pc, nil)
+		if err != nil {
+			return nil, err
+		}
+		providers[name] = p
+	}
+
+	return providers, nil
+}
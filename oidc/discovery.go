@@ -0,0 +1,353 @@
+package oidc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+
+	"github.com/andhikagama/lmnlo/keyring"
+)
+
+// _JWKSRefreshInterval bounds how often an unknown kid makes the provider
+// keys be fetched again
+const _JWKSRefreshInterval = time.Minute
+
+var _DefaultOIDCScopes = []string{`openid`, `email`, `profile`}
+
+// discovery is the part of the provider metadata the flow needs
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type oidcProvider struct {
+	name   string
+	cfg    ProviderConfig
+	client *http.Client
+
+	mu          sync.Mutex
+	meta        *discovery
+	keys        map[string]interface{}
+	keysFetched time.Time
+}
+
+func newOIDCProvider(name string, pc ProviderConfig, client *http.Client) *oidcProvider {
+	if pc.DiscoveryURL == `` {
+		pc.DiscoveryURL = strings.TrimSuffix(pc.Issuer, `/`) + `/.well-known/openid-configuration`
+	}
+
+	if len(pc.Scopes) == 0 {
+		pc.Scopes = _DefaultOIDCScopes
+	}
+
+	return &oidcProvider{
+		name:   name,
+		cfg:    pc,
+		client: client,
+	}
+}
+
+func (p *oidcProvider) Name() string {
+	return p.name
+}
+
+func (p *oidcProvider) AuthCodeURL(state string, nonce string, challenge string) (string, error) {
+	meta, err := p.metadata()
+	if err != nil {
+		return ``, err
+	}
+
+	q := url.Values{
+		`response_type`:         {`code`},
+		`client_id`:             {p.cfg.ClientID},
+		`redirect_uri`:          {p.cfg.RedirectURL},
+		`scope`:                 {strings.Join(p.cfg.Scopes, ` `)},
+		`state`:                 {state},
+		`nonce`:                 {nonce},
+		`code_challenge`:        {challenge},
+		`code_challenge_method`: {`S256`},
+	}
+
+	return addQuery(meta.AuthorizationEndpoint, q), nil
+}
+
+func (p *oidcProvider) Identify(code string, verifier string, nonce string) (*Identity, error) {
+	meta, err := p.metadata()
+	if err != nil {
+		return nil, err
+	}
+
+	tr, err := exchange(p.client, meta.TokenEndpoint, p.cfg, code, verifier)
+	if err != nil {
+		return nil, err
+	}
+
+	if tr.IDToken == `` {
+		return nil, ErrInvalidIDToken
+	}
+
+	id, err := p.verifyIDToken(meta, tr.IDToken, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	// Some providers only put the email into the userinfo response
+	if id.Email == `` && meta.UserinfoEndpoint != `` {
+		info := make(map[string]interface{})
+		if err := getJSON(p.client, meta.UserinfoEndpoint, tr.AccessToken, &info); err != nil {
+			return nil, err
+		}
+
+		if sub, _ := info[`sub`].(string); sub != id.Subject {
+			return nil, ErrInvalidIDToken
+		}
+
+		id.Email, _ = info[`email`].(string)
+		id.EmailVerified = isTrue(info[`email_verified`])
+	}
+
+	if id.Email == `` {
+		return nil, ErrNoEmail
+	}
+
+	return id, nil
+}
+
+// verifyIDToken checks signature, issuer, audience, expiry and nonce of an
+// ID token as OpenID Connect Core 3.1.3.7 asks
+func (p *oidcProvider) verifyIDToken(meta *discovery, raw string, nonce string) (*Identity, error) {
+	parser := &jwt.Parser{
+		ValidMethods: []string{`RS256`, `RS384`, `RS512`, `ES256`, `ES384`, `ES512`},
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := parser.ParseWithClaims(raw, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header[`kid`].(string)
+		return p.key(meta, kid)
+	})
+	if err != nil {
+		return nil, ErrInvalidIDToken
+	}
+
+	if _, ok := claims[`exp`]; !ok {
+		return nil, ErrInvalidIDToken
+	}
+
+	if iss, _ := claims[`iss`].(string); iss != meta.Issuer {
+		return nil, ErrInvalidIDToken
+	}
+
+	if !hasAudience(claims, p.cfg.ClientID) {
+		return nil, ErrInvalidIDToken
+	}
+
+	if got, _ := claims[`nonce`].(string); nonce == `` || got != nonce {
+		return nil, ErrInvalidIDToken
+	}
+
+	sub, _ := claims[`sub`].(string)
+	if sub == `` {
+		return nil, ErrInvalidIDToken
+	}
+
+	email, _ := claims[`email`].(string)
+
+	return &Identity{
+		Provider:      p.name,
+		Subject:       sub,
+		Email:         email,
+		EmailVerified: isTrue(claims[`email_verified`]),
+	}, nil
+}
+
+// metadata fetches the discovery document once, failures are retried on
+// the next call
+func (p *oidcProvider) metadata() (*discovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.meta != nil {
+		return p.meta, nil
+	}
+
+	meta := new(discovery)
+	if err := getJSON(p.client, p.cfg.DiscoveryURL, ``, meta); err != nil {
+		return nil, err
+	}
+
+	if meta.AuthorizationEndpoint == `` || meta.TokenEndpoint == `` || meta.JWKSURI == `` {
+		return nil, ErrDiscovery
+	}
+
+	if p.cfg.Issuer != `` && strings.TrimSuffix(meta.Issuer, `/`) != strings.TrimSuffix(p.cfg.Issuer, `/`) {
+		return nil, ErrDiscovery
+	}
+
+	p.meta = meta
+	return meta, nil
+}
+
+// key return the public key named kid, fetching the key set again when the
+// provider rotated to a key not seen yet
+func (p *oidcProvider) key(meta *discovery, kid string) (interface{}, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if k, ok := p.lookup(kid); ok {
+		return k, nil
+	}
+
+	if !p.keysFetched.IsZero() && time.Since(p.keysFetched) < _JWKSRefreshInterval {
+		return nil, ErrInvalidIDToken
+	}
+
+	set := new(keyring.JWKS)
+	if err := getJSON(p.client, meta.JWKSURI, ``, set); err != nil {
+		return nil, err
+	}
+
+	p.keys = make(map[string]interface{}, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != `` && jwk.Use != `sig` {
+			continue
+		}
+
+		if k, err := publicKey(jwk); err == nil {
+			p.keys[jwk.KeyID] = k
+		}
+	}
+	p.keysFetched = time.Now()
+
+	if k, ok := p.lookup(kid); ok {
+		return k, nil
+	}
+
+	return nil, ErrInvalidIDToken
+}
+
+// lookup finds kid in the cached keys, a token without kid is accepted
+// only when the provider has a single key
+func (p *oidcProvider) lookup(kid string) (interface{}, bool) {
+	if kid == `` && len(p.keys) == 1 {
+		for _, k := range p.keys {
+			return k, true
+		}
+	}
+
+	k, ok := p.keys[kid]
+	return k, ok
+}
+
+// publicKey decodes an RSA or EC JWK
+func publicKey(jwk *keyring.JWK) (interface{}, error) {
+	switch jwk.KeyType {
+	case `RSA`:
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := decodeBigInt(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case `EC`:
+		var curve elliptic.Curve
+		switch jwk.Curve {
+		case `P-256`:
+			curve = elliptic.P256()
+		case `P-384`:
+			curve = elliptic.P384()
+		case `P-521`:
+			curve = elliptic.P521()
+		default:
+			return nil, ErrInvalidIDToken
+		}
+
+		x, err := decodeBigInt(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := decodeBigInt(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+
+	return nil, ErrInvalidIDToken
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
+
+// hasAudience reports whether clientID is the audience of claims, aud may
+// be a string or an array. With several audiences azp has to name the
+// client.
+func hasAudience(claims jwt.MapClaims, clientID string) bool {
+	switch aud := claims[`aud`].(type) {
+	case string:
+		return aud == clientID
+	case []interface{}:
+		found := false
+		for _, a := range aud {
+			found = found || a == clientID
+		}
+
+		if !found {
+			return false
+		}
+
+		if len(aud) > 1 {
+			azp, _ := claims[`azp`].(string)
+			return azp == clientID
+		}
+
+		return true
+	}
+
+	return false
+}
+
+// isTrue reads a boolean claim, some providers send it as a string
+func isTrue(v interface{}) bool {
+	switch b := v.(type) {
+	case bool:
+		return b
+	case string:
+		return b == `true`
+	}
+
+	return false
+}
+
+func addQuery(endpoint string, q url.Values) string {
+	sep := `?`
+	if strings.Contains(endpoint, `?`) {
+		sep = `&`
+	}
+
+	return endpoint + sep + q.Encode()
+}
@@ -0,0 +1,192 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	ErrUnknownProvider = errors.New(`unknown identity provider`)
+	ErrDiscovery       = errors.New(`provider discovery failed`)
+	ErrExchange        = errors.New(`authorization code exchange failed`)
+	ErrInvalidIDToken  = errors.New(`invalid id token`)
+	ErrNoEmail         = errors.New(`provider returned no email`)
+)
+
+// _VerifierBytes gives a 43 character PKCE verifier, the shortest RFC 7636
+// allows
+const _VerifierBytes = 32
+
+// Identity is who the provider says signed in
+type Identity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+}
+
+// Provider runs the authorization code flow with PKCE against one identity
+// provider
+type Provider interface {
+	Name() string
+	// AuthCodeURL is where the user is sent to sign in, the provider
+	// redirects back with a code and state
+	AuthCodeURL(state string, nonce string, challenge string) (string, error)
+	// Identify exchanges code for tokens and return the verified identity
+	Identify(code string, verifier string, nonce string) (*Identity, error)
+}
+
+// ProviderConfig describes one provider in config.json. Type is `oidc` for
+// providers with discovery or `github`.
+type ProviderConfig struct {
+	Type         string   `mapstructure:"type"`
+	Issuer       string   `mapstructure:"issuer"`
+	DiscoveryURL string   `mapstructure:"discovery_url"`
+	ClientID     string   `mapstructure:"client_id"`
+	ClientSecret string   `mapstructure:"client_secret"`
+	RedirectURL  string   `mapstructure:"redirect_url"`
+	Scopes       []string `mapstructure:"scopes"`
+	// AuthURL, TokenURL and APIURL override the endpoints of GitHub, for
+	// GitHub Enterprise
+	AuthURL  string `mapstructure:"auth_url"`
+	TokenURL string `mapstructure:"token_url"`
+	APIURL   string `mapstructure:"api_url"`
+}
+
+// NewProvider return the provider described by pc under name
+func NewProvider(name string, pc ProviderConfig, client *http.Client) (Provider, error) {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	switch pc.Type {
+	case `github`:
+		return newGitHubProvider(name, pc, client), nil
+	case `oidc`, ``:
+		if pc.Issuer == `` && pc.DiscoveryURL == `` {
+			return nil, fmt.Errorf(`oidc provider %s needs an issuer`, name)
+		}
+		return newOIDCProvider(name, pc, client), nil
+	}
+
+	return nil, fmt.Errorf(`unknown type %s of provider %s`, pc.Type, name)
+}
+
+// NewVerifier return a random PKCE code verifier
+func NewVerifier() (string, error) {
+	return randomString(_VerifierBytes)
+}
+
+// NewNonce return a random value to bind an ID token to its request
+func NewNonce() (string, error) {
+	return randomString(_VerifierBytes)
+}
+
+// Challenge return the S256 PKCE challenge of verifier
+func Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return ``, err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// tokenResponse is the answer of a token endpoint
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	IDToken     string `json:"id_token"`
+	Error       string `json:"error"`
+}
+
+// exchange redeems an authorization code at tokenURL
+func exchange(client *http.Client, tokenURL string, pc ProviderConfig, code string, verifier string) (*tokenResponse, error) {
+	form := url.Values{
+		`grant_type`:    {`authorization_code`},
+		`code`:          {code},
+		`redirect_uri`:  {pc.RedirectURL},
+		`client_id`:     {pc.ClientID},
+		`client_secret`: {pc.ClientSecret},
+		`code_verifier`: {verifier},
+	}
+
+	req, err := http.NewRequest(http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(`Content-Type`, `application/x-www-form-urlencoded`)
+	req.Header.Set(`Accept`, `application/json`)
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	// Refused grants are answered with 400 or 401 and an error code
+	if res.StatusCode == http.StatusBadRequest || res.StatusCode == http.StatusUnauthorized {
+		io.Copy(ioutil.Discard, res.Body)
+		return nil, ErrExchange
+	}
+
+	if res.StatusCode != http.StatusOK {
+		io.Copy(ioutil.Discard, res.Body)
+		return nil, fmt.Errorf(`%s %s: %s`, req.Method, req.URL, res.Status)
+	}
+
+	tr := new(tokenResponse)
+	if err := json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(tr); err != nil {
+		return nil, err
+	}
+
+	// GitHub answers refused grants with 200 and an error code
+	if tr.Error != `` || tr.AccessToken == `` {
+		return nil, ErrExchange
+	}
+
+	return tr, nil
+}
+
+// getJSON fetches u with an optional bearer token and decodes the body
+func getJSON(client *http.Client, u string, accessToken string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(`Accept`, `application/json`)
+	if accessToken != `` {
+		req.Header.Set(`Authorization`, `Bearer `+accessToken)
+	}
+
+	return doJSON(client, req, v)
+}
+
+func doJSON(client *http.Client, req *http.Request, v interface{}) error {
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		io.Copy(ioutil.Discard, res.Body)
+		return fmt.Errorf(`%s %s: %s`, req.Method, req.URL, res.Status)
+	}
+
+	return json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(v)
+}
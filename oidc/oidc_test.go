@@ -0,0 +1,205 @@
+package oidc_test
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/andhikagama/lmnlo/oidc"
+	"github.com/andhikagama/lmnlo/oidc/oidctest"
+)
+
+// signIn runs the whole flow against p and return the identity
+func signIn(t *testing.T, srv *oidctest.Server, p oidc.Provider, nonce string) (*oidc.Identity, error) {
+	verifier, err := oidc.NewVerifier()
+	assert.NoError(t, err)
+
+	authURL, err := p.AuthCodeURL(`state-1`, nonce, oidc.Challenge(verifier))
+	assert.NoError(t, err)
+
+	code, state, err := srv.Authorize(authURL)
+	assert.NoError(t, err)
+	assert.Equal(t, `state-1`, state)
+
+	return p.Identify(code, verifier, nonce)
+}
+
+func TestOIDCProvider(t *testing.T) {
+	srv := oidctest.NewServer()
+	defer srv.Close()
+
+	t.Run(`success`, func(t *testing.T) {
+		p, err := oidc.NewProvider(`test`, srv.ProviderConfig(`oidc`), nil)
+		assert.NoError(t, err)
+		assert.Equal(t, `test`, p.Name())
+
+		id, err := signIn(t, srv, p, `nonce-1`)
+		assert.NoError(t, err)
+		assert.Equal(t, &oidc.Identity{
+			Provider:      `test`,
+			Subject:       srv.Subject,
+			Email:         srv.Email,
+			EmailVerified: true,
+		}, id)
+	})
+
+	t.Run(`auth-url`, func(t *testing.T) {
+		p, _ := oidc.NewProvider(`test`, srv.ProviderConfig(`oidc`), nil)
+
+		authURL, err := p.AuthCodeURL(`state-1`, `nonce-1`, `challenge`)
+		assert.NoError(t, err)
+
+		u, _ := url.Parse(authURL)
+		assert.Equal(t, `/authorize`, u.Path)
+		assert.Equal(t, `code`, u.Query().Get(`response_type`))
+		assert.Equal(t, `openid email profile`, u.Query().Get(`scope`))
+		assert.Equal(t, `challenge`, u.Query().Get(`code_challenge`))
+		assert.Equal(t, `S256`, u.Query().Get(`code_challenge_method`))
+	})
+
+	t.Run(`discovery-url`, func(t *testing.T) {
+		pc := srv.ProviderConfig(`oidc`)
+		pc.Issuer = ``
+		pc.DiscoveryURL = srv.URL + `/.well-known/openid-configuration`
+
+		p, err := oidc.NewProvider(`test`, pc, nil)
+		assert.NoError(t, err)
+
+		_, err = signIn(t, srv, p, `nonce-1`)
+		assert.NoError(t, err)
+	})
+
+	t.Run(`wrong-issuer`, func(t *testing.T) {
+		pc := srv.ProviderConfig(`oidc`)
+		pc.DiscoveryURL = srv.URL + `/.well-known/openid-configuration`
+		pc.Issuer = `https://accounts.example.com`
+
+		p, _ := oidc.NewProvider(`test`, pc, nil)
+
+		_, err := p.AuthCodeURL(`state-1`, `nonce-1`, `challenge`)
+		assert.Equal(t, oidc.ErrDiscovery, err)
+	})
+
+	t.Run(`wrong-verifier`, func(t *testing.T) {
+		p, _ := oidc.NewProvider(`test`, srv.ProviderConfig(`oidc`), nil)
+
+		verifier, _ := oidc.NewVerifier()
+		authURL, _ := p.AuthCodeURL(`state-1`, `nonce-1`, oidc.Challenge(verifier))
+		code, _, err := srv.Authorize(authURL)
+		assert.NoError(t, err)
+
+		other, _ := oidc.NewVerifier()
+		_, err = p.Identify(code, other, `nonce-1`)
+		assert.Equal(t, oidc.ErrExchange, err)
+	})
+
+	t.Run(`code-reused`, func(t *testing.T) {
+		p, _ := oidc.NewProvider(`test`, srv.ProviderConfig(`oidc`), nil)
+
+		verifier, _ := oidc.NewVerifier()
+		authURL, _ := p.AuthCodeURL(`state-1`, `nonce-1`, oidc.Challenge(verifier))
+		code, _, _ := srv.Authorize(authURL)
+
+		_, err := p.Identify(code, verifier, `nonce-1`)
+		assert.NoError(t, err)
+
+		_, err = p.Identify(code, verifier, `nonce-1`)
+		assert.Equal(t, oidc.ErrExchange, err)
+	})
+
+	t.Run(`wrong-audience`, func(t *testing.T) {
+		srv.Audience = `other-client`
+		defer func() { srv.Audience = `` }()
+
+		p, _ := oidc.NewProvider(`test`, srv.ProviderConfig(`oidc`), nil)
+
+		_, err := signIn(t, srv, p, `nonce-1`)
+		assert.Equal(t, oidc.ErrInvalidIDToken, err)
+	})
+
+	t.Run(`wrong-nonce`, func(t *testing.T) {
+		srv.Nonce = `replayed`
+		defer func() { srv.Nonce = `` }()
+
+		p, _ := oidc.NewProvider(`test`, srv.ProviderConfig(`oidc`), nil)
+
+		_, err := signIn(t, srv, p, `nonce-1`)
+		assert.Equal(t, oidc.ErrInvalidIDToken, err)
+	})
+
+	t.Run(`userinfo-email`, func(t *testing.T) {
+		srv.UserinfoOnly = true
+		srv.EmailVerified = false
+		defer func() {
+			srv.UserinfoOnly = false
+			srv.EmailVerified = true
+		}()
+
+		p, _ := oidc.NewProvider(`test`, srv.ProviderConfig(`oidc`), nil)
+
+		id, err := signIn(t, srv, p, `nonce-1`)
+		assert.NoError(t, err)
+		assert.Equal(t, srv.Email, id.Email)
+		assert.False(t, id.EmailVerified)
+	})
+
+	t.Run(`no-email`, func(t *testing.T) {
+		srv.UserinfoOnly = true
+		email := srv.Email
+		srv.Email = ``
+		defer func() {
+			srv.UserinfoOnly = false
+			srv.Email = email
+		}()
+
+		p, _ := oidc.NewProvider(`test`, srv.ProviderConfig(`oidc`), nil)
+
+		_, err := signIn(t, srv, p, `nonce-1`)
+		assert.Equal(t, oidc.ErrNoEmail, err)
+	})
+}
+
+func TestGitHubProvider(t *testing.T) {
+	srv := oidctest.NewServer()
+	defer srv.Close()
+
+	p, err := oidc.NewProvider(`github`, srv.ProviderConfig(`github`), nil)
+	assert.NoError(t, err)
+
+	t.Run(`success`, func(t *testing.T) {
+		id, err := signIn(t, srv, p, ``)
+		assert.NoError(t, err)
+		assert.Equal(t, &oidc.Identity{
+			Provider:      `github`,
+			Subject:       srv.Subject,
+			Email:         srv.Email,
+			EmailVerified: true,
+		}, id)
+	})
+
+	t.Run(`wrong-secret`, func(t *testing.T) {
+		pc := srv.ProviderConfig(`github`)
+		pc.ClientSecret = `wrong`
+		p, _ := oidc.NewProvider(`github`, pc, nil)
+
+		_, err := signIn(t, srv, p, ``)
+		assert.Equal(t, oidc.ErrExchange, err)
+	})
+}
+
+func TestNewProvider(t *testing.T) {
+	_, err := oidc.NewProvider(`test`, oidc.ProviderConfig{Type: `oidc`}, nil)
+	assert.Error(t, err)
+
+	_, err = oidc.NewProvider(`test`, oidc.ProviderConfig{Type: `saml`}, nil)
+	assert.Error(t, err)
+}
+
+func TestChallenge(t *testing.T) {
+	// Unpadded base64url of the SHA-256 of the verifier
+	assert.Equal(t,
+		`J3ExJPPxDItW0xHarCpTzsulg70SaFiOKyLfrnBh_kA`,
+		oidc.Challenge(`dBjftJeZ4CVP-mB92K9uzVLIhjmJZcfORW-8VPSgw9k`),
+	)
+}
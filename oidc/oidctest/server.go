@@ -0,0 +1,310 @@
+// Package oidctest runs a local identity provider for tests
+package oidctest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+
+	"github.com/andhikagama/lmnlo/helper"
+	"github.com/andhikagama/lmnlo/keyring"
+	"github.com/andhikagama/lmnlo/oidc"
+)
+
+// RedirectURL is the callback the server expects unless changed
+const RedirectURL = `http://localhost/callback`
+
+// Server is an OpenID Connect provider that approves every authorization
+// request at once for the configured user. It also answers the GitHub API
+// calls of the github provider, so one server covers both kinds.
+type Server struct {
+	*httptest.Server
+
+	ClientID     string
+	ClientSecret string
+
+	// Subject, Email and EmailVerified describe the user who signs in.
+	// Subject has to be numeric for the github provider.
+	Subject       string
+	Email         string
+	EmailVerified bool
+	// UserinfoOnly leaves the email out of ID tokens, as providers that
+	// only return it from userinfo do
+	UserinfoOnly bool
+	// Audience and Nonce, when set, replace the audience and nonce of
+	// issued ID tokens
+	Audience string
+	Nonce    string
+
+	keyRing *keyring.KeyRing
+
+	mu     sync.Mutex
+	codes  map[string]*grant
+	access map[string]*grant
+}
+
+// grant is an approved authorization request
+type grant struct {
+	clientID    string
+	redirectURI string
+	challenge   string
+	nonce       string
+}
+
+// NewServer starts a provider signing ID tokens with a fresh ES256 key
+func NewServer() *Server {
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+
+	key, err := keyring.NewPrivateKey(`oidctest`, private)
+	if err != nil {
+		panic(err)
+	}
+
+	kr, err := keyring.NewKeyRing(`oidctest`, key)
+	if err != nil {
+		panic(err)
+	}
+
+	s := &Server{
+		ClientID:      `client`,
+		ClientSecret:  `secret`,
+		Subject:       `1234`,
+		Email:         `oidc.user@example.com`,
+		EmailVerified: true,
+		keyRing:       kr,
+		codes:         make(map[string]*grant),
+		access:        make(map[string]*grant),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(`/.well-known/openid-configuration`, s.discovery)
+	mux.HandleFunc(`/jwks`, s.jwks)
+	mux.HandleFunc(`/authorize`, s.authorize)
+	mux.HandleFunc(`/token`, s.token)
+	mux.HandleFunc(`/userinfo`, s.userinfo)
+	mux.HandleFunc(`/user`, s.githubUser)
+	mux.HandleFunc(`/user/emails`, s.githubEmails)
+	s.Server = httptest.NewServer(mux)
+
+	return s
+}
+
+// ProviderConfig return the config of a provider of kind typ, `oidc` or
+// `github`, signing in against the server
+func (s *Server) ProviderConfig(typ string) oidc.ProviderConfig {
+	pc := oidc.ProviderConfig{
+		Type:         typ,
+		ClientID:     s.ClientID,
+		ClientSecret: s.ClientSecret,
+		RedirectURL:  RedirectURL,
+	}
+
+	if typ == `github` {
+		pc.AuthURL = s.URL + `/authorize`
+		pc.TokenURL = s.URL + `/token`
+		pc.APIURL = s.URL
+	} else {
+		pc.Issuer = s.URL
+	}
+
+	return pc
+}
+
+// Authorize follows authURL like a browser whose user approves the request
+// and return code and state of the callback
+func (s *Server) Authorize(authURL string) (code string, state string, err error) {
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	res, err := client.Get(authURL)
+	if err != nil {
+		return ``, ``, err
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusFound {
+		return ``, ``, errors.New(`authorization refused: ` + res.Status)
+	}
+
+	callback, err := url.Parse(res.Header.Get(`Location`))
+	if err != nil {
+		return ``, ``, err
+	}
+
+	return callback.Query().Get(`code`), callback.Query().Get(`state`), nil
+}
+
+func (s *Server) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		`issuer`:                 s.URL,
+		`authorization_endpoint`: s.URL + `/authorize`,
+		`token_endpoint`:         s.URL + `/token`,
+		`userinfo_endpoint`:      s.URL + `/userinfo`,
+		`jwks_uri`:               s.URL + `/jwks`,
+	})
+}
+
+func (s *Server) jwks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.keyRing.JWKS())
+}
+
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get(`client_id`) != s.ClientID || q.Get(`code_challenge_method`) != `S256` || q.Get(`code_challenge`) == `` {
+		http.Error(w, `invalid_request`, http.StatusBadRequest)
+		return
+	}
+
+	code, _ := helper.GenerateRandomHex(16)
+
+	s.mu.Lock()
+	s.codes[code] = &grant{
+		clientID:    q.Get(`client_id`),
+		redirectURI: q.Get(`redirect_uri`),
+		challenge:   q.Get(`code_challenge`),
+		nonce:       q.Get(`nonce`),
+	}
+	s.mu.Unlock()
+
+	callback := url.Values{`code`: {code}, `state`: {q.Get(`state`)}}
+	http.Redirect(w, r, q.Get(`redirect_uri`)+`?`+callback.Encode(), http.StatusFound)
+}
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, `invalid_request`, http.StatusBadRequest)
+		return
+	}
+
+	code := r.PostForm.Get(`code`)
+
+	s.mu.Lock()
+	g, ok := s.codes[code]
+	delete(s.codes, code)
+	s.mu.Unlock()
+
+	if !ok ||
+		r.PostForm.Get(`client_id`) != s.ClientID ||
+		r.PostForm.Get(`client_secret`) != s.ClientSecret ||
+		r.PostForm.Get(`redirect_uri`) != g.redirectURI ||
+		oidc.Challenge(r.PostForm.Get(`code_verifier`)) != g.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{`error`: `invalid_grant`})
+		return
+	}
+
+	access, _ := helper.GenerateRandomHex(16)
+
+	s.mu.Lock()
+	s.access[access] = g
+	s.mu.Unlock()
+
+	now := time.Now()
+	audience, nonce := g.clientID, g.nonce
+	if s.Audience != `` {
+		audience = s.Audience
+	}
+	if s.Nonce != `` {
+		nonce = s.Nonce
+	}
+
+	claims := jwt.MapClaims{
+		`iss`:   s.URL,
+		`sub`:   s.Subject,
+		`aud`:   audience,
+		`iat`:   now.Unix(),
+		`exp`:   now.Add(time.Hour).Unix(),
+		`nonce`: nonce,
+	}
+
+	if !s.UserinfoOnly {
+		claims[`email`] = s.Email
+		claims[`email_verified`] = s.EmailVerified
+	}
+
+	idToken, err := s.keyRing.Sign(claims)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		`access_token`: access,
+		`token_type`:   `Bearer`,
+		`id_token`:     idToken,
+	})
+}
+
+func (s *Server) userinfo(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		http.Error(w, `invalid_token`, http.StatusUnauthorized)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		`sub`:            s.Subject,
+		`email`:          s.Email,
+		`email_verified`: s.EmailVerified,
+	})
+}
+
+func (s *Server) githubUser(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		http.Error(w, `Bad credentials`, http.StatusUnauthorized)
+		return
+	}
+
+	id, _ := strconv.ParseInt(s.Subject, 10, 64)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		`id`:    id,
+		`login`: `oidctest`,
+	})
+}
+
+func (s *Server) githubEmails(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		http.Error(w, `Bad credentials`, http.StatusUnauthorized)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, []map[string]interface{}{
+		{`email`: `secondary@example.com`, `primary`: false, `verified`: true},
+		{`email`: s.Email, `primary`: true, `verified`: s.EmailVerified},
+	})
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	const prefix = `Bearer `
+
+	header := r.Header.Get(`Authorization`)
+	if len(header) <= len(prefix) || header[:len(prefix)] != prefix {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.access[header[len(prefix):]]
+	return ok
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set(`Content-Type`, `application/json`)
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,42 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/labstack/echo"
+)
+
+// Kinds of keys a rule counts requests by
+const (
+	KeyIP     = `ip`
+	KeyUser   = `user`
+	KeyAPIKey = `api_key`
+)
+
+// Rule is the quota of a route. Requests are counted per key, a user or API
+// key rule falls back to the address for requests without one.
+type Rule struct {
+	Method string        `mapstructure:"method"`
+	Path   string        `mapstructure:"path"`
+	Limit  int64         `mapstructure:"limit"`
+	Window time.Duration `mapstructure:"window"`
+	Key    string        `mapstructure:"key"`
+}
+
+// Repository counts requests in fixed windows
+type Repository interface {
+	// Hit counts a request in the window starting at start and return the
+	// hits of that window and of the window before it
+	Hit(ctx context.Context, key string, start time.Time, window time.Duration) (int64, int64, error)
+	// Purge deletes counters whose window started before before and return
+	// how many
+	Purge(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Usecase ...
+type Usecase interface {
+	Limit(next echo.HandlerFunc) echo.HandlerFunc
+	LimitClient(next echo.HandlerFunc) echo.HandlerFunc
+	Sweep(ctx context.Context) (int64, error)
+}
@@ -0,0 +1,93 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/andhikagama/lmnlo/ratelimit"
+)
+
+// _MinSweep is the size the counter map may reach before stale entries are
+// swept for the first time
+const _MinSweep = 1024
+
+type counter struct {
+	start    time.Time
+	window   time.Duration
+	hits     int64
+	previous int64
+}
+
+type rateLimitRepository struct {
+	mu       sync.Mutex
+	counters map[string]*counter
+	sweepAt  int
+}
+
+// NewRateLimitRepository return a repository that counts in the process,
+// every replica enforces the quota on its own
+func NewRateLimitRepository() ratelimit.Repository {
+	return &rateLimitRepository{
+		counters: make(map[string]*counter),
+		sweepAt:  _MinSweep,
+	}
+}
+
+func (m *rateLimitRepository) Hit(ctx context.Context, key string, start time.Time, window time.Duration) (int64, int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.counters[key]
+	if !ok {
+		m.sweep(start)
+
+		c = &counter{start: start, window: window}
+		m.counters[key] = c
+	}
+
+	switch {
+	case c.start.Equal(start):
+	case c.start.Equal(start.Add(-window)):
+		c.start, c.previous, c.hits = start, c.hits, 0
+	default:
+		c.start, c.previous, c.hits = start, 0, 0
+	}
+
+	c.hits++
+	return c.hits, c.previous, nil
+}
+
+func (m *rateLimitRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var n int64
+	for key, c := range m.counters {
+		if c.start.Before(before) {
+			delete(m.counters, key)
+			n++
+		}
+	}
+
+	return n, nil
+}
+
+// sweep drops counters whose windows no longer count once the map has
+// grown, so that one-off clients do not pile up
+func (m *rateLimitRepository) sweep(now time.Time) {
+	if len(m.counters) < m.sweepAt {
+		return
+	}
+
+	for key, c := range m.counters {
+		if c.start.Add(2 * c.window).Before(now) {
+			delete(m.counters, key)
+		}
+	}
+
+	m.sweepAt = 2 * len(m.counters)
+	if m.sweepAt < _MinSweep {
+		m.sweepAt = _MinSweep
+	}
+}
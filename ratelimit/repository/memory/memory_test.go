@@ -0,0 +1,78 @@
+package memory_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/andhikagama/lmnlo/ratelimit/repository/memory"
+)
+
+func TestHit(t *testing.T) {
+	start := time.Now().Truncate(time.Minute)
+
+	t.Run("same-window", func(t *testing.T) {
+		repo := memory.NewRateLimitRepository()
+
+		repo.Hit(context.TODO(), `ip:1.2.3.4`, start, time.Minute)
+		hits, previous, err := repo.Hit(context.TODO(), `ip:1.2.3.4`, start, time.Minute)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), hits)
+		assert.Equal(t, int64(0), previous)
+	})
+
+	t.Run("next-window", func(t *testing.T) {
+		repo := memory.NewRateLimitRepository()
+
+		repo.Hit(context.TODO(), `ip:1.2.3.4`, start, time.Minute)
+		repo.Hit(context.TODO(), `ip:1.2.3.4`, start, time.Minute)
+		hits, previous, err := repo.Hit(context.TODO(), `ip:1.2.3.4`, start.Add(time.Minute), time.Minute)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), hits)
+		assert.Equal(t, int64(2), previous)
+	})
+
+	t.Run("later-window", func(t *testing.T) {
+		repo := memory.NewRateLimitRepository()
+
+		repo.Hit(context.TODO(), `ip:1.2.3.4`, start, time.Minute)
+		hits, previous, err := repo.Hit(context.TODO(), `ip:1.2.3.4`, start.Add(2*time.Minute), time.Minute)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), hits)
+		assert.Equal(t, int64(0), previous)
+	})
+
+	t.Run("separate-keys", func(t *testing.T) {
+		repo := memory.NewRateLimitRepository()
+
+		repo.Hit(context.TODO(), `ip:1.2.3.4`, start, time.Minute)
+		hits, _, err := repo.Hit(context.TODO(), `ip:5.6.7.8`, start, time.Minute)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), hits)
+	})
+}
+
+func TestPurge(t *testing.T) {
+	start := time.Now().Truncate(time.Minute)
+	repo := memory.NewRateLimitRepository()
+
+	repo.Hit(context.TODO(), `ip:1.2.3.4`, start.Add(-time.Hour), time.Minute)
+	repo.Hit(context.TODO(), `ip:5.6.7.8`, start, time.Minute)
+
+	n, err := repo.Purge(context.TODO(), start.Add(-time.Minute))
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	// The purged counter starts over, the other one is kept
+	hits, _, _ := repo.Hit(context.TODO(), `ip:1.2.3.4`, start.Add(-time.Hour), time.Minute)
+	assert.Equal(t, int64(1), hits)
+	hits, _, _ = repo.Hit(context.TODO(), `ip:5.6.7.8`, start, time.Minute)
+	assert.Equal(t, int64(2), hits)
+}
@@ -0,0 +1,78 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/andhikagama/lmnlo/ratelimit"
+	sq "github.com/elgris/sqrl"
+)
+
+type rateLimitRepository struct {
+	Conn *sql.DB
+}
+
+// NewRateLimitRepository return a repository backed by the rate_limit
+// table, quotas are shared by every replica using the database
+func NewRateLimitRepository(Conn *sql.DB) ratelimit.Repository {
+	return &rateLimitRepository{Conn}
+}
+
+func (m *rateLimitRepository) Hit(ctx context.Context, key string, start time.Time, window time.Duration) (int64, int64, error) {
+	trx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// One row per key holds the current and the previous window.
+	// Assignments run left to right, both IFs still see the stored
+	// window_start.
+	query := sq.Insert(`rate_limit`).
+		Columns(`bucket_key`, `window_start`, `hits`, `previous_hits`).
+		Values(key, start, 1, 0).
+		Suffix(`ON DUPLICATE KEY UPDATE `+
+			`previous_hits = IF(window_start = ?, previous_hits, IF(window_start = ?, hits, 0)), `+
+			`hits = IF(window_start = ?, hits + 1, 1), `+
+			`window_start = VALUES(window_start)`,
+			start, start.Add(-window), start)
+
+	sql, args, _ := query.ToSql()
+	stmt, err := trx.PrepareContext(ctx, sql)
+	if err != nil {
+		trx.Rollback()
+		return 0, 0, err
+	}
+	defer stmt.Close()
+
+	if _, err := stmt.ExecContext(ctx, args...); err != nil {
+		trx.Rollback()
+		return 0, 0, err
+	}
+
+	sel := sq.Select(`hits, previous_hits`).
+		From(`rate_limit`).
+		Where(`bucket_key = ?`, key)
+
+	sql, args, _ = sel.ToSql()
+	var hits, previous int64
+	if err := trx.QueryRowContext(ctx, sql, args...).Scan(&hits, &previous); err != nil {
+		trx.Rollback()
+		return 0, 0, err
+	}
+
+	return hits, previous, trx.Commit()
+}
+
+func (m *rateLimitRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	query := sq.Delete(`rate_limit`).
+		Where(`window_start < ?`, before)
+
+	sql, args, _ := query.ToSql()
+	result, err := m.Conn.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,81 @@
+package mysql_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/andhikagama/lmnlo/ratelimit/repository/mysql"
+)
+
+func TestHit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	start := time.Now().Truncate(time.Minute)
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`INSERT INTO rate_limit (.+) ON DUPLICATE KEY UPDATE`).
+			ExpectExec().
+			WithArgs(`ip:1.2.3.4`, start, 1, 0, start, start.Add(-time.Minute), start).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectQuery(`SELECT hits, previous_hits FROM rate_limit WHERE bucket_key = \?`).
+			WithArgs(`ip:1.2.3.4`).
+			WillReturnRows(sqlmock.NewRows([]string{`hits`, `previous_hits`}).AddRow(3, 7))
+		mock.ExpectCommit()
+
+		repo := mysql.NewRateLimitRepository(db)
+		hits, previous, err := repo.Hit(context.TODO(), `ip:1.2.3.4`, start, time.Minute)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), hits)
+		assert.Equal(t, int64(7), previous)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("error", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`INSERT INTO rate_limit`).
+			ExpectExec().
+			WillReturnError(fmt.Errorf("Some error"))
+		mock.ExpectRollback()
+
+		repo := mysql.NewRateLimitRepository(db)
+		_, _, err := repo.Hit(context.TODO(), `ip:1.2.3.4`, start, time.Minute)
+
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPurge(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	before := time.Now()
+	mock.ExpectExec(`DELETE FROM rate_limit WHERE window_start < \?`).
+		WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+
+	repo := mysql.NewRateLimitRepository(db)
+	n, err := repo.Purge(context.TODO(), before)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), n)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,156 @@
+package usecase
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	cmware "github.com/andhikagama/lmnlo/cmiddleware"
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/andhikagama/lmnlo/models/response"
+	"github.com/andhikagama/lmnlo/ratelimit"
+	"github.com/labstack/echo"
+	log "github.com/sirupsen/logrus"
+)
+
+// Options holds the quotas of the limiter
+type Options struct {
+	// Default applies to routes without a rule of their own, a zero limit
+	// leaves them unlimited
+	Default ratelimit.Rule
+	Rules   []ratelimit.Rule
+}
+
+type rateLimitUsecase struct {
+	repo  ratelimit.Repository
+	def   ratelimit.Rule
+	rules map[string]ratelimit.Rule
+}
+
+// NewRateLimitUsecase ...
+func NewRateLimitUsecase(r ratelimit.Repository, opts Options) ratelimit.Usecase {
+	rules := make(map[string]ratelimit.Rule, len(opts.Rules))
+	for _, rule := range opts.Rules {
+		rules[ruleKey(strings.ToUpper(rule.Method), rule.Path)] = rule
+	}
+
+	return &rateLimitUsecase{
+		repo:  r,
+		def:   opts.Default,
+		rules: rules,
+	}
+}
+
+// Limit counts the request against the quota of its route when the rule is
+// keyed by address. It runs before CheckAuthHeader, so that floods are
+// refused before any credential is checked. Requests are let through when
+// the counter cannot be reached.
+func (rl *rateLimitUsecase) Limit(next echo.HandlerFunc) echo.HandlerFunc {
+	return rl.limit(next, false)
+}
+
+// LimitClient is Limit for rules keyed by user or API key. It runs after
+// CheckAuthHeader so that it sees who authenticated.
+func (rl *rateLimitUsecase) LimitClient(next echo.HandlerFunc) echo.HandlerFunc {
+	return rl.limit(next, true)
+}
+
+// Sweep deletes counters whose windows no longer count for any rule and
+// return how many
+func (rl *rateLimitUsecase) Sweep(ctx context.Context) (int64, error) {
+	longest := rl.def.Window
+	for _, rule := range rl.rules {
+		if rule.Window > longest {
+			longest = rule.Window
+		}
+	}
+
+	// A counter still weighs in during the window after its own
+	return rl.repo.Purge(ctx, time.Now().Add(-2*longest))
+}
+
+// limit counts requests of the rules keyed by client, or by address
+// unless client is set
+func (rl *rateLimitUsecase) limit(next echo.HandlerFunc, client bool) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		rule, bucket := rl.rule(c.Request().Method, c.Path())
+		if rule.Limit <= 0 || rule.Window <= 0 || byClient(rule) != client {
+			return next(c)
+		}
+
+		now := time.Now()
+		start := now.Truncate(rule.Window)
+
+		hits, previous, err := rl.repo.Hit(c.Request().Context(), bucket+`|`+clientKey(c, rule.Key), start, rule.Window)
+		if err != nil {
+			log.Error(err)
+			return next(c)
+		}
+
+		// The previous window weighs in by the part of it that still
+		// overlaps a window ending now
+		overlap := 1 - float64(now.Sub(start))/float64(rule.Window)
+		count := int64(math.Ceil(float64(previous)*overlap)) + hits
+
+		reset := start.Add(rule.Window).Sub(now)
+		resetSeconds := strconv.FormatInt(int64(math.Ceil(reset.Seconds())), 10)
+
+		remaining := rule.Limit - count
+		if remaining < 0 {
+			remaining = 0
+		}
+
+		header := c.Response().Header()
+		header.Set(`X-RateLimit-Limit`, strconv.FormatInt(rule.Limit, 10))
+		header.Set(`X-RateLimit-Remaining`, strconv.FormatInt(remaining, 10))
+		header.Set(`X-RateLimit-Reset`, resetSeconds)
+
+		if count > rule.Limit {
+			header.Set(`Retry-After`, resetSeconds)
+			return c.JSON(http.StatusTooManyRequests, &response.Wrapper{
+				Message: response.ErrRateLimited.Error(),
+			})
+		}
+
+		return next(c)
+	}
+}
+
+// rule return the rule of a route and the bucket its requests are counted
+// in, routes without a rule share the bucket of the default rule
+func (rl *rateLimitUsecase) rule(method, path string) (ratelimit.Rule, string) {
+	key := ruleKey(method, path)
+	if rule, ok := rl.rules[key]; ok {
+		return rule, key
+	}
+
+	return rl.def, `default`
+}
+
+// byClient reports whether rule counts requests by who authenticated
+func byClient(rule ratelimit.Rule) bool {
+	return rule.Key == ratelimit.KeyUser || rule.Key == ratelimit.KeyAPIKey
+}
+
+// clientKey identifies who a request is counted for
+func clientKey(c echo.Context, kind string) string {
+	switch kind {
+	case ratelimit.KeyUser:
+		if usr, ok := c.Get(`user`).(*entity.User); ok && usr.ID != 0 {
+			return `user:` + strconv.FormatInt(usr.ID, 10)
+		}
+	case ratelimit.KeyAPIKey:
+		if prefix, ok := c.Get(`api_key`).(string); ok && prefix != `` {
+			return `api_key:` + prefix
+		}
+	}
+
+	return `ip:` + cmware.ClientIP(c)
+}
+
+func ruleKey(method, path string) string {
+	return method + ` ` + path
+}
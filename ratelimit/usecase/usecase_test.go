@@ -0,0 +1,188 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	cmware "github.com/andhikagama/lmnlo/cmiddleware"
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/andhikagama/lmnlo/ratelimit"
+	"github.com/andhikagama/lmnlo/ratelimit/mocks"
+	"github.com/andhikagama/lmnlo/ratelimit/repository/memory"
+	"github.com/andhikagama/lmnlo/ratelimit/usecase"
+)
+
+var mockOptions = usecase.Options{
+	Default: ratelimit.Rule{Limit: 3, Window: time.Hour, Key: ratelimit.KeyIP},
+	Rules: []ratelimit.Rule{
+		{Method: `post`, Path: `/v1/login`, Limit: 1, Window: time.Hour, Key: ratelimit.KeyIP},
+		{Method: `GET`, Path: `/v1/user/:id`, Limit: 2, Window: time.Hour, Key: ratelimit.KeyUser},
+		{Method: `GET`, Path: `/v1/report`, Limit: 1, Window: time.Hour, Key: ratelimit.KeyAPIKey},
+	},
+}
+
+func okHandler(c echo.Context) error {
+	return c.NoContent(http.StatusOK)
+}
+
+// authenticated counts the requests that made it past Limit to the
+// authentication of newServer
+var authenticated int
+
+// newServer routes /v1 through the limiter, the user and the API key are
+// taken from the X-User and X-API-Key headers in place of CheckAuthHeader
+func newServer(r ratelimit.Repository, opts usecase.Options) *echo.Echo {
+	rl := usecase.NewRateLimitUsecase(r, opts)
+
+	e := echo.New()
+	g := e.Group(`/v1`)
+	g.Use(rl.Limit)
+	g.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			authenticated++
+			if id, err := strconv.ParseInt(c.Request().Header.Get(`X-User`), 10, 64); err == nil {
+				c.Set(`user`, &entity.User{ID: id})
+			}
+			if prefix := c.Request().Header.Get(cmware.HeaderAPIKey); prefix != `` {
+				c.Set(`api_key`, prefix)
+			}
+			return next(c)
+		}
+	})
+	g.Use(rl.LimitClient)
+
+	g.POST(`/login`, okHandler)
+	g.GET(`/user/:id`, okHandler)
+	g.GET(`/report`, okHandler)
+	g.GET(`/ping`, okHandler)
+	g.GET(`/pong`, okHandler)
+
+	return e
+}
+
+func serve(e *echo.Echo, method, path string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.RemoteAddr = `1.2.3.4:5555`
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestLimit(t *testing.T) {
+	t.Run("route-rule", func(t *testing.T) {
+		e := newServer(memory.NewRateLimitRepository(), mockOptions)
+
+		rec := serve(e, echo.POST, `/v1/login`, nil)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `1`, rec.Header().Get(`X-RateLimit-Limit`))
+		assert.Equal(t, `0`, rec.Header().Get(`X-RateLimit-Remaining`))
+		assert.NotEmpty(t, rec.Header().Get(`X-RateLimit-Reset`))
+
+		authenticated = 0
+		rec = serve(e, echo.POST, `/v1/login`, nil)
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+		assert.Equal(t, rec.Header().Get(`X-RateLimit-Reset`), rec.Header().Get(`Retry-After`))
+		assert.Zero(t, authenticated, `refused before authentication`)
+
+		// A forwarding header of an untrusted peer does not change the address
+		rec = serve(e, echo.POST, `/v1/login`, map[string]string{echo.HeaderXRealIP: `5.6.7.8`})
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+
+		// Another address has a quota of its own
+		req := httptest.NewRequest(echo.POST, `/v1/login`, nil)
+		req.RemoteAddr = `5.6.7.8:5555`
+		rec = httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("default-rule-shared", func(t *testing.T) {
+		e := newServer(memory.NewRateLimitRepository(), mockOptions)
+
+		assert.Equal(t, http.StatusOK, serve(e, echo.GET, `/v1/ping`, nil).Code)
+		assert.Equal(t, http.StatusOK, serve(e, echo.GET, `/v1/pong`, nil).Code)
+		assert.Equal(t, http.StatusOK, serve(e, echo.GET, `/v1/ping`, nil).Code)
+		assert.Equal(t, http.StatusTooManyRequests, serve(e, echo.GET, `/v1/pong`, nil).Code)
+	})
+
+	t.Run("per-user", func(t *testing.T) {
+		e := newServer(memory.NewRateLimitRepository(), mockOptions)
+
+		for i := 0; i < 2; i++ {
+			assert.Equal(t, http.StatusOK, serve(e, echo.GET, `/v1/user/1`, map[string]string{`X-User`: `1`}).Code)
+		}
+		assert.Equal(t, http.StatusTooManyRequests, serve(e, echo.GET, `/v1/user/1`, map[string]string{`X-User`: `1`}).Code)
+
+		// Same address, different user
+		assert.Equal(t, http.StatusOK, serve(e, echo.GET, `/v1/user/1`, map[string]string{`X-User`: `2`}).Code)
+	})
+
+	t.Run("per-api-key", func(t *testing.T) {
+		e := newServer(memory.NewRateLimitRepository(), mockOptions)
+
+		assert.Equal(t, http.StatusOK, serve(e, echo.GET, `/v1/report`, map[string]string{cmware.HeaderAPIKey: `a`}).Code)
+		assert.Equal(t, http.StatusTooManyRequests, serve(e, echo.GET, `/v1/report`, map[string]string{cmware.HeaderAPIKey: `a`}).Code)
+		assert.Equal(t, http.StatusOK, serve(e, echo.GET, `/v1/report`, map[string]string{cmware.HeaderAPIKey: `b`}).Code)
+	})
+
+	t.Run("unlimited", func(t *testing.T) {
+		e := newServer(memory.NewRateLimitRepository(), usecase.Options{})
+
+		for i := 0; i < 5; i++ {
+			rec := serve(e, echo.GET, `/v1/ping`, nil)
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Empty(t, rec.Header().Get(`X-RateLimit-Limit`))
+		}
+	})
+
+	t.Run("store-error", func(t *testing.T) {
+		mockRepo := new(mocks.Repository)
+		mockRepo.On("Hit", mock.Anything, `POST /v1/login|ip:1.2.3.4`, mock.AnythingOfType("time.Time"), time.Hour).Return(int64(0), int64(0), errors.New(`Unexpected Error`)).Once()
+		e := newServer(mockRepo, mockOptions)
+
+		rec := serve(e, echo.POST, `/v1/login`, nil)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("previous-window", func(t *testing.T) {
+		// Hits of the previous window still count in the current one
+		mockRepo := new(mocks.Repository)
+		mockRepo.On("Hit", mock.Anything, mock.Anything, mock.AnythingOfType("time.Time"), time.Hour).Return(int64(3), int64(1), nil).Once()
+		e := newServer(mockRepo, mockOptions)
+
+		rec := serve(e, echo.GET, `/v1/ping`, nil)
+
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestSweep(t *testing.T) {
+	mockRepo := new(mocks.Repository)
+	mockRepo.On("Purge", mock.Anything, mock.MatchedBy(func(before time.Time) bool {
+		// Twice the longest window of mockOptions
+		return time.Until(before) < -119*time.Minute && time.Until(before) > -121*time.Minute
+	})).Return(int64(3), nil).Once()
+	rl := usecase.NewRateLimitUsecase(mockRepo, mockOptions)
+
+	n, err := rl.Sweep(context.TODO())
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+	mockRepo.AssertExpectations(t)
+}
@@ -1,35 +1,71 @@
 package http
 
 import (
+	"crypto/subtle"
 	"io/ioutil"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
+	cmware "github.com/andhikagama/lmnlo/cmiddleware"
 	"github.com/andhikagama/lmnlo/models/entity"
 	"github.com/andhikagama/lmnlo/models/filter"
 	"github.com/andhikagama/lmnlo/models/response"
+	"github.com/andhikagama/lmnlo/oidc"
 	"github.com/andhikagama/lmnlo/user"
 	"github.com/labstack/echo"
 )
 
+const (
+	_OAuthStateCookie    = `oauth_state`
+	_OAuthStateCookieAge = 10 * time.Minute
+)
+
 // UserHTTPHandler ...
 type UserHTTPHandler struct {
 	Usecase user.Usecase
+	// Cookies switches logins to the cookie mode of browser clients, nil
+	// returns tokens in the body
+	Cookies *cmware.SessionCookies
 }
 
 // NewUserHTTPHandler ...
-func NewUserHTTPHandler(g *echo.Group, u user.Usecase) {
+func NewUserHTTPHandler(g *echo.Group, u user.Usecase, cm cmware.Usecase, sc *cmware.SessionCookies) {
 	handler := &UserHTTPHandler{
 		Usecase: u,
+		Cookies: sc,
 	}
 
-	g.POST(`/register`, handler.Register)
-	g.GET(`/user`, handler.Fetch)
-	g.PUT(`/user/:id`, handler.Update)
-	g.GET(`/user/:id`, handler.GetByID)
-	g.DELETE(`/user/:id`, handler.Delete)
-	g.PATCH(`/user/:id`, handler.PartialUpdate)
-	g.POST(`/login`, handler.Login)
+	cm.SetPolicy(g.POST(`/register`, handler.Register), cmware.Public)
+	g.GET(`/user`, handler.Fetch, cm.RequirePermission(entity.PermissionUserList))
+	g.PUT(`/user/:id`, handler.Update, cm.OwnerOrAdmin(`id`), cm.RequirePermission(entity.PermissionUserUpdate), cm.DenyImpersonation)
+	g.GET(`/user/:id`, handler.GetByID, cm.OwnerOrAdmin(`id`), cm.RequirePermission(entity.PermissionUserRead))
+	g.DELETE(`/user/:id`, handler.Delete, cm.RequirePermission(entity.PermissionUserDelete), cm.DenyImpersonation)
+	g.PATCH(`/user/:id`, handler.PartialUpdate, cm.OwnerOrAdmin(`id`), cm.RequirePermission(entity.PermissionUserUpdate), cm.DenyImpersonation)
+	g.GET(`/user/:id/roles`, handler.GetRoles, cm.OwnerOrAdmin(`id`))
+	g.PUT(`/user/:id/roles`, handler.AssignRoles, cm.RequirePermission(entity.PermissionRoleAssign), cm.DenyImpersonation)
+	g.POST(`/user/:id/impersonate`, handler.Impersonate, cm.RequirePermission(entity.PermissionUserImpersonate), cm.RequireSession, cm.DenyImpersonation)
+	cm.SetPolicy(g.POST(`/login`, handler.Login), cmware.Public)
+	cm.SetPolicy(g.POST(`/login/mfa`, handler.LoginMFA), cmware.Public)
+	cm.SetPolicy(g.GET(`/oauth/:provider`, handler.OAuth), cmware.Public)
+	cm.SetPolicy(g.GET(`/oauth/:provider/callback`, handler.OAuthCallback), cmware.Public)
+	cm.SetPolicy(g.POST(`/token/refresh`, handler.Refresh), cmware.Public)
+	g.POST(`/logout`, handler.Logout, cm.RequireSession)
+	g.GET(`/sessions`, handler.FetchSessions, cm.RequireSession)
+	g.DELETE(`/sessions`, handler.RevokeSessions, cm.RequireSession, cm.DenyImpersonation)
+	g.POST(`/user/me/password`, handler.ChangePassword, cm.RequireSession, cm.DenyImpersonation)
+	g.POST(`/user/me/mfa`, handler.EnrollMFA, cm.RequireSession, cm.DenyImpersonation)
+	g.POST(`/user/me/mfa/confirm`, handler.ConfirmMFA, cm.RequireSession, cm.DenyImpersonation)
+	g.DELETE(`/user/me/mfa`, handler.DisableMFA, cm.RequireSession, cm.DenyImpersonation)
+	g.POST(`/user/me/api-keys`, handler.CreateAPIKey, cm.RequireSession, cm.DenyImpersonation)
+	g.GET(`/user/me/api-keys`, handler.FetchAPIKeys, cm.RequireSession)
+	g.DELETE(`/user/me/api-keys/:id`, handler.RevokeAPIKey, cm.RequireSession, cm.DenyImpersonation)
+	cm.SetPolicy(g.POST(`/verify-email`, handler.VerifyEmail), cmware.Public)
+	cm.SetPolicy(g.POST(`/verify-email/resend`, handler.ResendVerification), cmware.Public)
+	cm.SetPolicy(g.POST(`/password/forgot`, handler.ForgotPassword), cmware.Public)
+	cm.SetPolicy(g.POST(`/password/reset`, handler.ResetPassword), cmware.Public)
 }
 
 // Register ...
@@ -37,7 +73,7 @@ func (h *UserHTTPHandler) Register(c echo.Context) error {
 	usr := new(entity.User)
 	c.Bind(usr)
 
-	err := h.Usecase.Register(usr)
+	err := h.Usecase.Register(c.Request().Context(), usr, cmware.Origin(c))
 
 	if err != nil {
 		if err == response.ErrAlreadyExist {
@@ -46,6 +82,12 @@ func (h *UserHTTPHandler) Register(c echo.Context) error {
 			})
 		}
 
+		if err == response.ErrWeakPassword {
+			return c.JSON(http.StatusBadRequest, &response.Wrapper{
+				Message: err.Error(),
+			})
+		}
+
 		return c.JSON(http.StatusInternalServerError, &response.Wrapper{
 			Message: response.ErrServer.Error(),
 		})
@@ -94,7 +136,7 @@ func (h *UserHTTPHandler) Fetch(c echo.Context) error {
 		f.Address = c.QueryParam(`address`)
 	}
 
-	res, err := h.Usecase.Fetch(f)
+	res, err := h.Usecase.Fetch(c.Request().Context(), f)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, &response.Wrapper{
 			Message: response.ErrServer.Error(),
@@ -125,7 +167,7 @@ func (h *UserHTTPHandler) Update(c echo.Context) error {
 
 	usr.ID = int64(id)
 
-	err = h.Usecase.Update(usr)
+	err = h.Usecase.Update(c.Request().Context(), usr, cmware.Origin(c))
 
 	if err != nil {
 		if err == response.ErrNotFound {
@@ -133,6 +175,19 @@ func (h *UserHTTPHandler) Update(c echo.Context) error {
 				Message: response.ErrNotFound.Error(),
 			})
 		}
+
+		if err == response.ErrBadRequest {
+			return c.JSON(http.StatusBadRequest, &response.Wrapper{
+				Message: err.Error(),
+			})
+		}
+
+		if err == response.ErrAlreadyExist {
+			return c.JSON(http.StatusConflict, &response.Wrapper{
+				Message: err.Error(),
+			})
+		}
+
 		return c.JSON(http.StatusInternalServerError, &response.Wrapper{
 			Message: response.ErrServer.Error(),
 		})
@@ -150,7 +205,7 @@ func (h *UserHTTPHandler) GetByID(c echo.Context) error {
 		})
 	}
 
-	res, err := h.Usecase.GetByID(int64(id))
+	res, err := h.Usecase.GetByID(c.Request().Context(), int64(id))
 
 	if err != nil {
 		if err == response.ErrNotFound {
@@ -176,7 +231,7 @@ func (h *UserHTTPHandler) Delete(c echo.Context) error {
 		})
 	}
 
-	err = h.Usecase.Delete(int64(id))
+	err = h.Usecase.Delete(c.Request().Context(), int64(id), cmware.Origin(c))
 
 	if err != nil {
 		if err == response.ErrNotFound {
@@ -209,7 +264,7 @@ func (h *UserHTTPHandler) PartialUpdate(c echo.Context) error {
 	}
 
 	jsonPatch, _ := ioutil.ReadAll(c.Request().Body)
-	res, err := h.Usecase.PartialUpdate(id, jsonPatch)
+	res, err := h.Usecase.PartialUpdate(c.Request().Context(), id, jsonPatch, cmware.Origin(c))
 
 	if err != nil {
 		if err == response.ErrNotFound {
@@ -217,6 +272,19 @@ func (h *UserHTTPHandler) PartialUpdate(c echo.Context) error {
 				Message: response.ErrNotFound.Error(),
 			})
 		}
+
+		if err == response.ErrBadRequest {
+			return c.JSON(http.StatusBadRequest, &response.Wrapper{
+				Message: err.Error(),
+			})
+		}
+
+		if err == response.ErrAlreadyExist {
+			return c.JSON(http.StatusConflict, &response.Wrapper{
+				Message: err.Error(),
+			})
+		}
+
 		return c.JSON(http.StatusInternalServerError, &response.Wrapper{
 			Message: response.ErrServer.Error(),
 		})
@@ -230,14 +298,678 @@ func (h *UserHTTPHandler) Login(c echo.Context) error {
 	auth := new(entity.User)
 	c.Bind(auth)
 
-	res, err := h.Usecase.Login(auth)
+	sess := &entity.Session{
+		UserAgent: c.Request().UserAgent(),
+		IP:        cmware.ClientIP(c),
+	}
+
+	res, err := h.Usecase.Login(c.Request().Context(), auth, sess, cmware.Origin(c))
 	if err != nil {
+		if locked, ok := err.(*response.LockedError); ok {
+			return tooManyAttempts(c, locked)
+		}
+
 		if err == response.ErrLogin {
 			return c.JSON(http.StatusNotFound, &response.Wrapper{
 				Message: err.Error(),
 			})
 		}
 
+		if err == response.ErrForbidden || err == response.ErrUnverified {
+			return c.JSON(http.StatusForbidden, &response.Wrapper{
+				Message: err.Error(),
+			})
+		}
+
+		return c.JSON(http.StatusInternalServerError, &response.Wrapper{
+			Message: response.ErrServer.Error(),
+		})
+	}
+
+	return h.loggedIn(c, res)
+}
+
+// OAuth sends the user to sign in at the provider. The state is also kept
+// in a cookie so that the callback only succeeds in the same browser.
+func (h *UserHTTPHandler) OAuth(c echo.Context) error {
+	authURL, state, err := h.Usecase.OAuthURL(c.Request().Context(), c.Param(`provider`))
+	if err != nil {
+		if err == oidc.ErrUnknownProvider {
+			return c.JSON(http.StatusNotFound, &response.Wrapper{
+				Message: response.ErrNotFound.Error(),
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, &response.Wrapper{
+			Message: response.ErrServer.Error(),
+		})
+	}
+
+	c.SetCookie(&http.Cookie{
+		Name:     _OAuthStateCookie,
+		Value:    state,
+		Path:     c.Request().URL.Path,
+		MaxAge:   int(_OAuthStateCookieAge.Seconds()),
+		HttpOnly: true,
+		Secure:   c.Scheme() == `https`,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return c.Redirect(http.StatusFound, authURL)
+}
+
+// OAuthCallback completes a sign-in the provider redirected back from
+func (h *UserHTTPHandler) OAuthCallback(c echo.Context) error {
+	state := c.QueryParam(`state`)
+
+	cookie, err := c.Cookie(_OAuthStateCookie)
+	if err != nil || state == `` || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		return c.JSON(http.StatusUnauthorized, &response.Wrapper{
+			Message: response.ErrInvalidToken.Error(),
+		})
+	}
+
+	c.SetCookie(&http.Cookie{
+		Name:     _OAuthStateCookie,
+		Path:     strings.TrimSuffix(c.Request().URL.Path, `/callback`),
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   c.Scheme() == `https`,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	// The user declined at the provider
+	if c.QueryParam(`error`) != `` {
+		return c.JSON(http.StatusUnauthorized, &response.Wrapper{
+			Message: response.ErrUnAuthorized.Error(),
+		})
+	}
+
+	sess := &entity.Session{
+		UserAgent: c.Request().UserAgent(),
+		IP:        cmware.ClientIP(c),
+	}
+
+	res, err := h.Usecase.LoginOAuth(c.Request().Context(), c.Param(`provider`), state, c.QueryParam(`code`), sess, cmware.Origin(c))
+	if err != nil {
+		if err == oidc.ErrUnknownProvider {
+			return c.JSON(http.StatusNotFound, &response.Wrapper{
+				Message: response.ErrNotFound.Error(),
+			})
+		}
+
+		if err == response.ErrInvalidToken || err == response.ErrUnAuthorized ||
+			err == oidc.ErrExchange || err == oidc.ErrInvalidIDToken {
+			return c.JSON(http.StatusUnauthorized, &response.Wrapper{
+				Message: err.Error(),
+			})
+		}
+
+		if err == oidc.ErrNoEmail {
+			return c.JSON(http.StatusBadRequest, &response.Wrapper{
+				Message: err.Error(),
+			})
+		}
+
+		if err == response.ErrUnverified {
+			return c.JSON(http.StatusForbidden, &response.Wrapper{
+				Message: err.Error(),
+			})
+		}
+
+		if err == response.ErrAlreadyExist {
+			return c.JSON(http.StatusConflict, &response.Wrapper{
+				Message: err.Error(),
+			})
+		}
+
+		return c.JSON(http.StatusInternalServerError, &response.Wrapper{
+			Message: response.ErrServer.Error(),
+		})
+	}
+
+	return h.loggedIn(c, res)
+}
+
+// loggedIn answers a successful login or refresh, in cookie mode the tokens
+// are set as cookies instead of returned
+func (h *UserHTTPHandler) loggedIn(c echo.Context, res *entity.User) error {
+	if h.Cookies != nil {
+		if err := h.Cookies.Set(c, res); err != nil {
+			return c.JSON(http.StatusInternalServerError, &response.Wrapper{
+				Message: response.ErrServer.Error(),
+			})
+		}
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
+
+// tooManyAttempts answers a refused login, Retry-After is rounded up to
+// whole seconds
+func tooManyAttempts(c echo.Context, locked *response.LockedError) error {
+	retryAfter := int64(math.Ceil(locked.RetryAfter.Seconds()))
+	c.Response().Header().Set(`Retry-After`, strconv.FormatInt(retryAfter, 10))
+
+	return c.JSON(http.StatusTooManyRequests, &response.Wrapper{
+		Message: locked.Error(),
+	})
+}
+
+// Refresh ...
+func (h *UserHTTPHandler) Refresh(c echo.Context) error {
+	auth := new(entity.User)
+	c.Bind(auth)
+
+	if auth.RefreshToken == `` && h.Cookies != nil {
+		auth.RefreshToken = h.Cookies.RefreshToken(c.Request())
+	}
+
+	res, err := h.Usecase.Refresh(c.Request().Context(), auth.RefreshToken, cmware.Origin(c))
+	if err != nil {
+		if err == response.ErrUnAuthorized {
+			if h.Cookies != nil {
+				h.Cookies.Clear(c)
+			}
+
+			return c.JSON(http.StatusUnauthorized, &response.Wrapper{
+				Message: err.Error(),
+			})
+		}
+
+		return c.JSON(http.StatusInternalServerError, &response.Wrapper{
+			Message: response.ErrServer.Error(),
+		})
+	}
+
+	return h.loggedIn(c, res)
+}
+
+// Logout ...
+func (h *UserHTTPHandler) Logout(c echo.Context) error {
+	token, _ := c.Get(`token`).(string)
+
+	err := h.Usecase.Logout(c.Request().Context(), token, cmware.Origin(c))
+	if err != nil {
+		if err == response.ErrUnAuthorized {
+			return c.JSON(http.StatusUnauthorized, &response.Wrapper{
+				Message: err.Error(),
+			})
+		}
+
+		return c.JSON(http.StatusInternalServerError, &response.Wrapper{
+			Message: response.ErrServer.Error(),
+		})
+	}
+
+	if h.Cookies != nil {
+		h.Cookies.Clear(c)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// FetchSessions ...
+func (h *UserHTTPHandler) FetchSessions(c echo.Context) error {
+	usr, ok := c.Get(`user`).(*entity.User)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, &response.Wrapper{
+			Message: response.ErrUnAuthorized.Error(),
+		})
+	}
+
+	token, _ := c.Get(`token`).(string)
+
+	res, err := h.Usecase.FetchSessions(c.Request().Context(), usr.ID, token)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, &response.Wrapper{
+			Message: response.ErrServer.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
+
+// RevokeSessions ...
+func (h *UserHTTPHandler) RevokeSessions(c echo.Context) error {
+	usr, ok := c.Get(`user`).(*entity.User)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, &response.Wrapper{
+			Message: response.ErrUnAuthorized.Error(),
+		})
+	}
+
+	err := h.Usecase.RevokeSessions(c.Request().Context(), usr.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, &response.Wrapper{
+			Message: response.ErrServer.Error(),
+		})
+	}
+
+	if h.Cookies != nil {
+		h.Cookies.Clear(c)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetRoles ...
+func (h *UserHTTPHandler) GetRoles(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param(`id`))
+	if err != nil || id == 0 {
+		return c.JSON(http.StatusNotFound, &response.Wrapper{
+			Message: response.ErrNotFound.Error(),
+		})
+	}
+
+	res, err := h.Usecase.GetRoles(c.Request().Context(), int64(id))
+	if err != nil {
+		if err == response.ErrNotFound {
+			return c.JSON(http.StatusNotFound, &response.Wrapper{
+				Message: response.ErrNotFound.Error(),
+			})
+		}
+
+		return c.JSON(http.StatusInternalServerError, &response.Wrapper{
+			Message: response.ErrServer.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
+
+// AssignRoles ...
+func (h *UserHTTPHandler) AssignRoles(c echo.Context) error {
+	usr := new(entity.User)
+	c.Bind(usr)
+
+	id, err := strconv.Atoi(c.Param(`id`))
+	if err != nil || id == 0 {
+		return c.JSON(http.StatusNotFound, &response.Wrapper{
+			Message: response.ErrNotFound.Error(),
+		})
+	}
+
+	err = h.Usecase.AssignRoles(c.Request().Context(), int64(id), usr.Roles, cmware.Origin(c))
+	if err != nil {
+		if err == response.ErrNotFound {
+			return c.JSON(http.StatusNotFound, &response.Wrapper{
+				Message: response.ErrNotFound.Error(),
+			})
+		}
+
+		if err == response.ErrBadRequest {
+			return c.JSON(http.StatusBadRequest, &response.Wrapper{
+				Message: response.ErrBadRequest.Error(),
+			})
+		}
+
+		return c.JSON(http.StatusInternalServerError, &response.Wrapper{
+			Message: response.ErrServer.Error(),
+		})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Impersonate issues the support admin a short-lived token acting as the
+// user in the path. It is always returned in the body, a cookie would
+// replace the admin's own session.
+func (h *UserHTTPHandler) Impersonate(c echo.Context) error {
+	actor, ok := c.Get(`user`).(*entity.User)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, &response.Wrapper{
+			Message: response.ErrUnAuthorized.Error(),
+		})
+	}
+
+	id, err := strconv.Atoi(c.Param(`id`))
+	if err != nil || id == 0 {
+		return c.JSON(http.StatusNotFound, &response.Wrapper{
+			Message: response.ErrNotFound.Error(),
+		})
+	}
+
+	sess := &entity.Session{
+		UserAgent: c.Request().UserAgent(),
+		IP:        cmware.ClientIP(c),
+	}
+
+	res, err := h.Usecase.Impersonate(c.Request().Context(), actor, int64(id), sess, cmware.Origin(c))
+	if err != nil {
+		if err == response.ErrNotFound {
+			return c.JSON(http.StatusNotFound, &response.Wrapper{
+				Message: response.ErrNotFound.Error(),
+			})
+		}
+
+		if err == response.ErrBadRequest {
+			return c.JSON(http.StatusBadRequest, &response.Wrapper{
+				Message: response.ErrBadRequest.Error(),
+			})
+		}
+
+		if err == response.ErrForbidden || err == response.ErrImpersonation {
+			return c.JSON(http.StatusForbidden, &response.Wrapper{
+				Message: err.Error(),
+			})
+		}
+
+		return c.JSON(http.StatusInternalServerError, &response.Wrapper{
+			Message: response.ErrServer.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
+
+// VerifyEmail ...
+func (h *UserHTTPHandler) VerifyEmail(c echo.Context) error {
+	req := new(struct {
+		Token string `json:"token"`
+	})
+	c.Bind(req)
+
+	if req.Token == `` {
+		return c.JSON(http.StatusBadRequest, &response.Wrapper{
+			Message: response.ErrBadRequest.Error(),
+		})
+	}
+
+	err := h.Usecase.VerifyEmail(c.Request().Context(), req.Token)
+	if err != nil {
+		if err == response.ErrInvalidToken {
+			return c.JSON(http.StatusBadRequest, &response.Wrapper{
+				Message: err.Error(),
+			})
+		}
+
+		return c.JSON(http.StatusInternalServerError, &response.Wrapper{
+			Message: response.ErrServer.Error(),
+		})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ResendVerification ...
+func (h *UserHTTPHandler) ResendVerification(c echo.Context) error {
+	usr := new(entity.User)
+	c.Bind(usr)
+
+	if usr.Email == `` {
+		return c.JSON(http.StatusBadRequest, &response.Wrapper{
+			Message: response.ErrBadRequest.Error(),
+		})
+	}
+
+	err := h.Usecase.ResendVerification(c.Request().Context(), usr.Email)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, &response.Wrapper{
+			Message: response.ErrServer.Error(),
+		})
+	}
+
+	return c.NoContent(http.StatusAccepted)
+}
+
+// ForgotPassword ...
+func (h *UserHTTPHandler) ForgotPassword(c echo.Context) error {
+	usr := new(entity.User)
+	c.Bind(usr)
+
+	if usr.Email == `` {
+		return c.JSON(http.StatusBadRequest, &response.Wrapper{
+			Message: response.ErrBadRequest.Error(),
+		})
+	}
+
+	err := h.Usecase.ForgotPassword(c.Request().Context(), usr.Email)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, &response.Wrapper{
+			Message: response.ErrServer.Error(),
+		})
+	}
+
+	return c.NoContent(http.StatusAccepted)
+}
+
+// ResetPassword ...
+func (h *UserHTTPHandler) ResetPassword(c echo.Context) error {
+	req := new(struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	})
+	c.Bind(req)
+
+	if req.Token == `` {
+		return c.JSON(http.StatusBadRequest, &response.Wrapper{
+			Message: response.ErrBadRequest.Error(),
+		})
+	}
+
+	err := h.Usecase.ResetPassword(c.Request().Context(), req.Token, req.Password, cmware.Origin(c))
+	if err != nil {
+		if err == response.ErrInvalidToken || err == response.ErrWeakPassword {
+			return c.JSON(http.StatusBadRequest, &response.Wrapper{
+				Message: err.Error(),
+			})
+		}
+
+		return c.JSON(http.StatusInternalServerError, &response.Wrapper{
+			Message: response.ErrServer.Error(),
+		})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ChangePassword ...
+func (h *UserHTTPHandler) ChangePassword(c echo.Context) error {
+	usr, ok := c.Get(`user`).(*entity.User)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, &response.Wrapper{
+			Message: response.ErrUnAuthorized.Error(),
+		})
+	}
+	token, _ := c.Get(`token`).(string)
+
+	req := new(struct {
+		CurrentPassword string `json:"current_password"`
+		Password        string `json:"password"`
+	})
+	c.Bind(req)
+
+	err := h.Usecase.ChangePassword(c.Request().Context(), usr.ID, token, req.CurrentPassword, req.Password, cmware.Origin(c))
+	if err != nil {
+		if err == response.ErrWrongPassword {
+			return c.JSON(http.StatusForbidden, &response.Wrapper{
+				Message: err.Error(),
+			})
+		}
+
+		if err == response.ErrWeakPassword {
+			return c.JSON(http.StatusBadRequest, &response.Wrapper{
+				Message: err.Error(),
+			})
+		}
+
+		if err == response.ErrNotFound {
+			return c.JSON(http.StatusNotFound, &response.Wrapper{
+				Message: err.Error(),
+			})
+		}
+
+		return c.JSON(http.StatusInternalServerError, &response.Wrapper{
+			Message: response.ErrServer.Error(),
+		})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// mfaRequest is the body of the MFA endpoints
+type mfaRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+}
+
+// LoginMFA ...
+func (h *UserHTTPHandler) LoginMFA(c echo.Context) error {
+	req := new(mfaRequest)
+	c.Bind(req)
+
+	sess := &entity.Session{
+		UserAgent: c.Request().UserAgent(),
+		IP:        cmware.ClientIP(c),
+	}
+
+	res, err := h.Usecase.LoginMFA(c.Request().Context(), req.ChallengeToken, req.Code, sess, cmware.Origin(c))
+	if err != nil {
+		if locked, ok := err.(*response.LockedError); ok {
+			return tooManyAttempts(c, locked)
+		}
+
+		if err == response.ErrInvalidToken || err == response.ErrInvalidCode {
+			return c.JSON(http.StatusUnauthorized, &response.Wrapper{
+				Message: err.Error(),
+			})
+		}
+
+		return c.JSON(http.StatusInternalServerError, &response.Wrapper{
+			Message: response.ErrServer.Error(),
+		})
+	}
+
+	return h.loggedIn(c, res)
+}
+
+// EnrollMFA ...
+func (h *UserHTTPHandler) EnrollMFA(c echo.Context) error {
+	usr, ok := c.Get(`user`).(*entity.User)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, &response.Wrapper{
+			Message: response.ErrUnAuthorized.Error(),
+		})
+	}
+
+	res, err := h.Usecase.EnrollMFA(c.Request().Context(), usr.ID)
+	if err != nil {
+		if err == response.ErrAlreadyExist {
+			return c.JSON(http.StatusConflict, &response.Wrapper{
+				Message: err.Error(),
+			})
+		}
+
+		if err == response.ErrNotFound {
+			return c.JSON(http.StatusNotFound, &response.Wrapper{
+				Message: err.Error(),
+			})
+		}
+
+		return c.JSON(http.StatusInternalServerError, &response.Wrapper{
+			Message: response.ErrServer.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
+
+// ConfirmMFA ...
+func (h *UserHTTPHandler) ConfirmMFA(c echo.Context) error {
+	usr, ok := c.Get(`user`).(*entity.User)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, &response.Wrapper{
+			Message: response.ErrUnAuthorized.Error(),
+		})
+	}
+
+	req := new(mfaRequest)
+	c.Bind(req)
+
+	codes, err := h.Usecase.ConfirmMFA(c.Request().Context(), usr.ID, req.Code, cmware.Origin(c))
+	if err != nil {
+		if err == response.ErrInvalidCode {
+			return c.JSON(http.StatusBadRequest, &response.Wrapper{
+				Message: err.Error(),
+			})
+		}
+
+		if err == response.ErrAlreadyExist {
+			return c.JSON(http.StatusConflict, &response.Wrapper{
+				Message: err.Error(),
+			})
+		}
+
+		if err == response.ErrNotFound {
+			return c.JSON(http.StatusNotFound, &response.Wrapper{
+				Message: err.Error(),
+			})
+		}
+
+		return c.JSON(http.StatusInternalServerError, &response.Wrapper{
+			Message: response.ErrServer.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string][]string{
+		`recovery_codes`: codes,
+	})
+}
+
+// DisableMFA ...
+func (h *UserHTTPHandler) DisableMFA(c echo.Context) error {
+	usr, ok := c.Get(`user`).(*entity.User)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, &response.Wrapper{
+			Message: response.ErrUnAuthorized.Error(),
+		})
+	}
+
+	req := new(mfaRequest)
+	c.Bind(req)
+
+	err := h.Usecase.DisableMFA(c.Request().Context(), usr.ID, req.Code, cmware.Origin(c))
+	if err != nil {
+		if err == response.ErrInvalidCode {
+			return c.JSON(http.StatusBadRequest, &response.Wrapper{
+				Message: err.Error(),
+			})
+		}
+
+		if err == response.ErrNotFound {
+			return c.JSON(http.StatusNotFound, &response.Wrapper{
+				Message: err.Error(),
+			})
+		}
+
+		return c.JSON(http.StatusInternalServerError, &response.Wrapper{
+			Message: response.ErrServer.Error(),
+		})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// CreateAPIKey ...
+func (h *UserHTTPHandler) CreateAPIKey(c echo.Context) error {
+	usr, ok := c.Get(`user`).(*entity.User)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, &response.Wrapper{
+			Message: response.ErrUnAuthorized.Error(),
+		})
+	}
+
+	key := new(entity.APIKey)
+	c.Bind(key)
+
+	err := h.Usecase.CreateAPIKey(c.Request().Context(), usr, key, cmware.Origin(c))
+	if err != nil {
+		if err == response.ErrBadRequest {
+			return c.JSON(http.StatusBadRequest, &response.Wrapper{
+				Message: err.Error(),
+			})
+		}
+
 		if err == response.ErrForbidden {
 			return c.JSON(http.StatusForbidden, &response.Wrapper{
 				Message: err.Error(),
@@ -249,5 +981,56 @@ func (h *UserHTTPHandler) Login(c echo.Context) error {
 		})
 	}
 
+	return c.JSON(http.StatusCreated, key)
+}
+
+// FetchAPIKeys ...
+func (h *UserHTTPHandler) FetchAPIKeys(c echo.Context) error {
+	usr, ok := c.Get(`user`).(*entity.User)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, &response.Wrapper{
+			Message: response.ErrUnAuthorized.Error(),
+		})
+	}
+
+	res, err := h.Usecase.FetchAPIKeys(c.Request().Context(), usr.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, &response.Wrapper{
+			Message: response.ErrServer.Error(),
+		})
+	}
+
 	return c.JSON(http.StatusOK, res)
 }
+
+// RevokeAPIKey ...
+func (h *UserHTTPHandler) RevokeAPIKey(c echo.Context) error {
+	usr, ok := c.Get(`user`).(*entity.User)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, &response.Wrapper{
+			Message: response.ErrUnAuthorized.Error(),
+		})
+	}
+
+	id, err := strconv.Atoi(c.Param(`id`))
+	if err != nil || id == 0 {
+		return c.JSON(http.StatusNotFound, &response.Wrapper{
+			Message: response.ErrNotFound.Error(),
+		})
+	}
+
+	err = h.Usecase.RevokeAPIKey(c.Request().Context(), usr.ID, int64(id), cmware.Origin(c))
+	if err != nil {
+		if err == response.ErrNotFound {
+			return c.JSON(http.StatusNotFound, &response.Wrapper{
+				Message: err.Error(),
+			})
+		}
+
+		return c.JSON(http.StatusInternalServerError, &response.Wrapper{
+			Message: response.ErrServer.Error(),
+		})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
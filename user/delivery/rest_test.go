@@ -6,9 +6,12 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
+	cmware "github.com/andhikagama/lmnlo/cmiddleware"
 	"github.com/andhikagama/lmnlo/models/entity"
 	"github.com/andhikagama/lmnlo/models/response"
+	"github.com/andhikagama/lmnlo/oidc"
 	handler "github.com/andhikagama/lmnlo/user/delivery"
 	"github.com/andhikagama/lmnlo/user/mocks"
 	"github.com/labstack/echo"
@@ -30,7 +33,7 @@ var mockUsers = []*entity.User{
 func TestStore(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		mockUCase := new(mocks.Usecase)
-		mockUCase.On("Register", mock.AnythingOfType(`*entity.User`)).Return(nil).Once()
+		mockUCase.On("Register", mock.Anything, mock.AnythingOfType(`*entity.User`), mock.AnythingOfType(`*entity.Origin`)).Return(nil).Once()
 
 		e := echo.New()
 		req := httptest.NewRequest(echo.POST, "/", strings.NewReader(""))
@@ -50,7 +53,7 @@ func TestStore(t *testing.T) {
 
 	t.Run("already-exist", func(t *testing.T) {
 		mockUCase := new(mocks.Usecase)
-		mockUCase.On("Register", mock.AnythingOfType(`*entity.User`)).Return(response.ErrAlreadyExist).Once()
+		mockUCase.On("Register", mock.Anything, mock.AnythingOfType(`*entity.User`), mock.AnythingOfType(`*entity.Origin`)).Return(response.ErrAlreadyExist).Once()
 
 		e := echo.New()
 		req := httptest.NewRequest(echo.POST, "/", strings.NewReader(""))
@@ -72,7 +75,7 @@ func TestStore(t *testing.T) {
 
 	t.Run("error", func(t *testing.T) {
 		mockUCase := new(mocks.Usecase)
-		mockUCase.On("Register", mock.AnythingOfType(`*entity.User`)).Return(errors.New(`error`)).Once()
+		mockUCase.On("Register", mock.Anything, mock.AnythingOfType(`*entity.User`), mock.AnythingOfType(`*entity.Origin`)).Return(errors.New(`error`)).Once()
 
 		e := echo.New()
 		req := httptest.NewRequest(echo.POST, "/", strings.NewReader(""))
@@ -96,7 +99,7 @@ func TestStore(t *testing.T) {
 func TestFetch(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		mockUCase := new(mocks.Usecase)
-		mockUCase.On("Fetch", mock.AnythingOfType(`*filter.User`)).Return(mockUsers, nil).Once()
+		mockUCase.On("Fetch", mock.Anything, mock.AnythingOfType(`*filter.User`)).Return(mockUsers, nil).Once()
 
 		e := echo.New()
 		req := httptest.NewRequest(echo.GET, "/", strings.NewReader(""))
@@ -117,7 +120,7 @@ func TestFetch(t *testing.T) {
 
 	t.Run("success-with-param", func(t *testing.T) {
 		mockUCase := new(mocks.Usecase)
-		mockUCase.On("Fetch", mock.AnythingOfType(`*filter.User`)).Return(mockUsers, nil).Once()
+		mockUCase.On("Fetch", mock.Anything, mock.AnythingOfType(`*filter.User`)).Return(mockUsers, nil).Once()
 
 		e := echo.New()
 		req := httptest.NewRequest(echo.GET, "/", strings.NewReader(""))
@@ -184,7 +187,7 @@ func TestFetch(t *testing.T) {
 	t.Run("error", func(t *testing.T) {
 
 		mockUCase := new(mocks.Usecase)
-		mockUCase.On("Fetch", mock.AnythingOfType(`*filter.User`)).Return(nil, errors.New(`Error`)).Once()
+		mockUCase.On("Fetch", mock.Anything, mock.AnythingOfType(`*filter.User`)).Return(nil, errors.New(`Error`)).Once()
 
 		e := echo.New()
 		req := httptest.NewRequest(echo.GET, "/user", strings.NewReader(""))
@@ -205,7 +208,7 @@ func TestFetch(t *testing.T) {
 func TestUpdate(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		mockUCase := new(mocks.Usecase)
-		mockUCase.On("Update", mock.AnythingOfType(`*entity.User`)).Return(nil).Once()
+		mockUCase.On("Update", mock.Anything, mock.AnythingOfType(`*entity.User`), mock.AnythingOfType(`*entity.Origin`)).Return(nil).Once()
 
 		e := echo.New()
 		req := httptest.NewRequest(echo.PUT, "/", strings.NewReader(""))
@@ -225,6 +228,29 @@ func TestUpdate(t *testing.T) {
 		mockUCase.AssertExpectations(t)
 	})
 
+	t.Run("password", func(t *testing.T) {
+		mockUCase := new(mocks.Usecase)
+		mockUCase.On("Update", mock.Anything, mock.AnythingOfType(`*entity.User`), mock.AnythingOfType(`*entity.Origin`)).Return(response.ErrBadRequest).Once()
+
+		e := echo.New()
+		req := httptest.NewRequest(echo.PUT, "/", strings.NewReader(`{"password":"secret"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+		c.SetPath("user")
+		c.SetParamNames(`id`)
+		c.SetParamValues(`1`)
+
+		handler := handler.UserHTTPHandler{
+			Usecase: mockUCase,
+		}
+		handler.Update(c)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		mockUCase.AssertExpectations(t)
+	})
+
 	t.Run("bad-params", func(t *testing.T) {
 		mockUCase := new(mocks.Usecase)
 
@@ -249,7 +275,7 @@ func TestUpdate(t *testing.T) {
 
 	t.Run("not-found", func(t *testing.T) {
 		mockUCase := new(mocks.Usecase)
-		mockUCase.On("Update", mock.AnythingOfType(`*entity.User`)).Return(response.ErrNotFound).Once()
+		mockUCase.On("Update", mock.Anything, mock.AnythingOfType(`*entity.User`), mock.AnythingOfType(`*entity.Origin`)).Return(response.ErrNotFound).Once()
 
 		e := echo.New()
 		req := httptest.NewRequest(echo.PUT, "/", strings.NewReader(""))
@@ -270,9 +296,32 @@ func TestUpdate(t *testing.T) {
 		mockUCase.AssertExpectations(t)
 	})
 
+	t.Run("already-exist", func(t *testing.T) {
+		mockUCase := new(mocks.Usecase)
+		mockUCase.On("Update", mock.Anything, mock.AnythingOfType(`*entity.User`), mock.AnythingOfType(`*entity.Origin`)).Return(response.ErrAlreadyExist).Once()
+
+		e := echo.New()
+		req := httptest.NewRequest(echo.PUT, "/", strings.NewReader(""))
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+		c.SetPath("user")
+		c.SetParamNames(`id`)
+		c.SetParamValues(`1`)
+
+		handler := handler.UserHTTPHandler{
+			Usecase: mockUCase,
+		}
+
+		handler.Update(c)
+
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		mockUCase.AssertExpectations(t)
+	})
+
 	t.Run("error", func(t *testing.T) {
 		mockUCase := new(mocks.Usecase)
-		mockUCase.On("Update", mock.AnythingOfType(`*entity.User`)).Return(errors.New(`error`)).Once()
+		mockUCase.On("Update", mock.Anything, mock.AnythingOfType(`*entity.User`), mock.AnythingOfType(`*entity.Origin`)).Return(errors.New(`error`)).Once()
 
 		e := echo.New()
 		req := httptest.NewRequest(echo.PUT, "/", strings.NewReader(""))
@@ -297,7 +346,7 @@ func TestUpdate(t *testing.T) {
 func TestGetByID(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		mockUCase := new(mocks.Usecase)
-		mockUCase.On("GetByID", mock.AnythingOfType(`int64`)).Return(&mockUser, nil).Once()
+		mockUCase.On("GetByID", mock.Anything, mock.AnythingOfType(`int64`)).Return(&mockUser, nil).Once()
 
 		e := echo.New()
 		req := httptest.NewRequest(echo.GET, "/", strings.NewReader(""))
@@ -341,7 +390,7 @@ func TestGetByID(t *testing.T) {
 
 	t.Run("not-found", func(t *testing.T) {
 		mockUCase := new(mocks.Usecase)
-		mockUCase.On("GetByID", mock.AnythingOfType(`int64`)).Return(new(entity.User), response.ErrNotFound).Once()
+		mockUCase.On("GetByID", mock.Anything, mock.AnythingOfType(`int64`)).Return(new(entity.User), response.ErrNotFound).Once()
 
 		e := echo.New()
 		req := httptest.NewRequest(echo.GET, "/", strings.NewReader(""))
@@ -364,7 +413,7 @@ func TestGetByID(t *testing.T) {
 
 	t.Run("error", func(t *testing.T) {
 		mockUCase := new(mocks.Usecase)
-		mockUCase.On("GetByID", mock.AnythingOfType(`int64`)).Return(new(entity.User), errors.New(`error`)).Once()
+		mockUCase.On("GetByID", mock.Anything, mock.AnythingOfType(`int64`)).Return(new(entity.User), errors.New(`error`)).Once()
 
 		e := echo.New()
 		req := httptest.NewRequest(echo.POST, "/", strings.NewReader(""))
@@ -389,7 +438,7 @@ func TestGetByID(t *testing.T) {
 func TestDelete(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		mockUCase := new(mocks.Usecase)
-		mockUCase.On("Delete", mock.AnythingOfType(`int64`)).Return(nil).Once()
+		mockUCase.On("Delete", mock.Anything, mock.AnythingOfType(`int64`), mock.AnythingOfType(`*entity.Origin`)).Return(nil).Once()
 
 		e := echo.New()
 		req := httptest.NewRequest(echo.DELETE, "/", strings.NewReader(""))
@@ -433,7 +482,7 @@ func TestDelete(t *testing.T) {
 
 	t.Run("not-found", func(t *testing.T) {
 		mockUCase := new(mocks.Usecase)
-		mockUCase.On("Delete", mock.AnythingOfType(`int64`)).Return(response.ErrNotFound).Once()
+		mockUCase.On("Delete", mock.Anything, mock.AnythingOfType(`int64`), mock.AnythingOfType(`*entity.Origin`)).Return(response.ErrNotFound).Once()
 
 		e := echo.New()
 		req := httptest.NewRequest(echo.DELETE, "/", strings.NewReader(""))
@@ -456,7 +505,7 @@ func TestDelete(t *testing.T) {
 
 	t.Run("error", func(t *testing.T) {
 		mockUCase := new(mocks.Usecase)
-		mockUCase.On("Delete", mock.AnythingOfType(`int64`)).Return(errors.New(`error`)).Once()
+		mockUCase.On("Delete", mock.Anything, mock.AnythingOfType(`int64`), mock.AnythingOfType(`*entity.Origin`)).Return(errors.New(`error`)).Once()
 
 		e := echo.New()
 		req := httptest.NewRequest(echo.DELETE, "/", strings.NewReader(""))
@@ -477,3 +526,1180 @@ func TestDelete(t *testing.T) {
 		mockUCase.AssertExpectations(t)
 	})
 }
+
+func TestLogin(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{`success`, nil, http.StatusOK},
+		{`invalid`, response.ErrLogin, http.StatusNotFound},
+		{`unverified`, response.ErrUnverified, http.StatusForbidden},
+		{`locked`, &response.LockedError{RetryAfter: 1500 * time.Millisecond}, http.StatusTooManyRequests},
+		{`error`, errors.New(`error`), http.StatusInternalServerError},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var res *entity.User
+			if tc.err == nil {
+				res = &entity.User{ID: 1, Token: `token`}
+			}
+
+			mockUCase := new(mocks.Usecase)
+			mockUCase.On("Login", mock.Anything, mock.AnythingOfType("*entity.User"), mock.AnythingOfType("*entity.Session"), mock.AnythingOfType("*entity.Origin")).Return(res, tc.err).Once()
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.POST, "/", strings.NewReader(`{"email":"andhika.gama@outlook.com","password":"aiueo"}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			c := e.NewContext(req, rec)
+			c.SetPath("login")
+
+			handler := handler.UserHTTPHandler{
+				Usecase: mockUCase,
+			}
+			handler.Login(c)
+
+			assert.Equal(t, tc.code, rec.Code)
+			if tc.code == http.StatusTooManyRequests {
+				assert.Equal(t, `2`, rec.Header().Get(`Retry-After`))
+			}
+			mockUCase.AssertExpectations(t)
+		})
+	}
+}
+
+// cookiesOf indexes the cookies an answer set by name
+func cookiesOf(rec *httptest.ResponseRecorder) map[string]*http.Cookie {
+	cookies := map[string]*http.Cookie{}
+	for _, cookie := range (&http.Response{Header: rec.Header()}).Cookies() {
+		cookies[cookie.Name] = cookie
+	}
+
+	return cookies
+}
+
+func TestLoginCookie(t *testing.T) {
+	mockUCase := new(mocks.Usecase)
+	mockUCase.On("Login", mock.Anything, mock.AnythingOfType("*entity.User"), mock.AnythingOfType("*entity.Session"), mock.AnythingOfType("*entity.Origin")).Return(&entity.User{ID: 1, Token: `token`, RefreshToken: `refresh`, ExpiresIn: 900}, nil).Once()
+
+	e := echo.New()
+	req := httptest.NewRequest(echo.POST, "/", strings.NewReader(`{"email":"andhika.gama@outlook.com","password":"aiueo"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	c := e.NewContext(req, rec)
+	c.SetPath("login")
+
+	handler := handler.UserHTTPHandler{
+		Usecase: mockUCase,
+		Cookies: cmware.NewSessionCookies(cmware.SessionCookies{Secure: true, MaxAge: time.Hour}),
+	}
+	handler.Login(c)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotContains(t, rec.Body.String(), `token`)
+
+	cookies := cookiesOf(rec)
+	assert.Equal(t, `token`, cookies[`lmnlo_session`].Value)
+	assert.True(t, cookies[`lmnlo_session`].HttpOnly)
+	assert.True(t, cookies[`lmnlo_session`].Secure)
+	assert.Equal(t, `refresh`, cookies[`lmnlo_refresh`].Value)
+	assert.NotEmpty(t, cookies[`lmnlo_csrf`].Value)
+	assert.False(t, cookies[`lmnlo_csrf`].HttpOnly)
+	mockUCase.AssertExpectations(t)
+}
+
+func TestRefresh(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		mockUCase := new(mocks.Usecase)
+		mockUCase.On("Refresh", mock.Anything, `refresh`, mock.AnythingOfType(`*entity.Origin`)).Return(&mockUser, nil).Once()
+
+		e := echo.New()
+		req := httptest.NewRequest(echo.POST, "/", strings.NewReader(`{"refresh_token":"refresh"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+		c.SetPath("token/refresh")
+
+		handler := handler.UserHTTPHandler{
+			Usecase: mockUCase,
+		}
+		handler.Refresh(c)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		mockUCase.AssertExpectations(t)
+	})
+
+	t.Run("unauthorized", func(t *testing.T) {
+		mockUCase := new(mocks.Usecase)
+		mockUCase.On("Refresh", mock.Anything, `refresh`, mock.AnythingOfType(`*entity.Origin`)).Return(nil, response.ErrUnAuthorized).Once()
+
+		e := echo.New()
+		req := httptest.NewRequest(echo.POST, "/", strings.NewReader(`{"refresh_token":"refresh"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+		c.SetPath("token/refresh")
+
+		handler := handler.UserHTTPHandler{
+			Usecase: mockUCase,
+		}
+		handler.Refresh(c)
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		mockUCase.AssertExpectations(t)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		mockUCase := new(mocks.Usecase)
+		mockUCase.On("Refresh", mock.Anything, `refresh`, mock.AnythingOfType(`*entity.Origin`)).Return(nil, errors.New(`error`)).Once()
+
+		e := echo.New()
+		req := httptest.NewRequest(echo.POST, "/", strings.NewReader(`{"refresh_token":"refresh"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+		c.SetPath("token/refresh")
+
+		handler := handler.UserHTTPHandler{
+			Usecase: mockUCase,
+		}
+		handler.Refresh(c)
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		mockUCase.AssertExpectations(t)
+	})
+
+	t.Run("cookie", func(t *testing.T) {
+		mockUCase := new(mocks.Usecase)
+		mockUCase.On("Refresh", mock.Anything, `refresh`, mock.AnythingOfType(`*entity.Origin`)).Return(&entity.User{ID: 1, Token: `new-token`, RefreshToken: `new-refresh`}, nil).Once()
+
+		e := echo.New()
+		req := httptest.NewRequest(echo.POST, "/", strings.NewReader(""))
+		req.AddCookie(&http.Cookie{Name: `lmnlo_refresh`, Value: `refresh`})
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+		c.SetPath("token/refresh")
+
+		handler := handler.UserHTTPHandler{
+			Usecase: mockUCase,
+			Cookies: cmware.NewSessionCookies(cmware.SessionCookies{}),
+		}
+		handler.Refresh(c)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `new-refresh`, cookiesOf(rec)[`lmnlo_refresh`].Value)
+		mockUCase.AssertExpectations(t)
+	})
+}
+
+func TestLogout(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		mockUCase := new(mocks.Usecase)
+		mockUCase.On("Logout", mock.Anything, `token`, mock.AnythingOfType(`*entity.Origin`)).Return(nil).Once()
+
+		e := echo.New()
+		req := httptest.NewRequest(echo.POST, "/", strings.NewReader(""))
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+		c.SetPath("logout")
+		c.Set(`token`, `token`)
+
+		handler := handler.UserHTTPHandler{
+			Usecase: mockUCase,
+		}
+		handler.Logout(c)
+
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		mockUCase.AssertExpectations(t)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		mockUCase := new(mocks.Usecase)
+		mockUCase.On("Logout", mock.Anything, `token`, mock.AnythingOfType(`*entity.Origin`)).Return(errors.New(`error`)).Once()
+
+		e := echo.New()
+		req := httptest.NewRequest(echo.POST, "/", strings.NewReader(""))
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+		c.SetPath("logout")
+		c.Set(`token`, `token`)
+
+		handler := handler.UserHTTPHandler{
+			Usecase: mockUCase,
+		}
+		handler.Logout(c)
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		mockUCase.AssertExpectations(t)
+	})
+	t.Run("cookie", func(t *testing.T) {
+		mockUCase := new(mocks.Usecase)
+		mockUCase.On("Logout", mock.Anything, `token`, mock.AnythingOfType(`*entity.Origin`)).Return(nil).Once()
+
+		e := echo.New()
+		req := httptest.NewRequest(echo.POST, "/", strings.NewReader(""))
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+		c.SetPath("logout")
+		c.Set(`token`, `token`)
+
+		handler := handler.UserHTTPHandler{
+			Usecase: mockUCase,
+			Cookies: cmware.NewSessionCookies(cmware.SessionCookies{}),
+		}
+		handler.Logout(c)
+
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		for _, name := range []string{`lmnlo_session`, `lmnlo_refresh`, `lmnlo_csrf`} {
+			assert.Equal(t, -1, cookiesOf(rec)[name].MaxAge)
+		}
+		mockUCase.AssertExpectations(t)
+	})
+}
+
+func TestFetchSessions(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		mockUCase := new(mocks.Usecase)
+		mockUCase.On("FetchSessions", mock.Anything, mockUser.ID, `token`).Return([]*entity.Session{{ID: 1, Current: true}}, nil).Once()
+
+		e := echo.New()
+		req := httptest.NewRequest(echo.GET, "/", strings.NewReader(""))
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+		c.SetPath("sessions")
+		c.Set(`user`, &mockUser)
+		c.Set(`token`, `token`)
+
+		handler := handler.UserHTTPHandler{
+			Usecase: mockUCase,
+		}
+		handler.FetchSessions(c)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		mockUCase.AssertExpectations(t)
+	})
+
+	t.Run("unauthorized", func(t *testing.T) {
+		mockUCase := new(mocks.Usecase)
+
+		e := echo.New()
+		req := httptest.NewRequest(echo.GET, "/", strings.NewReader(""))
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+		c.SetPath("sessions")
+
+		handler := handler.UserHTTPHandler{
+			Usecase: mockUCase,
+		}
+		handler.FetchSessions(c)
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		mockUCase.AssertExpectations(t)
+	})
+}
+
+func TestRevokeSessions(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		mockUCase := new(mocks.Usecase)
+		mockUCase.On("RevokeSessions", mock.Anything, mockUser.ID).Return(nil).Once()
+
+		e := echo.New()
+		req := httptest.NewRequest(echo.DELETE, "/", strings.NewReader(""))
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+		c.SetPath("sessions")
+		c.Set(`user`, &mockUser)
+
+		handler := handler.UserHTTPHandler{
+			Usecase: mockUCase,
+		}
+		handler.RevokeSessions(c)
+
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		mockUCase.AssertExpectations(t)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		mockUCase := new(mocks.Usecase)
+		mockUCase.On("RevokeSessions", mock.Anything, mockUser.ID).Return(errors.New(`error`)).Once()
+
+		e := echo.New()
+		req := httptest.NewRequest(echo.DELETE, "/", strings.NewReader(""))
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+		c.SetPath("sessions")
+		c.Set(`user`, &mockUser)
+
+		handler := handler.UserHTTPHandler{
+			Usecase: mockUCase,
+		}
+		handler.RevokeSessions(c)
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		mockUCase.AssertExpectations(t)
+	})
+}
+
+func TestGetRoles(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		mockUCase := new(mocks.Usecase)
+		mockUCase.On("GetRoles", mock.Anything, int64(1)).Return([]string{entity.RoleUser}, nil).Once()
+
+		e := echo.New()
+		req := httptest.NewRequest(echo.GET, "/", strings.NewReader(""))
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+		c.SetPath("user/:id/roles")
+		c.SetParamNames(`id`)
+		c.SetParamValues(`1`)
+
+		handler := handler.UserHTTPHandler{
+			Usecase: mockUCase,
+		}
+		handler.GetRoles(c)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		mockUCase.AssertExpectations(t)
+	})
+
+	t.Run("not-found", func(t *testing.T) {
+		mockUCase := new(mocks.Usecase)
+		mockUCase.On("GetRoles", mock.Anything, int64(1)).Return(nil, response.ErrNotFound).Once()
+
+		e := echo.New()
+		req := httptest.NewRequest(echo.GET, "/", strings.NewReader(""))
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+		c.SetPath("user/:id/roles")
+		c.SetParamNames(`id`)
+		c.SetParamValues(`1`)
+
+		handler := handler.UserHTTPHandler{
+			Usecase: mockUCase,
+		}
+		handler.GetRoles(c)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		mockUCase.AssertExpectations(t)
+	})
+}
+
+func TestAssignRoles(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		mockUCase := new(mocks.Usecase)
+		mockUCase.On("AssignRoles", mock.Anything, int64(1), []string{entity.RoleAdmin}, mock.AnythingOfType(`*entity.Origin`)).Return(nil).Once()
+
+		e := echo.New()
+		req := httptest.NewRequest(echo.PUT, "/", strings.NewReader(`{"roles":["admin"]}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+		c.SetPath("user/:id/roles")
+		c.SetParamNames(`id`)
+		c.SetParamValues(`1`)
+
+		handler := handler.UserHTTPHandler{
+			Usecase: mockUCase,
+		}
+		handler.AssignRoles(c)
+
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		mockUCase.AssertExpectations(t)
+	})
+
+	t.Run("unknown-role", func(t *testing.T) {
+		mockUCase := new(mocks.Usecase)
+		mockUCase.On("AssignRoles", mock.Anything, int64(1), []string{`wizard`}, mock.AnythingOfType(`*entity.Origin`)).Return(response.ErrBadRequest).Once()
+
+		e := echo.New()
+		req := httptest.NewRequest(echo.PUT, "/", strings.NewReader(`{"roles":["wizard"]}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+		c.SetPath("user/:id/roles")
+		c.SetParamNames(`id`)
+		c.SetParamValues(`1`)
+
+		handler := handler.UserHTTPHandler{
+			Usecase: mockUCase,
+		}
+		handler.AssignRoles(c)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		mockUCase.AssertExpectations(t)
+	})
+
+	t.Run("bad-params", func(t *testing.T) {
+		mockUCase := new(mocks.Usecase)
+
+		e := echo.New()
+		req := httptest.NewRequest(echo.PUT, "/", strings.NewReader(`{"roles":["admin"]}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+		c.SetPath("user/:id/roles")
+		c.SetParamNames(`id`)
+		c.SetParamValues(`a`)
+
+		handler := handler.UserHTTPHandler{
+			Usecase: mockUCase,
+		}
+		handler.AssignRoles(c)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		mockUCase.AssertExpectations(t)
+	})
+}
+
+func TestVerifyEmail(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		mockUCase := new(mocks.Usecase)
+		mockUCase.On("VerifyEmail", mock.Anything, `token`).Return(nil).Once()
+
+		e := echo.New()
+		req := httptest.NewRequest(echo.POST, "/", strings.NewReader(`{"token":"token"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+		c.SetPath("verify-email")
+
+		handler := handler.UserHTTPHandler{
+			Usecase: mockUCase,
+		}
+		handler.VerifyEmail(c)
+
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		mockUCase.AssertExpectations(t)
+	})
+
+	t.Run("invalid-token", func(t *testing.T) {
+		mockUCase := new(mocks.Usecase)
+		mockUCase.On("VerifyEmail", mock.Anything, `token`).Return(response.ErrInvalidToken).Once()
+
+		e := echo.New()
+		req := httptest.NewRequest(echo.POST, "/", strings.NewReader(`{"token":"token"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+		c.SetPath("verify-email")
+
+		handler := handler.UserHTTPHandler{
+			Usecase: mockUCase,
+		}
+		handler.VerifyEmail(c)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		mockUCase.AssertExpectations(t)
+	})
+
+	t.Run("missing-token", func(t *testing.T) {
+		mockUCase := new(mocks.Usecase)
+
+		e := echo.New()
+		req := httptest.NewRequest(echo.POST, "/", strings.NewReader(`{}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+		c.SetPath("verify-email")
+
+		handler := handler.UserHTTPHandler{
+			Usecase: mockUCase,
+		}
+		handler.VerifyEmail(c)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		mockUCase.AssertExpectations(t)
+	})
+}
+
+func TestResendVerification(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		mockUCase := new(mocks.Usecase)
+		mockUCase.On("ResendVerification", mock.Anything, mockUser.Email).Return(nil).Once()
+
+		e := echo.New()
+		req := httptest.NewRequest(echo.POST, "/", strings.NewReader(`{"email":"`+mockUser.Email+`"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+		c.SetPath("verify-email/resend")
+
+		handler := handler.UserHTTPHandler{
+			Usecase: mockUCase,
+		}
+		handler.ResendVerification(c)
+
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+		mockUCase.AssertExpectations(t)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		mockUCase := new(mocks.Usecase)
+		mockUCase.On("ResendVerification", mock.Anything, mockUser.Email).Return(errors.New(`error`)).Once()
+
+		e := echo.New()
+		req := httptest.NewRequest(echo.POST, "/", strings.NewReader(`{"email":"`+mockUser.Email+`"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+		c.SetPath("verify-email/resend")
+
+		handler := handler.UserHTTPHandler{
+			Usecase: mockUCase,
+		}
+		handler.ResendVerification(c)
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		mockUCase.AssertExpectations(t)
+	})
+}
+
+func TestForgotPassword(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		mockUCase := new(mocks.Usecase)
+		mockUCase.On("ForgotPassword", mock.Anything, mockUser.Email).Return(nil).Once()
+
+		e := echo.New()
+		req := httptest.NewRequest(echo.POST, "/", strings.NewReader(`{"email":"`+mockUser.Email+`"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+		c.SetPath("password/forgot")
+
+		handler := handler.UserHTTPHandler{
+			Usecase: mockUCase,
+		}
+		handler.ForgotPassword(c)
+
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+		mockUCase.AssertExpectations(t)
+	})
+
+	t.Run("missing-email", func(t *testing.T) {
+		mockUCase := new(mocks.Usecase)
+
+		e := echo.New()
+		req := httptest.NewRequest(echo.POST, "/", strings.NewReader(`{}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+		c.SetPath("password/forgot")
+
+		handler := handler.UserHTTPHandler{
+			Usecase: mockUCase,
+		}
+		handler.ForgotPassword(c)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		mockUCase.AssertExpectations(t)
+	})
+}
+
+func TestResetPassword(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		mockUCase := new(mocks.Usecase)
+		mockUCase.On("ResetPassword", mock.Anything, `token`, `secret`, mock.AnythingOfType(`*entity.Origin`)).Return(nil).Once()
+
+		e := echo.New()
+		req := httptest.NewRequest(echo.POST, "/", strings.NewReader(`{"token":"token","password":"secret"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+		c.SetPath("password/reset")
+
+		handler := handler.UserHTTPHandler{
+			Usecase: mockUCase,
+		}
+		handler.ResetPassword(c)
+
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		mockUCase.AssertExpectations(t)
+	})
+
+	t.Run("invalid-token", func(t *testing.T) {
+		mockUCase := new(mocks.Usecase)
+		mockUCase.On("ResetPassword", mock.Anything, `token`, `secret`, mock.AnythingOfType(`*entity.Origin`)).Return(response.ErrInvalidToken).Once()
+
+		e := echo.New()
+		req := httptest.NewRequest(echo.POST, "/", strings.NewReader(`{"token":"token","password":"secret"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+		c.SetPath("password/reset")
+
+		handler := handler.UserHTTPHandler{
+			Usecase: mockUCase,
+		}
+		handler.ResetPassword(c)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		mockUCase.AssertExpectations(t)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		mockUCase := new(mocks.Usecase)
+		mockUCase.On("ResetPassword", mock.Anything, `token`, `secret`, mock.AnythingOfType(`*entity.Origin`)).Return(errors.New(`error`)).Once()
+
+		e := echo.New()
+		req := httptest.NewRequest(echo.POST, "/", strings.NewReader(`{"token":"token","password":"secret"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+		c.SetPath("password/reset")
+
+		handler := handler.UserHTTPHandler{
+			Usecase: mockUCase,
+		}
+		handler.ResetPassword(c)
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		mockUCase.AssertExpectations(t)
+	})
+}
+
+func TestChangePassword(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{`success`, nil, http.StatusNoContent},
+		{`wrong-password`, response.ErrWrongPassword, http.StatusForbidden},
+		{`weak-password`, response.ErrWeakPassword, http.StatusBadRequest},
+		{`error`, errors.New(`error`), http.StatusInternalServerError},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockUCase := new(mocks.Usecase)
+			mockUCase.On("ChangePassword", mock.Anything, int64(1), `token`, `old`, `new`, mock.AnythingOfType(`*entity.Origin`)).Return(tc.err).Once()
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.POST, "/", strings.NewReader(`{"current_password":"old","password":"new"}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			c := e.NewContext(req, rec)
+			c.SetPath("user/me/password")
+			c.Set(`user`, &entity.User{ID: 1})
+			c.Set(`token`, `token`)
+
+			handler := handler.UserHTTPHandler{
+				Usecase: mockUCase,
+			}
+			handler.ChangePassword(c)
+
+			assert.Equal(t, tc.code, rec.Code)
+			mockUCase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestLoginMFA(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{`success`, nil, http.StatusOK},
+		{`invalid-challenge`, response.ErrInvalidToken, http.StatusUnauthorized},
+		{`invalid-code`, response.ErrInvalidCode, http.StatusUnauthorized},
+		{`locked`, &response.LockedError{RetryAfter: 90 * time.Second}, http.StatusTooManyRequests},
+		{`error`, errors.New(`error`), http.StatusInternalServerError},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var res *entity.User
+			if tc.err == nil {
+				res = &entity.User{ID: 1, Token: `token`}
+			}
+
+			mockUCase := new(mocks.Usecase)
+			mockUCase.On("LoginMFA", mock.Anything, `challenge`, `123456`, mock.AnythingOfType("*entity.Session"), mock.AnythingOfType(`*entity.Origin`)).Return(res, tc.err).Once()
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.POST, "/", strings.NewReader(`{"challenge_token":"challenge","code":"123456"}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			c := e.NewContext(req, rec)
+			c.SetPath("login/mfa")
+
+			handler := handler.UserHTTPHandler{
+				Usecase: mockUCase,
+			}
+			handler.LoginMFA(c)
+
+			assert.Equal(t, tc.code, rec.Code)
+			if tc.code == http.StatusTooManyRequests {
+				assert.Equal(t, `90`, rec.Header().Get(`Retry-After`))
+			}
+			mockUCase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestEnrollMFA(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{`success`, nil, http.StatusOK},
+		{`already-enabled`, response.ErrAlreadyExist, http.StatusConflict},
+		{`error`, errors.New(`error`), http.StatusInternalServerError},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var res *entity.MFAEnrollment
+			if tc.err == nil {
+				res = &entity.MFAEnrollment{Secret: `SECRET`, URI: `otpauth://totp/lmnlo`}
+			}
+
+			mockUCase := new(mocks.Usecase)
+			mockUCase.On("EnrollMFA", mock.Anything, int64(1)).Return(res, tc.err).Once()
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.POST, "/", nil)
+			rec := httptest.NewRecorder()
+
+			c := e.NewContext(req, rec)
+			c.SetPath("user/me/mfa")
+			c.Set(`user`, &entity.User{ID: 1})
+
+			handler := handler.UserHTTPHandler{
+				Usecase: mockUCase,
+			}
+			handler.EnrollMFA(c)
+
+			assert.Equal(t, tc.code, rec.Code)
+			mockUCase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestConfirmMFA(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{`success`, nil, http.StatusOK},
+		{`invalid-code`, response.ErrInvalidCode, http.StatusBadRequest},
+		{`already-confirmed`, response.ErrAlreadyExist, http.StatusConflict},
+		{`not-enrolled`, response.ErrNotFound, http.StatusNotFound},
+		{`error`, errors.New(`error`), http.StatusInternalServerError},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var codes []string
+			if tc.err == nil {
+				codes = []string{`AAAAA-BBBBB`}
+			}
+
+			mockUCase := new(mocks.Usecase)
+			mockUCase.On("ConfirmMFA", mock.Anything, int64(1), `123456`, mock.AnythingOfType(`*entity.Origin`)).Return(codes, tc.err).Once()
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.POST, "/", strings.NewReader(`{"code":"123456"}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			c := e.NewContext(req, rec)
+			c.SetPath("user/me/mfa/confirm")
+			c.Set(`user`, &entity.User{ID: 1})
+
+			handler := handler.UserHTTPHandler{
+				Usecase: mockUCase,
+			}
+			handler.ConfirmMFA(c)
+
+			assert.Equal(t, tc.code, rec.Code)
+			if tc.err == nil {
+				assert.Contains(t, rec.Body.String(), `AAAAA-BBBBB`)
+			}
+			mockUCase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDisableMFA(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{`success`, nil, http.StatusNoContent},
+		{`invalid-code`, response.ErrInvalidCode, http.StatusBadRequest},
+		{`not-enrolled`, response.ErrNotFound, http.StatusNotFound},
+		{`error`, errors.New(`error`), http.StatusInternalServerError},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockUCase := new(mocks.Usecase)
+			mockUCase.On("DisableMFA", mock.Anything, int64(1), `123456`, mock.AnythingOfType(`*entity.Origin`)).Return(tc.err).Once()
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.DELETE, "/", strings.NewReader(`{"code":"123456"}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			c := e.NewContext(req, rec)
+			c.SetPath("user/me/mfa")
+			c.Set(`user`, &entity.User{ID: 1})
+
+			handler := handler.UserHTTPHandler{
+				Usecase: mockUCase,
+			}
+			handler.DisableMFA(c)
+
+			assert.Equal(t, tc.code, rec.Code)
+			mockUCase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{`success`, nil, http.StatusCreated},
+		{`bad-request`, response.ErrBadRequest, http.StatusBadRequest},
+		{`scope-not-held`, response.ErrForbidden, http.StatusForbidden},
+		{`error`, errors.New(`error`), http.StatusInternalServerError},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			usr := &entity.User{ID: 1}
+
+			mockUCase := new(mocks.Usecase)
+			mockUCase.On("CreateAPIKey", mock.Anything, usr, mock.MatchedBy(func(key *entity.APIKey) bool {
+				return key.Name == `batch` && len(key.Scopes) == 1
+			}), mock.AnythingOfType(`*entity.Origin`)).Return(tc.err).Once()
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.POST, "/", strings.NewReader(`{"name":"batch","scopes":["user:read"]}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			c := e.NewContext(req, rec)
+			c.SetPath("user/me/api-keys")
+			c.Set(`user`, usr)
+
+			handler := handler.UserHTTPHandler{
+				Usecase: mockUCase,
+			}
+			handler.CreateAPIKey(c)
+
+			assert.Equal(t, tc.code, rec.Code)
+			mockUCase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestFetchAPIKeys(t *testing.T) {
+	mockUCase := new(mocks.Usecase)
+	mockUCase.On("FetchAPIKeys", mock.Anything, int64(1)).Return([]*entity.APIKey{{ID: 7, Name: `batch`}}, nil).Once()
+
+	e := echo.New()
+	req := httptest.NewRequest(echo.GET, "/", nil)
+	rec := httptest.NewRecorder()
+
+	c := e.NewContext(req, rec)
+	c.SetPath("user/me/api-keys")
+	c.Set(`user`, &entity.User{ID: 1})
+
+	handler := handler.UserHTTPHandler{
+		Usecase: mockUCase,
+	}
+	handler.FetchAPIKeys(c)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotContains(t, rec.Body.String(), `secret_hash`)
+	mockUCase.AssertExpectations(t)
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{`success`, nil, http.StatusNoContent},
+		{`not-found`, response.ErrNotFound, http.StatusNotFound},
+		{`error`, errors.New(`error`), http.StatusInternalServerError},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockUCase := new(mocks.Usecase)
+			mockUCase.On("RevokeAPIKey", mock.Anything, int64(1), int64(7), mock.AnythingOfType(`*entity.Origin`)).Return(tc.err).Once()
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.DELETE, "/", nil)
+			rec := httptest.NewRecorder()
+
+			c := e.NewContext(req, rec)
+			c.SetPath("user/me/api-keys/:id")
+			c.SetParamNames("id")
+			c.SetParamValues("7")
+			c.Set(`user`, &entity.User{ID: 1})
+
+			handler := handler.UserHTTPHandler{
+				Usecase: mockUCase,
+			}
+			handler.RevokeAPIKey(c)
+
+			assert.Equal(t, tc.code, rec.Code)
+			mockUCase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestOAuth(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		mockUCase := new(mocks.Usecase)
+		mockUCase.On("OAuthURL", mock.Anything, `google`).Return(`https://accounts.example.com/authorize?state=abc`, `abc`, nil).Once()
+
+		e := echo.New()
+		req := httptest.NewRequest(echo.GET, "/v1/oauth/google", nil)
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+		c.SetPath("oauth/:provider")
+		c.SetParamNames("provider")
+		c.SetParamValues("google")
+
+		handler := handler.UserHTTPHandler{
+			Usecase: mockUCase,
+		}
+		handler.OAuth(c)
+
+		assert.Equal(t, http.StatusFound, rec.Code)
+		assert.Equal(t, `https://accounts.example.com/authorize?state=abc`, rec.Header().Get(`Location`))
+
+		cookie := rec.Header().Get(`Set-Cookie`)
+		assert.Contains(t, cookie, `oauth_state=abc`)
+		assert.Contains(t, cookie, `Path=/v1/oauth/google`)
+		assert.Contains(t, cookie, `HttpOnly`)
+		assert.Contains(t, cookie, `SameSite=Lax`)
+		mockUCase.AssertExpectations(t)
+	})
+
+	t.Run("unknown-provider", func(t *testing.T) {
+		mockUCase := new(mocks.Usecase)
+		mockUCase.On("OAuthURL", mock.Anything, `facebook`).Return(``, ``, oidc.ErrUnknownProvider).Once()
+
+		e := echo.New()
+		req := httptest.NewRequest(echo.GET, "/v1/oauth/facebook", nil)
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+		c.SetPath("oauth/:provider")
+		c.SetParamNames("provider")
+		c.SetParamValues("facebook")
+
+		handler := handler.UserHTTPHandler{
+			Usecase: mockUCase,
+		}
+		handler.OAuth(c)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		mockUCase.AssertExpectations(t)
+	})
+}
+
+func TestOAuthCallback(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{`success`, nil, http.StatusOK},
+		{`unknown-provider`, oidc.ErrUnknownProvider, http.StatusNotFound},
+		{`invalid-state`, response.ErrInvalidToken, http.StatusUnauthorized},
+		{`exchange-failed`, oidc.ErrExchange, http.StatusUnauthorized},
+		{`invalid-id-token`, oidc.ErrInvalidIDToken, http.StatusUnauthorized},
+		{`no-email`, oidc.ErrNoEmail, http.StatusBadRequest},
+		{`unverified`, response.ErrUnverified, http.StatusForbidden},
+		{`email-taken`, response.ErrAlreadyExist, http.StatusConflict},
+		{`error`, errors.New(`error`), http.StatusInternalServerError},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var res *entity.User
+			if tc.err == nil {
+				res = &entity.User{ID: 1, Token: `token`}
+			}
+
+			mockUCase := new(mocks.Usecase)
+			mockUCase.On("LoginOAuth", mock.Anything, `google`, `abc`, `code`, mock.AnythingOfType("*entity.Session"), mock.AnythingOfType(`*entity.Origin`)).Return(res, tc.err).Once()
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.GET, "/v1/oauth/google/callback?state=abc&code=code", nil)
+			req.AddCookie(&http.Cookie{Name: `oauth_state`, Value: `abc`})
+			rec := httptest.NewRecorder()
+
+			c := e.NewContext(req, rec)
+			c.SetPath("oauth/:provider/callback")
+			c.SetParamNames("provider")
+			c.SetParamValues("google")
+
+			handler := handler.UserHTTPHandler{
+				Usecase: mockUCase,
+			}
+			handler.OAuthCallback(c)
+
+			assert.Equal(t, tc.code, rec.Code)
+			assert.Contains(t, rec.Header().Get(`Set-Cookie`), `Max-Age=0`)
+			mockUCase.AssertExpectations(t)
+		})
+	}
+
+	states := []struct {
+		name   string
+		cookie string
+		query  string
+	}{
+		{`no-cookie`, ``, `?state=abc&code=code`},
+		{`state-mismatch`, `xyz`, `?state=abc&code=code`},
+		{`no-state`, `abc`, `?code=code`},
+	}
+
+	for _, tc := range states {
+		t.Run(tc.name, func(t *testing.T) {
+			mockUCase := new(mocks.Usecase)
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.GET, "/v1/oauth/google/callback"+tc.query, nil)
+			if tc.cookie != `` {
+				req.AddCookie(&http.Cookie{Name: `oauth_state`, Value: tc.cookie})
+			}
+			rec := httptest.NewRecorder()
+
+			c := e.NewContext(req, rec)
+			c.SetParamNames("provider")
+			c.SetParamValues("google")
+
+			handler := handler.UserHTTPHandler{
+				Usecase: mockUCase,
+			}
+			handler.OAuthCallback(c)
+
+			assert.Equal(t, http.StatusUnauthorized, rec.Code)
+			mockUCase.AssertExpectations(t)
+		})
+	}
+
+	t.Run("access-denied", func(t *testing.T) {
+		mockUCase := new(mocks.Usecase)
+
+		e := echo.New()
+		req := httptest.NewRequest(echo.GET, "/v1/oauth/google/callback?state=abc&error=access_denied", nil)
+		req.AddCookie(&http.Cookie{Name: `oauth_state`, Value: `abc`})
+		rec := httptest.NewRecorder()
+
+		c := e.NewContext(req, rec)
+		c.SetParamNames("provider")
+		c.SetParamValues("google")
+
+		handler := handler.UserHTTPHandler{
+			Usecase: mockUCase,
+		}
+		handler.OAuthCallback(c)
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		mockUCase.AssertExpectations(t)
+	})
+}
+
+func TestImpersonate(t *testing.T) {
+	actor := &entity.User{ID: 9, Permissions: []string{entity.PermissionUserImpersonate}}
+
+	cases := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{`success`, nil, http.StatusOK},
+		{`not-found`, response.ErrNotFound, http.StatusNotFound},
+		{`self`, response.ErrBadRequest, http.StatusBadRequest},
+		{`admin`, response.ErrForbidden, http.StatusForbidden},
+		{`already-impersonating`, response.ErrImpersonation, http.StatusForbidden},
+		{`error`, errors.New(`error`), http.StatusInternalServerError},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var res *entity.User
+			if tc.err == nil {
+				res = &entity.User{ID: 1, Token: `token`, ActorID: 9}
+			}
+
+			mockUCase := new(mocks.Usecase)
+			mockUCase.On("Impersonate", mock.Anything, actor, int64(1), mock.AnythingOfType("*entity.Session"), mock.AnythingOfType("*entity.Origin")).Return(res, tc.err).Once()
+
+			e := echo.New()
+			req := httptest.NewRequest(echo.POST, "/", strings.NewReader(""))
+			rec := httptest.NewRecorder()
+
+			c := e.NewContext(req, rec)
+			c.SetPath("user/:id/impersonate")
+			c.SetParamNames("id")
+			c.SetParamValues("1")
+			c.Set(`user`, actor)
+
+			handler := handler.UserHTTPHandler{
+				Usecase: mockUCase,
+				Cookies: cmware.NewSessionCookies(cmware.SessionCookies{}),
+			}
+			handler.Impersonate(c)
+
+			assert.Equal(t, tc.code, rec.Code)
+			if tc.code == http.StatusOK {
+				assert.Contains(t, rec.Body.String(), `"token":"token"`)
+				assert.Empty(t, rec.Header().Get(`Set-Cookie`))
+			}
+			mockUCase.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,316 @@
+package http_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	_auditUsecase "github.com/andhikagama/lmnlo/audit/usecase"
+	cmware "github.com/andhikagama/lmnlo/cmiddleware"
+	_customMiddleware "github.com/andhikagama/lmnlo/cmiddleware/usecase"
+	"github.com/andhikagama/lmnlo/helper"
+	"github.com/andhikagama/lmnlo/keyring"
+	_lockoutMemoryRepository "github.com/andhikagama/lmnlo/lockout/repository/memory"
+	_lockoutUsecase "github.com/andhikagama/lmnlo/lockout/usecase"
+	"github.com/andhikagama/lmnlo/mailer"
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/andhikagama/lmnlo/user"
+	handler "github.com/andhikagama/lmnlo/user/delivery"
+	_userSQLiteRepository "github.com/andhikagama/lmnlo/user/repository/sqlite"
+	_userUsecase "github.com/andhikagama/lmnlo/user/usecase"
+)
+
+var verifyLink = regexp.MustCompile(`verify-email\?token=(\S+)`)
+
+// newServer wires NewUserHTTPHandler to the real usecase and middleware the
+// way main does, on an in-memory SQLite database. Mails land in mail.
+func newServer(t *testing.T, mail *bytes.Buffer) (*echo.Echo, user.Repository) {
+	db, err := sql.Open(_userSQLiteRepository.DriverName, `:memory:`)
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	repo, err := _userSQLiteRepository.NewUserRepository(db)
+	require.NoError(t, err)
+
+	kr, err := keyring.NewKeyRing(`test`, keyring.NewHMACKey(`test`, []byte(`secret`)))
+	require.NoError(t, err)
+
+	lockout := _lockoutUsecase.NewLockoutUsecase(_lockoutMemoryRepository.NewLockoutRepository(), _lockoutUsecase.Options{
+		AccountThreshold: 5,
+		IPThreshold:      20,
+		Window:           time.Minute,
+		LockDuration:     time.Minute,
+	})
+
+	u := _userUsecase.NewUserUsecase(repo, helper.NewBcryptHasher(4), kr, mailer.NewLogMailer(mail, `no-reply@lmnlo.test`), lockout, _auditUsecase.NewAuditUsecase(), _userUsecase.Options{
+		AccessTokenTTL:  time.Minute,
+		RefreshTokenTTL: time.Hour,
+		VerifyEmailTTL:  time.Hour,
+		VerifyEmailURL:  `http://lmnlo.test/verify-email?token=`,
+		PasswordPolicy:  helper.PasswordPolicy{MinLength: 8},
+		ContextTimeout:  5 * time.Second,
+	})
+
+	cm := _customMiddleware.NewMiddlewareUsecase(u, cmware.CredentialOptions{})
+
+	e := echo.New()
+	g := e.Group(`/v1`)
+	g.Use(cm.CheckCSRF)
+	g.Use(cm.CheckAuthHeader)
+	handler.NewUserHTTPHandler(g, u, cm, nil)
+
+	return e, repo
+}
+
+// serve sends body as JSON, authenticated with token unless it is empty
+func serve(e *echo.Echo, method string, path string, token string, body interface{}) *httptest.ResponseRecorder {
+	if token == `` {
+		return serveAs(e, method, path, ``, body)
+	}
+
+	return serveAs(e, method, path, `Bearer `+token, body)
+}
+
+// serveAs sends body as JSON with the Authorization header authorization
+func serveAs(e *echo.Echo, method string, path string, authorization string, body interface{}) *httptest.ResponseRecorder {
+	var payload []byte
+	if body != nil {
+		payload, _ = json.Marshal(body)
+	}
+
+	req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if authorization != `` {
+		req.Header.Set(echo.HeaderAuthorization, authorization)
+	}
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func decode(t *testing.T, rec *httptest.ResponseRecorder, v interface{}) {
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), v), rec.Body.String())
+}
+
+func TestSQLiteEndToEnd(t *testing.T) {
+	mail := new(bytes.Buffer)
+	e, repo := newServer(t, mail)
+
+	credentials := map[string]string{
+		`email`:    `andhika.gama@outlook.com`,
+		`password`: `aiueo1234`,
+		`address`:  `Menteng`,
+	}
+
+	rec := serve(e, echo.POST, `/v1/register`, ``, credentials)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	registered := new(entity.User)
+	decode(t, rec, registered)
+	require.NotZero(t, registered.ID)
+	assert.Empty(t, registered.Password)
+	userPath := fmt.Sprintf(`/v1/user/%d`, registered.ID)
+
+	rec = serve(e, echo.POST, `/v1/register`, ``, credentials)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	link := verifyLink.FindStringSubmatch(mail.String())
+	require.NotNil(t, link, mail.String())
+
+	rec = serve(e, echo.POST, `/v1/verify-email`, ``, map[string]string{`token`: link[1]})
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	rec = serve(e, echo.POST, `/v1/login`, ``, map[string]string{`email`: credentials[`email`], `password`: `wrong-password`})
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = serve(e, echo.POST, `/v1/login`, ``, credentials)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	login := new(entity.User)
+	decode(t, rec, login)
+	require.NotEmpty(t, login.Token)
+	require.NotEmpty(t, login.RefreshToken)
+
+	t.Run("get", func(t *testing.T) {
+		rec := serve(e, echo.GET, userPath, ``, nil)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		rec = serve(e, echo.GET, userPath, login.Token, nil)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		res := new(entity.User)
+		decode(t, rec, res)
+		assert.Equal(t, credentials[`email`], res.Email)
+		assert.NotNil(t, res.VerifiedAt)
+		assert.Empty(t, res.Password)
+	})
+
+	t.Run("api-key", func(t *testing.T) {
+		rec := serve(e, echo.POST, `/v1/user/me/api-keys`, login.Token, map[string]interface{}{
+			`name`:   `backup`,
+			`scopes`: []string{entity.PermissionUserRead},
+		})
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+		key := new(entity.APIKey)
+		decode(t, rec, key)
+
+		rec = serveAs(e, echo.GET, userPath, `ApiKey `+key.Key, nil)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		rec = serveAs(e, echo.PUT, userPath, `ApiKey `+key.Key, map[string]string{`email`: `pwned@x.test`})
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+
+		rec = serveAs(e, echo.PATCH, userPath, `ApiKey `+key.Key, []map[string]string{{`op`: `replace`, `path`: `/email`, `value`: `pwned@x.test`}})
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		rec := serve(e, echo.PUT, userPath, login.Token, map[string]string{`email`: credentials[`email`], `address`: `Kemang`})
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		rec = serve(e, echo.GET, userPath, login.Token, nil)
+		res := new(entity.User)
+		decode(t, rec, res)
+		assert.Equal(t, `Kemang`, res.Address)
+	})
+
+	t.Run("patch", func(t *testing.T) {
+		rec := serve(e, echo.POST, `/v1/register`, ``, map[string]string{`email`: `other@lmnlo.test`, `password`: `aiueo1234`})
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		other := new(entity.User)
+		decode(t, rec, other)
+
+		hijack := []map[string]interface{}{
+			{`op`: `replace`, `path`: `/id`, `value`: other.ID},
+			{`op`: `replace`, `path`: `/email`, `value`: `pwned@x.test`},
+		}
+		rec = serve(e, echo.PATCH, userPath, login.Token, hijack)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		stored, err := repo.GetByID(context.TODO(), other.ID)
+		require.NoError(t, err)
+		assert.Equal(t, `other@lmnlo.test`, stored.Email)
+
+		rec = serve(e, echo.PATCH, userPath, login.Token, []map[string]interface{}{
+			{`op`: `replace`, `path`: `/address`, `value`: `Kemang`},
+		})
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		res := new(entity.User)
+		decode(t, rec, res)
+		assert.Equal(t, registered.ID, res.ID)
+	})
+
+	t.Run("fetch", func(t *testing.T) {
+		rec := serve(e, echo.GET, `/v1/user`, login.Token, nil)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+
+		require.NoError(t, repo.SetRoles(context.TODO(), registered.ID, []string{entity.RoleAdmin, entity.RoleUser}))
+
+		rec = serve(e, echo.GET, `/v1/user?address=^KEM`, login.Token, nil)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.True(t, strings.Contains(rec.Body.String(), `Kemang`), rec.Body.String())
+
+		rec = serve(e, echo.GET, `/v1/user?address=^Menteng`, login.Token, nil)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.False(t, strings.Contains(rec.Body.String(), `Kemang`), rec.Body.String())
+	})
+
+	t.Run("refresh-and-logout", func(t *testing.T) {
+		rec := serve(e, echo.POST, `/v1/token/refresh`, ``, map[string]string{`refresh_token`: login.RefreshToken})
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		refreshed := new(entity.User)
+		decode(t, rec, refreshed)
+		require.NotEmpty(t, refreshed.Token)
+
+		rec = serve(e, echo.GET, userPath, refreshed.Token, nil)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		// A rotated refresh token is spent, replaying it ends the session
+		rec = serve(e, echo.POST, `/v1/token/refresh`, ``, map[string]string{`refresh_token`: login.RefreshToken})
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		rec = serve(e, echo.GET, userPath, refreshed.Token, nil)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		rec = serve(e, echo.POST, `/v1/login`, ``, credentials)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		session := new(entity.User)
+		decode(t, rec, session)
+
+		rec = serve(e, echo.POST, `/v1/logout`, session.Token, nil)
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+
+		rec = serve(e, echo.GET, userPath, session.Token, nil)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		doomed := map[string]string{`email`: `doomed@lmnlo.test`, `password`: `aiueo1234`}
+
+		rec := serve(e, echo.POST, `/v1/register`, ``, doomed)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		victim := new(entity.User)
+		decode(t, rec, victim)
+		_, err := repo.SetVerified(context.TODO(), victim.ID, victim.Email)
+		require.NoError(t, err)
+		victimPath := fmt.Sprintf(`/v1/user/%d`, victim.ID)
+
+		rec = serve(e, echo.POST, `/v1/login`, ``, doomed)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		victimLogin := new(entity.User)
+		decode(t, rec, victimLogin)
+
+		rec = serve(e, echo.POST, `/v1/user/me/api-keys`, victimLogin.Token, map[string]interface{}{
+			`name`:   `backup`,
+			`scopes`: []string{entity.PermissionUserRead},
+		})
+		require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+		key := new(entity.APIKey)
+		decode(t, rec, key)
+
+		rec = serve(e, echo.POST, `/v1/login`, ``, credentials)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		admin := new(entity.User)
+		decode(t, rec, admin)
+
+		rec = serve(e, echo.DELETE, victimPath, admin.Token, nil)
+		require.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+
+		stored, err := repo.GetByID(context.TODO(), victim.ID)
+		require.NoError(t, err)
+		assert.Zero(t, stored.ID)
+
+		rec = serve(e, echo.GET, victimPath, victimLogin.Token, nil)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		rec = serveAs(e, echo.GET, victimPath, `ApiKey `+key.Key, nil)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		rec = serve(e, echo.POST, `/v1/token/refresh`, ``, map[string]string{`refresh_token`: victimLogin.RefreshToken})
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+}
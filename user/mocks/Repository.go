@@ -2,29 +2,31 @@
 
 package mocks
 
+import context "context"
 import entity "github.com/andhikagama/lmnlo/models/entity"
 import filter "github.com/andhikagama/lmnlo/models/filter"
 import mock "github.com/stretchr/testify/mock"
+import time "time"
 
 // Repository is an autogenerated mock type for the Repository type
 type Repository struct {
 	mock.Mock
 }
 
-// Delete provides a mock function with given fields: id
-func (_m *Repository) Delete(id int64) (bool, error) {
-	ret := _m.Called(id)
+// ConfirmMFA provides a mock function with given fields: ctx, uid
+func (_m *Repository) ConfirmMFA(ctx context.Context, uid int64) (bool, error) {
+	ret := _m.Called(ctx, uid)
 
 	var r0 bool
-	if rf, ok := ret.Get(0).(func(int64) bool); ok {
-		r0 = rf(id)
+	if rf, ok := ret.Get(0).(func(context.Context, int64) bool); ok {
+		r0 = rf(ctx, uid)
 	} else {
 		r0 = ret.Get(0).(bool)
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(int64) error); ok {
-		r1 = rf(id)
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, uid)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -32,13 +34,125 @@ func (_m *Repository) Delete(id int64) (bool, error) {
 	return r0, r1
 }
 
-// Fetch provides a mock function with given fields: f
-func (_m *Repository) Fetch(f *filter.User) ([]*entity.User, error) {
-	ret := _m.Called(f)
+// CountUserTokens provides a mock function with given fields: ctx, uid, purpose, since
+func (_m *Repository) CountUserTokens(ctx context.Context, uid int64, purpose string, since time.Time) (int64, error) {
+	ret := _m.Called(ctx, uid, purpose, since)
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, time.Time) int64); ok {
+		r0 = rf(ctx, uid, purpose, since)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64, string, time.Time) error); ok {
+		r1 = rf(ctx, uid, purpose, since)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Delete provides a mock function with given fields: ctx, id
+func (_m *Repository) Delete(ctx context.Context, id int64) (bool, error) {
+	ret := _m.Called(ctx, id)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(context.Context, int64) bool); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeleteAPIKey provides a mock function with given fields: ctx, uid, id
+func (_m *Repository) DeleteAPIKey(ctx context.Context, uid int64, id int64) (bool, error) {
+	ret := _m.Called(ctx, uid, id)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) bool); ok {
+		r0 = rf(ctx, uid, id)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64) error); ok {
+		r1 = rf(ctx, uid, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeleteAPIKeysByUser provides a mock function with given fields: ctx, uid
+func (_m *Repository) DeleteAPIKeysByUser(ctx context.Context, uid int64) error {
+	ret := _m.Called(ctx, uid)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(ctx, uid)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteMFA provides a mock function with given fields: ctx, uid
+func (_m *Repository) DeleteMFA(ctx context.Context, uid int64) error {
+	ret := _m.Called(ctx, uid)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(ctx, uid)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ExistRoles provides a mock function with given fields: ctx, roles
+func (_m *Repository) ExistRoles(ctx context.Context, roles []string) (bool, error) {
+	ret := _m.Called(ctx, roles)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(context.Context, []string) bool); ok {
+		r0 = rf(ctx, roles)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(ctx, roles)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Fetch provides a mock function with given fields: ctx, f
+func (_m *Repository) Fetch(ctx context.Context, f *filter.User) ([]*entity.User, error) {
+	ret := _m.Called(ctx, f)
 
 	var r0 []*entity.User
-	if rf, ok := ret.Get(0).(func(*filter.User) []*entity.User); ok {
-		r0 = rf(f)
+	if rf, ok := ret.Get(0).(func(context.Context, *filter.User) []*entity.User); ok {
+		r0 = rf(ctx, f)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]*entity.User)
@@ -46,8 +160,100 @@ func (_m *Repository) Fetch(f *filter.User) ([]*entity.User, error) {
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(*filter.User) error); ok {
-		r1 = rf(f)
+	if rf, ok := ret.Get(1).(func(context.Context, *filter.User) error); ok {
+		r1 = rf(ctx, f)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FetchAPIKeys provides a mock function with given fields: ctx, uid
+func (_m *Repository) FetchAPIKeys(ctx context.Context, uid int64) ([]*entity.APIKey, error) {
+	ret := _m.Called(ctx, uid)
+
+	var r0 []*entity.APIKey
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []*entity.APIKey); ok {
+		r0 = rf(ctx, uid)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*entity.APIKey)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, uid)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FetchSessions provides a mock function with given fields: ctx, uid
+func (_m *Repository) FetchSessions(ctx context.Context, uid int64) ([]*entity.Session, error) {
+	ret := _m.Called(ctx, uid)
+
+	var r0 []*entity.Session
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []*entity.Session); ok {
+		r0 = rf(ctx, uid)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*entity.Session)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, uid)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetAPIKeyByPrefix provides a mock function with given fields: ctx, prefix
+func (_m *Repository) GetAPIKeyByPrefix(ctx context.Context, prefix string) (*entity.APIKey, error) {
+	ret := _m.Called(ctx, prefix)
+
+	var r0 *entity.APIKey
+	if rf, ok := ret.Get(0).(func(context.Context, string) *entity.APIKey); ok {
+		r0 = rf(ctx, prefix)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entity.APIKey)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, prefix)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetByEmail provides a mock function with given fields: ctx, email
+func (_m *Repository) GetByEmail(ctx context.Context, email string) (*entity.User, error) {
+	ret := _m.Called(ctx, email)
+
+	var r0 *entity.User
+	if rf, ok := ret.Get(0).(func(context.Context, string) *entity.User); ok {
+		r0 = rf(ctx, email)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entity.User)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, email)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -55,13 +261,13 @@ func (_m *Repository) Fetch(f *filter.User) ([]*entity.User, error) {
 	return r0, r1
 }
 
-// GetByID provides a mock function with given fields: id
-func (_m *Repository) GetByID(id int64) (*entity.User, error) {
-	ret := _m.Called(id)
+// GetByID provides a mock function with given fields: ctx, id
+func (_m *Repository) GetByID(ctx context.Context, id int64) (*entity.User, error) {
+	ret := _m.Called(ctx, id)
 
 	var r0 *entity.User
-	if rf, ok := ret.Get(0).(func(int64) *entity.User); ok {
-		r0 = rf(id)
+	if rf, ok := ret.Get(0).(func(context.Context, int64) *entity.User); ok {
+		r0 = rf(ctx, id)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*entity.User)
@@ -69,8 +275,167 @@ func (_m *Repository) GetByID(id int64) (*entity.User, error) {
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(int64) error); ok {
-		r1 = rf(id)
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetIdentity provides a mock function with given fields: ctx, provider, subject
+func (_m *Repository) GetIdentity(ctx context.Context, provider string, subject string) (*entity.Identity, error) {
+	ret := _m.Called(ctx, provider, subject)
+
+	var r0 *entity.Identity
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *entity.Identity); ok {
+		r0 = rf(ctx, provider, subject)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entity.Identity)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, provider, subject)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetMFA provides a mock function with given fields: ctx, uid
+func (_m *Repository) GetMFA(ctx context.Context, uid int64) (*entity.MFA, error) {
+	ret := _m.Called(ctx, uid)
+
+	var r0 *entity.MFA
+	if rf, ok := ret.Get(0).(func(context.Context, int64) *entity.MFA); ok {
+		r0 = rf(ctx, uid)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entity.MFA)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, uid)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPassword provides a mock function with given fields: ctx, id
+func (_m *Repository) GetPassword(ctx context.Context, id int64) (string, error) {
+	ret := _m.Called(ctx, id)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(context.Context, int64) string); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPermissions provides a mock function with given fields: ctx, uid
+func (_m *Repository) GetPermissions(ctx context.Context, uid int64) ([]string, error) {
+	ret := _m.Called(ctx, uid)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []string); ok {
+		r0 = rf(ctx, uid)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, uid)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetRefreshToken provides a mock function with given fields: ctx, tokenHash
+func (_m *Repository) GetRefreshToken(ctx context.Context, tokenHash string) (*entity.RefreshToken, error) {
+	ret := _m.Called(ctx, tokenHash)
+
+	var r0 *entity.RefreshToken
+	if rf, ok := ret.Get(0).(func(context.Context, string) *entity.RefreshToken); ok {
+		r0 = rf(ctx, tokenHash)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entity.RefreshToken)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, tokenHash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetRoles provides a mock function with given fields: ctx, uid
+func (_m *Repository) GetRoles(ctx context.Context, uid int64) ([]string, error) {
+	ret := _m.Called(ctx, uid)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []string); ok {
+		r0 = rf(ctx, uid)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, uid)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetSessionByToken provides a mock function with given fields: ctx, tokenHash
+func (_m *Repository) GetSessionByToken(ctx context.Context, tokenHash string) (*entity.Session, error) {
+	ret := _m.Called(ctx, tokenHash)
+
+	var r0 *entity.Session
+	if rf, ok := ret.Get(0).(func(context.Context, string) *entity.Session); ok {
+		r0 = rf(ctx, tokenHash)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entity.Session)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, tokenHash)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -78,13 +443,13 @@ func (_m *Repository) GetByID(id int64) (*entity.User, error) {
 	return r0, r1
 }
 
-// InsertToken provides a mock function with given fields: uid, token
-func (_m *Repository) InsertToken(uid int64, token string) error {
-	ret := _m.Called(uid, token)
+// InsertToken provides a mock function with given fields: ctx, sess
+func (_m *Repository) InsertToken(ctx context.Context, sess *entity.Session) error {
+	ret := _m.Called(ctx, sess)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(int64, string) error); ok {
-		r0 = rf(uid, token)
+	if rf, ok := ret.Get(0).(func(context.Context, *entity.Session) error); ok {
+		r0 = rf(ctx, sess)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -92,13 +457,13 @@ func (_m *Repository) InsertToken(uid int64, token string) error {
 	return r0
 }
 
-// Store provides a mock function with given fields: usr
-func (_m *Repository) Store(usr *entity.User) error {
-	ret := _m.Called(usr)
+// InvalidateUserTokens provides a mock function with given fields: ctx, uid, purpose
+func (_m *Repository) InvalidateUserTokens(ctx context.Context, uid int64, purpose string) error {
+	ret := _m.Called(ctx, uid, purpose)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(*entity.User) error); ok {
-		r0 = rf(usr)
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) error); ok {
+		r0 = rf(ctx, uid, purpose)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -106,20 +471,470 @@ func (_m *Repository) Store(usr *entity.User) error {
 	return r0
 }
 
-// Update provides a mock function with given fields: usr
-func (_m *Repository) Update(usr *entity.User) (bool, error) {
-	ret := _m.Called(usr)
+// PurgeTokens provides a mock function with given fields: ctx, before
+func (_m *Repository) PurgeTokens(ctx context.Context, before time.Time) (int64, error) {
+	ret := _m.Called(ctx, before)
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) int64); ok {
+		r0 = rf(ctx, before)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(ctx, before)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ReplaceToken provides a mock function with given fields: ctx, sess
+func (_m *Repository) ReplaceToken(ctx context.Context, sess *entity.Session) (bool, error) {
+	ret := _m.Called(ctx, sess)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(context.Context, *entity.Session) bool); ok {
+		r0 = rf(ctx, sess)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *entity.Session) error); ok {
+		r1 = rf(ctx, sess)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RevokeOtherRefreshTokens provides a mock function with given fields: ctx, uid, keepFamilyID
+func (_m *Repository) RevokeOtherRefreshTokens(ctx context.Context, uid int64, keepFamilyID string) error {
+	ret := _m.Called(ctx, uid, keepFamilyID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) error); ok {
+		r0 = rf(ctx, uid, keepFamilyID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RevokeOtherSessions provides a mock function with given fields: ctx, uid, keepID
+func (_m *Repository) RevokeOtherSessions(ctx context.Context, uid int64, keepID int64) error {
+	ret := _m.Called(ctx, uid, keepID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) error); ok {
+		r0 = rf(ctx, uid, keepID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RevokeRefreshTokenFamily provides a mock function with given fields: ctx, familyID
+func (_m *Repository) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	ret := _m.Called(ctx, familyID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, familyID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RevokeRefreshTokensByUser provides a mock function with given fields: ctx, uid
+func (_m *Repository) RevokeRefreshTokensByUser(ctx context.Context, uid int64) error {
+	ret := _m.Called(ctx, uid)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(ctx, uid)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RevokeSession provides a mock function with given fields: ctx, id
+func (_m *Repository) RevokeSession(ctx context.Context, id int64) (bool, error) {
+	ret := _m.Called(ctx, id)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(context.Context, int64) bool); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RevokeSessionFamily provides a mock function with given fields: ctx, familyID
+func (_m *Repository) RevokeSessionFamily(ctx context.Context, familyID string) error {
+	ret := _m.Called(ctx, familyID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, familyID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RevokeSessionsByUser provides a mock function with given fields: ctx, uid
+func (_m *Repository) RevokeSessionsByUser(ctx context.Context, uid int64) error {
+	ret := _m.Called(ctx, uid)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(ctx, uid)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RotateRefreshToken provides a mock function with given fields: ctx, id, next
+func (_m *Repository) RotateRefreshToken(ctx context.Context, id int64, next *entity.RefreshToken) (bool, error) {
+	ret := _m.Called(ctx, id, next)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(context.Context, int64, *entity.RefreshToken) bool); ok {
+		r0 = rf(ctx, id, next)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64, *entity.RefreshToken) error); ok {
+		r1 = rf(ctx, id, next)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetRecoveryCodes provides a mock function with given fields: ctx, uid, codeHashes
+func (_m *Repository) SetRecoveryCodes(ctx context.Context, uid int64, codeHashes []string) error {
+	ret := _m.Called(ctx, uid, codeHashes)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, []string) error); ok {
+		r0 = rf(ctx, uid, codeHashes)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetRoles provides a mock function with given fields: ctx, uid, roles
+func (_m *Repository) SetRoles(ctx context.Context, uid int64, roles []string) error {
+	ret := _m.Called(ctx, uid, roles)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, []string) error); ok {
+		r0 = rf(ctx, uid, roles)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetVerified provides a mock function with given fields: ctx, uid, email
+func (_m *Repository) SetVerified(ctx context.Context, uid int64, email string) (bool, error) {
+	ret := _m.Called(ctx, uid, email)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) bool); ok {
+		r0 = rf(ctx, uid, email)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64, string) error); ok {
+		r1 = rf(ctx, uid, email)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Store provides a mock function with given fields: ctx, usr
+func (_m *Repository) Store(ctx context.Context, usr *entity.User) error {
+	ret := _m.Called(ctx, usr)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *entity.User) error); ok {
+		r0 = rf(ctx, usr)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// StoreAPIKey provides a mock function with given fields: ctx, key
+func (_m *Repository) StoreAPIKey(ctx context.Context, key *entity.APIKey) error {
+	ret := _m.Called(ctx, key)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *entity.APIKey) error); ok {
+		r0 = rf(ctx, key)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// StoreIdentity provides a mock function with given fields: ctx, id
+func (_m *Repository) StoreIdentity(ctx context.Context, id *entity.Identity) error {
+	ret := _m.Called(ctx, id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *entity.Identity) error); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// StoreMFA provides a mock function with given fields: ctx, mfa
+func (_m *Repository) StoreMFA(ctx context.Context, mfa *entity.MFA) error {
+	ret := _m.Called(ctx, mfa)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *entity.MFA) error); ok {
+		r0 = rf(ctx, mfa)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// StoreOAuthState provides a mock function with given fields: ctx, st
+func (_m *Repository) StoreOAuthState(ctx context.Context, st *entity.OAuthState) error {
+	ret := _m.Called(ctx, st)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *entity.OAuthState) error); ok {
+		r0 = rf(ctx, st)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// StoreRefreshToken provides a mock function with given fields: ctx, rt
+func (_m *Repository) StoreRefreshToken(ctx context.Context, rt *entity.RefreshToken) error {
+	ret := _m.Called(ctx, rt)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *entity.RefreshToken) error); ok {
+		r0 = rf(ctx, rt)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// StoreUserToken provides a mock function with given fields: ctx, ut
+func (_m *Repository) StoreUserToken(ctx context.Context, ut *entity.UserToken) error {
+	ret := _m.Called(ctx, ut)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *entity.UserToken) error); ok {
+		r0 = rf(ctx, ut)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// TouchAPIKey provides a mock function with given fields: ctx, id
+func (_m *Repository) TouchAPIKey(ctx context.Context, id int64) error {
+	ret := _m.Called(ctx, id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// TouchToken provides a mock function with given fields: ctx, jti
+func (_m *Repository) TouchToken(ctx context.Context, jti string) error {
+	ret := _m.Called(ctx, jti)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, jti)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Update provides a mock function with given fields: ctx, usr
+func (_m *Repository) Update(ctx context.Context, usr *entity.User) (bool, error) {
+	ret := _m.Called(ctx, usr)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(context.Context, *entity.User) bool); ok {
+		r0 = rf(ctx, usr)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *entity.User) error); ok {
+		r1 = rf(ctx, usr)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdatePassword provides a mock function with given fields: ctx, id, password
+func (_m *Repository) UpdatePassword(ctx context.Context, id int64, password string) (bool, error) {
+	ret := _m.Called(ctx, id, password)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) bool); ok {
+		r0 = rf(ctx, id, password)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64, string) error); ok {
+		r1 = rf(ctx, id, password)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UseMFAStep provides a mock function with given fields: ctx, uid, step
+func (_m *Repository) UseMFAStep(ctx context.Context, uid int64, step int64) (bool, error) {
+	ret := _m.Called(ctx, uid, step)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) bool); ok {
+		r0 = rf(ctx, uid, step)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64) error); ok {
+		r1 = rf(ctx, uid, step)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UseOAuthState provides a mock function with given fields: ctx, stateHash
+func (_m *Repository) UseOAuthState(ctx context.Context, stateHash string) (*entity.OAuthState, error) {
+	ret := _m.Called(ctx, stateHash)
+
+	var r0 *entity.OAuthState
+	if rf, ok := ret.Get(0).(func(context.Context, string) *entity.OAuthState); ok {
+		r0 = rf(ctx, stateHash)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entity.OAuthState)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, stateHash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UseRecoveryCode provides a mock function with given fields: ctx, uid, codeHash
+func (_m *Repository) UseRecoveryCode(ctx context.Context, uid int64, codeHash string) (bool, error) {
+	ret := _m.Called(ctx, uid, codeHash)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) bool); ok {
+		r0 = rf(ctx, uid, codeHash)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64, string) error); ok {
+		r1 = rf(ctx, uid, codeHash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UseUserToken provides a mock function with given fields: ctx, uid, purpose, tokenHash
+func (_m *Repository) UseUserToken(ctx context.Context, uid int64, purpose string, tokenHash string) (bool, error) {
+	ret := _m.Called(ctx, uid, purpose, tokenHash)
 
 	var r0 bool
-	if rf, ok := ret.Get(0).(func(*entity.User) bool); ok {
-		r0 = rf(usr)
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, string) bool); ok {
+		r0 = rf(ctx, uid, purpose, tokenHash)
 	} else {
 		r0 = ret.Get(0).(bool)
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(*entity.User) error); ok {
-		r1 = rf(usr)
+	if rf, ok := ret.Get(1).(func(context.Context, int64, string, string) error); ok {
+		r1 = rf(ctx, uid, purpose, tokenHash)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -127,20 +942,20 @@ func (_m *Repository) Update(usr *entity.User) (bool, error) {
 	return r0, r1
 }
 
-// ValidateToken provides a mock function with given fields: token
-func (_m *Repository) ValidateToken(token string) (bool, error) {
-	ret := _m.Called(token)
+// ValidateToken provides a mock function with given fields: ctx, jti, tokenHash
+func (_m *Repository) ValidateToken(ctx context.Context, jti string, tokenHash string) (bool, error) {
+	ret := _m.Called(ctx, jti, tokenHash)
 
 	var r0 bool
-	if rf, ok := ret.Get(0).(func(string) bool); ok {
-		r0 = rf(token)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) bool); ok {
+		r0 = rf(ctx, jti, tokenHash)
 	} else {
 		r0 = ret.Get(0).(bool)
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(token)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, jti, tokenHash)
 	} else {
 		r1 = ret.Error(1)
 	}
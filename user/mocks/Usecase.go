@@ -2,6 +2,7 @@
 
 package mocks
 
+import context "context"
 import entity "github.com/andhikagama/lmnlo/models/entity"
 import filter "github.com/andhikagama/lmnlo/models/filter"
 import mock "github.com/stretchr/testify/mock"
@@ -11,13 +12,13 @@ type Usecase struct {
 	mock.Mock
 }
 
-// Delete provides a mock function with given fields: id
-func (_m *Usecase) Delete(id int64) error {
-	ret := _m.Called(id)
+// AssignRoles provides a mock function with given fields: ctx, id, roles, o
+func (_m *Usecase) AssignRoles(ctx context.Context, id int64, roles []string, o *entity.Origin) error {
+	ret := _m.Called(ctx, id, roles, o)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(int64) error); ok {
-		r0 = rf(id)
+	if rf, ok := ret.Get(0).(func(context.Context, int64, []string, *entity.Origin) error); ok {
+		r0 = rf(ctx, id, roles, o)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -25,13 +26,161 @@ func (_m *Usecase) Delete(id int64) error {
 	return r0
 }
 
-// Fetch provides a mock function with given fields: f
-func (_m *Usecase) Fetch(f *filter.User) ([]*entity.User, error) {
-	ret := _m.Called(f)
+// AuthenticateAPIKey provides a mock function with given fields: ctx, key
+func (_m *Usecase) AuthenticateAPIKey(ctx context.Context, key string) (*entity.User, error) {
+	ret := _m.Called(ctx, key)
+
+	var r0 *entity.User
+	if rf, ok := ret.Get(0).(func(context.Context, string) *entity.User); ok {
+		r0 = rf(ctx, key)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entity.User)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, key)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// AuthenticateToken provides a mock function with given fields: ctx, token
+func (_m *Usecase) AuthenticateToken(ctx context.Context, token string) (*entity.User, error) {
+	ret := _m.Called(ctx, token)
+
+	var r0 *entity.User
+	if rf, ok := ret.Get(0).(func(context.Context, string) *entity.User); ok {
+		r0 = rf(ctx, token)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entity.User)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, token)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ChangePassword provides a mock function with given fields: ctx, uid, token, current, password, o
+func (_m *Usecase) ChangePassword(ctx context.Context, uid int64, token string, current string, password string, o *entity.Origin) error {
+	ret := _m.Called(ctx, uid, token, current, password, o)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, string, string, *entity.Origin) error); ok {
+		r0 = rf(ctx, uid, token, current, password, o)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ConfirmMFA provides a mock function with given fields: ctx, uid, code, o
+func (_m *Usecase) ConfirmMFA(ctx context.Context, uid int64, code string, o *entity.Origin) ([]string, error) {
+	ret := _m.Called(ctx, uid, code, o)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, *entity.Origin) []string); ok {
+		r0 = rf(ctx, uid, code, o)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64, string, *entity.Origin) error); ok {
+		r1 = rf(ctx, uid, code, o)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateAPIKey provides a mock function with given fields: ctx, usr, key, o
+func (_m *Usecase) CreateAPIKey(ctx context.Context, usr *entity.User, key *entity.APIKey, o *entity.Origin) error {
+	ret := _m.Called(ctx, usr, key, o)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *entity.User, *entity.APIKey, *entity.Origin) error); ok {
+		r0 = rf(ctx, usr, key, o)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Delete provides a mock function with given fields: ctx, id, o
+func (_m *Usecase) Delete(ctx context.Context, id int64, o *entity.Origin) error {
+	ret := _m.Called(ctx, id, o)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, *entity.Origin) error); ok {
+		r0 = rf(ctx, id, o)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DisableMFA provides a mock function with given fields: ctx, uid, code, o
+func (_m *Usecase) DisableMFA(ctx context.Context, uid int64, code string, o *entity.Origin) error {
+	ret := _m.Called(ctx, uid, code, o)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, *entity.Origin) error); ok {
+		r0 = rf(ctx, uid, code, o)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// EnrollMFA provides a mock function with given fields: ctx, uid
+func (_m *Usecase) EnrollMFA(ctx context.Context, uid int64) (*entity.MFAEnrollment, error) {
+	ret := _m.Called(ctx, uid)
+
+	var r0 *entity.MFAEnrollment
+	if rf, ok := ret.Get(0).(func(context.Context, int64) *entity.MFAEnrollment); ok {
+		r0 = rf(ctx, uid)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entity.MFAEnrollment)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, uid)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Fetch provides a mock function with given fields: ctx, f
+func (_m *Usecase) Fetch(ctx context.Context, f *filter.User) ([]*entity.User, error) {
+	ret := _m.Called(ctx, f)
 
 	var r0 []*entity.User
-	if rf, ok := ret.Get(0).(func(*filter.User) []*entity.User); ok {
-		r0 = rf(f)
+	if rf, ok := ret.Get(0).(func(context.Context, *filter.User) []*entity.User); ok {
+		r0 = rf(ctx, f)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]*entity.User)
@@ -39,8 +188,54 @@ func (_m *Usecase) Fetch(f *filter.User) ([]*entity.User, error) {
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(*filter.User) error); ok {
-		r1 = rf(f)
+	if rf, ok := ret.Get(1).(func(context.Context, *filter.User) error); ok {
+		r1 = rf(ctx, f)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FetchAPIKeys provides a mock function with given fields: ctx, uid
+func (_m *Usecase) FetchAPIKeys(ctx context.Context, uid int64) ([]*entity.APIKey, error) {
+	ret := _m.Called(ctx, uid)
+
+	var r0 []*entity.APIKey
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []*entity.APIKey); ok {
+		r0 = rf(ctx, uid)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*entity.APIKey)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, uid)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FetchSessions provides a mock function with given fields: ctx, uid, token
+func (_m *Usecase) FetchSessions(ctx context.Context, uid int64, token string) ([]*entity.Session, error) {
+	ret := _m.Called(ctx, uid, token)
+
+	var r0 []*entity.Session
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) []*entity.Session); ok {
+		r0 = rf(ctx, uid, token)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*entity.Session)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64, string) error); ok {
+		r1 = rf(ctx, uid, token)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -48,13 +243,27 @@ func (_m *Usecase) Fetch(f *filter.User) ([]*entity.User, error) {
 	return r0, r1
 }
 
-// GetByID provides a mock function with given fields: id
-func (_m *Usecase) GetByID(id int64) (*entity.User, error) {
-	ret := _m.Called(id)
+// ForgotPassword provides a mock function with given fields: ctx, email
+func (_m *Usecase) ForgotPassword(ctx context.Context, email string) error {
+	ret := _m.Called(ctx, email)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, email)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetByID provides a mock function with given fields: ctx, id
+func (_m *Usecase) GetByID(ctx context.Context, id int64) (*entity.User, error) {
+	ret := _m.Called(ctx, id)
 
 	var r0 *entity.User
-	if rf, ok := ret.Get(0).(func(int64) *entity.User); ok {
-		r0 = rf(id)
+	if rf, ok := ret.Get(0).(func(context.Context, int64) *entity.User); ok {
+		r0 = rf(ctx, id)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*entity.User)
@@ -62,8 +271,8 @@ func (_m *Usecase) GetByID(id int64) (*entity.User, error) {
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(int64) error); ok {
-		r1 = rf(id)
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, id)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -71,13 +280,170 @@ func (_m *Usecase) GetByID(id int64) (*entity.User, error) {
 	return r0, r1
 }
 
-// Login provides a mock function with given fields: u
-func (_m *Usecase) Login(u *entity.User) (*entity.User, error) {
-	ret := _m.Called(u)
+// GetRoles provides a mock function with given fields: ctx, id
+func (_m *Usecase) GetRoles(ctx context.Context, id int64) ([]string, error) {
+	ret := _m.Called(ctx, id)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []string); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Impersonate provides a mock function with given fields: ctx, actor, uid, sess, o
+func (_m *Usecase) Impersonate(ctx context.Context, actor *entity.User, uid int64, sess *entity.Session, o *entity.Origin) (*entity.User, error) {
+	ret := _m.Called(ctx, actor, uid, sess, o)
+
+	var r0 *entity.User
+	if rf, ok := ret.Get(0).(func(context.Context, *entity.User, int64, *entity.Session, *entity.Origin) *entity.User); ok {
+		r0 = rf(ctx, actor, uid, sess, o)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entity.User)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *entity.User, int64, *entity.Session, *entity.Origin) error); ok {
+		r1 = rf(ctx, actor, uid, sess, o)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Login provides a mock function with given fields: ctx, u, sess, o
+func (_m *Usecase) Login(ctx context.Context, u *entity.User, sess *entity.Session, o *entity.Origin) (*entity.User, error) {
+	ret := _m.Called(ctx, u, sess, o)
+
+	var r0 *entity.User
+	if rf, ok := ret.Get(0).(func(context.Context, *entity.User, *entity.Session, *entity.Origin) *entity.User); ok {
+		r0 = rf(ctx, u, sess, o)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entity.User)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *entity.User, *entity.Session, *entity.Origin) error); ok {
+		r1 = rf(ctx, u, sess, o)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// LoginMFA provides a mock function with given fields: ctx, challenge, code, sess, o
+func (_m *Usecase) LoginMFA(ctx context.Context, challenge string, code string, sess *entity.Session, o *entity.Origin) (*entity.User, error) {
+	ret := _m.Called(ctx, challenge, code, sess, o)
+
+	var r0 *entity.User
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, *entity.Session, *entity.Origin) *entity.User); ok {
+		r0 = rf(ctx, challenge, code, sess, o)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entity.User)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, *entity.Session, *entity.Origin) error); ok {
+		r1 = rf(ctx, challenge, code, sess, o)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// LoginOAuth provides a mock function with given fields: ctx, provider, state, code, sess, o
+func (_m *Usecase) LoginOAuth(ctx context.Context, provider string, state string, code string, sess *entity.Session, o *entity.Origin) (*entity.User, error) {
+	ret := _m.Called(ctx, provider, state, code, sess, o)
+
+	var r0 *entity.User
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, *entity.Session, *entity.Origin) *entity.User); ok {
+		r0 = rf(ctx, provider, state, code, sess, o)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*entity.User)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string, *entity.Session, *entity.Origin) error); ok {
+		r1 = rf(ctx, provider, state, code, sess, o)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Logout provides a mock function with given fields: ctx, token, o
+func (_m *Usecase) Logout(ctx context.Context, token string, o *entity.Origin) error {
+	ret := _m.Called(ctx, token, o)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, *entity.Origin) error); ok {
+		r0 = rf(ctx, token, o)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// OAuthURL provides a mock function with given fields: ctx, provider
+func (_m *Usecase) OAuthURL(ctx context.Context, provider string) (string, string, error) {
+	ret := _m.Called(ctx, provider)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(context.Context, string) string); ok {
+		r0 = rf(ctx, provider)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 string
+	if rf, ok := ret.Get(1).(func(context.Context, string) string); ok {
+		r1 = rf(ctx, provider)
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(context.Context, string) error); ok {
+		r2 = rf(ctx, provider)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// PartialUpdate provides a mock function with given fields: ctx, id, byteFacility, o
+func (_m *Usecase) PartialUpdate(ctx context.Context, id int64, byteFacility []byte, o *entity.Origin) (*entity.User, error) {
+	ret := _m.Called(ctx, id, byteFacility, o)
 
 	var r0 *entity.User
-	if rf, ok := ret.Get(0).(func(*entity.User) *entity.User); ok {
-		r0 = rf(u)
+	if rf, ok := ret.Get(0).(func(context.Context, int64, []byte, *entity.Origin) *entity.User); ok {
+		r0 = rf(ctx, id, byteFacility, o)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*entity.User)
@@ -85,8 +451,8 @@ func (_m *Usecase) Login(u *entity.User) (*entity.User, error) {
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(*entity.User) error); ok {
-		r1 = rf(u)
+	if rf, ok := ret.Get(1).(func(context.Context, int64, []byte, *entity.Origin) error); ok {
+		r1 = rf(ctx, id, byteFacility, o)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -94,13 +460,13 @@ func (_m *Usecase) Login(u *entity.User) (*entity.User, error) {
 	return r0, r1
 }
 
-// PartialUpdate provides a mock function with given fields: id, byteFacility
-func (_m *Usecase) PartialUpdate(id int64, byteFacility []byte) (*entity.User, error) {
-	ret := _m.Called(id, byteFacility)
+// Refresh provides a mock function with given fields: ctx, refreshToken, o
+func (_m *Usecase) Refresh(ctx context.Context, refreshToken string, o *entity.Origin) (*entity.User, error) {
+	ret := _m.Called(ctx, refreshToken, o)
 
 	var r0 *entity.User
-	if rf, ok := ret.Get(0).(func(int64, []byte) *entity.User); ok {
-		r0 = rf(id, byteFacility)
+	if rf, ok := ret.Get(0).(func(context.Context, string, *entity.Origin) *entity.User); ok {
+		r0 = rf(ctx, refreshToken, o)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*entity.User)
@@ -108,8 +474,99 @@ func (_m *Usecase) PartialUpdate(id int64, byteFacility []byte) (*entity.User, e
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(int64, []byte) error); ok {
-		r1 = rf(id, byteFacility)
+	if rf, ok := ret.Get(1).(func(context.Context, string, *entity.Origin) error); ok {
+		r1 = rf(ctx, refreshToken, o)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Register provides a mock function with given fields: ctx, usr, o
+func (_m *Usecase) Register(ctx context.Context, usr *entity.User, o *entity.Origin) error {
+	ret := _m.Called(ctx, usr, o)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *entity.User, *entity.Origin) error); ok {
+		r0 = rf(ctx, usr, o)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ResendVerification provides a mock function with given fields: ctx, email
+func (_m *Usecase) ResendVerification(ctx context.Context, email string) error {
+	ret := _m.Called(ctx, email)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, email)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ResetPassword provides a mock function with given fields: ctx, token, password, o
+func (_m *Usecase) ResetPassword(ctx context.Context, token string, password string, o *entity.Origin) error {
+	ret := _m.Called(ctx, token, password, o)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, *entity.Origin) error); ok {
+		r0 = rf(ctx, token, password, o)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RevokeAPIKey provides a mock function with given fields: ctx, uid, id, o
+func (_m *Usecase) RevokeAPIKey(ctx context.Context, uid int64, id int64, o *entity.Origin) error {
+	ret := _m.Called(ctx, uid, id, o)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, *entity.Origin) error); ok {
+		r0 = rf(ctx, uid, id, o)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RevokeSessions provides a mock function with given fields: ctx, uid
+func (_m *Usecase) RevokeSessions(ctx context.Context, uid int64) error {
+	ret := _m.Called(ctx, uid)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(ctx, uid)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SweepSessions provides a mock function with given fields: ctx
+func (_m *Usecase) SweepSessions(ctx context.Context) (int64, error) {
+	ret := _m.Called(ctx)
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func(context.Context) int64); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -117,13 +574,13 @@ func (_m *Usecase) PartialUpdate(id int64, byteFacility []byte) (*entity.User, e
 	return r0, r1
 }
 
-// Register provides a mock function with given fields: usr
-func (_m *Usecase) Register(usr *entity.User) error {
-	ret := _m.Called(usr)
+// Update provides a mock function with given fields: ctx, usr, o
+func (_m *Usecase) Update(ctx context.Context, usr *entity.User, o *entity.Origin) error {
+	ret := _m.Called(ctx, usr, o)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(*entity.User) error); ok {
-		r0 = rf(usr)
+	if rf, ok := ret.Get(0).(func(context.Context, *entity.User, *entity.Origin) error); ok {
+		r0 = rf(ctx, usr, o)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -131,13 +588,13 @@ func (_m *Usecase) Register(usr *entity.User) error {
 	return r0
 }
 
-// Update provides a mock function with given fields: usr
-func (_m *Usecase) Update(usr *entity.User) error {
-	ret := _m.Called(usr)
+// VerifyEmail provides a mock function with given fields: ctx, token
+func (_m *Usecase) VerifyEmail(ctx context.Context, token string) error {
+	ret := _m.Called(ctx, token)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(*entity.User) error); ok {
-		r0 = rf(usr)
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, token)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/andhikagama/lmnlo/models/response"
+	"github.com/sirupsen/logrus"
+)
+
+func (m *userRepository) StoreAPIKey(ctx context.Context, key *entity.APIKey) error {
+	key.CreatedAt = m.now()
+
+	trx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	query := m.builder.Insert(`api_key`)
+	query.Columns(`user_id`, `name`, `prefix`, `secret_hash`, `scopes`, `expire_time`, `create_time`)
+	query.Values(key.UserID, key.Name, key.Prefix, key.SecretHash, strings.Join(key.Scopes, `,`), m.atNullable(key.ExpiresAt), key.CreatedAt)
+
+	key.ID, err = m.insert(ctx, trx, query)
+	if err != nil {
+		trx.Rollback()
+		if m.dialect.Duplicate(err) {
+			return response.ErrAlreadyExist
+		}
+		return err
+	}
+
+	return trx.Commit()
+}
+
+func (m *userRepository) GetAPIKeyByPrefix(ctx context.Context, prefix string) (*entity.APIKey, error) {
+	query := m.builder.Select(`id, user_id, name, prefix, secret_hash, scopes, expire_time, last_used_time, create_time`)
+	query.From(`api_key`)
+	query.Where(`prefix = ?`, prefix)
+
+	sql, args, _ := query.ToSql()
+	rows, err := m.Conn.QueryContext(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result, err := m.unmarshalAPIKeys(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result) == 0 {
+		return new(entity.APIKey), nil
+	}
+
+	return result[0], nil
+}
+
+func (m *userRepository) FetchAPIKeys(ctx context.Context, uid int64) ([]*entity.APIKey, error) {
+	query := m.builder.Select(`id, user_id, name, prefix, secret_hash, scopes, expire_time, last_used_time, create_time`)
+	query.From(`api_key`)
+	query.Where(`user_id = ?`, uid)
+	query.OrderBy(`id DESC`)
+
+	sql, args, _ := query.ToSql()
+	rows, err := m.Conn.QueryContext(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return m.unmarshalAPIKeys(rows)
+}
+
+func (m *userRepository) DeleteAPIKey(ctx context.Context, uid int64, id int64) (bool, error) {
+	query := m.builder.Delete(`api_key`).
+		Where(`id = ?`, id).
+		Where(`user_id = ?`, uid)
+
+	affected, err := m.exec(ctx, query)
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+func (m *userRepository) DeleteAPIKeysByUser(ctx context.Context, uid int64) error {
+	query := m.builder.Delete(`api_key`).
+		Where(`user_id = ?`, uid)
+
+	_, err := m.exec(ctx, query)
+	return err
+}
+
+func (m *userRepository) TouchAPIKey(ctx context.Context, id int64) error {
+	now := m.now()
+
+	query := m.builder.Update(`api_key`).
+		Set(`last_used_time`, now).
+		Where(`id = ?`, id).
+		Where(`(last_used_time IS NULL OR last_used_time < ?)`, now.Add(-_TouchInterval))
+
+	_, err := m.exec(ctx, query)
+	return err
+}
+
+func (m *userRepository) unmarshalAPIKeys(rows *sql.Rows) ([]*entity.APIKey, error) {
+	results := []*entity.APIKey{}
+
+	for rows.Next() {
+		var key entity.APIKey
+		var scopes string
+
+		err := rows.Scan(
+			&key.ID,
+			&key.UserID,
+			&key.Name,
+			&key.Prefix,
+			&key.SecretHash,
+			&scopes,
+			&key.ExpiresAt,
+			&key.LastUsedAt,
+			&key.CreatedAt,
+		)
+
+		if err != nil {
+			logrus.Error(err, key.ID)
+			return nil, err
+		}
+
+		key.Scopes = []string{}
+		if scopes != `` {
+			key.Scopes = strings.Split(scopes, `,`)
+		}
+
+		results = append(results, &key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
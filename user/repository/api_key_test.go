@@ -0,0 +1,221 @@
+package repository_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/andhikagama/lmnlo/models/response"
+	userRepo "github.com/andhikagama/lmnlo/user/repository"
+)
+
+var apiKeyColumns = []string{
+	`id`, `user_id`, `name`, `prefix`, `secret_hash`, `scopes`, `expire_time`, `last_used_time`, `create_time`,
+}
+
+func TestStoreAPIKey(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`INSERT INTO api_key`).
+			ExpectExec().
+			WithArgs(1, `batch`, `ABCD`, `hash`, `user:read,user:update`, nil, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(7, 1))
+		mock.ExpectCommit()
+
+		key := &entity.APIKey{UserID: 1, Name: `batch`, Prefix: `ABCD`, SecretHash: `hash`, Scopes: []string{`user:read`, `user:update`}}
+		repo := userRepo.NewUserRepository(db)
+		err := repo.StoreAPIKey(context.TODO(), key)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(7), key.ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("error-exec", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`INSERT INTO api_key`).ExpectExec().WillReturnError(fmt.Errorf("Some error"))
+		mock.ExpectRollback()
+
+		repo := userRepo.NewUserRepository(db)
+		err := repo.StoreAPIKey(context.TODO(), &entity.APIKey{UserID: 1})
+
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("error-duplicate", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`INSERT INTO api_key`).ExpectExec().WillReturnError(&mysql.MySQLError{Number: 1062})
+		mock.ExpectRollback()
+
+		repo := userRepo.NewUserRepository(db)
+		err := repo.StoreAPIKey(context.TODO(), &entity.APIKey{UserID: 1})
+
+		assert.Equal(t, response.ErrAlreadyExist, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestGetAPIKeyByPrefix(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		rows := sqlmock.NewRows(apiKeyColumns).
+			AddRow(7, 1, `batch`, `ABCD`, `hash`, `user:read,user:update`, nil, nil, time.Now())
+
+		mock.ExpectQuery(`SELECT (.+) FROM api_key WHERE prefix = \?`).WithArgs(`ABCD`).WillReturnRows(rows)
+
+		repo := userRepo.NewUserRepository(db)
+		res, err := repo.GetAPIKeyByPrefix(context.TODO(), `ABCD`)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(7), res.ID)
+		assert.Equal(t, []string{`user:read`, `user:update`}, res.Scopes)
+		assert.Nil(t, res.ExpiresAt)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("success-no-data", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT (.+) FROM api_key`).WillReturnRows(sqlmock.NewRows(apiKeyColumns))
+
+		repo := userRepo.NewUserRepository(db)
+		res, err := repo.GetAPIKeyByPrefix(context.TODO(), `ABCD`)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), res.ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestFetchAPIKeys(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		rows := sqlmock.NewRows(apiKeyColumns).
+			AddRow(8, 1, `report`, `EFGH`, `hash`, ``, time.Now(), time.Now(), time.Now()).
+			AddRow(7, 1, `batch`, `ABCD`, `hash`, `user:read`, nil, nil, time.Now())
+
+		mock.ExpectQuery(`SELECT (.+) FROM api_key WHERE user_id = \? ORDER BY id DESC`).WithArgs(1).WillReturnRows(rows)
+
+		repo := userRepo.NewUserRepository(db)
+		res, err := repo.FetchAPIKeys(context.TODO(), 1)
+
+		assert.NoError(t, err)
+		assert.Len(t, res, 2)
+		assert.Equal(t, []string{}, res[0].Scopes)
+		assert.NotNil(t, res[0].LastUsedAt)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestDeleteAPIKey(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`DELETE FROM api_key WHERE id = \? AND user_id = \?`).
+			ExpectExec().
+			WithArgs(7, 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		repo := userRepo.NewUserRepository(db)
+		ok, err := repo.DeleteAPIKey(context.TODO(), 1, 7)
+
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("other-user", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`DELETE FROM api_key`).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		repo := userRepo.NewUserRepository(db)
+		ok, err := repo.DeleteAPIKey(context.TODO(), 2, 7)
+
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestDeleteAPIKeysByUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare(`DELETE FROM api_key WHERE user_id = \?`).
+		ExpectExec().
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	repo := userRepo.NewUserRepository(db)
+	err = repo.DeleteAPIKeysByUser(context.TODO(), 1)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTouchAPIKey(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`UPDATE api_key SET last_used_time = \? WHERE id = \? AND \(last_used_time IS NULL OR last_used_time < \?\)`).
+			ExpectExec().
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		repo := userRepo.NewUserRepository(db)
+		err := repo.TouchAPIKey(context.TODO(), 7)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,27 @@
+package repository_test
+
+import (
+	"testing"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/require"
+
+	"github.com/andhikagama/lmnlo/user"
+	userRepo "github.com/andhikagama/lmnlo/user/repository"
+	"github.com/andhikagama/lmnlo/user/repository/repositorytest"
+)
+
+// TestConformance needs LMNLO_TEST_MYSQL_DSN, such as
+// root:secret@tcp(localhost:3306)/lmnlo_test?parseTime=1
+func TestConformance(t *testing.T) {
+	db := repositorytest.Open(t, `mysql`, `LMNLO_TEST_MYSQL_DSN`)
+
+	repositorytest.Run(t, func(t *testing.T) user.Repository {
+		for _, table := range repositorytest.Tables {
+			_, err := db.Exec("TRUNCATE TABLE `" + table + "`")
+			require.NoError(t, err)
+		}
+
+		return userRepo.NewUserRepository(db)
+	})
+}
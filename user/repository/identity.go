@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/sirupsen/logrus"
+)
+
+func (m *userRepository) GetIdentity(ctx context.Context, provider string, subject string) (*entity.Identity, error) {
+	query := m.builder.Select(`id, user_id, provider, subject, email, create_time`)
+	query.From(`user_identity`)
+	query.Where(`provider = ?`, provider)
+	query.Where(`subject = ?`, subject)
+
+	sql, args, _ := query.ToSql()
+	rows, err := m.Conn.QueryContext(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result, err := m.unmarshalIdentities(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result) == 0 {
+		return new(entity.Identity), nil
+	}
+
+	return result[0], nil
+}
+
+func (m *userRepository) StoreIdentity(ctx context.Context, id *entity.Identity) error {
+	id.CreatedAt = m.now()
+
+	trx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	query := m.builder.Insert(`user_identity`)
+	query.Columns(`user_id`, `provider`, `subject`, `email`, `create_time`)
+	query.Values(id.UserID, id.Provider, id.Subject, id.Email, id.CreatedAt)
+
+	id.ID, err = m.insert(ctx, trx, query)
+	if err != nil {
+		trx.Rollback()
+		return err
+	}
+
+	return trx.Commit()
+}
+
+// StoreOAuthState saves a pending sign-in and drops the expired ones, which
+// are left behind by users who never came back from the provider
+func (m *userRepository) StoreOAuthState(ctx context.Context, st *entity.OAuthState) error {
+	trx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	cleanup := m.builder.Delete(`oauth_state`).
+		Where(`expire_time < ?`, m.now())
+
+	if err := execTx(ctx, trx, cleanup); err != nil {
+		trx.Rollback()
+		return err
+	}
+
+	query := m.builder.Insert(`oauth_state`)
+	query.Columns(`state_hash`, `provider`, `verifier`, `nonce`, `expire_time`)
+	query.Values(st.StateHash, st.Provider, st.Verifier, st.Nonce, m.at(st.ExpiresAt))
+
+	if err := execTx(ctx, trx, query); err != nil {
+		trx.Rollback()
+		return err
+	}
+
+	return trx.Commit()
+}
+
+// UseOAuthState deletes the pending sign-in and return it. An empty state
+// is returned when it does not exist, has expired or was used by a
+// concurrent callback.
+func (m *userRepository) UseOAuthState(ctx context.Context, stateHash string) (*entity.OAuthState, error) {
+	query := m.builder.Select(`state_hash, provider, verifier, nonce, expire_time`)
+	query.From(`oauth_state`)
+	query.Where(`state_hash = ?`, stateHash)
+
+	sql, args, _ := query.ToSql()
+	rows, err := m.Conn.QueryContext(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return new(entity.OAuthState), rows.Err()
+	}
+
+	st := new(entity.OAuthState)
+	if err := rows.Scan(&st.StateHash, &st.Provider, &st.Verifier, &st.Nonce, &st.ExpiresAt); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	affected, err := m.exec(ctx, m.builder.Delete(`oauth_state`).Where(`state_hash = ?`, stateHash))
+	if err != nil {
+		return nil, err
+	}
+
+	if affected != 1 || !st.ExpiresAt.After(m.now()) {
+		return new(entity.OAuthState), nil
+	}
+
+	return st, nil
+}
+
+func (m *userRepository) unmarshalIdentities(rows *sql.Rows) ([]*entity.Identity, error) {
+	results := []*entity.Identity{}
+
+	for rows.Next() {
+		var id entity.Identity
+
+		err := rows.Scan(
+			&id.ID,
+			&id.UserID,
+			&id.Provider,
+			&id.Subject,
+			&id.Email,
+			&id.CreatedAt,
+		)
+
+		if err != nil {
+			logrus.Error(err, id.ID)
+			return nil, err
+		}
+
+		results = append(results, &id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
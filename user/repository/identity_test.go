@@ -0,0 +1,227 @@
+package repository_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/andhikagama/lmnlo/models/entity"
+	userRepo "github.com/andhikagama/lmnlo/user/repository"
+)
+
+var identityColumns = []string{`id`, `user_id`, `provider`, `subject`, `email`, `create_time`}
+
+var oauthStateColumns = []string{`state_hash`, `provider`, `verifier`, `nonce`, `expire_time`}
+
+func TestGetIdentity(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		rows := sqlmock.NewRows(identityColumns).
+			AddRow(3, 1, `google`, `1234`, `andhika.gama@outlook.com`, time.Now())
+
+		mock.ExpectQuery(`SELECT (.+) FROM user_identity`).
+			WithArgs(`google`, `1234`).
+			WillReturnRows(rows)
+
+		repo := userRepo.NewUserRepository(db)
+		id, err := repo.GetIdentity(context.TODO(), `google`, `1234`)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), id.ID)
+		assert.Equal(t, int64(1), id.UserID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("not-found", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT (.+) FROM user_identity`).
+			WillReturnRows(sqlmock.NewRows(identityColumns))
+
+		repo := userRepo.NewUserRepository(db)
+		id, err := repo.GetIdentity(context.TODO(), `google`, `1234`)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), id.ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestStoreIdentity(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`INSERT INTO user_identity`).
+			ExpectExec().
+			WithArgs(1, `google`, `1234`, `andhika.gama@outlook.com`, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(3, 1))
+		mock.ExpectCommit()
+
+		id := &entity.Identity{UserID: 1, Provider: `google`, Subject: `1234`, Email: `andhika.gama@outlook.com`}
+		repo := userRepo.NewUserRepository(db)
+		err := repo.StoreIdentity(context.TODO(), id)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), id.ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("error-exec", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`INSERT INTO user_identity`).ExpectExec().WillReturnError(fmt.Errorf("Duplicate entry"))
+		mock.ExpectRollback()
+
+		repo := userRepo.NewUserRepository(db)
+		err := repo.StoreIdentity(context.TODO(), &entity.Identity{UserID: 1})
+
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestStoreOAuthState(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		expires := time.Now().Add(10 * time.Minute)
+
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`DELETE FROM oauth_state WHERE expire_time < \?`).
+			ExpectExec().
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectPrepare(`INSERT INTO oauth_state`).
+			ExpectExec().
+			WithArgs(`hash`, `google`, `verifier`, `nonce`, expires).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		repo := userRepo.NewUserRepository(db)
+		err := repo.StoreOAuthState(context.TODO(), &entity.OAuthState{
+			StateHash: `hash`,
+			Provider:  `google`,
+			Verifier:  `verifier`,
+			Nonce:     `nonce`,
+			ExpiresAt: expires,
+		})
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("error-exec", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`DELETE FROM oauth_state`).
+			ExpectExec().
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectPrepare(`INSERT INTO oauth_state`).ExpectExec().WillReturnError(fmt.Errorf("Some error"))
+		mock.ExpectRollback()
+
+		repo := userRepo.NewUserRepository(db)
+		err := repo.StoreOAuthState(context.TODO(), &entity.OAuthState{StateHash: `hash`})
+
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestUseOAuthState(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT (.+) FROM oauth_state`).
+			WithArgs(`hash`).
+			WillReturnRows(sqlmock.NewRows(oauthStateColumns).
+				AddRow(`hash`, `google`, `verifier`, `nonce`, time.Now().Add(time.Minute)))
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`DELETE FROM oauth_state WHERE state_hash = \?`).
+			ExpectExec().
+			WithArgs(`hash`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		repo := userRepo.NewUserRepository(db)
+		st, err := repo.UseOAuthState(context.TODO(), `hash`)
+
+		assert.NoError(t, err)
+		assert.Equal(t, `google`, st.Provider)
+		assert.Equal(t, `verifier`, st.Verifier)
+		assert.Equal(t, `nonce`, st.Nonce)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("not-found", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT (.+) FROM oauth_state`).
+			WillReturnRows(sqlmock.NewRows(oauthStateColumns))
+
+		repo := userRepo.NewUserRepository(db)
+		st, err := repo.UseOAuthState(context.TODO(), `hash`)
+
+		assert.NoError(t, err)
+		assert.Equal(t, ``, st.Provider)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT (.+) FROM oauth_state`).
+			WillReturnRows(sqlmock.NewRows(oauthStateColumns).
+				AddRow(`hash`, `google`, `verifier`, `nonce`, time.Now().Add(-time.Minute)))
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`DELETE FROM oauth_state`).
+			ExpectExec().
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		repo := userRepo.NewUserRepository(db)
+		st, err := repo.UseOAuthState(context.TODO(), `hash`)
+
+		assert.NoError(t, err)
+		assert.Equal(t, ``, st.Provider)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("used-concurrently", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT (.+) FROM oauth_state`).
+			WillReturnRows(sqlmock.NewRows(oauthStateColumns).
+				AddRow(`hash`, `google`, `verifier`, `nonce`, time.Now().Add(time.Minute)))
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`DELETE FROM oauth_state`).
+			ExpectExec().
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		repo := userRepo.NewUserRepository(db)
+		st, err := repo.UseOAuthState(context.TODO(), `hash`)
+
+		assert.NoError(t, err)
+		assert.Equal(t, ``, st.Provider)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/sirupsen/logrus"
+)
+
+func (m *userRepository) GetMFA(ctx context.Context, uid int64) (*entity.MFA, error) {
+	query := m.builder.Select(`user_id, secret, last_used_step, confirm_time, create_time`)
+	query.From(`user_mfa`)
+	query.Where(`user_id = ?`, uid)
+
+	sql, args, _ := query.ToSql()
+	rows, err := m.Conn.QueryContext(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	mfa := new(entity.MFA)
+	for rows.Next() {
+		err := rows.Scan(
+			&mfa.UserID,
+			&mfa.Secret,
+			&mfa.LastUsedStep,
+			&mfa.ConfirmedAt,
+			&mfa.CreatedAt,
+		)
+
+		if err != nil {
+			logrus.Error(err, mfa.UserID)
+			return nil, err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return mfa, nil
+}
+
+// StoreMFA replaces any earlier enrollment of the user
+func (m *userRepository) StoreMFA(ctx context.Context, mfa *entity.MFA) error {
+	mfa.CreatedAt = m.now()
+
+	trx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := execTx(ctx, trx, m.builder.Delete(`user_mfa`).Where(`user_id = ?`, mfa.UserID)); err != nil {
+		trx.Rollback()
+		return err
+	}
+
+	query := m.builder.Insert(`user_mfa`)
+	query.Columns(`user_id`, `secret`, `last_used_step`, `create_time`)
+	query.Values(mfa.UserID, mfa.Secret, 0, mfa.CreatedAt)
+
+	if err := execTx(ctx, trx, query); err != nil {
+		trx.Rollback()
+		return err
+	}
+
+	return trx.Commit()
+}
+
+func (m *userRepository) ConfirmMFA(ctx context.Context, uid int64) (bool, error) {
+	query := m.builder.Update(`user_mfa`).
+		Set(`confirm_time`, m.now()).
+		Where(`user_id = ?`, uid).
+		Where(`confirm_time IS NULL`)
+
+	affected, err := m.exec(ctx, query)
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+// UseMFAStep records step as the last one a code was accepted for. It
+// reports false when a code of the same or a later step was used already,
+// so every code works only once.
+func (m *userRepository) UseMFAStep(ctx context.Context, uid int64, step int64) (bool, error) {
+	query := m.builder.Update(`user_mfa`).
+		Set(`last_used_step`, step).
+		Where(`user_id = ?`, uid).
+		Where(`last_used_step < ?`, step)
+
+	affected, err := m.exec(ctx, query)
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+// DeleteMFA removes the second factor with its recovery codes
+func (m *userRepository) DeleteMFA(ctx context.Context, uid int64) error {
+	trx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, table := range []string{`user_mfa`, `user_recovery_code`} {
+		if err := execTx(ctx, trx, m.builder.Delete(table).Where(`user_id = ?`, uid)); err != nil {
+			trx.Rollback()
+			return err
+		}
+	}
+
+	return trx.Commit()
+}
+
+// SetRecoveryCodes replaces the recovery codes of the user
+func (m *userRepository) SetRecoveryCodes(ctx context.Context, uid int64, codeHashes []string) error {
+	trx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := execTx(ctx, trx, m.builder.Delete(`user_recovery_code`).Where(`user_id = ?`, uid)); err != nil {
+		trx.Rollback()
+		return err
+	}
+
+	if len(codeHashes) > 0 {
+		now := m.now()
+
+		query := m.builder.Insert(`user_recovery_code`)
+		query.Columns(`user_id`, `code_hash`, `create_time`)
+		for _, h := range codeHashes {
+			query.Values(uid, h, now)
+		}
+
+		if err := execTx(ctx, trx, query); err != nil {
+			trx.Rollback()
+			return err
+		}
+	}
+
+	return trx.Commit()
+}
+
+func (m *userRepository) UseRecoveryCode(ctx context.Context, uid int64, codeHash string) (bool, error) {
+	query := m.builder.Update(`user_recovery_code`).
+		Set(`use_time`, m.now()).
+		Where(`user_id = ?`, uid).
+		Where(`code_hash = ?`, codeHash).
+		Where(`use_time IS NULL`)
+
+	affected, err := m.exec(ctx, query)
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
@@ -0,0 +1,228 @@
+package repository_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/andhikagama/lmnlo/models/entity"
+	userRepo "github.com/andhikagama/lmnlo/user/repository"
+)
+
+func TestGetMFA(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	columns := []string{`user_id`, `secret`, `last_used_step`, `confirm_time`, `create_time`}
+
+	t.Run("success", func(t *testing.T) {
+		now := time.Now()
+		rows := sqlmock.NewRows(columns).AddRow(1, `SECRET`, 10, now, now)
+
+		mock.ExpectQuery(`SELECT (.+) FROM user_mfa WHERE user_id = \?`).WithArgs(1).WillReturnRows(rows)
+
+		repo := userRepo.NewUserRepository(db)
+		res, err := repo.GetMFA(context.TODO(), 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, `SECRET`, res.Secret)
+		assert.True(t, res.Confirmed())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("success-no-data", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT (.+) FROM user_mfa`).WillReturnRows(sqlmock.NewRows(columns))
+
+		repo := userRepo.NewUserRepository(db)
+		res, err := repo.GetMFA(context.TODO(), 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), res.UserID)
+		assert.False(t, res.Confirmed())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestStoreMFA(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`DELETE FROM user_mfa WHERE user_id = \?`).ExpectExec().WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectPrepare(`INSERT INTO user_mfa`).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		repo := userRepo.NewUserRepository(db)
+		err := repo.StoreMFA(context.TODO(), &entity.MFA{UserID: 1, Secret: `SECRET`})
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("error-insert", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`DELETE FROM user_mfa`).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectPrepare(`INSERT INTO user_mfa`).ExpectExec().WillReturnError(fmt.Errorf("Some error"))
+		mock.ExpectRollback()
+
+		repo := userRepo.NewUserRepository(db)
+		err := repo.StoreMFA(context.TODO(), &entity.MFA{UserID: 1, Secret: `SECRET`})
+
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestConfirmMFA(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`UPDATE user_mfa SET confirm_time = \? WHERE user_id = \? AND confirm_time IS NULL`).
+			ExpectExec().
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		repo := userRepo.NewUserRepository(db)
+		ok, err := repo.ConfirmMFA(context.TODO(), 1)
+
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestUseMFAStep(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`UPDATE user_mfa SET last_used_step = \? WHERE user_id = \? AND last_used_step < \?`).
+			ExpectExec().
+			WithArgs(100, 1, 100).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		repo := userRepo.NewUserRepository(db)
+		ok, err := repo.UseMFAStep(context.TODO(), 1, 100)
+
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("replayed", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`UPDATE user_mfa SET last_used_step`).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		repo := userRepo.NewUserRepository(db)
+		ok, err := repo.UseMFAStep(context.TODO(), 1, 100)
+
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestDeleteMFA(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`DELETE FROM user_mfa WHERE user_id = \?`).ExpectExec().WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectPrepare(`DELETE FROM user_recovery_code WHERE user_id = \?`).ExpectExec().WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 10))
+		mock.ExpectCommit()
+
+		repo := userRepo.NewUserRepository(db)
+		err := repo.DeleteMFA(context.TODO(), 1)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestSetRecoveryCodes(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`DELETE FROM user_recovery_code WHERE user_id = \?`).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectPrepare(`INSERT INTO user_recovery_code \(user_id,code_hash,create_time\) VALUES \(\?,\?,\?\),\(\?,\?,\?\)`).
+			ExpectExec().
+			WithArgs(1, `a`, sqlmock.AnyArg(), 1, `b`, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		repo := userRepo.NewUserRepository(db)
+		err := repo.SetRecoveryCodes(context.TODO(), 1, []string{`a`, `b`})
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestUseRecoveryCode(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`UPDATE user_recovery_code SET use_time = \? WHERE user_id = \? AND code_hash = \? AND use_time IS NULL`).
+			ExpectExec().
+			WithArgs(sqlmock.AnyArg(), 1, `hash`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		repo := userRepo.NewUserRepository(db)
+		ok, err := repo.UseRecoveryCode(context.TODO(), 1, `hash`)
+
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
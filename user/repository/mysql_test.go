@@ -1,14 +1,17 @@
-package mysql_test
+package repository_test
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
 	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/andhikagama/lmnlo/models/entity"
 	"github.com/andhikagama/lmnlo/models/filter"
+	"github.com/andhikagama/lmnlo/models/response"
 	userRepo "github.com/andhikagama/lmnlo/user/repository"
 )
 
@@ -38,7 +41,7 @@ func TestStore(t *testing.T) {
 		mock.ExpectCommit()
 
 		repo := userRepo.NewUserRepository(db)
-		err := repo.Store(&mockUser)
+		err := repo.Store(context.TODO(), &mockUser)
 
 		assert.NoError(t, err)
 		assert.NoError(t, mock.ExpectationsWereMet())
@@ -47,7 +50,7 @@ func TestStore(t *testing.T) {
 	t.Run("error-begin", func(t *testing.T) {
 		mock.ExpectBegin().WillReturnError(fmt.Errorf("Some error"))
 		repo := userRepo.NewUserRepository(db)
-		err := repo.Store(&mockUser)
+		err := repo.Store(context.TODO(), &mockUser)
 
 		assert.Error(t, err)
 		assert.NoError(t, mock.ExpectationsWereMet())
@@ -58,7 +61,7 @@ func TestStore(t *testing.T) {
 		mock.ExpectPrepare(`INSERT INTO user`).WillReturnError(fmt.Errorf("Some error"))
 
 		repo := userRepo.NewUserRepository(db)
-		err := repo.Store(&mockUser)
+		err := repo.Store(context.TODO(), &mockUser)
 
 		assert.Error(t, err)
 		assert.NoError(t, mock.ExpectationsWereMet())
@@ -69,19 +72,31 @@ func TestStore(t *testing.T) {
 		mock.ExpectPrepare(`INSERT INTO user`).ExpectExec().WillReturnError(fmt.Errorf("Some error"))
 
 		repo := userRepo.NewUserRepository(db)
-		err := repo.Store(&mockUser)
+		err := repo.Store(context.TODO(), &mockUser)
 
 		assert.Error(t, err)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
+	t.Run("error-duplicate", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`INSERT INTO user`).ExpectExec().WillReturnError(&mysql.MySQLError{Number: 1062})
+		mock.ExpectRollback()
+
+		repo := userRepo.NewUserRepository(db)
+		err := repo.Store(context.TODO(), &mockUser)
+
+		assert.Equal(t, response.ErrAlreadyExist, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
 	t.Run("error-id", func(t *testing.T) {
 		mock.ExpectBegin()
 		mock.ExpectPrepare(`INSERT INTO user`).ExpectExec().WillReturnResult(sqlmock.NewErrorResult(fmt.Errorf("Some error")))
 		mock.ExpectRollback()
 
 		repo := userRepo.NewUserRepository(db)
-		err := repo.Store(&mockUser)
+		err := repo.Store(context.TODO(), &mockUser)
 
 		assert.Error(t, err)
 		assert.NoError(t, mock.ExpectationsWereMet())
@@ -108,7 +123,7 @@ func TestFetch(t *testing.T) {
 
 		f := new(filter.User)
 		repo := userRepo.NewUserRepository(db)
-		res, err := repo.Fetch(f)
+		res, err := repo.Fetch(context.TODO(), f)
 
 		assert.NoError(t, err)
 		assert.Equal(t, mockUsers[0].ID, res[0].ID)
@@ -128,7 +143,30 @@ func TestFetch(t *testing.T) {
 		f.Email = `andhika.gama@outlook.com`
 
 		repo := userRepo.NewUserRepository(db)
-		res, err := repo.Fetch(f)
+		res, err := repo.Fetch(context.TODO(), f)
+
+		assert.NoError(t, err)
+		assert.Equal(t, mockUsers[0].ID, res[0].ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("success-with-address", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{
+			`id`, `email`, `address`,
+		}).AddRow(
+			mockUsers[0].ID, mockUsers[0].Email, mockUsers[0].Address,
+		)
+
+		mock.ExpectQuery(`SELECT (.+) FROM user WHERE address REGEXP \? AND`).
+			WithArgs(`^ment' OR '1'='1`).
+			WillReturnRows(rows)
+
+		f := new(filter.User)
+		f.Address = `^ment' OR '1'='1`
+		f.Num = 10
+
+		repo := userRepo.NewUserRepository(db)
+		res, err := repo.Fetch(context.TODO(), f)
 
 		assert.NoError(t, err)
 		assert.Equal(t, mockUsers[0].ID, res[0].ID)
@@ -145,7 +183,7 @@ func TestFetch(t *testing.T) {
 		f := new(filter.User)
 
 		repo := userRepo.NewUserRepository(db)
-		res, err := repo.Fetch(f)
+		res, err := repo.Fetch(context.TODO(), f)
 
 		assert.NoError(t, err)
 		assert.Len(t, res, 0)
@@ -166,12 +204,26 @@ func TestFetch(t *testing.T) {
 		f := new(filter.User)
 
 		repo := userRepo.NewUserRepository(db)
-		res, err := repo.Fetch(f)
+		res, err := repo.Fetch(context.TODO(), f)
 
 		assert.Error(t, err)
 		assert.Nil(t, res)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
+
+	t.Run("error-canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		f := new(filter.User)
+
+		repo := userRepo.NewUserRepository(db)
+		res, err := repo.Fetch(ctx, f)
+
+		assert.Equal(t, context.Canceled, err)
+		assert.Nil(t, res)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
 }
 
 func TestUpdate(t *testing.T) {
@@ -185,24 +237,36 @@ func TestUpdate(t *testing.T) {
 
 	t.Run("success", func(t *testing.T) {
 		mock.ExpectBegin()
-		mock.ExpectPrepare(`UPDATE user`).ExpectExec().WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectPrepare(`UPDATE user SET verified_at = CASE WHEN email = \? THEN verified_at END, email = \?`).ExpectExec().WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectCommit()
 
 		repo := userRepo.NewUserRepository(db)
-		ok, err := repo.Update(&mockUser)
+		ok, err := repo.Update(context.TODO(), &mockUser)
 
 		assert.NoError(t, err)
 		assert.True(t, ok)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
+	t.Run("error-commit", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`UPDATE user`).ExpectExec().WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit().WillReturnError(fmt.Errorf("Some error"))
+
+		repo := userRepo.NewUserRepository(db)
+		_, err := repo.Update(context.TODO(), &mockUser)
+
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
 	t.Run("success-no-data", func(t *testing.T) {
 		mock.ExpectBegin()
 		mock.ExpectPrepare(`UPDATE user`).ExpectExec().WillReturnResult(sqlmock.NewResult(1, 0))
 		mock.ExpectRollback()
 
 		repo := userRepo.NewUserRepository(db)
-		ok, err := repo.Update(&mockUser)
+		ok, err := repo.Update(context.TODO(), &mockUser)
 
 		assert.NoError(t, err)
 		assert.False(t, ok)
@@ -212,7 +276,7 @@ func TestUpdate(t *testing.T) {
 	t.Run("error-begin", func(t *testing.T) {
 		mock.ExpectBegin().WillReturnError(fmt.Errorf("Some error"))
 		repo := userRepo.NewUserRepository(db)
-		ok, err := repo.Update(&mockUser)
+		ok, err := repo.Update(context.TODO(), &mockUser)
 
 		assert.Error(t, err)
 		assert.False(t, ok)
@@ -224,7 +288,7 @@ func TestUpdate(t *testing.T) {
 		mock.ExpectPrepare(`UPDATE user`).WillReturnError(fmt.Errorf("Some error"))
 
 		repo := userRepo.NewUserRepository(db)
-		ok, err := repo.Update(&mockUser)
+		ok, err := repo.Update(context.TODO(), &mockUser)
 
 		assert.Error(t, err)
 		assert.False(t, ok)
@@ -236,7 +300,7 @@ func TestUpdate(t *testing.T) {
 		mock.ExpectPrepare(`UPDATE user`).ExpectExec().WillReturnError(fmt.Errorf("Some error"))
 
 		repo := userRepo.NewUserRepository(db)
-		ok, err := repo.Update(&mockUser)
+		ok, err := repo.Update(context.TODO(), &mockUser)
 
 		assert.Error(t, err)
 		assert.False(t, ok)
@@ -249,7 +313,7 @@ func TestUpdate(t *testing.T) {
 		mock.ExpectRollback()
 
 		repo := userRepo.NewUserRepository(db)
-		ok, err := repo.Update(&mockUser)
+		ok, err := repo.Update(context.TODO(), &mockUser)
 
 		assert.Error(t, err)
 		assert.False(t, ok)
@@ -268,15 +332,15 @@ func TestGetByID(t *testing.T) {
 
 	t.Run("success", func(t *testing.T) {
 		rows := sqlmock.NewRows([]string{
-			`id`, `email`, `address`,
+			`id`, `email`, `address`, `verified_at`,
 		}).AddRow(
-			mockUsers[0].ID, mockUsers[0].Email, mockUsers[0].Address,
+			mockUsers[0].ID, mockUsers[0].Email, mockUsers[0].Address, nil,
 		)
 
-		mock.ExpectQuery(`SELECT (.+) FROM user`).WillReturnRows(rows)
+		mock.ExpectQuery(`SELECT (.+) FROM user WHERE id = \? AND delete_time IS NULL`).WillReturnRows(rows)
 
 		repo := userRepo.NewUserRepository(db)
-		res, err := repo.GetByID(mockUser.ID)
+		res, err := repo.GetByID(context.TODO(), mockUser.ID)
 
 		assert.NoError(t, err)
 		assert.Equal(t, mockUsers[0].ID, res.ID)
@@ -285,13 +349,13 @@ func TestGetByID(t *testing.T) {
 
 	t.Run("success-no-data", func(t *testing.T) {
 		rows := sqlmock.NewRows([]string{
-			`id`, `email`, `address`,
+			`id`, `email`, `address`, `verified_at`,
 		})
 
 		mock.ExpectQuery(`SELECT (.+) FROM user`).WillReturnRows(rows)
 
 		repo := userRepo.NewUserRepository(db)
-		res, err := repo.GetByID(mockUser.ID)
+		res, err := repo.GetByID(context.TODO(), mockUser.ID)
 
 		assert.NoError(t, err)
 		assert.Equal(t, res.ID, int64(0))
@@ -300,9 +364,9 @@ func TestGetByID(t *testing.T) {
 
 	t.Run("error", func(t *testing.T) {
 		rows := sqlmock.NewRows([]string{
-			`id`, `email`, `address`,
+			`id`, `email`, `address`, `verified_at`,
 		}).AddRow(
-			mockUsers[0].ID, mockUsers[0].Email, nil,
+			mockUsers[0].ID, mockUsers[0].Email, nil, nil,
 		).RowError(
 			1, fmt.Errorf("row error"),
 		)
@@ -310,7 +374,7 @@ func TestGetByID(t *testing.T) {
 		mock.ExpectQuery(`SELECT (.+) FROM user`).WillReturnRows(rows)
 
 		repo := userRepo.NewUserRepository(db)
-		res, err := repo.GetByID(mockUser.ID)
+		res, err := repo.GetByID(context.TODO(), mockUser.ID)
 
 		assert.Error(t, err)
 		assert.Nil(t, res)
@@ -333,20 +397,32 @@ func TestDelete(t *testing.T) {
 		mock.ExpectCommit()
 
 		repo := userRepo.NewUserRepository(db)
-		ok, err := repo.Delete(mockUser.ID)
+		ok, err := repo.Delete(context.TODO(), mockUser.ID)
 
 		assert.NoError(t, err)
 		assert.True(t, ok)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
+	t.Run("error-commit", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`UPDATE user`).ExpectExec().WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit().WillReturnError(fmt.Errorf("Some error"))
+
+		repo := userRepo.NewUserRepository(db)
+		_, err := repo.Delete(context.TODO(), mockUser.ID)
+
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
 	t.Run("success-no-data", func(t *testing.T) {
 		mock.ExpectBegin()
 		mock.ExpectPrepare(`UPDATE user`).ExpectExec().WillReturnResult(sqlmock.NewResult(1, 0))
 		mock.ExpectRollback()
 
 		repo := userRepo.NewUserRepository(db)
-		ok, err := repo.Delete(mockUser.ID)
+		ok, err := repo.Delete(context.TODO(), mockUser.ID)
 
 		assert.NoError(t, err)
 		assert.False(t, ok)
@@ -356,7 +432,7 @@ func TestDelete(t *testing.T) {
 	t.Run("error-begin", func(t *testing.T) {
 		mock.ExpectBegin().WillReturnError(fmt.Errorf("Some error"))
 		repo := userRepo.NewUserRepository(db)
-		ok, err := repo.Delete(mockUser.ID)
+		ok, err := repo.Delete(context.TODO(), mockUser.ID)
 
 		assert.Error(t, err)
 		assert.False(t, ok)
@@ -368,7 +444,7 @@ func TestDelete(t *testing.T) {
 		mock.ExpectPrepare(`UPDATE user`).WillReturnError(fmt.Errorf("Some error"))
 
 		repo := userRepo.NewUserRepository(db)
-		ok, err := repo.Delete(mockUser.ID)
+		ok, err := repo.Delete(context.TODO(), mockUser.ID)
 
 		assert.Error(t, err)
 		assert.False(t, ok)
@@ -380,7 +456,7 @@ func TestDelete(t *testing.T) {
 		mock.ExpectPrepare(`UPDATE user`).ExpectExec().WillReturnError(fmt.Errorf("Some error"))
 
 		repo := userRepo.NewUserRepository(db)
-		ok, err := repo.Delete(mockUser.ID)
+		ok, err := repo.Delete(context.TODO(), mockUser.ID)
 
 		assert.Error(t, err)
 		assert.False(t, ok)
@@ -393,7 +469,155 @@ func TestDelete(t *testing.T) {
 		mock.ExpectRollback()
 
 		repo := userRepo.NewUserRepository(db)
-		ok, err := repo.Delete(mockUser.ID)
+		ok, err := repo.Delete(context.TODO(), mockUser.ID)
+
+		assert.Error(t, err)
+		assert.False(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestGetByEmail(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{
+			`id`, `email`, `password`, `address`, `verified_at`,
+		}).AddRow(
+			mockUser.ID, mockUser.Email, `$2a$04$hash`, mockUser.Address, nil,
+		)
+
+		mock.ExpectQuery(`SELECT (.+) FROM user WHERE email = \?`).WithArgs(mockUser.Email).WillReturnRows(rows)
+
+		repo := userRepo.NewUserRepository(db)
+		res, err := repo.GetByEmail(context.TODO(), mockUser.Email)
+
+		assert.NoError(t, err)
+		assert.Equal(t, mockUser.ID, res.ID)
+		assert.Equal(t, `$2a$04$hash`, res.Password)
+		assert.Nil(t, res.VerifiedAt)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("success-no-data", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{
+			`id`, `email`, `password`, `address`, `verified_at`,
+		})
+
+		mock.ExpectQuery(`SELECT (.+) FROM user`).WillReturnRows(rows)
+
+		repo := userRepo.NewUserRepository(db)
+		res, err := repo.GetByEmail(context.TODO(), mockUser.Email)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), res.ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("error", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT (.+) FROM user`).WillReturnError(fmt.Errorf("Some error"))
+
+		repo := userRepo.NewUserRepository(db)
+		res, err := repo.GetByEmail(context.TODO(), mockUser.Email)
+
+		assert.Error(t, err)
+		assert.Nil(t, res)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestGetPassword(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{`password`}).AddRow(`$2a$04$hash`)
+
+		mock.ExpectQuery(`SELECT password FROM user WHERE id = \? AND delete_time IS NULL`).WithArgs(1).WillReturnRows(rows)
+
+		repo := userRepo.NewUserRepository(db)
+		res, err := repo.GetPassword(context.TODO(), 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, `$2a$04$hash`, res)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("success-no-data", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT password FROM user`).WillReturnRows(sqlmock.NewRows([]string{`password`}))
+
+		repo := userRepo.NewUserRepository(db)
+		res, err := repo.GetPassword(context.TODO(), 1)
+
+		assert.NoError(t, err)
+		assert.Empty(t, res)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("error", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT password FROM user`).WillReturnError(fmt.Errorf("Some error"))
+
+		repo := userRepo.NewUserRepository(db)
+		_, err := repo.GetPassword(context.TODO(), 1)
+
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestUpdatePassword(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`UPDATE user SET password`).ExpectExec().WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		repo := userRepo.NewUserRepository(db)
+		ok, err := repo.UpdatePassword(context.TODO(), mockUser.ID, `$2a$04$hash`)
+
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("success-no-data", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`UPDATE user SET password`).ExpectExec().WillReturnResult(sqlmock.NewResult(1, 0))
+		mock.ExpectRollback()
+
+		repo := userRepo.NewUserRepository(db)
+		ok, err := repo.UpdatePassword(context.TODO(), mockUser.ID, `$2a$04$hash`)
+
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("error-exec", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`UPDATE user SET password`).ExpectExec().WillReturnError(fmt.Errorf("Some error"))
+		mock.ExpectRollback()
+
+		repo := userRepo.NewUserRepository(db)
+		ok, err := repo.UpdatePassword(context.TODO(), mockUser.ID, `$2a$04$hash`)
 
 		assert.Error(t, err)
 		assert.False(t, ok)
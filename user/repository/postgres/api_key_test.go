@@ -0,0 +1,83 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/andhikagama/lmnlo/user/repository/postgres"
+)
+
+func TestStoreAPIKey(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare(`INSERT INTO api_key \(user_id,name,prefix,secret_hash,scopes,expire_time,create_time\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6,\$7\) RETURNING id`).
+		ExpectQuery().
+		WithArgs(int64(1), `ci`, `prefix`, `hash`, `user:read,user:update`, nil, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{`id`}).AddRow(9))
+	mock.ExpectCommit()
+
+	key := &entity.APIKey{
+		UserID:     1,
+		Name:       `ci`,
+		Prefix:     `prefix`,
+		SecretHash: `hash`,
+		Scopes:     []string{entity.PermissionUserRead, entity.PermissionUserUpdate},
+	}
+	repo := postgres.NewUserRepository(db)
+	err = repo.StoreAPIKey(context.TODO(), key)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(9), key.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetAPIKeyByPrefix(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{
+			`id`, `user_id`, `name`, `prefix`, `secret_hash`, `scopes`, `expire_time`, `last_used_time`, `create_time`,
+		}).AddRow(
+			9, 1, `ci`, `prefix`, `hash`, `user:read`, nil, nil, time.Now(),
+		)
+
+		mock.ExpectQuery(`SELECT (.+) FROM api_key WHERE prefix = \$1`).WithArgs(`prefix`).WillReturnRows(rows)
+
+		repo := postgres.NewUserRepository(db)
+		key, err := repo.GetAPIKeyByPrefix(context.TODO(), `prefix`)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(9), key.ID)
+		assert.Equal(t, []string{entity.PermissionUserRead}, key.Scopes)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("success-no-data", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT (.+) FROM api_key`).WillReturnRows(sqlmock.NewRows([]string{`id`}))
+
+		repo := postgres.NewUserRepository(db)
+		key, err := repo.GetAPIKeyByPrefix(context.TODO(), `missing`)
+
+		assert.NoError(t, err)
+		assert.Zero(t, key.ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
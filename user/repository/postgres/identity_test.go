@@ -0,0 +1,68 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/andhikagama/lmnlo/user/repository/postgres"
+)
+
+func TestStoreIdentity(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare(`INSERT INTO user_identity \(user_id,provider,subject,email,create_time\) VALUES \(\$1,\$2,\$3,\$4,\$5\) RETURNING id`).
+		ExpectQuery().
+		WillReturnRows(sqlmock.NewRows([]string{`id`}).AddRow(4))
+	mock.ExpectCommit()
+
+	id := &entity.Identity{UserID: 1, Provider: `github`, Subject: `42`, Email: `andhika.gama@outlook.com`}
+	repo := postgres.NewUserRepository(db)
+	err = repo.StoreIdentity(context.TODO(), id)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), id.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUseOAuthState(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{
+		`state_hash`, `provider`, `verifier`, `nonce`, `expire_time`,
+	}).AddRow(
+		`state`, `github`, `verifier`, `nonce`, time.Now().Add(time.Minute),
+	)
+
+	mock.ExpectQuery(`SELECT (.+) FROM oauth_state WHERE state_hash = \$1`).WithArgs(`state`).WillReturnRows(rows)
+	mock.ExpectBegin()
+	mock.ExpectPrepare(`DELETE FROM oauth_state WHERE state_hash = \$1`).
+		ExpectExec().
+		WithArgs(`state`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	repo := postgres.NewUserRepository(db)
+	st, err := repo.UseOAuthState(context.TODO(), `state`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, `verifier`, st.Verifier)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
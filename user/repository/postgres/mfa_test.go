@@ -0,0 +1,76 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/andhikagama/lmnlo/user/repository/postgres"
+)
+
+func TestUseMFAStep(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`UPDATE user_mfa SET last_used_step = \$1 WHERE user_id = \$2 AND last_used_step < \$3`).
+			ExpectExec().
+			WithArgs(int64(10), int64(1), int64(10)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		repo := postgres.NewUserRepository(db)
+		ok, err := repo.UseMFAStep(context.TODO(), 1, 10)
+
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("success-replayed", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`UPDATE user_mfa`).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		repo := postgres.NewUserRepository(db)
+		ok, err := repo.UseMFAStep(context.TODO(), 1, 10)
+
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestSetRecoveryCodes(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare(`DELETE FROM user_recovery_code WHERE user_id = \$1`).
+		ExpectExec().
+		WithArgs(int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectPrepare(`INSERT INTO user_recovery_code \(user_id,code_hash,create_time\) VALUES \(\$1,\$2,\$3\),\(\$4,\$5,\$6\)`).
+		ExpectExec().
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	repo := postgres.NewUserRepository(db)
+	err = repo.SetRecoveryCodes(context.TODO(), 1, []string{`a`, `b`})
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
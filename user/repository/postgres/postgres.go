@@ -0,0 +1,32 @@
+package postgres
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/andhikagama/lmnlo/user"
+	"github.com/andhikagama/lmnlo/user/repository"
+	sq "github.com/elgris/sqrl"
+	"github.com/lib/pq"
+)
+
+// dialect is PostgreSQL through lib/pq, which has numbered placeholders,
+// reserves user and has no LastInsertId
+var dialect = repository.Dialect{
+	Placeholder: sq.Dollar,
+	UserTable:   `"user"`,
+	AddressMatch: func(pattern string) sq.Sqlizer {
+		return sq.Expr(`address ~* ?`, pattern)
+	},
+	Returning: true,
+	Duplicate: func(err error) bool {
+		e, ok := err.(*pq.Error)
+		return ok && e.Code.Name() == `unique_violation`
+	},
+	Now: time.Now,
+}
+
+// NewUserRepository return a user.Repository backed by PostgreSQL
+func NewUserRepository(Conn *sql.DB) user.Repository {
+	return repository.NewRepository(Conn, dialect)
+}
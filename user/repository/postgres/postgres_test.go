@@ -0,0 +1,236 @@
+package postgres_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/andhikagama/lmnlo/models/filter"
+	"github.com/andhikagama/lmnlo/user/repository/postgres"
+)
+
+var mockUser = entity.User{
+	ID:       1,
+	Email:    `andhika.gama@outlook.com`,
+	Password: `aiueo`,
+	Address:  `Menteng`,
+	Roles:    []string{entity.RoleUser},
+}
+
+func TestStore(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`INSERT INTO "user" \(email,password,address,create_time\) VALUES \(\$1,\$2,\$3,\$4\) RETURNING id`).
+			ExpectQuery().
+			WillReturnRows(sqlmock.NewRows([]string{`id`}).AddRow(7))
+		mock.ExpectPrepare(`INSERT INTO user_role \(user_id,role\) VALUES \(\$1,\$2\)`).
+			ExpectExec().
+			WithArgs(int64(7), entity.RoleUser).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		usr := mockUser
+		repo := postgres.NewUserRepository(db)
+		err := repo.Store(context.TODO(), &usr)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(7), usr.ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("error-query", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`INSERT INTO "user"`).ExpectQuery().WillReturnError(fmt.Errorf("Some error"))
+		mock.ExpectRollback()
+
+		usr := mockUser
+		repo := postgres.NewUserRepository(db)
+		err := repo.Store(context.TODO(), &usr)
+
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestFetch(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success-with-params", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{
+			`id`, `email`, `address`,
+		}).AddRow(
+			mockUser.ID, mockUser.Email, mockUser.Address,
+		)
+
+		mock.ExpectQuery(`SELECT id, email, address FROM "user" WHERE email = \$1 AND address ~\* \$2 AND id  < \$3 AND delete_time IS NULL ORDER BY id DESC LIMIT 10`).
+			WithArgs(mockUser.Email, `^ment`, int64(5)).
+			WillReturnRows(rows)
+
+		f := &filter.User{Email: mockUser.Email, Address: `^ment`, Cursor: 5, Num: 10}
+		repo := postgres.NewUserRepository(db)
+		res, err := repo.Fetch(context.TODO(), f)
+
+		assert.NoError(t, err)
+		assert.Len(t, res, 1)
+		assert.Equal(t, mockUser.ID, res[0].ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("success-no-data", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT (.+) FROM "user"`).WillReturnRows(sqlmock.NewRows([]string{`id`, `email`, `address`}))
+
+		repo := postgres.NewUserRepository(db)
+		res, err := repo.Fetch(context.TODO(), &filter.User{Num: 10})
+
+		assert.NoError(t, err)
+		assert.NotNil(t, res)
+		assert.Len(t, res, 0)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("error-query", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT (.+) FROM "user"`).WillReturnError(fmt.Errorf("Some error"))
+
+		repo := postgres.NewUserRepository(db)
+		res, err := repo.Fetch(context.TODO(), &filter.User{Num: 10})
+
+		assert.Error(t, err)
+		assert.Nil(t, res)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestGetByEmail(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{
+			`id`, `email`, `password`, `address`, `verified_at`,
+		}).AddRow(
+			mockUser.ID, mockUser.Email, mockUser.Password, mockUser.Address, nil,
+		)
+
+		mock.ExpectQuery(`SELECT id, email, password, address, verified_at FROM "user" WHERE email = \$1 AND delete_time IS NULL LIMIT 1`).
+			WithArgs(mockUser.Email).
+			WillReturnRows(rows)
+
+		repo := postgres.NewUserRepository(db)
+		res, err := repo.GetByEmail(context.TODO(), mockUser.Email)
+
+		assert.NoError(t, err)
+		assert.Equal(t, mockUser.ID, res.ID)
+		assert.Equal(t, mockUser.Password, res.Password)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("success-no-data", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT (.+) FROM "user"`).
+			WillReturnRows(sqlmock.NewRows([]string{`id`, `email`, `password`, `address`, `verified_at`}))
+
+		repo := postgres.NewUserRepository(db)
+		res, err := repo.GetByEmail(context.TODO(), `missing@lmnlo.test`)
+
+		assert.NoError(t, err)
+		assert.Zero(t, res.ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestUpdate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`UPDATE "user" SET verified_at = CASE WHEN email = \$1 THEN verified_at END, email = \$2, address = \$3, update_time = \$4 WHERE id = \$5`).
+			ExpectExec().
+			WithArgs(mockUser.Email, mockUser.Email, mockUser.Address, sqlmock.AnyArg(), mockUser.ID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		repo := postgres.NewUserRepository(db)
+		ok, err := repo.Update(context.TODO(), &mockUser)
+
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("success-not-found", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`UPDATE "user"`).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		repo := postgres.NewUserRepository(db)
+		ok, err := repo.Update(context.TODO(), &mockUser)
+
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("error-commit", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`UPDATE "user"`).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit().WillReturnError(fmt.Errorf("Some error"))
+
+		repo := postgres.NewUserRepository(db)
+		_, err := repo.Update(context.TODO(), &mockUser)
+
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestDelete(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare(`UPDATE "user" SET delete_time = \$1 WHERE id = \$2`).
+		ExpectExec().
+		WithArgs(sqlmock.AnyArg(), mockUser.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	repo := postgres.NewUserRepository(db)
+	ok, err := repo.Delete(context.TODO(), mockUser.ID)
+
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
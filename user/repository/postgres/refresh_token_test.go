@@ -0,0 +1,81 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/andhikagama/lmnlo/user/repository/postgres"
+)
+
+func TestStoreRefreshToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare(`INSERT INTO refresh_token (.+) VALUES \(\$1,\$2,\$3,\$4,\$5\) RETURNING id`).
+		ExpectQuery().
+		WillReturnRows(sqlmock.NewRows([]string{`id`}).AddRow(3))
+	mock.ExpectCommit()
+
+	rt := &entity.RefreshToken{UserID: 1, FamilyID: `family`, TokenHash: `hash`, ExpiresAt: time.Now().Add(time.Hour)}
+	repo := postgres.NewUserRepository(db)
+	err = repo.StoreRefreshToken(context.TODO(), rt)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), rt.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRotateRefreshToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`UPDATE refresh_token SET use_time = \$1 WHERE id = \$2 AND use_time IS NULL AND revoke_time IS NULL`).
+			ExpectExec().
+			WithArgs(sqlmock.AnyArg(), int64(3)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectPrepare(`INSERT INTO refresh_token (.+) RETURNING id`).
+			ExpectQuery().
+			WillReturnRows(sqlmock.NewRows([]string{`id`}).AddRow(4))
+		mock.ExpectCommit()
+
+		next := &entity.RefreshToken{UserID: 1, FamilyID: `family`, TokenHash: `next`, ExpiresAt: time.Now().Add(time.Hour)}
+		repo := postgres.NewUserRepository(db)
+		ok, err := repo.RotateRefreshToken(context.TODO(), 3, next)
+
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, int64(4), next.ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("success-already-used", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`UPDATE refresh_token`).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		repo := postgres.NewUserRepository(db)
+		ok, err := repo.RotateRefreshToken(context.TODO(), 3, &entity.RefreshToken{})
+
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,97 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/andhikagama/lmnlo/user/repository/postgres"
+)
+
+func TestGetPermissions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{`permission`}).
+		AddRow(entity.PermissionRoleAssign).
+		AddRow(entity.PermissionUserRead)
+
+	mock.ExpectQuery(`SELECT DISTINCT rp.permission FROM (.+) WHERE ur.user_id = \$1`).WithArgs(int64(1)).WillReturnRows(rows)
+
+	repo := postgres.NewUserRepository(db)
+	res, err := repo.GetPermissions(context.TODO(), 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{entity.PermissionRoleAssign, entity.PermissionUserRead}, res)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSetRoles(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare(`DELETE FROM user_role WHERE user_id = \$1`).
+		ExpectExec().
+		WithArgs(int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare(`INSERT INTO user_role \(user_id,role\) VALUES \(\$1,\$2\),\(\$3,\$4\)`).
+		ExpectExec().
+		WithArgs(int64(1), entity.RoleAdmin, int64(1), entity.RoleUser).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	repo := postgres.NewUserRepository(db)
+	err = repo.SetRoles(context.TODO(), 1, []string{entity.RoleAdmin, entity.RoleUser})
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExistRoles(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT COUNT\(\*\) FROM role WHERE name IN \(\$1,\$2\)`).
+			WithArgs(entity.RoleAdmin, entity.RoleUser).
+			WillReturnRows(sqlmock.NewRows([]string{`count`}).AddRow(2))
+
+		repo := postgres.NewUserRepository(db)
+		ok, err := repo.ExistRoles(context.TODO(), []string{entity.RoleAdmin, entity.RoleUser})
+
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("success-unknown", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT COUNT\(\*\) FROM role`).
+			WillReturnRows(sqlmock.NewRows([]string{`count`}).AddRow(1))
+
+		repo := postgres.NewUserRepository(db)
+		ok, err := repo.ExistRoles(context.TODO(), []string{entity.RoleAdmin, `superuser`})
+
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,145 @@
+package postgres_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/andhikagama/lmnlo/user/repository/postgres"
+)
+
+func TestInsertToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`INSERT INTO token \(user_id,family_id,jti,token_hash,user_agent,ip,create_time,expire_time\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6,\$7,\$8\) RETURNING id`).
+			ExpectQuery().
+			WillReturnRows(sqlmock.NewRows([]string{`id`}).AddRow(5))
+		mock.ExpectCommit()
+
+		sess := &entity.Session{UserID: 1, FamilyID: `family`, TokenID: `jti`, TokenHash: `hash`, ExpiresAt: time.Now().Add(time.Hour)}
+		repo := postgres.NewUserRepository(db)
+		err := repo.InsertToken(context.TODO(), sess)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(5), sess.ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("error-query", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`INSERT INTO token`).ExpectQuery().WillReturnError(fmt.Errorf("Some error"))
+		mock.ExpectRollback()
+
+		repo := postgres.NewUserRepository(db)
+		err := repo.InsertToken(context.TODO(), &entity.Session{UserID: 1})
+
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestValidateToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT 1 FROM token WHERE jti = \$1 AND token_hash = \$2 AND revoke_time IS NULL AND expire_time > \$3`).
+		WithArgs(`jti`, `hash`, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{`1`}).AddRow(1))
+
+	repo := postgres.NewUserRepository(db)
+	ok, err := repo.ValidateToken(context.TODO(), `jti`, `hash`)
+
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRevokeSessionFamily(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare(`UPDATE token SET revoke_time = \$1 WHERE family_id = \$2 AND revoke_time IS NULL`).
+		ExpectExec().
+		WithArgs(sqlmock.AnyArg(), `family`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	repo := postgres.NewUserRepository(db)
+	err = repo.RevokeSessionFamily(context.TODO(), `family`)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRevokeOtherSessions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare(`UPDATE token SET revoke_time = \$1 WHERE user_id = \$2 AND id <> \$3 AND revoke_time IS NULL`).
+		ExpectExec().
+		WithArgs(sqlmock.AnyArg(), int64(1), int64(5)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	repo := postgres.NewUserRepository(db)
+	err = repo.RevokeOtherSessions(context.TODO(), 1, 5)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPurgeTokens(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	before := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare(`DELETE FROM token WHERE \(expire_time < \$1 OR revoke_time < \$2\)`).
+		ExpectExec().
+		WithArgs(before, before).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	repo := postgres.NewUserRepository(db)
+	n, err := repo.PurgeTokens(context.TODO(), before)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,84 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/andhikagama/lmnlo/user/repository/postgres"
+)
+
+func TestStoreUserToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare(`INSERT INTO user_token (.+) RETURNING id`).
+		ExpectQuery().
+		WillReturnRows(sqlmock.NewRows([]string{`id`}).AddRow(2))
+	mock.ExpectCommit()
+
+	ut := &entity.UserToken{UserID: 1, Purpose: entity.TokenPurposeVerifyEmail, TokenHash: `hash`, ExpiresAt: time.Now().Add(time.Hour)}
+	repo := postgres.NewUserRepository(db)
+	err = repo.StoreUserToken(context.TODO(), ut)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), ut.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSetVerified(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare(`UPDATE "user" SET verified_at = \$1, update_time = \$2 WHERE id = \$3 AND email = \$4 AND verified_at IS NULL`).
+		ExpectExec().
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), int64(1), `a@lmnlo.test`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	repo := postgres.NewUserRepository(db)
+	ok, err := repo.SetVerified(context.TODO(), 1, `a@lmnlo.test`)
+
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCountUserTokens(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	since := time.Now().Add(-time.Hour)
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM user_token WHERE user_id = \$1 AND purpose = \$2 AND create_time >= \$3`).
+		WithArgs(int64(1), entity.TokenPurposeVerifyEmail, since).
+		WillReturnRows(sqlmock.NewRows([]string{`count`}).AddRow(2))
+
+	repo := postgres.NewUserRepository(db)
+	n, err := repo.CountUserTokens(context.TODO(), 1, entity.TokenPurposeVerifyEmail, since)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
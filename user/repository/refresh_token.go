@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/sirupsen/logrus"
+)
+
+func (m *userRepository) StoreRefreshToken(ctx context.Context, rt *entity.RefreshToken) error {
+	trx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := m.storeRefreshToken(ctx, trx, rt); err != nil {
+		trx.Rollback()
+		return err
+	}
+
+	return trx.Commit()
+}
+
+func (m *userRepository) GetRefreshToken(ctx context.Context, tokenHash string) (*entity.RefreshToken, error) {
+	query := m.builder.Select(`id, user_id, family_id, token_hash, expire_time, use_time, revoke_time, create_time`)
+	query.From(`refresh_token`)
+	query.Where(`token_hash = ?`, tokenHash)
+
+	sql, args, _ := query.ToSql()
+	rows, err := m.Conn.QueryContext(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rt := new(entity.RefreshToken)
+	for rows.Next() {
+		err := rows.Scan(
+			&rt.ID,
+			&rt.UserID,
+			&rt.FamilyID,
+			&rt.TokenHash,
+			&rt.ExpiresAt,
+			&rt.UsedAt,
+			&rt.RevokedAt,
+			&rt.CreatedAt,
+		)
+
+		if err != nil {
+			logrus.Error(err, rt.ID)
+			return nil, err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return rt, nil
+}
+
+func (m *userRepository) RotateRefreshToken(ctx context.Context, id int64, next *entity.RefreshToken) (bool, error) {
+	trx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+
+	// Marking the old token used only when it is still unused makes two
+	// concurrent refreshes with the same token race for a single winner
+	query := m.builder.Update(`refresh_token`).
+		Set(`use_time`, m.now()).
+		Where(`id = ?`, id).
+		Where(`use_time IS NULL`).
+		Where(`revoke_time IS NULL`)
+
+	sql, args, _ := query.ToSql()
+	stmt, err := trx.PrepareContext(ctx, sql)
+	if err != nil {
+		trx.Rollback()
+		return false, err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, args...)
+	if err != nil {
+		trx.Rollback()
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		trx.Rollback()
+		return false, err
+	}
+
+	if affected != 1 {
+		trx.Rollback()
+		return false, nil
+	}
+
+	if err := m.storeRefreshToken(ctx, trx, next); err != nil {
+		trx.Rollback()
+		return false, err
+	}
+
+	return true, trx.Commit()
+}
+
+func (m *userRepository) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	query := m.builder.Update(`refresh_token`).
+		Set(`revoke_time`, m.now()).
+		Where(`family_id = ?`, familyID).
+		Where(`revoke_time IS NULL`)
+
+	_, err := m.exec(ctx, query)
+	return err
+}
+
+func (m *userRepository) storeRefreshToken(ctx context.Context, trx *sql.Tx, rt *entity.RefreshToken) error {
+	rt.CreatedAt = m.now()
+
+	query := m.builder.Insert(`refresh_token`)
+	query.Columns(`user_id`, `family_id`, `token_hash`, `expire_time`, `create_time`)
+	query.Values(rt.UserID, rt.FamilyID, rt.TokenHash, m.at(rt.ExpiresAt), rt.CreatedAt)
+
+	id, err := m.insert(ctx, trx, query)
+	if err != nil {
+		return err
+	}
+
+	rt.ID = id
+	return nil
+}
+
+func (m *userRepository) RevokeRefreshTokensByUser(ctx context.Context, uid int64) error {
+	query := m.builder.Update(`refresh_token`).
+		Set(`revoke_time`, m.now()).
+		Where(`user_id = ?`, uid).
+		Where(`revoke_time IS NULL`)
+
+	_, err := m.exec(ctx, query)
+	return err
+}
+
+func (m *userRepository) RevokeOtherRefreshTokens(ctx context.Context, uid int64, keepFamilyID string) error {
+	query := m.builder.Update(`refresh_token`).
+		Set(`revoke_time`, m.now()).
+		Where(`user_id = ?`, uid).
+		Where(`family_id <> ?`, keepFamilyID).
+		Where(`revoke_time IS NULL`)
+
+	_, err := m.exec(ctx, query)
+	return err
+}
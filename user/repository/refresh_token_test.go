@@ -0,0 +1,228 @@
+package repository_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/andhikagama/lmnlo/models/entity"
+	userRepo "github.com/andhikagama/lmnlo/user/repository"
+)
+
+var mockRefreshToken = entity.RefreshToken{
+	ID:        1,
+	UserID:    1,
+	FamilyID:  `family`,
+	TokenHash: `hash`,
+	ExpiresAt: time.Now().Add(time.Hour),
+}
+
+func TestStoreRefreshToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`INSERT INTO refresh_token`).ExpectExec().WillReturnResult(sqlmock.NewResult(3, 1))
+		mock.ExpectCommit()
+
+		rt := mockRefreshToken
+		repo := userRepo.NewUserRepository(db)
+		err := repo.StoreRefreshToken(context.TODO(), &rt)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), rt.ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("error-exec", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`INSERT INTO refresh_token`).ExpectExec().WillReturnError(fmt.Errorf("Some error"))
+		mock.ExpectRollback()
+
+		rt := mockRefreshToken
+		repo := userRepo.NewUserRepository(db)
+		err := repo.StoreRefreshToken(context.TODO(), &rt)
+
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestGetRefreshToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	columns := []string{
+		`id`, `user_id`, `family_id`, `token_hash`, `expire_time`, `use_time`, `revoke_time`, `create_time`,
+	}
+
+	t.Run("success", func(t *testing.T) {
+		usedAt := time.Now()
+		rows := sqlmock.NewRows(columns).AddRow(
+			mockRefreshToken.ID, mockRefreshToken.UserID, mockRefreshToken.FamilyID, mockRefreshToken.TokenHash,
+			mockRefreshToken.ExpiresAt, usedAt, nil, time.Now(),
+		)
+
+		mock.ExpectQuery(`SELECT (.+) FROM refresh_token WHERE token_hash = \?`).WithArgs(`hash`).WillReturnRows(rows)
+
+		repo := userRepo.NewUserRepository(db)
+		res, err := repo.GetRefreshToken(context.TODO(), `hash`)
+
+		assert.NoError(t, err)
+		assert.Equal(t, mockRefreshToken.ID, res.ID)
+		assert.NotNil(t, res.UsedAt)
+		assert.Nil(t, res.RevokedAt)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("success-no-data", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT (.+) FROM refresh_token`).WillReturnRows(sqlmock.NewRows(columns))
+
+		repo := userRepo.NewUserRepository(db)
+		res, err := repo.GetRefreshToken(context.TODO(), `hash`)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), res.ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("error", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT (.+) FROM refresh_token`).WillReturnError(fmt.Errorf("Some error"))
+
+		repo := userRepo.NewUserRepository(db)
+		res, err := repo.GetRefreshToken(context.TODO(), `hash`)
+
+		assert.Error(t, err)
+		assert.Nil(t, res)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRotateRefreshToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`UPDATE refresh_token SET use_time`).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectPrepare(`INSERT INTO refresh_token`).ExpectExec().WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectCommit()
+
+		next := mockRefreshToken
+		repo := userRepo.NewUserRepository(db)
+		ok, err := repo.RotateRefreshToken(context.TODO(), mockRefreshToken.ID, &next)
+
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, int64(2), next.ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("already-used", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`UPDATE refresh_token SET use_time`).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		next := mockRefreshToken
+		repo := userRepo.NewUserRepository(db)
+		ok, err := repo.RotateRefreshToken(context.TODO(), mockRefreshToken.ID, &next)
+
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("error-insert", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`UPDATE refresh_token SET use_time`).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectPrepare(`INSERT INTO refresh_token`).ExpectExec().WillReturnError(fmt.Errorf("Some error"))
+		mock.ExpectRollback()
+
+		next := mockRefreshToken
+		repo := userRepo.NewUserRepository(db)
+		ok, err := repo.RotateRefreshToken(context.TODO(), mockRefreshToken.ID, &next)
+
+		assert.Error(t, err)
+		assert.False(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRevokeRefreshTokenFamily(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`UPDATE refresh_token SET revoke_time`).ExpectExec().WithArgs(sqlmock.AnyArg(), `family`).WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectCommit()
+
+		repo := userRepo.NewUserRepository(db)
+		err := repo.RevokeRefreshTokenFamily(context.TODO(), `family`)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("error-exec", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`UPDATE refresh_token SET revoke_time`).ExpectExec().WillReturnError(fmt.Errorf("Some error"))
+		mock.ExpectRollback()
+
+		repo := userRepo.NewUserRepository(db)
+		err := repo.RevokeRefreshTokenFamily(context.TODO(), `family`)
+
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRevokeOtherRefreshTokens(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`UPDATE refresh_token SET revoke_time = \? WHERE user_id = \? AND family_id <> \? AND revoke_time IS NULL`).
+			ExpectExec().
+			WithArgs(sqlmock.AnyArg(), 1, `family`).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		repo := userRepo.NewUserRepository(db)
+		err := repo.RevokeOtherRefreshTokens(context.TODO(), 1, `family`)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,99 @@
+// Package repository implements user.Repository once for the SQL databases,
+// each database only brings the Dialect of its driver
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/andhikagama/lmnlo/user"
+	sq "github.com/elgris/sqrl"
+)
+
+// Dialect is what sets a database apart, every query is shared
+type Dialect struct {
+	// Placeholder formats the arguments of statements
+	Placeholder sq.PlaceholderFormat
+	// UserTable names the user table, quoted where user is a reserved word
+	UserTable string
+	// AddressMatch return the condition of an address matching pattern, a
+	// regular expression compared without regard to case
+	AddressMatch func(pattern string) sq.Sqlizer
+	// Returning reads the ID of inserted rows with RETURNING id, for drivers
+	// without LastInsertId
+	Returning bool
+	// Duplicate reports whether err violates a unique index
+	Duplicate func(err error) bool
+	// Now is the clock of the database, every time is written in its
+	// location
+	Now func() time.Time
+}
+
+type userRepository struct {
+	Conn    *sql.DB
+	dialect Dialect
+	builder sq.StatementBuilderType
+	loc     *time.Location
+}
+
+// NewRepository return a user.Repository writing to Conn in dialect
+func NewRepository(Conn *sql.DB, dialect Dialect) user.Repository {
+	return &userRepository{
+		Conn:    Conn,
+		dialect: dialect,
+		builder: sq.StatementBuilder.PlaceholderFormat(dialect.Placeholder),
+		loc:     dialect.Now().Location(),
+	}
+}
+
+// now is the current time of the database clock
+func (m *userRepository) now() time.Time {
+	return m.dialect.Now()
+}
+
+// at is t in the location of the database clock
+func (m *userRepository) at(t time.Time) time.Time {
+	if t.Location() == m.loc {
+		return t
+	}
+
+	return t.In(m.loc)
+}
+
+// atNullable is at for optional times
+func (m *userRepository) atNullable(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+
+	at := m.at(*t)
+	return &at
+}
+
+// insert runs query inside trx and returns the ID of the new row
+func (m *userRepository) insert(ctx context.Context, trx *sql.Tx, query *sq.InsertBuilder) (int64, error) {
+	if m.dialect.Returning {
+		query.Suffix(`RETURNING id`)
+	}
+
+	sql, args, _ := query.ToSql()
+	stmt, err := trx.PrepareContext(ctx, sql)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	var id int64
+	if m.dialect.Returning {
+		err := stmt.QueryRowContext(ctx, args...).Scan(&id)
+		return id, err
+	}
+
+	r, err := stmt.ExecContext(ctx, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return r.LastInsertId()
+}
@@ -0,0 +1,734 @@
+// Package repositorytest checks a user.Repository against a real database,
+// so every backend behaves the way the user usecase expects
+package repositorytest
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/andhikagama/lmnlo/migration"
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/andhikagama/lmnlo/models/filter"
+	"github.com/andhikagama/lmnlo/models/response"
+	"github.com/andhikagama/lmnlo/user"
+)
+
+// Tables are written by user.Repository. Emptying them between tests keeps
+// the seeded roles and permissions.
+var Tables = []string{
+	`user`,
+	`user_role`,
+	`user_token`,
+	`token`,
+	`refresh_token`,
+	`user_mfa`,
+	`user_recovery_code`,
+	`api_key`,
+	`user_identity`,
+	`oauth_state`,
+}
+
+// Open connects to the database whose DSN is in the environment variable
+// env and migrates it up, the test is skipped while env is unset. Point it
+// at a scratch database, the tests empty Tables.
+func Open(t *testing.T, driver string, env string) *sql.DB {
+	dsn := os.Getenv(env)
+	if dsn == `` {
+		t.Skipf(`%s is not set`, env)
+	}
+
+	db, err := sql.Open(driver, dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	m, err := migration.NewMigrator(db, driver)
+	require.NoError(t, err)
+
+	_, err = m.Up(context.Background())
+	require.NoError(t, err)
+
+	return db
+}
+
+// Run runs every check, each on a repository from newRepository. It has to
+// return a repository on a migrated schema without rows in Tables.
+func Run(t *testing.T, newRepository func(t *testing.T) user.Repository) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, repo user.Repository)
+	}{
+		{`users`, testUsers},
+		{`roles`, testRoles},
+		{`sessions`, testSessions},
+		{`refresh-tokens`, testRefreshTokens},
+		{`user-tokens`, testUserTokens},
+		{`mfa`, testMFA},
+		{`api-keys`, testAPIKeys},
+		{`identities`, testIdentities},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newRepository(t))
+		})
+	}
+}
+
+// storeUser stores a user with the user role
+func storeUser(t *testing.T, repo user.Repository, email string, address string) *entity.User {
+	usr := &entity.User{
+		Email:    email,
+		Password: `hash-of-` + email,
+		Address:  address,
+		Roles:    []string{entity.RoleUser},
+	}
+	require.NoError(t, repo.Store(context.TODO(), usr))
+	require.NotZero(t, usr.ID)
+
+	return usr
+}
+
+func ids(users []*entity.User) []int64 {
+	res := []int64{}
+	for _, usr := range users {
+		res = append(res, usr.ID)
+	}
+
+	return res
+}
+
+func testUsers(t *testing.T, repo user.Repository) {
+	ctx := context.TODO()
+	a := storeUser(t, repo, `a@lmnlo.test`, `Menteng`)
+	b := storeUser(t, repo, `b@lmnlo.test`, `Kemang`)
+
+	usr, err := repo.GetByID(ctx, a.ID)
+	require.NoError(t, err)
+	assert.Equal(t, a.ID, usr.ID)
+	assert.Equal(t, a.Email, usr.Email)
+	assert.Equal(t, a.Address, usr.Address)
+	assert.Nil(t, usr.VerifiedAt)
+
+	usr, err = repo.GetByID(ctx, b.ID+1000)
+	require.NoError(t, err)
+	assert.Zero(t, usr.ID)
+
+	usr, err = repo.GetByEmail(ctx, a.Email)
+	require.NoError(t, err)
+	assert.Equal(t, a.ID, usr.ID)
+	assert.Equal(t, a.Password, usr.Password)
+
+	usr, err = repo.GetByEmail(ctx, `missing@lmnlo.test`)
+	require.NoError(t, err)
+	assert.Zero(t, usr.ID)
+
+	list, err := repo.Fetch(ctx, &filter.User{Num: 10})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{b.ID, a.ID}, ids(list))
+
+	list, err = repo.Fetch(ctx, &filter.User{Email: a.Email, Num: 10})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{a.ID}, ids(list))
+
+	list, err = repo.Fetch(ctx, &filter.User{Address: `^ment`, Num: 10})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{a.ID}, ids(list))
+
+	// The address is a pattern, never part of the statement
+	list, err = repo.Fetch(ctx, &filter.User{Address: `x' OR '1'='1`, Num: 10})
+	require.NoError(t, err)
+	assert.Empty(t, list)
+
+	list, err = repo.Fetch(ctx, &filter.User{Cursor: b.ID, Num: 10})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{a.ID}, ids(list))
+
+	list, err = repo.Fetch(ctx, &filter.User{Num: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{b.ID}, ids(list))
+
+	a.Email = `a2@lmnlo.test`
+	ok, err := repo.Update(ctx, a)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	usr, err = repo.GetByID(ctx, a.ID)
+	require.NoError(t, err)
+	assert.Equal(t, a.Email, usr.Email)
+
+	err = repo.Store(ctx, &entity.User{Email: b.Email, Roles: []string{entity.RoleUser}})
+	assert.Equal(t, response.ErrAlreadyExist, err)
+
+	ok, err = repo.Update(ctx, &entity.User{ID: a.ID, Email: b.Email})
+	assert.Equal(t, response.ErrAlreadyExist, err)
+	assert.False(t, ok)
+
+	ok, err = repo.Update(ctx, &entity.User{ID: b.ID + 1000, Email: `x@lmnlo.test`})
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	ok, err = repo.UpdatePassword(ctx, a.ID, `new-hash`)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	password, err := repo.GetPassword(ctx, a.ID)
+	require.NoError(t, err)
+	assert.Equal(t, `new-hash`, password)
+
+	ok, err = repo.UpdatePassword(ctx, b.ID+1000, `new-hash`)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	ok, err = repo.SetVerified(ctx, a.ID, `a@lmnlo.test`)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	ok, err = repo.SetVerified(ctx, a.ID, a.Email)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = repo.SetVerified(ctx, a.ID, a.Email)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	usr, err = repo.GetByID(ctx, a.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, usr.VerifiedAt)
+
+	// Only a new address is unverified
+	ok, err = repo.Update(ctx, a)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	usr, err = repo.GetByID(ctx, a.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, usr.VerifiedAt)
+
+	a.Email = `a3@lmnlo.test`
+	ok, err = repo.Update(ctx, a)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	usr, err = repo.GetByID(ctx, a.ID)
+	require.NoError(t, err)
+	assert.Nil(t, usr.VerifiedAt)
+
+	ok, err = repo.Delete(ctx, b.ID)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	list, err = repo.Fetch(ctx, &filter.User{Num: 10})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{a.ID}, ids(list))
+
+	usr, err = repo.GetByID(ctx, b.ID)
+	require.NoError(t, err)
+	assert.Zero(t, usr.ID)
+
+	usr, err = repo.GetByEmail(ctx, b.Email)
+	require.NoError(t, err)
+	assert.Zero(t, usr.ID)
+
+	password, err = repo.GetPassword(ctx, b.ID)
+	require.NoError(t, err)
+	assert.Empty(t, password)
+
+	ok, err = repo.Delete(ctx, b.ID+1000)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// A deleted account frees its email
+	c := storeUser(t, repo, b.Email, `Kemang`)
+	assert.NotEqual(t, b.ID, c.ID)
+}
+
+func testRoles(t *testing.T, repo user.Repository) {
+	ctx := context.TODO()
+	usr := storeUser(t, repo, `roles@lmnlo.test`, `Menteng`)
+
+	roles, err := repo.GetRoles(ctx, usr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{entity.RoleUser}, roles)
+
+	permissions, err := repo.GetPermissions(ctx, usr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{entity.PermissionUserRead, entity.PermissionUserUpdate}, permissions)
+
+	require.NoError(t, repo.SetRoles(ctx, usr.ID, []string{entity.RoleUser, entity.RoleAdmin}))
+
+	roles, err = repo.GetRoles(ctx, usr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{entity.RoleAdmin, entity.RoleUser}, roles)
+
+	permissions, err = repo.GetPermissions(ctx, usr.ID)
+	require.NoError(t, err)
+	assert.Contains(t, permissions, entity.PermissionUserList)
+	assert.Contains(t, permissions, entity.PermissionRoleAssign)
+	assert.True(t, sort.StringsAreSorted(permissions))
+
+	require.NoError(t, repo.SetRoles(ctx, usr.ID, []string{}))
+
+	roles, err = repo.GetRoles(ctx, usr.ID)
+	require.NoError(t, err)
+	assert.Empty(t, roles)
+
+	ok, err := repo.ExistRoles(ctx, []string{entity.RoleAdmin, entity.RoleUser})
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = repo.ExistRoles(ctx, []string{entity.RoleAdmin, `superuser`})
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func newSession(uid int64, family string, expiresAt time.Time) *entity.Session {
+	return &entity.Session{
+		UserID:    uid,
+		FamilyID:  family,
+		TokenID:   family + `-jti`,
+		TokenHash: family + `-hash`,
+		UserAgent: `Mozilla/5.0`,
+		IP:        `127.0.0.1`,
+		ExpiresAt: expiresAt,
+	}
+}
+
+func sessionIDs(sessions []*entity.Session) []int64 {
+	res := []int64{}
+	for _, sess := range sessions {
+		res = append(res, sess.ID)
+	}
+
+	return res
+}
+
+func testSessions(t *testing.T, repo user.Repository) {
+	ctx := context.TODO()
+	usr := storeUser(t, repo, `sessions@lmnlo.test`, `Menteng`)
+	later := time.Now().Add(time.Hour)
+
+	first := newSession(usr.ID, `f1`, later)
+	require.NoError(t, repo.InsertToken(ctx, first))
+	assert.NotZero(t, first.ID)
+
+	ok, err := repo.ValidateToken(ctx, first.TokenID, first.TokenHash)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = repo.ValidateToken(ctx, first.TokenID, `other-hash`)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	sess, err := repo.GetSessionByToken(ctx, first.TokenHash)
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, sess.ID)
+	assert.Equal(t, first.FamilyID, sess.FamilyID)
+	assert.Equal(t, first.UserAgent, sess.UserAgent)
+	assert.WithinDuration(t, later, sess.ExpiresAt, time.Second)
+
+	sess, err = repo.GetSessionByToken(ctx, `missing-hash`)
+	require.NoError(t, err)
+	assert.Zero(t, sess.ID)
+
+	expired := newSession(usr.ID, `expired`, time.Now().Add(-time.Hour))
+	require.NoError(t, repo.InsertToken(ctx, expired))
+
+	ok, err = repo.ValidateToken(ctx, expired.TokenID, expired.TokenHash)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, repo.TouchToken(ctx, first.TokenID))
+
+	sessions, err := repo.FetchSessions(ctx, usr.ID)
+	require.NoError(t, err)
+	require.Equal(t, []int64{first.ID}, sessionIDs(sessions))
+	assert.NotNil(t, sessions[0].LastUsedAt)
+
+	ok, err = repo.ReplaceToken(ctx, &entity.Session{FamilyID: `f1`, TokenID: `f1-jti-2`, TokenHash: `f1-hash-2`, ExpiresAt: later})
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = repo.ValidateToken(ctx, first.TokenID, first.TokenHash)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	ok, err = repo.ValidateToken(ctx, `f1-jti-2`, `f1-hash-2`)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = repo.ReplaceToken(ctx, &entity.Session{FamilyID: `missing`, TokenID: `x`, TokenHash: `x`, ExpiresAt: later})
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	second := newSession(usr.ID, `f2`, later)
+	require.NoError(t, repo.InsertToken(ctx, second))
+
+	sessions, err = repo.FetchSessions(ctx, usr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{second.ID, first.ID}, sessionIDs(sessions))
+
+	require.NoError(t, repo.RevokeOtherSessions(ctx, usr.ID, first.ID))
+
+	sessions, err = repo.FetchSessions(ctx, usr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{first.ID}, sessionIDs(sessions))
+
+	ok, err = repo.RevokeSession(ctx, first.ID)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = repo.RevokeSession(ctx, first.ID)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	fourth := newSession(usr.ID, `f4`, later)
+	require.NoError(t, repo.InsertToken(ctx, fourth))
+	require.NoError(t, repo.RevokeSessionFamily(ctx, `f4`))
+
+	ok, err = repo.ValidateToken(ctx, fourth.TokenID, fourth.TokenHash)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	third := newSession(usr.ID, `f3`, later)
+	require.NoError(t, repo.InsertToken(ctx, third))
+	require.NoError(t, repo.RevokeSessionsByUser(ctx, usr.ID))
+
+	sessions, err = repo.FetchSessions(ctx, usr.ID)
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+
+	// expired plus first, second, third and fourth, which are revoked
+	purged, err := repo.PurgeTokens(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), purged)
+}
+
+func newRefreshToken(uid int64, family string, hash string) *entity.RefreshToken {
+	return &entity.RefreshToken{
+		UserID:    uid,
+		FamilyID:  family,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+}
+
+func testRefreshTokens(t *testing.T, repo user.Repository) {
+	ctx := context.TODO()
+	usr := storeUser(t, repo, `refresh@lmnlo.test`, `Menteng`)
+
+	first := newRefreshToken(usr.ID, `f1`, `r1`)
+	require.NoError(t, repo.StoreRefreshToken(ctx, first))
+	assert.NotZero(t, first.ID)
+
+	rt, err := repo.GetRefreshToken(ctx, `r1`)
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, rt.ID)
+	assert.Equal(t, usr.ID, rt.UserID)
+	assert.Equal(t, `f1`, rt.FamilyID)
+	assert.Nil(t, rt.UsedAt)
+	assert.Nil(t, rt.RevokedAt)
+
+	rt, err = repo.GetRefreshToken(ctx, `missing`)
+	require.NoError(t, err)
+	assert.Zero(t, rt.ID)
+
+	next := newRefreshToken(usr.ID, `f1`, `r2`)
+	ok, err := repo.RotateRefreshToken(ctx, first.ID, next)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.NotZero(t, next.ID)
+
+	rt, err = repo.GetRefreshToken(ctx, `r1`)
+	require.NoError(t, err)
+	assert.NotNil(t, rt.UsedAt)
+
+	// a rotated token cannot be rotated again and its successor is dropped
+	ok, err = repo.RotateRefreshToken(ctx, first.ID, newRefreshToken(usr.ID, `f1`, `r3`))
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	rt, err = repo.GetRefreshToken(ctx, `r3`)
+	require.NoError(t, err)
+	assert.Zero(t, rt.ID)
+
+	require.NoError(t, repo.RevokeRefreshTokenFamily(ctx, `f1`))
+
+	rt, err = repo.GetRefreshToken(ctx, `r2`)
+	require.NoError(t, err)
+	assert.NotNil(t, rt.RevokedAt)
+
+	require.NoError(t, repo.StoreRefreshToken(ctx, newRefreshToken(usr.ID, `keep`, `keep`)))
+	require.NoError(t, repo.StoreRefreshToken(ctx, newRefreshToken(usr.ID, `other`, `other`)))
+	require.NoError(t, repo.RevokeOtherRefreshTokens(ctx, usr.ID, `keep`))
+
+	rt, err = repo.GetRefreshToken(ctx, `keep`)
+	require.NoError(t, err)
+	assert.Nil(t, rt.RevokedAt)
+
+	rt, err = repo.GetRefreshToken(ctx, `other`)
+	require.NoError(t, err)
+	assert.NotNil(t, rt.RevokedAt)
+
+	require.NoError(t, repo.RevokeRefreshTokensByUser(ctx, usr.ID))
+
+	rt, err = repo.GetRefreshToken(ctx, `keep`)
+	require.NoError(t, err)
+	assert.NotNil(t, rt.RevokedAt)
+}
+
+func testUserTokens(t *testing.T, repo user.Repository) {
+	ctx := context.TODO()
+	usr := storeUser(t, repo, `tokens@lmnlo.test`, `Menteng`)
+	purpose := entity.TokenPurposeVerifyEmail
+
+	store := func(hash string, expiresAt time.Time) {
+		ut := &entity.UserToken{UserID: usr.ID, Purpose: purpose, TokenHash: hash, ExpiresAt: expiresAt}
+		require.NoError(t, repo.StoreUserToken(ctx, ut))
+		assert.NotZero(t, ut.ID)
+	}
+
+	store(`t1`, time.Now().Add(time.Hour))
+
+	n, err := repo.CountUserTokens(ctx, usr.ID, purpose, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	n, err = repo.CountUserTokens(ctx, usr.ID, entity.TokenPurposeResetPassword, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	ok, err := repo.UseUserToken(ctx, usr.ID+1000, purpose, `t1`)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	ok, err = repo.UseUserToken(ctx, usr.ID, purpose, `t1`)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = repo.UseUserToken(ctx, usr.ID, purpose, `t1`)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	store(`t2`, time.Now().Add(time.Hour))
+	require.NoError(t, repo.InvalidateUserTokens(ctx, usr.ID, purpose))
+
+	ok, err = repo.UseUserToken(ctx, usr.ID, purpose, `t2`)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	store(`t3`, time.Now().Add(-time.Minute))
+
+	ok, err = repo.UseUserToken(ctx, usr.ID, purpose, `t3`)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func testMFA(t *testing.T, repo user.Repository) {
+	ctx := context.TODO()
+	usr := storeUser(t, repo, `mfa@lmnlo.test`, `Menteng`)
+
+	mfa, err := repo.GetMFA(ctx, usr.ID)
+	require.NoError(t, err)
+	assert.Zero(t, mfa.UserID)
+
+	require.NoError(t, repo.StoreMFA(ctx, &entity.MFA{UserID: usr.ID, Secret: `secret-1`}))
+
+	mfa, err = repo.GetMFA(ctx, usr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, usr.ID, mfa.UserID)
+	assert.Equal(t, `secret-1`, mfa.Secret)
+	assert.False(t, mfa.Confirmed())
+
+	ok, err := repo.ConfirmMFA(ctx, usr.ID)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = repo.ConfirmMFA(ctx, usr.ID)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	for _, tt := range []struct {
+		step int64
+		ok   bool
+	}{
+		{10, true},
+		{10, false},
+		{9, false},
+		{11, true},
+	} {
+		ok, err = repo.UseMFAStep(ctx, usr.ID, tt.step)
+		require.NoError(t, err)
+		assert.Equal(t, tt.ok, ok, `step %d`, tt.step)
+	}
+
+	// enrolling again starts over unconfirmed
+	require.NoError(t, repo.StoreMFA(ctx, &entity.MFA{UserID: usr.ID, Secret: `secret-2`}))
+
+	mfa, err = repo.GetMFA(ctx, usr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, `secret-2`, mfa.Secret)
+	assert.False(t, mfa.Confirmed())
+	assert.Zero(t, mfa.LastUsedStep)
+
+	require.NoError(t, repo.SetRecoveryCodes(ctx, usr.ID, []string{`c1`, `c2`}))
+
+	ok, err = repo.UseRecoveryCode(ctx, usr.ID, `c1`)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = repo.UseRecoveryCode(ctx, usr.ID, `c1`)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, repo.SetRecoveryCodes(ctx, usr.ID, []string{`c3`}))
+
+	ok, err = repo.UseRecoveryCode(ctx, usr.ID, `c2`)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, repo.DeleteMFA(ctx, usr.ID))
+
+	mfa, err = repo.GetMFA(ctx, usr.ID)
+	require.NoError(t, err)
+	assert.Zero(t, mfa.UserID)
+
+	ok, err = repo.UseRecoveryCode(ctx, usr.ID, `c3`)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func testAPIKeys(t *testing.T, repo user.Repository) {
+	ctx := context.TODO()
+	usr := storeUser(t, repo, `keys@lmnlo.test`, `Menteng`)
+	expiresAt := time.Now().Add(time.Hour)
+
+	first := &entity.APIKey{
+		UserID:     usr.ID,
+		Name:       `ci`,
+		Prefix:     `p1`,
+		SecretHash: `s1`,
+		Scopes:     []string{entity.PermissionUserRead, entity.PermissionUserUpdate},
+	}
+	require.NoError(t, repo.StoreAPIKey(ctx, first))
+	assert.NotZero(t, first.ID)
+
+	second := &entity.APIKey{
+		UserID:     usr.ID,
+		Name:       `cron`,
+		Prefix:     `p2`,
+		SecretHash: `s2`,
+		Scopes:     []string{entity.PermissionUserRead},
+		ExpiresAt:  &expiresAt,
+	}
+	require.NoError(t, repo.StoreAPIKey(ctx, second))
+
+	err := repo.StoreAPIKey(ctx, &entity.APIKey{UserID: usr.ID, Name: `again`, Prefix: `p1`, SecretHash: `s3`})
+	assert.Equal(t, response.ErrAlreadyExist, err)
+
+	key, err := repo.GetAPIKeyByPrefix(ctx, `p1`)
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, key.ID)
+	assert.Equal(t, usr.ID, key.UserID)
+	assert.Equal(t, `s1`, key.SecretHash)
+	assert.Equal(t, first.Scopes, key.Scopes)
+	assert.Nil(t, key.ExpiresAt)
+	assert.Nil(t, key.LastUsedAt)
+
+	key, err = repo.GetAPIKeyByPrefix(ctx, `p2`)
+	require.NoError(t, err)
+	require.NotNil(t, key.ExpiresAt)
+	assert.WithinDuration(t, expiresAt, *key.ExpiresAt, time.Second)
+
+	key, err = repo.GetAPIKeyByPrefix(ctx, `missing`)
+	require.NoError(t, err)
+	assert.Zero(t, key.ID)
+
+	keys, err := repo.FetchAPIKeys(ctx, usr.ID)
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, second.ID, keys[0].ID)
+	assert.Equal(t, first.ID, keys[1].ID)
+
+	require.NoError(t, repo.TouchAPIKey(ctx, first.ID))
+
+	key, err = repo.GetAPIKeyByPrefix(ctx, `p1`)
+	require.NoError(t, err)
+	assert.NotNil(t, key.LastUsedAt)
+
+	ok, err := repo.DeleteAPIKey(ctx, usr.ID+1000, first.ID)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	ok, err = repo.DeleteAPIKey(ctx, usr.ID, first.ID)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	keys, err = repo.FetchAPIKeys(ctx, usr.ID)
+	require.NoError(t, err)
+	assert.Len(t, keys, 1)
+
+	require.NoError(t, repo.DeleteAPIKeysByUser(ctx, usr.ID))
+
+	keys, err = repo.FetchAPIKeys(ctx, usr.ID)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+func testIdentities(t *testing.T, repo user.Repository) {
+	ctx := context.TODO()
+	usr := storeUser(t, repo, `identity@lmnlo.test`, `Menteng`)
+
+	id, err := repo.GetIdentity(ctx, `github`, `42`)
+	require.NoError(t, err)
+	assert.Zero(t, id.ID)
+
+	stored := &entity.Identity{UserID: usr.ID, Provider: `github`, Subject: `42`, Email: usr.Email}
+	require.NoError(t, repo.StoreIdentity(ctx, stored))
+	assert.NotZero(t, stored.ID)
+
+	id, err = repo.GetIdentity(ctx, `github`, `42`)
+	require.NoError(t, err)
+	assert.Equal(t, stored.ID, id.ID)
+	assert.Equal(t, usr.ID, id.UserID)
+	assert.Equal(t, usr.Email, id.Email)
+
+	id, err = repo.GetIdentity(ctx, `google`, `42`)
+	require.NoError(t, err)
+	assert.Zero(t, id.ID)
+
+	state := &entity.OAuthState{
+		StateHash: `state`,
+		Provider:  `github`,
+		Verifier:  `verifier`,
+		Nonce:     `nonce`,
+		ExpiresAt: time.Now().Add(10 * time.Minute),
+	}
+	require.NoError(t, repo.StoreOAuthState(ctx, state))
+
+	st, err := repo.UseOAuthState(ctx, `state`)
+	require.NoError(t, err)
+	assert.Equal(t, state.Provider, st.Provider)
+	assert.Equal(t, state.Verifier, st.Verifier)
+	assert.Equal(t, state.Nonce, st.Nonce)
+
+	st, err = repo.UseOAuthState(ctx, `state`)
+	require.NoError(t, err)
+	assert.Empty(t, st.StateHash)
+
+	require.NoError(t, repo.StoreOAuthState(ctx, &entity.OAuthState{
+		StateHash: `expired`,
+		Provider:  `github`,
+		ExpiresAt: time.Now().Add(-time.Minute),
+	}))
+
+	st, err = repo.UseOAuthState(ctx, `expired`)
+	require.NoError(t, err)
+	assert.Empty(t, st.StateHash)
+}
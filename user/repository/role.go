@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	sq "github.com/elgris/sqrl"
+	"github.com/sirupsen/logrus"
+)
+
+func (m *userRepository) GetRoles(ctx context.Context, uid int64) ([]string, error) {
+	query := m.builder.Select(`role`)
+	query.From(`user_role`)
+	query.Where(`user_id = ?`, uid)
+	query.OrderBy(`role`)
+
+	sql, args, _ := query.ToSql()
+	rows, err := m.Conn.QueryContext(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return m.unmarshalStrings(rows)
+}
+
+func (m *userRepository) GetPermissions(ctx context.Context, uid int64) ([]string, error) {
+	query := m.builder.Select(`DISTINCT rp.permission`)
+	query.From(`user_role ur`)
+	query.Join(`role_permission rp ON rp.role = ur.role`)
+	query.Where(`ur.user_id = ?`, uid)
+	query.OrderBy(`rp.permission`)
+
+	sql, args, _ := query.ToSql()
+	rows, err := m.Conn.QueryContext(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return m.unmarshalStrings(rows)
+}
+
+func (m *userRepository) SetRoles(ctx context.Context, uid int64, roles []string) error {
+	trx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	query := m.builder.Delete(`user_role`).
+		Where(`user_id = ?`, uid)
+
+	sql, args, _ := query.ToSql()
+	stmt, err := trx.PrepareContext(ctx, sql)
+	if err != nil {
+		trx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	if _, err := stmt.ExecContext(ctx, args...); err != nil {
+		trx.Rollback()
+		return err
+	}
+
+	if err := m.insertRoles(ctx, trx, uid, roles); err != nil {
+		trx.Rollback()
+		return err
+	}
+
+	return trx.Commit()
+}
+
+func (m *userRepository) ExistRoles(ctx context.Context, roles []string) (bool, error) {
+	if len(roles) == 0 {
+		return true, nil
+	}
+
+	unique := make(map[string]bool, len(roles))
+	for _, r := range roles {
+		unique[r] = true
+	}
+
+	query := m.builder.Select(`COUNT(*)`)
+	query.From(`role`)
+	query.Where(sq.Eq{`name`: roles})
+
+	sql, args, _ := query.ToSql()
+
+	var count int
+	if err := m.Conn.QueryRowContext(ctx, sql, args...).Scan(&count); err != nil {
+		return false, err
+	}
+
+	return count == len(unique), nil
+}
+
+func (m *userRepository) insertRoles(ctx context.Context, trx *sql.Tx, uid int64, roles []string) error {
+	if len(roles) == 0 {
+		return nil
+	}
+
+	query := m.builder.Insert(`user_role`)
+	query.Columns(`user_id`, `role`)
+	for _, r := range roles {
+		query.Values(uid, r)
+	}
+
+	sql, args, _ := query.ToSql()
+	stmt, err := trx.PrepareContext(ctx, sql)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, args...)
+	return err
+}
+
+func (m *userRepository) unmarshalStrings(rows *sql.Rows) ([]string, error) {
+	results := []string{}
+
+	for rows.Next() {
+		var s string
+
+		if err := rows.Scan(&s); err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+
+		results = append(results, s)
+	}
+
+	return results, rows.Err()
+}
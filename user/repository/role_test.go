@@ -0,0 +1,206 @@
+package repository_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/andhikagama/lmnlo/models/entity"
+	userRepo "github.com/andhikagama/lmnlo/user/repository"
+)
+
+func TestStoreWithRoles(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`INSERT INTO user`).ExpectExec().WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectPrepare(`INSERT INTO user_role`).ExpectExec().WithArgs(1, entity.RoleUser).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		usr := mockUser
+		usr.Roles = []string{entity.RoleUser}
+
+		repo := userRepo.NewUserRepository(db)
+		err := repo.Store(context.TODO(), &usr)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("error-role", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`INSERT INTO user`).ExpectExec().WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectPrepare(`INSERT INTO user_role`).ExpectExec().WillReturnError(fmt.Errorf("Some error"))
+		mock.ExpectRollback()
+
+		usr := mockUser
+		usr.Roles = []string{entity.RoleUser}
+
+		repo := userRepo.NewUserRepository(db)
+		err := repo.Store(context.TODO(), &usr)
+
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestGetRoles(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{`role`}).AddRow(entity.RoleAdmin).AddRow(entity.RoleUser)
+
+		mock.ExpectQuery(`SELECT role FROM user_role WHERE user_id = \?`).WithArgs(1).WillReturnRows(rows)
+
+		repo := userRepo.NewUserRepository(db)
+		res, err := repo.GetRoles(context.TODO(), 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{entity.RoleAdmin, entity.RoleUser}, res)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("error", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT role FROM user_role`).WillReturnError(fmt.Errorf("Some error"))
+
+		repo := userRepo.NewUserRepository(db)
+		res, err := repo.GetRoles(context.TODO(), 1)
+
+		assert.Error(t, err)
+		assert.Nil(t, res)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestGetPermissions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{`permission`}).AddRow(entity.PermissionUserDelete)
+
+		mock.ExpectQuery(`SELECT DISTINCT rp.permission FROM user_role ur JOIN role_permission rp`).WithArgs(1).WillReturnRows(rows)
+
+		repo := userRepo.NewUserRepository(db)
+		res, err := repo.GetPermissions(context.TODO(), 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{entity.PermissionUserDelete}, res)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("success-no-data", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT DISTINCT rp.permission`).WillReturnRows(sqlmock.NewRows([]string{`permission`}))
+
+		repo := userRepo.NewUserRepository(db)
+		res, err := repo.GetPermissions(context.TODO(), 1)
+
+		assert.NoError(t, err)
+		assert.Len(t, res, 0)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestSetRoles(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`DELETE FROM user_role WHERE user_id = \?`).ExpectExec().WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectPrepare(`INSERT INTO user_role`).ExpectExec().WithArgs(1, entity.RoleAdmin, 1, entity.RoleUser).WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		repo := userRepo.NewUserRepository(db)
+		err := repo.SetRoles(context.TODO(), 1, []string{entity.RoleAdmin, entity.RoleUser})
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("success-clear", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`DELETE FROM user_role`).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		repo := userRepo.NewUserRepository(db)
+		err := repo.SetRoles(context.TODO(), 1, nil)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("error-insert", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`DELETE FROM user_role`).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectPrepare(`INSERT INTO user_role`).ExpectExec().WillReturnError(fmt.Errorf("Some error"))
+		mock.ExpectRollback()
+
+		repo := userRepo.NewUserRepository(db)
+		err := repo.SetRoles(context.TODO(), 1, []string{entity.RoleAdmin})
+
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestExistRoles(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT COUNT\(\*\) FROM role WHERE name IN \(\?,\?\)`).
+			WithArgs(entity.RoleAdmin, entity.RoleUser).
+			WillReturnRows(sqlmock.NewRows([]string{`count`}).AddRow(2))
+
+		repo := userRepo.NewUserRepository(db)
+		ok, err := repo.ExistRoles(context.TODO(), []string{entity.RoleAdmin, entity.RoleUser})
+
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT COUNT\(\*\) FROM role`).
+			WillReturnRows(sqlmock.NewRows([]string{`count`}).AddRow(1))
+
+		repo := userRepo.NewUserRepository(db)
+		ok, err := repo.ExistRoles(context.TODO(), []string{entity.RoleAdmin, `wizard`})
+
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
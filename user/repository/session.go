@@ -0,0 +1,225 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/andhikagama/lmnlo/models/entity"
+	sq "github.com/elgris/sqrl"
+	"github.com/sirupsen/logrus"
+)
+
+// _TouchInterval bounds how often last_used_time is written for a token,
+// so that a busy client does not cause a write on every request
+const _TouchInterval = time.Minute
+
+const _SessionColumns = `id, user_id, family_id, jti, token_hash, user_agent, ip, create_time, expire_time, last_used_time`
+
+// ReplaceToken swaps the access token of the active session of
+// sess.FamilyID for the one in sess
+func (m *userRepository) ReplaceToken(ctx context.Context, sess *entity.Session) (bool, error) {
+	query := m.builder.Update(`token`).
+		Set(`jti`, sess.TokenID).
+		Set(`token_hash`, sess.TokenHash).
+		Set(`expire_time`, m.at(sess.ExpiresAt)).
+		Set(`last_used_time`, m.now()).
+		Where(`family_id = ?`, sess.FamilyID).
+		Where(`revoke_time IS NULL`)
+
+	affected, err := m.exec(ctx, query)
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+func (m *userRepository) TouchToken(ctx context.Context, jti string) error {
+	now := m.now()
+
+	query := m.builder.Update(`token`).
+		Set(`last_used_time`, now).
+		Where(`jti = ?`, jti).
+		Where(`(last_used_time IS NULL OR last_used_time < ?)`, now.Add(-_TouchInterval))
+
+	_, err := m.exec(ctx, query)
+	return err
+}
+
+// GetSessionByToken return the active session whose access token has
+// tokenHash
+func (m *userRepository) GetSessionByToken(ctx context.Context, tokenHash string) (*entity.Session, error) {
+	query := m.builder.Select(_SessionColumns)
+	query.From(`token`)
+	query.Where(`token_hash = ?`, tokenHash)
+	query.Where(`revoke_time IS NULL`)
+	query.Where(`expire_time > ?`, m.now())
+
+	sql, args, _ := query.ToSql()
+	rows, err := m.Conn.QueryContext(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result, err := m.unmarshalSessions(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result) == 0 {
+		return new(entity.Session), nil
+	}
+
+	return result[0], nil
+}
+
+// FetchSessions lists the active sessions of uid
+func (m *userRepository) FetchSessions(ctx context.Context, uid int64) ([]*entity.Session, error) {
+	query := m.builder.Select(_SessionColumns)
+	query.From(`token`)
+	query.Where(`user_id = ?`, uid)
+	query.Where(`revoke_time IS NULL`)
+	query.Where(`expire_time > ?`, m.now())
+	query.OrderBy(`id DESC`)
+
+	sql, args, _ := query.ToSql()
+	rows, err := m.Conn.QueryContext(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return m.unmarshalSessions(rows)
+}
+
+func (m *userRepository) RevokeSession(ctx context.Context, id int64) (bool, error) {
+	query := m.builder.Update(`token`).
+		Set(`revoke_time`, m.now()).
+		Where(`id = ?`, id).
+		Where(`revoke_time IS NULL`)
+
+	affected, err := m.exec(ctx, query)
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+func (m *userRepository) RevokeSessionsByUser(ctx context.Context, uid int64) error {
+	query := m.builder.Update(`token`).
+		Set(`revoke_time`, m.now()).
+		Where(`user_id = ?`, uid).
+		Where(`revoke_time IS NULL`)
+
+	_, err := m.exec(ctx, query)
+	return err
+}
+
+// RevokeSessionFamily ends the session whose refresh tokens belong to
+// familyID
+func (m *userRepository) RevokeSessionFamily(ctx context.Context, familyID string) error {
+	query := m.builder.Update(`token`).
+		Set(`revoke_time`, m.now()).
+		Where(`family_id = ?`, familyID).
+		Where(`revoke_time IS NULL`)
+
+	_, err := m.exec(ctx, query)
+	return err
+}
+
+func (m *userRepository) RevokeOtherSessions(ctx context.Context, uid int64, keepID int64) error {
+	query := m.builder.Update(`token`).
+		Set(`revoke_time`, m.now()).
+		Where(`user_id = ?`, uid).
+		Where(`id <> ?`, keepID).
+		Where(`revoke_time IS NULL`)
+
+	_, err := m.exec(ctx, query)
+	return err
+}
+
+// PurgeTokens deletes sessions that expired or were revoked before before
+// and returns how many
+func (m *userRepository) PurgeTokens(ctx context.Context, before time.Time) (int64, error) {
+	query := m.builder.Delete(`token`).
+		Where(`(expire_time < ? OR revoke_time < ?)`, m.at(before), m.at(before))
+
+	return m.exec(ctx, query)
+}
+
+// execTx prepares and runs a write statement inside trx
+func execTx(ctx context.Context, trx *sql.Tx, query sq.Sqlizer) error {
+	sql, args, _ := query.ToSql()
+	stmt, err := trx.PrepareContext(ctx, sql)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, args...)
+	return err
+}
+
+// exec runs a single write statement in its own transaction and returns
+// the number of affected rows
+func (m *userRepository) exec(ctx context.Context, query sq.Sqlizer) (int64, error) {
+	trx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	sql, args, _ := query.ToSql()
+	stmt, err := trx.PrepareContext(ctx, sql)
+	if err != nil {
+		trx.Rollback()
+		return 0, err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, args...)
+	if err != nil {
+		trx.Rollback()
+		return 0, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		trx.Rollback()
+		return 0, err
+	}
+
+	return affected, trx.Commit()
+}
+
+func (m *userRepository) unmarshalSessions(rows *sql.Rows) ([]*entity.Session, error) {
+	results := []*entity.Session{}
+
+	for rows.Next() {
+		var sess entity.Session
+
+		err := rows.Scan(
+			&sess.ID,
+			&sess.UserID,
+			&sess.FamilyID,
+			&sess.TokenID,
+			&sess.TokenHash,
+			&sess.UserAgent,
+			&sess.IP,
+			&sess.CreatedAt,
+			&sess.ExpiresAt,
+			&sess.LastUsedAt,
+		)
+
+		if err != nil {
+			logrus.Error(err, sess.ID)
+			return nil, err
+		}
+
+		results = append(results, &sess)
+	}
+
+	return results, rows.Err()
+}
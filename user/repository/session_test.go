@@ -0,0 +1,402 @@
+package repository_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/andhikagama/lmnlo/models/entity"
+	userRepo "github.com/andhikagama/lmnlo/user/repository"
+)
+
+var sessionColumns = []string{
+	`id`, `user_id`, `family_id`, `jti`, `token_hash`, `user_agent`, `ip`, `create_time`, `expire_time`, `last_used_time`,
+}
+
+func TestInsertToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`INSERT INTO token`).ExpectExec().WillReturnResult(sqlmock.NewResult(5, 1))
+		mock.ExpectCommit()
+
+		sess := &entity.Session{UserID: 1, FamilyID: `family`, TokenID: `jti`, TokenHash: `hash`, ExpiresAt: time.Now().Add(time.Hour)}
+		repo := userRepo.NewUserRepository(db)
+		err := repo.InsertToken(context.TODO(), sess)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(5), sess.ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("error-exec", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`INSERT INTO token`).ExpectExec().WillReturnError(fmt.Errorf("Some error"))
+		mock.ExpectRollback()
+
+		repo := userRepo.NewUserRepository(db)
+		err := repo.InsertToken(context.TODO(), &entity.Session{UserID: 1})
+
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestValidateToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT 1 FROM token WHERE jti = \? AND token_hash = \? AND revoke_time IS NULL AND expire_time > \?`).
+			WithArgs(`jti`, `hash`, sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{`1`}).AddRow(1))
+
+		repo := userRepo.NewUserRepository(db)
+		ok, err := repo.ValidateToken(context.TODO(), `jti`, `hash`)
+
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("success-no-data", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT 1 FROM token`).WillReturnRows(sqlmock.NewRows([]string{`1`}))
+
+		repo := userRepo.NewUserRepository(db)
+		ok, err := repo.ValidateToken(context.TODO(), `jti`, `hash`)
+
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("error", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT 1 FROM token`).WillReturnError(fmt.Errorf("Some error"))
+
+		repo := userRepo.NewUserRepository(db)
+		ok, err := repo.ValidateToken(context.TODO(), `jti`, `hash`)
+
+		assert.Error(t, err)
+		assert.False(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestReplaceToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`UPDATE token SET jti = \?, token_hash = \?, expire_time = \?, last_used_time = \? WHERE family_id = \? AND revoke_time IS NULL`).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		repo := userRepo.NewUserRepository(db)
+		ok, err := repo.ReplaceToken(context.TODO(), &entity.Session{FamilyID: `family`, TokenID: `jti`, TokenHash: `hash`})
+
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("success-no-data", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`UPDATE token SET jti`).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		repo := userRepo.NewUserRepository(db)
+		ok, err := repo.ReplaceToken(context.TODO(), &entity.Session{FamilyID: `family`, TokenID: `jti`, TokenHash: `hash`})
+
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestTouchToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`UPDATE token SET last_used_time`).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		repo := userRepo.NewUserRepository(db)
+		err := repo.TouchToken(context.TODO(), `jti`)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("error-begin", func(t *testing.T) {
+		mock.ExpectBegin().WillReturnError(fmt.Errorf("Some error"))
+
+		repo := userRepo.NewUserRepository(db)
+		err := repo.TouchToken(context.TODO(), `jti`)
+
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestGetSessionByToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		rows := sqlmock.NewRows(sessionColumns).AddRow(
+			1, 1, `family`, `jti`, `hash`, `curl/7.64`, `127.0.0.1`, time.Now(), time.Now().Add(time.Hour), nil,
+		)
+
+		mock.ExpectQuery(`SELECT (.+) FROM token WHERE token_hash = \? AND revoke_time IS NULL AND expire_time > \?`).WithArgs(`hash`, sqlmock.AnyArg()).WillReturnRows(rows)
+
+		repo := userRepo.NewUserRepository(db)
+		res, err := repo.GetSessionByToken(context.TODO(), `hash`)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), res.ID)
+		assert.Equal(t, `family`, res.FamilyID)
+		assert.Nil(t, res.LastUsedAt)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("success-no-data", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT (.+) FROM token`).WillReturnRows(sqlmock.NewRows(sessionColumns))
+
+		repo := userRepo.NewUserRepository(db)
+		res, err := repo.GetSessionByToken(context.TODO(), `hash`)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), res.ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("error", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT (.+) FROM token`).WillReturnError(fmt.Errorf("Some error"))
+
+		repo := userRepo.NewUserRepository(db)
+		res, err := repo.GetSessionByToken(context.TODO(), `hash`)
+
+		assert.Error(t, err)
+		assert.Nil(t, res)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestFetchSessions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		rows := sqlmock.NewRows(sessionColumns).AddRow(
+			2, 1, `family-2`, `jti-2`, `hash-2`, `curl/7.64`, `127.0.0.1`, time.Now(), time.Now().Add(time.Hour), time.Now(),
+		).AddRow(
+			1, 1, `family-1`, `jti-1`, `hash-1`, `Mozilla/5.0`, `10.0.0.1`, time.Now(), time.Now().Add(time.Hour), nil,
+		)
+
+		mock.ExpectQuery(`SELECT (.+) FROM token WHERE user_id = \? AND revoke_time IS NULL AND expire_time > \?`).WithArgs(1, sqlmock.AnyArg()).WillReturnRows(rows)
+
+		repo := userRepo.NewUserRepository(db)
+		res, err := repo.FetchSessions(context.TODO(), 1)
+
+		assert.NoError(t, err)
+		assert.Len(t, res, 2)
+		assert.NotNil(t, res[0].LastUsedAt)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("error", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT (.+) FROM token`).WillReturnError(fmt.Errorf("Some error"))
+
+		repo := userRepo.NewUserRepository(db)
+		res, err := repo.FetchSessions(context.TODO(), 1)
+
+		assert.Error(t, err)
+		assert.Nil(t, res)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRevokeSession(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`UPDATE token SET revoke_time = \? WHERE id = \? AND revoke_time IS NULL`).ExpectExec().WithArgs(sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		repo := userRepo.NewUserRepository(db)
+		ok, err := repo.RevokeSession(context.TODO(), 1)
+
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("error-exec", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`UPDATE token`).ExpectExec().WillReturnError(fmt.Errorf("Some error"))
+		mock.ExpectRollback()
+
+		repo := userRepo.NewUserRepository(db)
+		ok, err := repo.RevokeSession(context.TODO(), 1)
+
+		assert.Error(t, err)
+		assert.False(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRevokeSessionsByUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`UPDATE token SET revoke_time = \? WHERE user_id = \? AND revoke_time IS NULL`).ExpectExec().WithArgs(sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectCommit()
+
+		repo := userRepo.NewUserRepository(db)
+		err := repo.RevokeSessionsByUser(context.TODO(), 1)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("error-prepare", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`UPDATE token`).WillReturnError(fmt.Errorf("Some error"))
+		mock.ExpectRollback()
+
+		repo := userRepo.NewUserRepository(db)
+		err := repo.RevokeSessionsByUser(context.TODO(), 1)
+
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRevokeSessionFamily(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare(`UPDATE token SET revoke_time = \? WHERE family_id = \? AND revoke_time IS NULL`).ExpectExec().WithArgs(sqlmock.AnyArg(), `family`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	repo := userRepo.NewUserRepository(db)
+	err = repo.RevokeSessionFamily(context.TODO(), `family`)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRevokeOtherSessions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`UPDATE token SET revoke_time = \? WHERE user_id = \? AND id <> \? AND revoke_time IS NULL`).ExpectExec().WithArgs(sqlmock.AnyArg(), 1, 7).WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		repo := userRepo.NewUserRepository(db)
+		err := repo.RevokeOtherSessions(context.TODO(), 1, 7)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPurgeTokens(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	before := time.Now()
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`DELETE FROM token WHERE \(expire_time < \? OR revoke_time < \?\)`).ExpectExec().WithArgs(before, before).WillReturnResult(sqlmock.NewResult(0, 4))
+		mock.ExpectCommit()
+
+		repo := userRepo.NewUserRepository(db)
+		n, err := repo.PurgeTokens(context.TODO(), before)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(4), n)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("error-begin", func(t *testing.T) {
+		mock.ExpectBegin().WillReturnError(fmt.Errorf("Some error"))
+
+		repo := userRepo.NewUserRepository(db)
+		_, err := repo.PurgeTokens(context.TODO(), before)
+
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
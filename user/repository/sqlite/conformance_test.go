@@ -0,0 +1,14 @@
+package sqlite_test
+
+import (
+	"testing"
+
+	"github.com/andhikagama/lmnlo/user"
+	"github.com/andhikagama/lmnlo/user/repository/repositorytest"
+)
+
+func TestConformance(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) user.Repository {
+		return newRepository(t, `:memory:`)
+	})
+}
@@ -0,0 +1,61 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"time"
+
+	"github.com/andhikagama/lmnlo/migration"
+	"github.com/andhikagama/lmnlo/user"
+	"github.com/andhikagama/lmnlo/user/repository"
+	sq "github.com/elgris/sqrl"
+	"github.com/mattn/go-sqlite3"
+)
+
+// DriverName is the database/sql driver registered by this package, SQLite
+// with the REGEXP operator the address filter needs
+const DriverName = `sqlite`
+
+func init() {
+	sql.Register(DriverName, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			return conn.RegisterFunc(`regexp`, regexp.MatchString, true)
+		},
+	})
+}
+
+// dialect is SQLite through DriverName. SQLite keeps times as text and
+// compares them as text, which only orders times of the same offset, so
+// its clock is UTC.
+var dialect = repository.Dialect{
+	Placeholder: sq.Question,
+	UserTable:   `user`,
+	AddressMatch: func(pattern string) sq.Sqlizer {
+		return sq.Expr(`address REGEXP ?`, `(?i)`+pattern)
+	},
+	Duplicate: func(err error) bool {
+		e, ok := err.(sqlite3.Error)
+		return ok && e.ExtendedCode == sqlite3.ErrConstraintUnique
+	},
+	Now: func() time.Time {
+		return time.Now().UTC()
+	},
+}
+
+// NewUserRepository return a user.Repository backed by SQLite, creating or
+// upgrading its schema first. Open Conn with DriverName and at most one
+// connection: SQLite takes one writer at a time and every connection to
+// :memory: is a database of its own.
+func NewUserRepository(Conn *sql.DB) (user.Repository, error) {
+	migrator, err := migration.NewMigrator(Conn, DriverName)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := migrator.Up(context.Background()); err != nil {
+		return nil, err
+	}
+
+	return repository.NewRepository(Conn, dialect), nil
+}
@@ -0,0 +1,71 @@
+package sqlite_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/andhikagama/lmnlo/user"
+	"github.com/andhikagama/lmnlo/user/repository/sqlite"
+)
+
+func openDB(t *testing.T, dsn string) *sql.DB {
+	db, err := sql.Open(sqlite.DriverName, dsn)
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+func newRepository(t *testing.T, dsn string) user.Repository {
+	repo, err := sqlite.NewUserRepository(openDB(t, dsn))
+	require.NoError(t, err)
+
+	return repo
+}
+
+func TestNewUserRepository(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), `lmnlo.db`)
+
+	repo := newRepository(t, dsn)
+	usr := &entity.User{Email: `andhika.gama@outlook.com`, Password: `aiueo`, Address: `Menteng`, Roles: []string{entity.RoleUser}}
+	require.NoError(t, repo.Store(context.TODO(), usr))
+
+	// Opening the file again keeps its rows
+	repo = newRepository(t, dsn)
+	res, err := repo.GetByEmail(context.TODO(), usr.Email)
+
+	assert.NoError(t, err)
+	assert.Equal(t, usr.ID, res.ID)
+}
+
+func TestTimeZones(t *testing.T) {
+	repo := newRepository(t, `:memory:`)
+	east := time.FixedZone(`WIB`, 7*60*60)
+	west := time.FixedZone(`PDT`, -7*60*60)
+
+	usr := &entity.User{Email: `andhika.gama@outlook.com`, Roles: []string{entity.RoleUser}}
+	require.NoError(t, repo.Store(context.TODO(), usr))
+
+	// Written as text in its own offset, the first would read as expired
+	// and the second as active
+	active := &entity.Session{UserID: usr.ID, FamilyID: `active`, TokenID: `active`, TokenHash: `active`, ExpiresAt: time.Now().Add(time.Minute).In(west)}
+	expired := &entity.Session{UserID: usr.ID, FamilyID: `expired`, TokenID: `expired`, TokenHash: `expired`, ExpiresAt: time.Now().Add(-time.Minute).In(east)}
+	require.NoError(t, repo.InsertToken(context.TODO(), active))
+	require.NoError(t, repo.InsertToken(context.TODO(), expired))
+
+	ok, err := repo.ValidateToken(context.TODO(), `active`, `active`)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = repo.ValidateToken(context.TODO(), `expired`, `expired`)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
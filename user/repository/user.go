@@ -0,0 +1,386 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/labstack/gommon/log"
+
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/andhikagama/lmnlo/models/filter"
+	"github.com/andhikagama/lmnlo/models/response"
+	sq "github.com/elgris/sqrl"
+	"github.com/sirupsen/logrus"
+)
+
+func (m *userRepository) Store(ctx context.Context, usr *entity.User) error {
+	trx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	query := m.builder.Insert(m.dialect.UserTable)
+	query.Columns(`email`, `password`, `address`, `create_time`)
+	query.Values(usr.Email, usr.Password, usr.Address, m.now())
+
+	usr.ID, err = m.insert(ctx, trx, query)
+	if err != nil {
+		trx.Rollback()
+		if m.dialect.Duplicate(err) {
+			return response.ErrAlreadyExist
+		}
+		return err
+	}
+
+	if err := m.insertRoles(ctx, trx, usr.ID, usr.Roles); err != nil {
+		trx.Rollback()
+		return err
+	}
+
+	return trx.Commit()
+}
+
+func (m *userRepository) Fetch(ctx context.Context, f *filter.User) ([]*entity.User, error) {
+	query := m.builder.Select(`id, email, address`)
+	query.From(m.dialect.UserTable)
+
+	if f.Email != `` {
+		query.Where(`email = ?`, f.Email)
+	}
+
+	if f.Address != `` {
+		query.Where(m.dialect.AddressMatch(f.Address))
+	}
+
+	if f.Cursor != 0 {
+		query.Where(`id  < ?`, f.Cursor)
+	}
+
+	query.OrderBy(`id DESC`).Limit(uint64(f.Num))
+
+	query.Where(`delete_time IS NULL`)
+
+	sql, args, _ := query.ToSql()
+	res, err := m.Conn.QueryContext(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+
+	result, err := m.unmarshal(res)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result) == 0 {
+		return make([]*entity.User, 0), nil
+	}
+
+	return result, err
+}
+
+func (m *userRepository) Update(ctx context.Context, usr *entity.User) (bool, error) {
+	trx, err := m.Conn.BeginTx(ctx, nil)
+
+	if err != nil {
+		return false, err
+	}
+
+	// A new address is unverified. MySQL assigns from left to right, so
+	// verified_at is compared against the email before it is written.
+	query := m.builder.Update(m.dialect.UserTable).
+		Set("verified_at", sq.Expr("CASE WHEN email = ? THEN verified_at END", usr.Email)).
+		Set("email", usr.Email).
+		Set("address", usr.Address).
+		Set("update_time", m.now()).
+		Where("id = ?", usr.ID)
+
+	sql, args, _ := query.ToSql()
+	stmt, err := trx.PrepareContext(ctx, sql)
+	if err != nil {
+		trx.Rollback()
+		return false, err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, args...)
+
+	if err != nil {
+		trx.Rollback()
+		if m.dialect.Duplicate(err) {
+			return false, response.ErrAlreadyExist
+		}
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		trx.Rollback()
+		return false, err
+	}
+
+	if affected != 1 {
+		trx.Rollback()
+		return false, nil
+	}
+
+	return true, trx.Commit()
+}
+
+func (m *userRepository) GetByID(ctx context.Context, id int64) (*entity.User, error) {
+	query := m.builder.Select(`id, email, address, verified_at`)
+	query.From(m.dialect.UserTable)
+	query.Where(`id = ?`, id)
+	query.Where(`delete_time IS NULL`)
+
+	sql, args, _ := query.ToSql()
+	rows, err := m.Conn.QueryContext(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	usr := new(entity.User)
+	for rows.Next() {
+		err := rows.Scan(
+			&usr.ID,
+			&usr.Email,
+			&usr.Address,
+			&usr.VerifiedAt,
+		)
+
+		if err != nil {
+			logrus.Error(err, usr.ID)
+			return nil, err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return usr, nil
+}
+
+func (m *userRepository) GetByEmail(ctx context.Context, email string) (*entity.User, error) {
+	query := m.builder.Select(`id, email, password, address, verified_at`)
+	query.From(m.dialect.UserTable)
+	query.Where(`email = ?`, email)
+	query.Where(`delete_time IS NULL`)
+	query.Limit(1)
+
+	sql, args, _ := query.ToSql()
+	rows, err := m.Conn.QueryContext(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	usr := new(entity.User)
+	for rows.Next() {
+		err := rows.Scan(
+			&usr.ID,
+			&usr.Email,
+			&usr.Password,
+			&usr.Address,
+			&usr.VerifiedAt,
+		)
+
+		if err != nil {
+			logrus.Error(err, usr.ID)
+			return nil, err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return usr, nil
+}
+
+func (m *userRepository) GetPassword(ctx context.Context, id int64) (string, error) {
+	query := m.builder.Select(`password`)
+	query.From(m.dialect.UserTable)
+	query.Where(`id = ?`, id)
+	query.Where(`delete_time IS NULL`)
+
+	sql, args, _ := query.ToSql()
+	rows, err := m.Conn.QueryContext(ctx, sql, args...)
+	if err != nil {
+		return ``, err
+	}
+	defer rows.Close()
+
+	var password string
+	for rows.Next() {
+		if err := rows.Scan(&password); err != nil {
+			return ``, err
+		}
+	}
+
+	return password, rows.Err()
+}
+
+func (m *userRepository) UpdatePassword(ctx context.Context, id int64, password string) (bool, error) {
+	trx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+
+	query := m.builder.Update(m.dialect.UserTable).
+		Set(`password`, password).
+		Set(`update_time`, m.now()).
+		Where(`id = ?`, id)
+
+	sql, args, _ := query.ToSql()
+	stmt, err := trx.PrepareContext(ctx, sql)
+	if err != nil {
+		trx.Rollback()
+		return false, err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, args...)
+	if err != nil {
+		trx.Rollback()
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		trx.Rollback()
+		return false, err
+	}
+
+	if affected != 1 {
+		trx.Rollback()
+		return false, nil
+	}
+
+	return true, trx.Commit()
+}
+
+func (m *userRepository) Delete(ctx context.Context, id int64) (bool, error) {
+	trx, err := m.Conn.BeginTx(ctx, nil)
+
+	if err != nil {
+		return false, err
+	}
+
+	query := m.builder.Update(m.dialect.UserTable).
+		Set("delete_time", m.now()).
+		Where("id = ?", id)
+
+	sql, args, _ := query.ToSql()
+
+	stmt, err := trx.PrepareContext(ctx, sql)
+	if err != nil {
+		trx.Rollback()
+		return false, err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, args...)
+
+	if err != nil {
+		trx.Rollback()
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		trx.Rollback()
+		return false, err
+	}
+
+	if affected != 1 {
+		trx.Rollback()
+		return false, nil
+	}
+
+	return true, trx.Commit()
+}
+
+func (m *userRepository) InsertToken(ctx context.Context, sess *entity.Session) error {
+	trx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	sess.CreatedAt = m.now()
+
+	query := m.builder.Insert("token")
+	query.Columns("user_id", "family_id", "jti", "token_hash", "user_agent", "ip", "create_time", "expire_time")
+	query.Values(sess.UserID, sess.FamilyID, sess.TokenID, sess.TokenHash, sess.UserAgent, sess.IP, sess.CreatedAt, m.at(sess.ExpiresAt))
+
+	sess.ID, err = m.insert(ctx, trx, query)
+	if err != nil {
+		trx.Rollback()
+		return err
+	}
+
+	return trx.Commit()
+}
+
+// ValidateToken reports whether the session of jti is active and was
+// issued the token with tokenHash
+func (m *userRepository) ValidateToken(ctx context.Context, jti string, tokenHash string) (bool, error) {
+	query := m.builder.Select(`1`)
+	query.From(`token`)
+	query.Where(`jti = ?`, jti)
+	query.Where(`token_hash = ?`, tokenHash)
+	query.Where(`revoke_time IS NULL`)
+	query.Where(`expire_time > ?`, m.now())
+
+	sql, args, _ := query.ToSql()
+
+	rows, err := m.Conn.QueryContext(ctx, sql, args...)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	var res int64
+
+	for rows.Next() {
+
+		err := rows.Scan(
+			&res,
+		)
+
+		if err != nil {
+			log.Error(err, res)
+			return false, err
+		}
+	}
+
+	if res == int64(0) {
+		return false, rows.Err()
+	}
+
+	return true, nil
+}
+
+func (m *userRepository) unmarshal(rows *sql.Rows) ([]*entity.User, error) {
+	results := []*entity.User{}
+
+	for rows.Next() {
+		var usr entity.User
+
+		err := rows.Scan(
+			&usr.ID,
+			&usr.Email,
+			&usr.Address,
+		)
+
+		if err != nil {
+			logrus.Error(err, usr.ID)
+			return results, err
+		}
+
+		results = append(results, &usr)
+	}
+
+	return results, nil
+}
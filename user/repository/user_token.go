@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/andhikagama/lmnlo/models/entity"
+)
+
+func (m *userRepository) StoreUserToken(ctx context.Context, ut *entity.UserToken) error {
+	ut.CreatedAt = m.now()
+
+	trx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	query := m.builder.Insert(`user_token`)
+	query.Columns(`user_id`, `purpose`, `token_hash`, `expire_time`, `create_time`)
+	query.Values(ut.UserID, ut.Purpose, ut.TokenHash, m.at(ut.ExpiresAt), ut.CreatedAt)
+
+	ut.ID, err = m.insert(ctx, trx, query)
+	if err != nil {
+		trx.Rollback()
+		return err
+	}
+
+	return trx.Commit()
+}
+
+// UseUserToken marks the token used. It reports false when the token does
+// not exist, belongs to another user or purpose, has expired or was used
+// already, so only one of several concurrent attempts can succeed.
+func (m *userRepository) UseUserToken(ctx context.Context, uid int64, purpose string, tokenHash string) (bool, error) {
+	now := m.now()
+
+	query := m.builder.Update(`user_token`).
+		Set(`use_time`, now).
+		Where(`token_hash = ?`, tokenHash).
+		Where(`user_id = ?`, uid).
+		Where(`purpose = ?`, purpose).
+		Where(`use_time IS NULL`).
+		Where(`expire_time > ?`, now)
+
+	affected, err := m.exec(ctx, query)
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+func (m *userRepository) InvalidateUserTokens(ctx context.Context, uid int64, purpose string) error {
+	query := m.builder.Update(`user_token`).
+		Set(`use_time`, m.now()).
+		Where(`user_id = ?`, uid).
+		Where(`purpose = ?`, purpose).
+		Where(`use_time IS NULL`)
+
+	_, err := m.exec(ctx, query)
+	return err
+}
+
+// SetVerified marks email verified while it is still the address of uid
+func (m *userRepository) SetVerified(ctx context.Context, uid int64, email string) (bool, error) {
+	now := m.now()
+
+	query := m.builder.Update(m.dialect.UserTable).
+		Set(`verified_at`, now).
+		Set(`update_time`, now).
+		Where(`id = ?`, uid).
+		Where(`email = ?`, email).
+		Where(`verified_at IS NULL`)
+
+	affected, err := m.exec(ctx, query)
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+func (m *userRepository) CountUserTokens(ctx context.Context, uid int64, purpose string, since time.Time) (int64, error) {
+	query := m.builder.Select(`COUNT(*)`)
+	query.From(`user_token`)
+	query.Where(`user_id = ?`, uid)
+	query.Where(`purpose = ?`, purpose)
+	query.Where(`create_time >= ?`, m.at(since))
+
+	sql, args, _ := query.ToSql()
+
+	var count int64
+	if err := m.Conn.QueryRowContext(ctx, sql, args...).Scan(&count); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
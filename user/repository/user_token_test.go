@@ -0,0 +1,192 @@
+package repository_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/andhikagama/lmnlo/models/entity"
+	userRepo "github.com/andhikagama/lmnlo/user/repository"
+)
+
+func TestStoreUserToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`INSERT INTO user_token`).ExpectExec().WillReturnResult(sqlmock.NewResult(3, 1))
+		mock.ExpectCommit()
+
+		ut := &entity.UserToken{
+			UserID:    1,
+			Purpose:   entity.TokenPurposeVerifyEmail,
+			TokenHash: `hash`,
+			ExpiresAt: time.Now().Add(time.Hour),
+		}
+		repo := userRepo.NewUserRepository(db)
+		err := repo.StoreUserToken(context.TODO(), ut)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), ut.ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("error-exec", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`INSERT INTO user_token`).ExpectExec().WillReturnError(fmt.Errorf("Some error"))
+		mock.ExpectRollback()
+
+		repo := userRepo.NewUserRepository(db)
+		err := repo.StoreUserToken(context.TODO(), &entity.UserToken{UserID: 1})
+
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestUseUserToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`UPDATE user_token SET use_time = \? WHERE token_hash = \? AND user_id = \? AND purpose = \? AND use_time IS NULL AND expire_time > \?`).
+			ExpectExec().
+			WithArgs(sqlmock.AnyArg(), `hash`, 1, entity.TokenPurposeVerifyEmail, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		repo := userRepo.NewUserRepository(db)
+		ok, err := repo.UseUserToken(context.TODO(), 1, entity.TokenPurposeVerifyEmail, `hash`)
+
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("spent", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`UPDATE user_token`).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		repo := userRepo.NewUserRepository(db)
+		ok, err := repo.UseUserToken(context.TODO(), 1, entity.TokenPurposeVerifyEmail, `hash`)
+
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestInvalidateUserTokens(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`UPDATE user_token SET use_time = \? WHERE user_id = \? AND purpose = \? AND use_time IS NULL`).
+			ExpectExec().
+			WithArgs(sqlmock.AnyArg(), 1, entity.TokenPurposeVerifyEmail).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		repo := userRepo.NewUserRepository(db)
+		err := repo.InvalidateUserTokens(context.TODO(), 1, entity.TokenPurposeVerifyEmail)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestSetVerified(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`UPDATE user SET verified_at = \?, update_time = \? WHERE id = \? AND email = \? AND verified_at IS NULL`).
+			ExpectExec().
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		repo := userRepo.NewUserRepository(db)
+		ok, err := repo.SetVerified(context.TODO(), 1, `a@lmnlo.test`)
+
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("already-verified", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(`UPDATE user SET verified_at`).ExpectExec().WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		repo := userRepo.NewUserRepository(db)
+		ok, err := repo.SetVerified(context.TODO(), 1, `a@lmnlo.test`)
+
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestCountUserTokens(t *testing.T) {
+	db, mock, err := sqlmock.New()
+
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	defer db.Close()
+
+	since := time.Now().Add(-time.Hour)
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT COUNT\(\*\) FROM user_token WHERE user_id = \? AND purpose = \? AND create_time >= \?`).
+			WithArgs(1, entity.TokenPurposeResetPassword, since).
+			WillReturnRows(sqlmock.NewRows([]string{`count`}).AddRow(2))
+
+		repo := userRepo.NewUserRepository(db)
+		count, err := repo.CountUserTokens(context.TODO(), 1, entity.TokenPurposeResetPassword, since)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), count)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("error", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT COUNT\(\*\) FROM user_token`).WillReturnError(fmt.Errorf("Some error"))
+
+		repo := userRepo.NewUserRepository(db)
+		_, err := repo.CountUserTokens(context.TODO(), 1, entity.TokenPurposeResetPassword, since)
+
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,152 @@
+package usecase
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/andhikagama/lmnlo/helper"
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/andhikagama/lmnlo/models/response"
+)
+
+// _APIKeyAttempts bounds how often CreateAPIKey draws a key whose prefix
+// is taken
+const _APIKeyAttempts = 3
+
+// CreateAPIKey issues a key for usr, the user of the current request. Every
+// scope has to be a permission usr holds right now. The full key is set on
+// key.Key and cannot be retrieved afterwards.
+func (u *userUsecase) CreateAPIKey(ctx context.Context, usr *entity.User, key *entity.APIKey, o *entity.Origin) error {
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+
+	key.Name = strings.TrimSpace(key.Name)
+	if key.Name == `` || len(key.Scopes) == 0 {
+		return response.ErrBadRequest
+	}
+
+	if key.ExpiresAt != nil && !key.ExpiresAt.After(time.Now()) {
+		return response.ErrBadRequest
+	}
+
+	for _, scope := range key.Scopes {
+		if !usr.HasPermission(scope) {
+			return response.ErrForbidden
+		}
+	}
+
+	key.UserID = usr.ID
+	key.LastUsedAt = nil
+
+	// Prefixes are unique, a colliding one is drawn again
+	for attempt := 1; ; attempt++ {
+		raw, prefix, secret, err := helper.GenerateAPIKey()
+		if err != nil {
+			return err
+		}
+
+		key.Prefix = prefix
+		key.SecretHash = helper.HashToken(secret)
+
+		err = u.userRepo.StoreAPIKey(ctx, key)
+		if err == response.ErrAlreadyExist && attempt < _APIKeyAttempts {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		u.record(ctx, o, entity.AuditUserCreateAPIKey, usr.ID, nil, key)
+		key.Key = raw
+		return nil
+	}
+}
+
+// FetchAPIKeys ...
+func (u *userUsecase) FetchAPIKeys(ctx context.Context, uid int64) ([]*entity.APIKey, error) {
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+
+	return u.userRepo.FetchAPIKeys(ctx, uid)
+}
+
+// RevokeAPIKey deletes a key of uid, keys of other users are not found
+func (u *userUsecase) RevokeAPIKey(ctx context.Context, uid int64, id int64, o *entity.Origin) error {
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+
+	ok, err := u.userRepo.DeleteAPIKey(ctx, uid, id)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return response.ErrNotFound
+	}
+
+	u.record(ctx, o, entity.AuditUserRevokeAPIKey, uid, map[string]int64{`id`: id}, nil)
+	return nil
+}
+
+// AuthenticateAPIKey return the owner of key as the user of a request. The
+// owner's permissions are narrowed to the scopes of the key and roles are
+// left out, so that a key never passes a role check such as admin.
+func (u *userUsecase) AuthenticateAPIKey(ctx context.Context, raw string) (*entity.User, error) {
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+
+	prefix, secret, ok := helper.SplitAPIKey(raw)
+	if !ok {
+		return nil, response.ErrUnAuthorized
+	}
+
+	key, err := u.userRepo.GetAPIKeyByPrefix(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	if key.ID == 0 || key.Expired(time.Now()) {
+		return nil, response.ErrUnAuthorized
+	}
+
+	hash := helper.HashToken(secret)
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(key.SecretHash)) != 1 {
+		return nil, response.ErrUnAuthorized
+	}
+
+	usr, err := u.userRepo.GetByID(ctx, key.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if usr.ID == 0 {
+		return nil, response.ErrUnAuthorized
+	}
+
+	// Permissions the owner lost since the key was created are not granted
+	// through the key either
+	if err := u.loadAuthorization(ctx, usr); err != nil {
+		return nil, err
+	}
+
+	permissions := []string{}
+	for _, p := range usr.Permissions {
+		if containsString(key.Scopes, p) {
+			permissions = append(permissions, p)
+		}
+	}
+
+	usr.Roles = nil
+	usr.Permissions = permissions
+
+	if err := u.userRepo.TouchAPIKey(ctx, key.ID); err != nil {
+		log.Error(err)
+	}
+
+	return usr, nil
+}
@@ -0,0 +1,217 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/andhikagama/lmnlo/helper"
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/andhikagama/lmnlo/models/response"
+	"github.com/andhikagama/lmnlo/user/mocks"
+	"github.com/andhikagama/lmnlo/user/usecase"
+)
+
+func TestCreateAPIKey(t *testing.T) {
+	usr := &entity.User{ID: 1, Permissions: []string{entity.PermissionUserRead}}
+	past := time.Now().Add(-time.Hour)
+
+	t.Run("success", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("StoreAPIKey", mock.Anything, mock.MatchedBy(func(key *entity.APIKey) bool {
+			return key.UserID == 1 && key.Prefix != `` && key.SecretHash != `` && key.Key == ``
+		})).Return(nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		key := &entity.APIKey{Name: `batch`, Scopes: []string{entity.PermissionUserRead}}
+		err := u.CreateAPIKey(context.TODO(), usr, key, mockOrigin)
+
+		assert.NoError(t, err)
+		prefix, secret, ok := helper.SplitAPIKey(key.Key)
+		assert.True(t, ok)
+		assert.Equal(t, key.Prefix, prefix)
+		assert.Equal(t, helper.HashToken(secret), key.SecretHash)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("taken-prefix", func(t *testing.T) {
+		prefixes := make([]string, 0)
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("StoreAPIKey", mock.Anything, mock.AnythingOfType("*entity.APIKey")).Run(func(args mock.Arguments) {
+			prefixes = append(prefixes, args.Get(1).(*entity.APIKey).Prefix)
+		}).Return(response.ErrAlreadyExist).Once()
+		mockUserRepo.On("StoreAPIKey", mock.Anything, mock.AnythingOfType("*entity.APIKey")).Run(func(args mock.Arguments) {
+			prefixes = append(prefixes, args.Get(1).(*entity.APIKey).Prefix)
+		}).Return(nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		key := &entity.APIKey{Name: `batch`, Scopes: []string{entity.PermissionUserRead}}
+		err := u.CreateAPIKey(context.TODO(), usr, key, mockOrigin)
+
+		assert.NoError(t, err)
+		assert.Len(t, prefixes, 2)
+		assert.NotEqual(t, prefixes[0], prefixes[1])
+		prefix, _, _ := helper.SplitAPIKey(key.Key)
+		assert.Equal(t, prefixes[1], prefix)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("taken-prefix-exhausted", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("StoreAPIKey", mock.Anything, mock.AnythingOfType("*entity.APIKey")).Return(response.ErrAlreadyExist).Times(3)
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		key := &entity.APIKey{Name: `batch`, Scopes: []string{entity.PermissionUserRead}}
+		err := u.CreateAPIKey(context.TODO(), usr, key, mockOrigin)
+
+		assert.Equal(t, response.ErrAlreadyExist, err)
+		assert.Empty(t, key.Key)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	cases := []struct {
+		name string
+		key  *entity.APIKey
+		err  error
+	}{
+		{`no-name`, &entity.APIKey{Scopes: []string{entity.PermissionUserRead}}, response.ErrBadRequest},
+		{`no-scopes`, &entity.APIKey{Name: `batch`}, response.ErrBadRequest},
+		{`expired`, &entity.APIKey{Name: `batch`, Scopes: []string{entity.PermissionUserRead}, ExpiresAt: &past}, response.ErrBadRequest},
+		{`scope-not-held`, &entity.APIKey{Name: `batch`, Scopes: []string{entity.PermissionUserDelete}}, response.ErrForbidden},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockUserRepo := new(mocks.Repository)
+			u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+			err := u.CreateAPIKey(context.TODO(), usr, tc.key, mockOrigin)
+
+			assert.Equal(t, tc.err, err)
+			mockUserRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("DeleteAPIKey", mock.Anything, int64(1), int64(7)).Return(true, nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		assert.NoError(t, u.RevokeAPIKey(context.TODO(), 1, 7, mockOrigin))
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("not-found", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("DeleteAPIKey", mock.Anything, int64(1), int64(7)).Return(false, nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		assert.Equal(t, response.ErrNotFound, u.RevokeAPIKey(context.TODO(), 1, 7, mockOrigin))
+		mockUserRepo.AssertExpectations(t)
+	})
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	raw, prefix, secret, _ := helper.GenerateAPIKey()
+	storedKey := func() *entity.APIKey {
+		return &entity.APIKey{
+			ID:         7,
+			UserID:     1,
+			Prefix:     prefix,
+			SecretHash: helper.HashToken(secret),
+			Scopes:     []string{entity.PermissionUserRead, entity.PermissionUserDelete},
+		}
+	}
+
+	t.Run("success", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("GetAPIKeyByPrefix", mock.Anything, prefix).Return(storedKey(), nil).Once()
+		mockUserRepo.On("GetByID", mock.Anything, int64(1)).Return(&entity.User{ID: 1, Email: mockUser.Email}, nil).Once()
+		mockUserRepo.On("GetRoles", mock.Anything, int64(1)).Return([]string{entity.RoleUser}, nil).Once()
+		mockUserRepo.On("GetPermissions", mock.Anything, int64(1)).Return([]string{entity.PermissionUserRead, entity.PermissionUserUpdate}, nil).Once()
+		mockUserRepo.On("TouchAPIKey", mock.Anything, int64(7)).Return(nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		usr, err := u.AuthenticateAPIKey(context.TODO(), raw)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), usr.ID)
+		assert.Nil(t, usr.Roles)
+		// user:update is not a scope, user:delete is no longer held
+		assert.Equal(t, []string{entity.PermissionUserRead}, usr.Permissions)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("touch-error", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("GetAPIKeyByPrefix", mock.Anything, prefix).Return(storedKey(), nil).Once()
+		mockUserRepo.On("GetByID", mock.Anything, int64(1)).Return(&entity.User{ID: 1}, nil).Once()
+		mockUserRepo.On("GetRoles", mock.Anything, int64(1)).Return([]string{entity.RoleUser}, nil).Once()
+		mockUserRepo.On("GetPermissions", mock.Anything, int64(1)).Return([]string{}, nil).Once()
+		mockUserRepo.On("TouchAPIKey", mock.Anything, int64(7)).Return(errors.New(`Unexpected Error`)).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		usr, err := u.AuthenticateAPIKey(context.TODO(), raw)
+
+		assert.NoError(t, err)
+		assert.NotNil(t, usr)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		usr, err := u.AuthenticateAPIKey(context.TODO(), `not-a-key`)
+
+		assert.Equal(t, response.ErrUnAuthorized, err)
+		assert.Nil(t, usr)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("GetAPIKeyByPrefix", mock.Anything, prefix).Return(new(entity.APIKey), nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		usr, err := u.AuthenticateAPIKey(context.TODO(), raw)
+
+		assert.Equal(t, response.ErrUnAuthorized, err)
+		assert.Nil(t, usr)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("wrong-secret", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("GetAPIKeyByPrefix", mock.Anything, prefix).Return(storedKey(), nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		usr, err := u.AuthenticateAPIKey(context.TODO(), helper.APIKeyTag+`_`+prefix+`_WRONG`)
+
+		assert.Equal(t, response.ErrUnAuthorized, err)
+		assert.Nil(t, usr)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		past := time.Now().Add(-time.Minute)
+		key := storedKey()
+		key.ExpiresAt = &past
+
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("GetAPIKeyByPrefix", mock.Anything, prefix).Return(key, nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		usr, err := u.AuthenticateAPIKey(context.TODO(), raw)
+
+		assert.Equal(t, response.ErrUnAuthorized, err)
+		assert.Nil(t, usr)
+		mockUserRepo.AssertExpectations(t)
+	})
+}
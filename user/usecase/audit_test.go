@@ -0,0 +1,362 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	auditMocks "github.com/andhikagama/lmnlo/audit/mocks"
+	"github.com/andhikagama/lmnlo/helper"
+	mailerMocks "github.com/andhikagama/lmnlo/mailer/mocks"
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/andhikagama/lmnlo/oidc/oidctest"
+	"github.com/andhikagama/lmnlo/user/mocks"
+	"github.com/andhikagama/lmnlo/user/usecase"
+)
+
+func TestAudit(t *testing.T) {
+	t.Run("update", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("GetByID", mock.Anything, int64(1)).Return(&entity.User{ID: 1, Email: `old@lmnlo.io`, Address: `Menteng`}, nil).Once()
+		mockUserRepo.On("Update", mock.Anything, mock.AnythingOfType("*entity.User")).Return(true, nil).Once()
+		mockAuditor := new(auditMocks.Usecase)
+		mockAuditor.On("Record", mock.Anything, mockOrigin, entity.AuditUserUpdate, int64(1), mock.MatchedBy(func(before *entity.User) bool {
+			return before.Email == `old@lmnlo.io`
+		}), mock.MatchedBy(func(after *entity.User) bool {
+			return after.Email == `new@lmnlo.io` && after.Address == `Menteng`
+		})).Return(nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAuditor, mockOptions)
+
+		err := u.Update(context.TODO(), &entity.User{ID: 1, Email: `new@lmnlo.io`, Address: `Menteng`}, mockOrigin)
+
+		assert.NoError(t, err)
+		mockUserRepo.AssertExpectations(t)
+		mockAuditor.AssertExpectations(t)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("Delete", mock.Anything, int64(1)).Return(true, nil).Once()
+		mockUserRepo.On("RevokeSessionsByUser", mock.Anything, int64(1)).Return(nil).Once()
+		mockUserRepo.On("RevokeRefreshTokensByUser", mock.Anything, int64(1)).Return(nil).Once()
+		mockUserRepo.On("DeleteAPIKeysByUser", mock.Anything, int64(1)).Return(nil).Once()
+		mockAuditor := new(auditMocks.Usecase)
+		mockAuditor.On("Record", mock.Anything, mockOrigin, entity.AuditUserDelete, int64(1), nil, nil).Return(nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAuditor, mockOptions)
+
+		err := u.Delete(context.TODO(), 1, mockOrigin)
+
+		assert.NoError(t, err)
+		mockAuditor.AssertExpectations(t)
+	})
+
+	t.Run("patch", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("GetByID", mock.Anything, int64(1)).Return(&entity.User{ID: 1, Address: `Menteng`}, nil).Once()
+		mockUserRepo.On("Update", mock.Anything, mock.AnythingOfType("*entity.User")).Return(true, nil).Once()
+		mockAuditor := new(auditMocks.Usecase)
+		mockAuditor.On("Record", mock.Anything, mockOrigin, entity.AuditUserUpdate, int64(1), mock.AnythingOfType("*entity.User"), mock.MatchedBy(func(after *entity.User) bool {
+			return after.Address == `Kemang`
+		})).Return(nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAuditor, mockOptions)
+
+		_, err := u.PartialUpdate(context.TODO(), 1, []byte(`[{"op":"replace","path":"/address","value":"Kemang"}]`), mockOrigin)
+
+		assert.NoError(t, err)
+		mockAuditor.AssertExpectations(t)
+	})
+
+	t.Run("login-failed", func(t *testing.T) {
+		hashedPass, _ := mockHasher.Hash(`aiueo`)
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("GetByEmail", mock.Anything, mockUser.Email).Return(&entity.User{ID: 1, Email: mockUser.Email, Password: hashedPass}, nil).Once()
+		mockAuditor := new(auditMocks.Usecase)
+		mockAuditor.On("Record", mock.Anything, mockOrigin, entity.AuditUserLoginFailed, int64(1), nil, nil).Return(nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAuditor, mockOptions)
+
+		_, err := u.Login(context.TODO(), &entity.User{Email: mockUser.Email, Password: `wrong`}, new(entity.Session), mockOrigin)
+
+		assert.Error(t, err)
+		mockAuditor.AssertExpectations(t)
+	})
+
+	t.Run("login", func(t *testing.T) {
+		hashedPass, _ := mockHasher.Hash(`aiueo`)
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("GetByEmail", mock.Anything, mockUser.Email).Return(&entity.User{ID: 1, Email: mockUser.Email, Password: hashedPass}, nil).Once()
+		mockUserRepo.On("GetMFA", mock.Anything, int64(1)).Return(new(entity.MFA), nil).Once()
+		mockUserRepo.On("GetRoles", mock.Anything, int64(1)).Return([]string{entity.RoleUser}, nil).Once()
+		mockUserRepo.On("GetPermissions", mock.Anything, int64(1)).Return([]string{}, nil).Once()
+		mockUserRepo.On("InsertToken", mock.Anything, mock.AnythingOfType("*entity.Session")).Return(nil).Once()
+		mockUserRepo.On("StoreRefreshToken", mock.Anything, mock.AnythingOfType("*entity.RefreshToken")).Return(nil).Once()
+		mockUserRepo.On("Update", mock.Anything, mock.AnythingOfType("*entity.User")).Return(true, nil).Once()
+		mockAuditor := new(auditMocks.Usecase)
+		mockAuditor.On("Record", mock.Anything, mock.MatchedBy(func(o *entity.Origin) bool {
+			return o.ActorID == 1 && o.RequestID == mockOrigin.RequestID
+		}), entity.AuditUserLogin, int64(1), nil, nil).Return(nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAuditor, mockOptions)
+
+		_, err := u.Login(context.TODO(), &entity.User{Email: mockUser.Email, Password: `aiueo`}, new(entity.Session), mockOrigin)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), mockOrigin.ActorID)
+		mockAuditor.AssertExpectations(t)
+	})
+
+	t.Run("register", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("Fetch", mock.Anything, mock.AnythingOfType("*filter.User")).Return(make([]*entity.User, 0), nil).Once()
+		mockUserRepo.On("Store", mock.Anything, mock.AnythingOfType("*entity.User")).Return(nil).Once()
+		mockUserRepo.On("StoreUserToken", mock.Anything, mock.AnythingOfType("*entity.UserToken")).Return(nil).Once()
+		mockMailer := new(mailerMocks.Mailer)
+		mockMailer.On("Send", mock.AnythingOfType("*mailer.Message")).Return(nil).Once()
+		mockAuditor := new(auditMocks.Usecase)
+		mockAuditor.On("Record", mock.Anything, mockOrigin, entity.AuditUserRegister, mock.AnythingOfType("int64"), nil, mock.MatchedBy(func(after *entity.User) bool {
+			return after.Email == `new@lmnlo.io`
+		})).Return(nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAuditor, mockOptions)
+
+		err := u.Register(context.TODO(), &entity.User{Email: `new@lmnlo.io`, Password: `aiueo`}, mockOrigin)
+
+		assert.NoError(t, err)
+		mockAuditor.AssertExpectations(t)
+	})
+
+	t.Run("impersonate", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("GetByID", mock.Anything, int64(1)).Return(&entity.User{ID: 1}, nil).Once()
+		mockUserRepo.On("GetRoles", mock.Anything, int64(1)).Return([]string{entity.RoleUser}, nil).Once()
+		mockUserRepo.On("GetPermissions", mock.Anything, int64(1)).Return([]string{}, nil).Once()
+		mockUserRepo.On("InsertToken", mock.Anything, mock.AnythingOfType("*entity.Session")).Return(nil).Once()
+		mockAuditor := new(auditMocks.Usecase)
+		mockAuditor.On("Record", mock.Anything, mockOrigin, entity.AuditUserImpersonate, int64(1), nil, nil).Return(nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAuditor, mockOptions)
+
+		_, err := u.Impersonate(context.TODO(), mockSupport, 1, new(entity.Session), mockOrigin)
+
+		assert.NoError(t, err)
+		mockAuditor.AssertExpectations(t)
+	})
+
+	t.Run("login-mfa", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		challenge := challengeToken(t, mockUserRepo)
+
+		mockUserRepo.On("GetMFA", mock.Anything, int64(1)).Return(confirmedMFA(), nil).Once()
+		mockUserRepo.On("UseMFAStep", mock.Anything, int64(1), mock.AnythingOfType("int64")).Return(true, nil).Once()
+		mockUserRepo.On("UseUserToken", mock.Anything, int64(1), entity.TokenPurposeMFAChallenge, helper.HashToken(challenge)).Return(true, nil).Once()
+		mockUserRepo.On("GetByID", mock.Anything, int64(1)).Return(&entity.User{ID: 1, Email: mockUser.Email}, nil).Once()
+		mockUserRepo.On("GetRoles", mock.Anything, int64(1)).Return([]string{entity.RoleUser}, nil).Once()
+		mockUserRepo.On("GetPermissions", mock.Anything, int64(1)).Return([]string{}, nil).Once()
+		mockUserRepo.On("InsertToken", mock.Anything, mock.AnythingOfType("*entity.Session")).Return(nil).Once()
+		mockUserRepo.On("StoreRefreshToken", mock.Anything, mock.AnythingOfType("*entity.RefreshToken")).Return(nil).Once()
+		mockUserRepo.On("Update", mock.Anything, mock.AnythingOfType("*entity.User")).Return(true, nil).Once()
+		mockAuditor := new(auditMocks.Usecase)
+		mockAuditor.On("Record", mock.Anything, mock.MatchedBy(func(o *entity.Origin) bool {
+			return o.ActorID == 1
+		}), entity.AuditUserLogin, int64(1), nil, nil).Return(nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAuditor, mockOptions)
+
+		_, err := u.LoginMFA(context.TODO(), challenge, currentCode(), new(entity.Session), mockOrigin)
+
+		assert.NoError(t, err)
+		mockAuditor.AssertExpectations(t)
+	})
+
+	t.Run("login-mfa-failed", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		challenge := challengeToken(t, mockUserRepo)
+
+		mockUserRepo.On("GetMFA", mock.Anything, int64(1)).Return(confirmedMFA(), nil).Once()
+		mockUserRepo.On("GetByID", mock.Anything, int64(1)).Return(&entity.User{ID: 1, Email: mockUser.Email}, nil).Once()
+		mockUserRepo.On("UseRecoveryCode", mock.Anything, int64(1), mock.AnythingOfType("string")).Return(false, nil).Maybe()
+		mockAuditor := new(auditMocks.Usecase)
+		mockAuditor.On("Record", mock.Anything, mockOrigin, entity.AuditUserLoginFailed, int64(1), nil, nil).Return(nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAuditor, mockOptions)
+
+		_, err := u.LoginMFA(context.TODO(), challenge, `000000`, new(entity.Session), mockOrigin)
+
+		assert.Error(t, err)
+		mockAuditor.AssertExpectations(t)
+	})
+
+	t.Run("login-oauth", func(t *testing.T) {
+		srv := oidctest.NewServer()
+		defer srv.Close()
+
+		mockUserRepo := new(mocks.Repository)
+		mockAuditor := new(auditMocks.Usecase)
+		mockAuditor.On("Record", mock.Anything, mock.MatchedBy(func(o *entity.Origin) bool {
+			return o.ActorID == 1
+		}), entity.AuditUserLogin, int64(1), nil, nil).Return(nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAuditor, oauthOptions(srv))
+		state, code := startOAuth(t, srv, mockUserRepo, u)
+
+		mockUserRepo.On("GetIdentity", mock.Anything, `test`, srv.Subject).Return(&entity.Identity{ID: 3, UserID: 1}, nil).Once()
+		mockUserRepo.On("GetByID", mock.Anything, int64(1)).Return(&entity.User{ID: 1, Email: srv.Email}, nil).Once()
+		expectCompleteLogin(mockUserRepo, 1)
+
+		_, err := u.LoginOAuth(context.TODO(), `test`, state, code, new(entity.Session), mockOrigin)
+
+		assert.NoError(t, err)
+		mockAuditor.AssertExpectations(t)
+	})
+
+	t.Run("logout", func(t *testing.T) {
+		sess := mockSession
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("GetSessionByToken", mock.Anything, helper.HashToken(`token`)).Return(&sess, nil).Once()
+		mockUserRepo.On("RevokeSession", mock.Anything, int64(1)).Return(true, nil).Once()
+		mockUserRepo.On("RevokeRefreshTokenFamily", mock.Anything, `family`).Return(nil).Once()
+		mockAuditor := new(auditMocks.Usecase)
+		mockAuditor.On("Record", mock.Anything, mockOrigin, entity.AuditUserLogout, int64(1), nil, nil).Return(nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAuditor, mockOptions)
+
+		err := u.Logout(context.TODO(), `token`, mockOrigin)
+
+		assert.NoError(t, err)
+		mockAuditor.AssertExpectations(t)
+	})
+
+	t.Run("revoke-token-family", func(t *testing.T) {
+		usedAt := time.Now().Add(-time.Minute)
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("GetRefreshToken", mock.Anything, helper.HashToken(`refresh`)).Return(&entity.RefreshToken{
+			ID: 7, UserID: 1, FamilyID: `family`, ExpiresAt: time.Now().Add(time.Hour), UsedAt: &usedAt,
+		}, nil).Once()
+		mockUserRepo.On("RevokeRefreshTokenFamily", mock.Anything, `family`).Return(nil).Once()
+		mockUserRepo.On("RevokeSessionFamily", mock.Anything, `family`).Return(nil).Once()
+		mockAuditor := new(auditMocks.Usecase)
+		mockAuditor.On("Record", mock.Anything, mockOrigin, entity.AuditUserRevokeTokenFamily, int64(1), nil, nil).Return(nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAuditor, mockOptions)
+
+		_, err := u.Refresh(context.TODO(), `refresh`, mockOrigin)
+
+		assert.Error(t, err)
+		mockAuditor.AssertExpectations(t)
+	})
+
+	t.Run("assign-roles", func(t *testing.T) {
+		roles := []string{entity.RoleAdmin, entity.RoleUser}
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("ExistRoles", mock.Anything, roles).Return(true, nil).Once()
+		mockUserRepo.On("GetByID", mock.Anything, int64(1)).Return(&entity.User{ID: 1}, nil).Once()
+		mockUserRepo.On("GetRoles", mock.Anything, int64(1)).Return([]string{entity.RoleUser}, nil).Once()
+		mockUserRepo.On("SetRoles", mock.Anything, int64(1), roles).Return(nil).Once()
+		mockAuditor := new(auditMocks.Usecase)
+		mockAuditor.On("Record", mock.Anything, mockOrigin, entity.AuditUserAssignRoles, int64(1), mock.MatchedBy(func(before *entity.User) bool {
+			return assert.ObjectsAreEqual([]string{entity.RoleUser}, before.Roles)
+		}), mock.MatchedBy(func(after *entity.User) bool {
+			return assert.ObjectsAreEqual(roles, after.Roles)
+		})).Return(nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAuditor, mockOptions)
+
+		err := u.AssignRoles(context.TODO(), 1, roles, mockOrigin)
+
+		assert.NoError(t, err)
+		mockAuditor.AssertExpectations(t)
+	})
+
+	t.Run("change-password", func(t *testing.T) {
+		hashedPass, _ := mockHasher.Hash(`aiueo`)
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("GetPassword", mock.Anything, int64(1)).Return(hashedPass, nil).Once()
+		mockUserRepo.On("UpdatePassword", mock.Anything, int64(1), mock.AnythingOfType("string")).Return(true, nil).Once()
+		mockUserRepo.On("InvalidateUserTokens", mock.Anything, int64(1), entity.TokenPurposeResetPassword).Return(nil).Once()
+		mockUserRepo.On("GetSessionByToken", mock.Anything, helper.HashToken(`token`)).Return(&mockSession, nil).Once()
+		mockUserRepo.On("RevokeOtherSessions", mock.Anything, int64(1), int64(1)).Return(nil).Once()
+		mockUserRepo.On("RevokeOtherRefreshTokens", mock.Anything, int64(1), `family`).Return(nil).Once()
+		mockAuditor := new(auditMocks.Usecase)
+		mockAuditor.On("Record", mock.Anything, mockOrigin, entity.AuditUserChangePassword, int64(1), nil, nil).Return(nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAuditor, mockOptions)
+
+		err := u.ChangePassword(context.TODO(), 1, `token`, `aiueo`, `new-password`, mockOrigin)
+
+		assert.NoError(t, err)
+		mockAuditor.AssertExpectations(t)
+	})
+
+	t.Run("reset-password", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		token := mailedResetToken(t, mockUserRepo)
+
+		mockUserRepo.On("UseUserToken", mock.Anything, int64(1), entity.TokenPurposeResetPassword, helper.HashToken(token)).Return(true, nil).Once()
+		mockUserRepo.On("UpdatePassword", mock.Anything, int64(1), mock.AnythingOfType("string")).Return(true, nil).Once()
+		mockUserRepo.On("InvalidateUserTokens", mock.Anything, int64(1), entity.TokenPurposeResetPassword).Return(nil).Once()
+		mockUserRepo.On("RevokeSessionsByUser", mock.Anything, int64(1)).Return(nil).Once()
+		mockUserRepo.On("RevokeRefreshTokensByUser", mock.Anything, int64(1)).Return(nil).Once()
+		mockAuditor := new(auditMocks.Usecase)
+		mockAuditor.On("Record", mock.Anything, mock.MatchedBy(func(o *entity.Origin) bool {
+			return o.ActorID == 1
+		}), entity.AuditUserResetPassword, int64(1), nil, nil).Return(nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAuditor, mockOptions)
+
+		err := u.ResetPassword(context.TODO(), token, `new-password`, mockOrigin)
+
+		assert.NoError(t, err)
+		mockAuditor.AssertExpectations(t)
+	})
+
+	t.Run("enable-mfa", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("GetMFA", mock.Anything, int64(1)).Return(&entity.MFA{UserID: 1, Secret: mockMFASecret}, nil).Once()
+		mockUserRepo.On("UseMFAStep", mock.Anything, int64(1), mock.AnythingOfType("int64")).Return(true, nil).Once()
+		mockUserRepo.On("ConfirmMFA", mock.Anything, int64(1)).Return(true, nil).Once()
+		mockUserRepo.On("SetRecoveryCodes", mock.Anything, int64(1), mock.AnythingOfType("[]string")).Return(nil).Once()
+		mockAuditor := new(auditMocks.Usecase)
+		mockAuditor.On("Record", mock.Anything, mockOrigin, entity.AuditUserEnableMFA, int64(1), nil, nil).Return(nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAuditor, mockOptions)
+
+		_, err := u.ConfirmMFA(context.TODO(), 1, currentCode(), mockOrigin)
+
+		assert.NoError(t, err)
+		mockAuditor.AssertExpectations(t)
+	})
+
+	t.Run("disable-mfa", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("GetMFA", mock.Anything, int64(1)).Return(confirmedMFA(), nil).Once()
+		mockUserRepo.On("UseMFAStep", mock.Anything, int64(1), mock.AnythingOfType("int64")).Return(true, nil).Once()
+		mockUserRepo.On("DeleteMFA", mock.Anything, int64(1)).Return(nil).Once()
+		mockAuditor := new(auditMocks.Usecase)
+		mockAuditor.On("Record", mock.Anything, mockOrigin, entity.AuditUserDisableMFA, int64(1), nil, nil).Return(nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAuditor, mockOptions)
+
+		err := u.DisableMFA(context.TODO(), 1, currentCode(), mockOrigin)
+
+		assert.NoError(t, err)
+		mockAuditor.AssertExpectations(t)
+	})
+
+	t.Run("create-api-key", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("StoreAPIKey", mock.Anything, mock.AnythingOfType("*entity.APIKey")).Return(nil).Once()
+		mockAuditor := new(auditMocks.Usecase)
+		mockAuditor.On("Record", mock.Anything, mockOrigin, entity.AuditUserCreateAPIKey, int64(1), nil, mock.MatchedBy(func(key *entity.APIKey) bool {
+			return key.Name == `batch` && key.Key == ``
+		})).Return(nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAuditor, mockOptions)
+
+		usr := &entity.User{ID: 1, Permissions: []string{entity.PermissionUserRead}}
+		err := u.CreateAPIKey(context.TODO(), usr, &entity.APIKey{Name: `batch`, Scopes: []string{entity.PermissionUserRead}}, mockOrigin)
+
+		assert.NoError(t, err)
+		mockAuditor.AssertExpectations(t)
+	})
+
+	t.Run("revoke-api-key", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("DeleteAPIKey", mock.Anything, int64(1), int64(7)).Return(true, nil).Once()
+		mockAuditor := new(auditMocks.Usecase)
+		mockAuditor.On("Record", mock.Anything, mockOrigin, entity.AuditUserRevokeAPIKey, int64(1), map[string]int64{`id`: 7}, nil).Return(nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAuditor, mockOptions)
+
+		err := u.RevokeAPIKey(context.TODO(), 1, 7, mockOrigin)
+
+		assert.NoError(t, err)
+		mockAuditor.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,78 @@
+package usecase
+
+import (
+	"sync"
+	"time"
+
+	"github.com/andhikagama/lmnlo/models/entity"
+)
+
+type cachedUser struct {
+	usr       entity.User
+	expiresAt time.Time
+}
+
+// userCache keeps users with their authorization for ttl, so that every
+// request does not have to load them again. A zero ttl caches nothing.
+type userCache struct {
+	ttl time.Duration
+
+	mu    sync.Mutex
+	users map[int64]*cachedUser
+}
+
+func newUserCache(ttl time.Duration) *userCache {
+	return &userCache{
+		ttl:   ttl,
+		users: make(map[int64]*cachedUser),
+	}
+}
+
+// get return a copy of the cached user, handlers may change it freely
+func (c *userCache) get(id int64) (*entity.User, bool) {
+	if c.ttl <= 0 {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cu, ok := c.users[id]
+	if !ok {
+		return nil, false
+	}
+
+	if time.Now().After(cu.expiresAt) {
+		delete(c.users, id)
+		return nil, false
+	}
+
+	usr := cu.usr
+	return &usr, true
+}
+
+func (c *userCache) put(usr *entity.User) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for id, cu := range c.users {
+		if now.After(cu.expiresAt) {
+			delete(c.users, id)
+		}
+	}
+
+	c.users[usr.ID] = &cachedUser{usr: *usr, expiresAt: now.Add(c.ttl)}
+}
+
+// forget drops id after its user, roles or second factor changed
+func (c *userCache) forget(id int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.users, id)
+}
@@ -0,0 +1,98 @@
+package usecase
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/andhikagama/lmnlo/helper"
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/andhikagama/lmnlo/models/response"
+)
+
+// Impersonate starts a session as the user of uid for the support admin
+// actor. Its token names actor in the act claim, lasts ImpersonationTTL and
+// comes without refresh token. Admins can not be impersonated and an
+// impersonation can not start another.
+func (u *userUsecase) Impersonate(ctx context.Context, actor *entity.User, uid int64, sess *entity.Session, o *entity.Origin) (*entity.User, error) {
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+
+	if actor.ActorID != 0 {
+		return nil, response.ErrImpersonation
+	}
+
+	if actor.ID == uid {
+		return nil, response.ErrBadRequest
+	}
+
+	usr, err := u.userRepo.GetByID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	if usr.ID == 0 {
+		return nil, response.ErrNotFound
+	}
+
+	if err := u.loadAuthorization(ctx, usr); err != nil {
+		return nil, err
+	}
+
+	if usr.HasRole(entity.RoleAdmin) {
+		return nil, response.ErrForbidden
+	}
+
+	familyID, err := helper.GenerateRandomHex(_FamilyIDBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	sess.UserID = usr.ID
+	sess.FamilyID = familyID
+	sess.ExpiresAt = time.Now().Add(u.opts.ImpersonationTTL)
+
+	act := &entity.Actor{Subject: strconv.FormatInt(actor.ID, 10)}
+	if err := u.signToken(usr, sess, act, u.opts.ImpersonationTTL); err != nil {
+		return nil, err
+	}
+
+	if err := u.userRepo.InsertToken(ctx, sess); err != nil {
+		return nil, err
+	}
+
+	log.WithFields(log.Fields{
+		`actor_id`:   actor.ID,
+		`user_id`:    usr.ID,
+		`jti`:        sess.TokenID,
+		`ip`:         sess.IP,
+		`user_agent`: sess.UserAgent,
+		`expires_at`: sess.ExpiresAt,
+	}).Warn(`impersonation started`)
+	u.record(ctx, o, entity.AuditUserImpersonate, usr.ID, nil, nil)
+
+	usr.ActorID = actor.ID
+	return usr, nil
+}
+
+// authenticateActor return the user ID named by act, who still has to be
+// allowed to impersonate
+func (u *userUsecase) authenticateActor(ctx context.Context, act *entity.Actor) (int64, error) {
+	aid, err := strconv.ParseInt(act.Subject, 10, 64)
+	if err != nil {
+		return 0, response.ErrUnAuthorized
+	}
+
+	actor, err := u.authorizedUser(ctx, aid)
+	if err != nil {
+		return 0, err
+	}
+
+	if !actor.HasPermission(entity.PermissionUserImpersonate) {
+		return 0, response.ErrUnAuthorized
+	}
+
+	return actor.ID, nil
+}
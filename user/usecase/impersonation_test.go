@@ -0,0 +1,147 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/andhikagama/lmnlo/helper"
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/andhikagama/lmnlo/models/response"
+	"github.com/andhikagama/lmnlo/user/mocks"
+	"github.com/andhikagama/lmnlo/user/usecase"
+)
+
+var mockSupport = &entity.User{ID: 9, Roles: []string{entity.RoleAdmin}, Permissions: []string{entity.PermissionUserImpersonate}}
+
+func TestImpersonate(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("GetByID", mock.Anything, int64(1)).Return(&entity.User{ID: 1, Email: mockUser.Email}, nil).Once()
+		mockUserRepo.On("GetRoles", mock.Anything, int64(1)).Return([]string{entity.RoleUser}, nil).Once()
+		mockUserRepo.On("GetPermissions", mock.Anything, int64(1)).Return([]string{}, nil).Once()
+		var inserted *entity.Session
+		mockUserRepo.On("InsertToken", mock.Anything, mock.AnythingOfType("*entity.Session")).Run(func(args mock.Arguments) {
+			inserted = args.Get(1).(*entity.Session)
+		}).Return(nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		res, err := u.Impersonate(context.TODO(), mockSupport, 1, &entity.Session{IP: `10.0.0.1`}, mockOrigin)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(9), res.ActorID)
+		assert.Empty(t, res.RefreshToken)
+		assert.Equal(t, int64(300), res.ExpiresIn)
+		assert.Equal(t, int64(1), inserted.UserID)
+		assert.Equal(t, helper.HashToken(res.Token), inserted.TokenHash)
+
+		cc := jwt.MapClaims{}
+		_, _, err = new(jwt.Parser).ParseUnverified(res.Token, cc)
+		assert.NoError(t, err)
+		assert.Equal(t, `1`, cc[`sub`])
+		assert.Equal(t, map[string]interface{}{`sub`: `9`}, cc[`act`])
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("self", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		res, err := u.Impersonate(context.TODO(), mockSupport, 9, new(entity.Session), mockOrigin)
+
+		assert.Equal(t, response.ErrBadRequest, err)
+		assert.Nil(t, res)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("already-impersonating", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		res, err := u.Impersonate(context.TODO(), &entity.User{ID: 2, ActorID: 9}, 1, new(entity.Session), mockOrigin)
+
+		assert.Equal(t, response.ErrImpersonation, err)
+		assert.Nil(t, res)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("admin", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("GetByID", mock.Anything, int64(2)).Return(&entity.User{ID: 2}, nil).Once()
+		mockUserRepo.On("GetRoles", mock.Anything, int64(2)).Return([]string{entity.RoleAdmin}, nil).Once()
+		mockUserRepo.On("GetPermissions", mock.Anything, int64(2)).Return([]string{}, nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		res, err := u.Impersonate(context.TODO(), mockSupport, 2, new(entity.Session), mockOrigin)
+
+		assert.Equal(t, response.ErrForbidden, err)
+		assert.Nil(t, res)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("not-found", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("GetByID", mock.Anything, int64(99)).Return(new(entity.User), nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		res, err := u.Impersonate(context.TODO(), mockSupport, 99, new(entity.Session), mockOrigin)
+
+		assert.Equal(t, response.ErrNotFound, err)
+		assert.Nil(t, res)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("GetByID", mock.Anything, int64(1)).Return(nil, errors.New(`Error`)).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		res, err := u.Impersonate(context.TODO(), mockSupport, 1, new(entity.Session), mockOrigin)
+
+		assert.Error(t, err)
+		assert.Nil(t, res)
+		mockUserRepo.AssertExpectations(t)
+	})
+}
+
+func TestAuthenticateImpersonation(t *testing.T) {
+	token := accessToken(func(cc *entity.Claims) {
+		cc.Act = &entity.Actor{Subject: `9`}
+	})
+
+	cases := []struct {
+		name        string
+		permissions []string
+		err         error
+	}{
+		{`allowed`, []string{entity.PermissionUserImpersonate}, nil},
+		{`permission-revoked`, []string{}, response.ErrUnAuthorized},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockUserRepo := new(mocks.Repository)
+			activeSession(mockUserRepo, token)
+			mockUserRepo.On("GetByID", mock.Anything, int64(1)).Return(&entity.User{ID: 1}, nil).Once()
+			mockUserRepo.On("GetRoles", mock.Anything, int64(1)).Return([]string{entity.RoleUser}, nil).Once()
+			mockUserRepo.On("GetPermissions", mock.Anything, int64(1)).Return([]string{}, nil).Once()
+			mockUserRepo.On("GetByID", mock.Anything, int64(9)).Return(&entity.User{ID: 9}, nil).Once()
+			mockUserRepo.On("GetRoles", mock.Anything, int64(9)).Return([]string{entity.RoleAdmin}, nil).Once()
+			mockUserRepo.On("GetPermissions", mock.Anything, int64(9)).Return(tc.permissions, nil).Once()
+			u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+			res, err := u.AuthenticateToken(context.TODO(), token)
+
+			assert.Equal(t, tc.err, err)
+			if tc.err == nil {
+				assert.Equal(t, int64(1), res.ID)
+				assert.Equal(t, int64(9), res.ActorID)
+			}
+			mockUserRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,244 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/andhikagama/lmnlo/helper"
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/andhikagama/lmnlo/models/response"
+)
+
+const (
+	// _TOTPSkew accepts codes one period either side of now for clock drift
+	_TOTPSkew = 1
+
+	_RecoveryCodeCount = 10
+	_RecoveryCodeBytes = 5
+)
+
+// LoginMFA completes a login that was answered with a challenge token,
+// using either a TOTP or a recovery code
+func (u *userUsecase) LoginMFA(ctx context.Context, challenge string, code string, sess *entity.Session, o *entity.Origin) (*entity.User, error) {
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+
+	uid, _, err := u.parsePurposeToken(challenge, entity.TokenPurposeMFAChallenge)
+	if err != nil {
+		return nil, err
+	}
+
+	mfa, err := u.userRepo.GetMFA(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	if !mfa.Confirmed() {
+		return nil, response.ErrInvalidToken
+	}
+
+	usr, err := u.userRepo.GetByID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	if usr.ID == 0 {
+		return nil, response.ErrInvalidToken
+	}
+
+	if err := u.checkLockout(ctx, usr.Email, sess); err != nil {
+		return nil, err
+	}
+
+	if err := u.verifySecondFactor(ctx, mfa, code); err != nil {
+		if err == response.ErrInvalidCode {
+			u.failLogin(ctx, usr.Email, sess)
+			u.record(ctx, o, entity.AuditUserLoginFailed, usr.ID, nil, nil)
+		}
+		return nil, err
+	}
+
+	if err := u.spendPurposeToken(ctx, uid, challenge, entity.TokenPurposeMFAChallenge); err != nil {
+		return nil, err
+	}
+
+	u.record(ctx, selfOrigin(o, usr.ID), entity.AuditUserLogin, usr.ID, nil, nil)
+	return u.completeLogin(ctx, usr, sess)
+}
+
+// EnrollMFA starts setting up TOTP for uid, replacing any enrollment that
+// was not confirmed
+func (u *userUsecase) EnrollMFA(ctx context.Context, uid int64) (*entity.MFAEnrollment, error) {
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+
+	mfa, err := u.userRepo.GetMFA(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	if mfa.Confirmed() {
+		return nil, response.ErrAlreadyExist
+	}
+
+	usr, err := u.userRepo.GetByID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	if usr.ID == 0 {
+		return nil, response.ErrNotFound
+	}
+
+	secret, err := helper.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.userRepo.StoreMFA(ctx, &entity.MFA{UserID: uid, Secret: secret}); err != nil {
+		return nil, err
+	}
+
+	return &entity.MFAEnrollment{
+		Secret: secret,
+		URI:    helper.TOTPURI(u.opts.MFAIssuer, usr.Email, secret),
+	}, nil
+}
+
+// ConfirmMFA activates the enrollment of uid with a code from the
+// authenticator app and return fresh recovery codes, which are only ever
+// shown here
+func (u *userUsecase) ConfirmMFA(ctx context.Context, uid int64, code string, o *entity.Origin) ([]string, error) {
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+
+	mfa, err := u.userRepo.GetMFA(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	if mfa.UserID == 0 {
+		return nil, response.ErrNotFound
+	}
+
+	if mfa.Confirmed() {
+		return nil, response.ErrAlreadyExist
+	}
+
+	step, ok := helper.ValidateTOTP(mfa.Secret, code, time.Now(), _TOTPSkew)
+	if !ok {
+		return nil, response.ErrInvalidCode
+	}
+
+	if _, err := u.userRepo.UseMFAStep(ctx, uid, step); err != nil {
+		return nil, err
+	}
+
+	ok, err = u.userRepo.ConfirmMFA(ctx, uid)
+	u.users.forget(uid)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return nil, response.ErrAlreadyExist
+	}
+
+	u.record(ctx, o, entity.AuditUserEnableMFA, uid, nil, nil)
+
+	codes := make([]string, _RecoveryCodeCount)
+	hashes := make([]string, _RecoveryCodeCount)
+	for i := range codes {
+		raw, err := helper.GenerateRandomHex(_RecoveryCodeBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		codes[i] = raw[:len(raw)/2] + `-` + raw[len(raw)/2:]
+		hashes[i] = helper.HashToken(raw)
+	}
+
+	if err := u.userRepo.SetRecoveryCodes(ctx, uid, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// DisableMFA removes the second factor of uid after checking a code
+func (u *userUsecase) DisableMFA(ctx context.Context, uid int64, code string, o *entity.Origin) error {
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+
+	mfa, err := u.userRepo.GetMFA(ctx, uid)
+	if err != nil {
+		return err
+	}
+
+	if !mfa.Confirmed() {
+		return response.ErrNotFound
+	}
+
+	if err := u.verifySecondFactor(ctx, mfa, code); err != nil {
+		return err
+	}
+
+	if err := u.userRepo.DeleteMFA(ctx, uid); err != nil {
+		return err
+	}
+
+	u.users.forget(uid)
+	u.record(ctx, o, entity.AuditUserDisableMFA, uid, nil, nil)
+	return nil
+}
+
+// challengeMFA answers the first login step of a user with MFA
+func (u *userUsecase) challengeMFA(ctx context.Context, usr *entity.User) (*entity.User, error) {
+	challenge, err := u.issuePurposeToken(ctx, usr, entity.TokenPurposeMFAChallenge, u.opts.MFAChallengeTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.User{
+		ID:             usr.ID,
+		Email:          usr.Email,
+		ChallengeToken: challenge,
+	}, nil
+}
+
+// verifySecondFactor accepts a TOTP code that was not used before or an
+// unused recovery code
+func (u *userUsecase) verifySecondFactor(ctx context.Context, mfa *entity.MFA, code string) error {
+	code = strings.TrimSpace(code)
+
+	if len(code) == helper.TOTPDigits {
+		step, ok := helper.ValidateTOTP(mfa.Secret, code, time.Now(), _TOTPSkew)
+		if !ok {
+			return response.ErrInvalidCode
+		}
+
+		ok, err := u.userRepo.UseMFAStep(ctx, mfa.UserID, step)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return response.ErrInvalidCode
+		}
+
+		return nil
+	}
+
+	normalized := strings.ToUpper(strings.Replace(code, `-`, ``, -1))
+	ok, err := u.userRepo.UseRecoveryCode(ctx, mfa.UserID, helper.HashToken(normalized))
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return response.ErrInvalidCode
+	}
+
+	return nil
+}
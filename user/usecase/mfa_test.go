@@ -0,0 +1,269 @@
+package usecase_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/andhikagama/lmnlo/helper"
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/andhikagama/lmnlo/models/response"
+	"github.com/andhikagama/lmnlo/user/mocks"
+	"github.com/andhikagama/lmnlo/user/usecase"
+)
+
+var mockMFASecret, _ = helper.GenerateTOTPSecret()
+
+func confirmedMFA() *entity.MFA {
+	now := time.Now()
+	return &entity.MFA{UserID: 1, Secret: mockMFASecret, ConfirmedAt: &now}
+}
+
+func currentCode() string {
+	code, _ := helper.TOTPCode(mockMFASecret, helper.TOTPStep(time.Now()))
+	return code
+}
+
+// challengeToken runs the first login step of a user with MFA and return
+// the challenge token it answered with
+func challengeToken(t *testing.T, mockUserRepo *mocks.Repository) string {
+	hashedPass, _ := mockHasher.Hash(`aiueo`)
+	existingUser := &entity.User{ID: 1, Email: mockUser.Email, Password: hashedPass}
+	mockUserRepo.On("GetByEmail", mock.Anything, mockUser.Email).Return(existingUser, nil).Once()
+	mockUserRepo.On("GetMFA", mock.Anything, int64(1)).Return(confirmedMFA(), nil).Once()
+	mockUserRepo.On("StoreUserToken", mock.Anything, mock.MatchedBy(func(ut *entity.UserToken) bool {
+		return ut.Purpose == entity.TokenPurposeMFAChallenge
+	})).Return(nil).Once()
+	u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+	res, err := u.Login(context.TODO(), &entity.User{Email: mockUser.Email, Password: `aiueo`}, new(entity.Session), mockOrigin)
+
+	assert.NoError(t, err)
+	assert.Empty(t, res.Token)
+	assert.Empty(t, res.RefreshToken)
+	assert.NotEmpty(t, res.ChallengeToken)
+	return res.ChallengeToken
+}
+
+func TestLoginMFA(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		challenge := challengeToken(t, mockUserRepo)
+
+		mockUserRepo.On("GetMFA", mock.Anything, int64(1)).Return(confirmedMFA(), nil).Once()
+		mockUserRepo.On("UseMFAStep", mock.Anything, int64(1), mock.AnythingOfType("int64")).Return(true, nil).Once()
+		mockUserRepo.On("UseUserToken", mock.Anything, int64(1), entity.TokenPurposeMFAChallenge, helper.HashToken(challenge)).Return(true, nil).Once()
+		mockUserRepo.On("GetByID", mock.Anything, int64(1)).Return(&entity.User{ID: 1, Email: mockUser.Email}, nil).Once()
+		mockUserRepo.On("GetRoles", mock.Anything, int64(1)).Return([]string{entity.RoleUser}, nil).Once()
+		mockUserRepo.On("GetPermissions", mock.Anything, int64(1)).Return([]string{}, nil).Once()
+		mockUserRepo.On("InsertToken", mock.Anything, mock.AnythingOfType("*entity.Session")).Return(nil).Once()
+		mockUserRepo.On("StoreRefreshToken", mock.Anything, mock.AnythingOfType("*entity.RefreshToken")).Return(nil).Once()
+		mockUserRepo.On("Update", mock.Anything, mock.AnythingOfType("*entity.User")).Return(true, nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		res, err := u.LoginMFA(context.TODO(), challenge, currentCode(), new(entity.Session), mockOrigin)
+
+		assert.NoError(t, err)
+		assert.NotEmpty(t, res.Token)
+		assert.NotEmpty(t, res.RefreshToken)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("recovery-code", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		challenge := challengeToken(t, mockUserRepo)
+
+		mockUserRepo.On("GetMFA", mock.Anything, int64(1)).Return(confirmedMFA(), nil).Once()
+		mockUserRepo.On("UseRecoveryCode", mock.Anything, int64(1), helper.HashToken(`ABCDE12345`)).Return(true, nil).Once()
+		mockUserRepo.On("UseUserToken", mock.Anything, int64(1), entity.TokenPurposeMFAChallenge, helper.HashToken(challenge)).Return(true, nil).Once()
+		mockUserRepo.On("GetByID", mock.Anything, int64(1)).Return(&entity.User{ID: 1, Email: mockUser.Email}, nil).Once()
+		mockUserRepo.On("GetRoles", mock.Anything, int64(1)).Return([]string{entity.RoleUser}, nil).Once()
+		mockUserRepo.On("GetPermissions", mock.Anything, int64(1)).Return([]string{}, nil).Once()
+		mockUserRepo.On("InsertToken", mock.Anything, mock.AnythingOfType("*entity.Session")).Return(nil).Once()
+		mockUserRepo.On("StoreRefreshToken", mock.Anything, mock.AnythingOfType("*entity.RefreshToken")).Return(nil).Once()
+		mockUserRepo.On("Update", mock.Anything, mock.AnythingOfType("*entity.User")).Return(true, nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		res, err := u.LoginMFA(context.TODO(), challenge, `abcde-12345`, new(entity.Session), mockOrigin)
+
+		assert.NoError(t, err)
+		assert.NotEmpty(t, res.Token)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("wrong-code", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		challenge := challengeToken(t, mockUserRepo)
+
+		mockUserRepo.On("GetMFA", mock.Anything, int64(1)).Return(confirmedMFA(), nil).Once()
+		mockUserRepo.On("GetByID", mock.Anything, int64(1)).Return(&entity.User{ID: 1, Email: mockUser.Email}, nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		res, err := u.LoginMFA(context.TODO(), challenge, `12345x`, new(entity.Session), mockOrigin)
+
+		assert.Equal(t, response.ErrInvalidCode, err)
+		assert.Nil(t, res)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("replayed-code", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		challenge := challengeToken(t, mockUserRepo)
+
+		mockUserRepo.On("GetMFA", mock.Anything, int64(1)).Return(confirmedMFA(), nil).Once()
+		mockUserRepo.On("GetByID", mock.Anything, int64(1)).Return(&entity.User{ID: 1, Email: mockUser.Email}, nil).Once()
+		mockUserRepo.On("UseMFAStep", mock.Anything, int64(1), mock.AnythingOfType("int64")).Return(false, nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		res, err := u.LoginMFA(context.TODO(), challenge, currentCode(), new(entity.Session), mockOrigin)
+
+		assert.Equal(t, response.ErrInvalidCode, err)
+		assert.Nil(t, res)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("access-token-as-challenge", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		token, _ := mockKeyRing.Sign(&entity.Claims{StandardClaims: jwt.StandardClaims{Subject: `1`, ExpiresAt: time.Now().Add(time.Hour).Unix()}})
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		res, err := u.LoginMFA(context.TODO(), token, currentCode(), new(entity.Session), mockOrigin)
+
+		assert.Equal(t, response.ErrInvalidToken, err)
+		assert.Nil(t, res)
+		mockUserRepo.AssertExpectations(t)
+	})
+}
+
+func TestEnrollMFA(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("GetMFA", mock.Anything, int64(1)).Return(new(entity.MFA), nil).Once()
+		mockUserRepo.On("GetByID", mock.Anything, int64(1)).Return(&entity.User{ID: 1, Email: mockUser.Email}, nil).Once()
+		mockUserRepo.On("StoreMFA", mock.Anything, mock.MatchedBy(func(mfa *entity.MFA) bool {
+			return mfa.UserID == 1 && mfa.Secret != ``
+		})).Return(nil).Once()
+		opts := mockOptions
+		opts.MFAIssuer = `lmnlo`
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, opts)
+
+		res, err := u.EnrollMFA(context.TODO(), 1)
+
+		assert.NoError(t, err)
+		assert.NotEmpty(t, res.Secret)
+		assert.True(t, strings.HasPrefix(res.URI, `otpauth://totp/lmnlo:`))
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("already-enabled", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("GetMFA", mock.Anything, int64(1)).Return(confirmedMFA(), nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		res, err := u.EnrollMFA(context.TODO(), 1)
+
+		assert.Equal(t, response.ErrAlreadyExist, err)
+		assert.Nil(t, res)
+		mockUserRepo.AssertExpectations(t)
+	})
+}
+
+func TestConfirmMFA(t *testing.T) {
+	pending := &entity.MFA{UserID: 1, Secret: mockMFASecret}
+
+	t.Run("success", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("GetMFA", mock.Anything, int64(1)).Return(pending, nil).Once()
+		mockUserRepo.On("UseMFAStep", mock.Anything, int64(1), mock.AnythingOfType("int64")).Return(true, nil).Once()
+		mockUserRepo.On("ConfirmMFA", mock.Anything, int64(1)).Return(true, nil).Once()
+		mockUserRepo.On("SetRecoveryCodes", mock.Anything, int64(1), mock.MatchedBy(func(hashes []string) bool {
+			return len(hashes) == 10
+		})).Return(nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		codes, err := u.ConfirmMFA(context.TODO(), 1, currentCode(), mockOrigin)
+
+		assert.NoError(t, err)
+		assert.Len(t, codes, 10)
+		assert.Len(t, codes[0], 11)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("wrong-code", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("GetMFA", mock.Anything, int64(1)).Return(pending, nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		codes, err := u.ConfirmMFA(context.TODO(), 1, `abc`, mockOrigin)
+
+		assert.Equal(t, response.ErrInvalidCode, err)
+		assert.Nil(t, codes)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("not-enrolled", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("GetMFA", mock.Anything, int64(1)).Return(new(entity.MFA), nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		codes, err := u.ConfirmMFA(context.TODO(), 1, currentCode(), mockOrigin)
+
+		assert.Equal(t, response.ErrNotFound, err)
+		assert.Nil(t, codes)
+		mockUserRepo.AssertExpectations(t)
+	})
+}
+
+func TestDisableMFA(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("GetMFA", mock.Anything, int64(1)).Return(confirmedMFA(), nil).Once()
+		mockUserRepo.On("UseMFAStep", mock.Anything, int64(1), mock.AnythingOfType("int64")).Return(true, nil).Once()
+		mockUserRepo.On("DeleteMFA", mock.Anything, int64(1)).Return(nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		err := u.DisableMFA(context.TODO(), 1, currentCode(), mockOrigin)
+
+		assert.NoError(t, err)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("not-enabled", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("GetMFA", mock.Anything, int64(1)).Return(new(entity.MFA), nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		err := u.DisableMFA(context.TODO(), 1, currentCode(), mockOrigin)
+
+		assert.Equal(t, response.ErrNotFound, err)
+		mockUserRepo.AssertExpectations(t)
+	})
+}
+
+func TestMFARequiredRoles(t *testing.T) {
+	hashedPass, _ := mockHasher.Hash(`aiueo`)
+	opts := mockOptions
+	opts.MFARoles = []string{entity.RoleAdmin}
+
+	mockUserRepo := new(mocks.Repository)
+	mockUserRepo.On("GetByEmail", mock.Anything, mockUser.Email).Return(&entity.User{ID: 1, Email: mockUser.Email, Password: hashedPass}, nil).Once()
+	mockUserRepo.On("GetMFA", mock.Anything, int64(1)).Return(new(entity.MFA), nil).Twice()
+	mockUserRepo.On("GetRoles", mock.Anything, int64(1)).Return([]string{entity.RoleAdmin, entity.RoleUser}, nil).Once()
+	mockUserRepo.On("GetPermissions", mock.Anything, int64(1)).Return([]string{entity.PermissionUserDelete}, nil).Once()
+	mockUserRepo.On("InsertToken", mock.Anything, mock.AnythingOfType("*entity.Session")).Return(nil).Once()
+	mockUserRepo.On("StoreRefreshToken", mock.Anything, mock.AnythingOfType("*entity.RefreshToken")).Return(nil).Once()
+	mockUserRepo.On("Update", mock.Anything, mock.AnythingOfType("*entity.User")).Return(true, nil).Once()
+	u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, opts)
+
+	res, err := u.Login(context.TODO(), &entity.User{Email: mockUser.Email, Password: `aiueo`}, new(entity.Session), mockOrigin)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{entity.RoleUser}, res.Roles)
+	assert.Empty(t, res.Permissions)
+	mockUserRepo.AssertExpectations(t)
+}
@@ -0,0 +1,207 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/andhikagama/lmnlo/helper"
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/andhikagama/lmnlo/models/response"
+	"github.com/andhikagama/lmnlo/oidc"
+)
+
+const (
+	_OAuthStateBytes = 32
+
+	// _DefaultOAuthStateTTL bounds how long a user may take at the provider
+	_DefaultOAuthStateTTL = 10 * time.Minute
+)
+
+// OAuthURL starts a sign-in with provider. It return where to send the
+// user and the state, which the caller binds to the browser to compare on
+// the callback.
+func (u *userUsecase) OAuthURL(ctx context.Context, provider string) (string, string, error) {
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+
+	p, ok := u.opts.OAuthProviders[provider]
+	if !ok {
+		return ``, ``, oidc.ErrUnknownProvider
+	}
+
+	state, err := helper.GenerateRandomHex(_OAuthStateBytes)
+	if err != nil {
+		return ``, ``, err
+	}
+
+	verifier, err := oidc.NewVerifier()
+	if err != nil {
+		return ``, ``, err
+	}
+
+	nonce, err := oidc.NewNonce()
+	if err != nil {
+		return ``, ``, err
+	}
+
+	ttl := u.opts.OAuthStateTTL
+	if ttl == 0 {
+		ttl = _DefaultOAuthStateTTL
+	}
+
+	authURL, err := p.AuthCodeURL(state, nonce, oidc.Challenge(verifier))
+	if err != nil {
+		return ``, ``, err
+	}
+
+	err = u.userRepo.StoreOAuthState(ctx, &entity.OAuthState{
+		StateHash: helper.HashToken(state),
+		Provider:  provider,
+		Verifier:  verifier,
+		Nonce:     nonce,
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return ``, ``, err
+	}
+
+	return authURL, state, nil
+}
+
+// LoginOAuth completes a sign-in with provider. A new user is created for
+// an unknown identity, unless its email belongs to an existing user, who
+// is linked when both sides verified the email. Users with MFA still get
+// a challenge.
+func (u *userUsecase) LoginOAuth(ctx context.Context, provider string, state string, code string, sess *entity.Session, o *entity.Origin) (*entity.User, error) {
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+
+	p, ok := u.opts.OAuthProviders[provider]
+	if !ok {
+		return nil, oidc.ErrUnknownProvider
+	}
+
+	st, err := u.userRepo.UseOAuthState(ctx, helper.HashToken(state))
+	if err != nil {
+		return nil, err
+	}
+
+	if st.Provider != provider {
+		return nil, response.ErrInvalidToken
+	}
+
+	id, err := p.Identify(code, st.Verifier, st.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	usr, err := u.identityUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.opts.RequireVerifiedEmail && usr.VerifiedAt == nil {
+		return nil, response.ErrUnverified
+	}
+
+	usr.Password = ``
+
+	mfa, err := u.userRepo.GetMFA(ctx, usr.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if mfa.Confirmed() {
+		return u.challengeMFA(ctx, usr)
+	}
+
+	u.record(ctx, selfOrigin(o, usr.ID), entity.AuditUserLogin, usr.ID, nil, nil)
+	return u.completeLogin(ctx, usr, sess)
+}
+
+// identityUser return the user linked to id, linking or creating one on
+// the first sign-in
+func (u *userUsecase) identityUser(ctx context.Context, id *oidc.Identity) (*entity.User, error) {
+	link, err := u.userRepo.GetIdentity(ctx, id.Provider, id.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	if link.ID != 0 {
+		usr, err := u.userRepo.GetByID(ctx, link.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		if usr.ID == 0 {
+			return nil, response.ErrUnAuthorized
+		}
+
+		return usr, nil
+	}
+
+	email := strings.TrimSpace(id.Email)
+
+	usr, err := u.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+
+	if usr.ID != 0 {
+		// Linking on an unverified email on either side would let whoever
+		// registered the address first take over the other account
+		if !id.EmailVerified || usr.VerifiedAt == nil {
+			return nil, response.ErrAlreadyExist
+		}
+	} else {
+		usr, err = u.registerIdentity(ctx, email, id.EmailVerified)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = u.userRepo.StoreIdentity(ctx, &entity.Identity{
+		UserID:   usr.ID,
+		Provider: id.Provider,
+		Subject:  id.Subject,
+		Email:    email,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return usr, nil
+}
+
+// registerIdentity creates a user without a password, one can be set
+// through a password reset
+func (u *userUsecase) registerIdentity(ctx context.Context, email string, verified bool) (*entity.User, error) {
+	usr := &entity.User{
+		Email: email,
+		Roles: []string{entity.RoleUser},
+	}
+
+	if err := u.userRepo.Store(ctx, usr); err != nil {
+		return nil, err
+	}
+
+	if !verified {
+		// A failed mail leaves the account in place, as on Register
+		if err := u.sendVerification(ctx, usr); err != nil {
+			log.Error(err)
+		}
+		return usr, nil
+	}
+
+	if _, err := u.userRepo.SetVerified(ctx, usr.ID, usr.Email); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	usr.VerifiedAt = &now
+
+	return usr, nil
+}
@@ -0,0 +1,273 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/andhikagama/lmnlo/helper"
+	"github.com/andhikagama/lmnlo/mailer"
+	mailerMocks "github.com/andhikagama/lmnlo/mailer/mocks"
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/andhikagama/lmnlo/models/response"
+	"github.com/andhikagama/lmnlo/oidc"
+	"github.com/andhikagama/lmnlo/oidc/oidctest"
+	"github.com/andhikagama/lmnlo/user"
+	"github.com/andhikagama/lmnlo/user/mocks"
+	"github.com/andhikagama/lmnlo/user/usecase"
+)
+
+func oauthOptions(srv *oidctest.Server) usecase.Options {
+	p, _ := oidc.NewProvider(`test`, srv.ProviderConfig(`oidc`), nil)
+
+	opts := mockOptions
+	opts.OAuthProviders = map[string]oidc.Provider{`test`: p}
+	return opts
+}
+
+// startOAuth runs the browser side of a sign-in and return state and code
+// of the callback, with the stored state handed back by UseOAuthState
+func startOAuth(t *testing.T, srv *oidctest.Server, repo *mocks.Repository, u user.Usecase) (string, string) {
+	var stored *entity.OAuthState
+	repo.On("StoreOAuthState", mock.Anything, mock.AnythingOfType("*entity.OAuthState")).
+		Run(func(args mock.Arguments) { stored = args.Get(1).(*entity.OAuthState) }).
+		Return(nil).Once()
+
+	authURL, state, err := u.OAuthURL(context.TODO(), `test`)
+	assert.NoError(t, err)
+
+	code, returned, err := srv.Authorize(authURL)
+	assert.NoError(t, err)
+	assert.Equal(t, state, returned)
+	assert.Equal(t, helper.HashToken(state), stored.StateHash)
+
+	repo.On("UseOAuthState", mock.Anything, helper.HashToken(state)).Return(stored, nil).Once()
+	return state, code
+}
+
+func expectCompleteLogin(repo *mocks.Repository, uid int64) {
+	repo.On("GetMFA", mock.Anything, uid).Return(new(entity.MFA), nil).Once()
+	repo.On("GetRoles", mock.Anything, uid).Return([]string{entity.RoleUser}, nil).Once()
+	repo.On("GetPermissions", mock.Anything, uid).Return([]string{}, nil).Once()
+	repo.On("InsertToken", mock.Anything, mock.AnythingOfType("*entity.Session")).Return(nil).Once()
+	repo.On("StoreRefreshToken", mock.Anything, mock.AnythingOfType("*entity.RefreshToken")).Return(nil).Once()
+	repo.On("Update", mock.Anything, mock.AnythingOfType("*entity.User")).Return(true, nil).Once()
+}
+
+func TestOAuthURL(t *testing.T) {
+	srv := oidctest.NewServer()
+	defer srv.Close()
+
+	t.Run("success", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("StoreOAuthState", mock.Anything, mock.MatchedBy(func(st *entity.OAuthState) bool {
+			return st.Provider == `test` && st.Verifier != `` && st.Nonce != `` && st.ExpiresAt.After(time.Now())
+		})).Return(nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, oauthOptions(srv))
+
+		authURL, state, err := u.OAuthURL(context.TODO(), `test`)
+
+		assert.NoError(t, err)
+		assert.Contains(t, authURL, srv.URL+`/authorize?`)
+		assert.Contains(t, authURL, `state=`+state)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("unknown-provider", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, oauthOptions(srv))
+
+		_, _, err := u.OAuthURL(context.TODO(), `facebook`)
+
+		assert.Equal(t, oidc.ErrUnknownProvider, err)
+		mockUserRepo.AssertExpectations(t)
+	})
+}
+
+func TestLoginOAuth(t *testing.T) {
+	srv := oidctest.NewServer()
+	defer srv.Close()
+
+	verifiedAt := time.Now()
+
+	t.Run("success-linked", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, oauthOptions(srv))
+		state, code := startOAuth(t, srv, mockUserRepo, u)
+
+		mockUserRepo.On("GetIdentity", mock.Anything, `test`, srv.Subject).Return(&entity.Identity{ID: 3, UserID: 1}, nil).Once()
+		mockUserRepo.On("GetByID", mock.Anything, int64(1)).Return(&entity.User{ID: 1, Email: srv.Email, Password: `hash`}, nil).Once()
+		expectCompleteLogin(mockUserRepo, 1)
+
+		res, err := u.LoginOAuth(context.TODO(), `test`, state, code, new(entity.Session), mockOrigin)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), res.ID)
+		assert.Empty(t, res.Password)
+		assert.NotEmpty(t, res.Token)
+		assert.NotEmpty(t, res.RefreshToken)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("success-link-existing-email", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, oauthOptions(srv))
+		state, code := startOAuth(t, srv, mockUserRepo, u)
+
+		mockUserRepo.On("GetIdentity", mock.Anything, `test`, srv.Subject).Return(new(entity.Identity), nil).Once()
+		mockUserRepo.On("GetByEmail", mock.Anything, srv.Email).Return(&entity.User{ID: 1, Email: srv.Email, VerifiedAt: &verifiedAt}, nil).Once()
+		mockUserRepo.On("StoreIdentity", mock.Anything, &entity.Identity{UserID: 1, Provider: `test`, Subject: srv.Subject, Email: srv.Email}).Return(nil).Once()
+		expectCompleteLogin(mockUserRepo, 1)
+
+		res, err := u.LoginOAuth(context.TODO(), `test`, state, code, new(entity.Session), mockOrigin)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), res.ID)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("success-register", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, oauthOptions(srv))
+		state, code := startOAuth(t, srv, mockUserRepo, u)
+
+		mockUserRepo.On("GetIdentity", mock.Anything, `test`, srv.Subject).Return(new(entity.Identity), nil).Once()
+		mockUserRepo.On("GetByEmail", mock.Anything, srv.Email).Return(new(entity.User), nil).Once()
+		mockUserRepo.On("Store", mock.Anything, mock.MatchedBy(func(usr *entity.User) bool {
+			return usr.Email == srv.Email && usr.Password == `` && len(usr.Roles) == 1
+		})).Run(func(args mock.Arguments) { args.Get(1).(*entity.User).ID = 2 }).Return(nil).Once()
+		mockUserRepo.On("SetVerified", mock.Anything, int64(2), srv.Email).Return(true, nil).Once()
+		mockUserRepo.On("StoreIdentity", mock.Anything, mock.MatchedBy(func(id *entity.Identity) bool {
+			return id.UserID == 2 && id.Subject == srv.Subject
+		})).Return(nil).Once()
+		expectCompleteLogin(mockUserRepo, 2)
+
+		res, err := u.LoginOAuth(context.TODO(), `test`, state, code, new(entity.Session), mockOrigin)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), res.ID)
+		assert.NotNil(t, res.VerifiedAt)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("success-register-unverified", func(t *testing.T) {
+		srv.EmailVerified = false
+		defer func() { srv.EmailVerified = true }()
+
+		mockUserRepo := new(mocks.Repository)
+		mockMailer := new(mailerMocks.Mailer)
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, oauthOptions(srv))
+		state, code := startOAuth(t, srv, mockUserRepo, u)
+
+		mockUserRepo.On("GetIdentity", mock.Anything, `test`, srv.Subject).Return(new(entity.Identity), nil).Once()
+		mockUserRepo.On("GetByEmail", mock.Anything, srv.Email).Return(new(entity.User), nil).Once()
+		mockUserRepo.On("Store", mock.Anything, mock.AnythingOfType("*entity.User")).
+			Run(func(args mock.Arguments) { args.Get(1).(*entity.User).ID = 2 }).Return(nil).Once()
+		mockUserRepo.On("StoreUserToken", mock.Anything, mock.AnythingOfType("*entity.UserToken")).Return(nil).Once()
+		mockMailer.On("Send", mock.MatchedBy(func(msg *mailer.Message) bool {
+			return msg.To == srv.Email
+		})).Return(nil).Once()
+		mockUserRepo.On("StoreIdentity", mock.Anything, mock.AnythingOfType("*entity.Identity")).Return(nil).Once()
+		expectCompleteLogin(mockUserRepo, 2)
+
+		res, err := u.LoginOAuth(context.TODO(), `test`, state, code, new(entity.Session), mockOrigin)
+
+		assert.NoError(t, err)
+		assert.Nil(t, res.VerifiedAt)
+		mockUserRepo.AssertExpectations(t)
+		mockMailer.AssertExpectations(t)
+	})
+
+	t.Run("unverified-email-taken", func(t *testing.T) {
+		srv.EmailVerified = false
+		defer func() { srv.EmailVerified = true }()
+
+		mockUserRepo := new(mocks.Repository)
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, oauthOptions(srv))
+		state, code := startOAuth(t, srv, mockUserRepo, u)
+
+		mockUserRepo.On("GetIdentity", mock.Anything, `test`, srv.Subject).Return(new(entity.Identity), nil).Once()
+		mockUserRepo.On("GetByEmail", mock.Anything, srv.Email).Return(&entity.User{ID: 1, Email: srv.Email, VerifiedAt: &verifiedAt}, nil).Once()
+
+		res, err := u.LoginOAuth(context.TODO(), `test`, state, code, new(entity.Session), mockOrigin)
+
+		assert.Equal(t, response.ErrAlreadyExist, err)
+		assert.Nil(t, res)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("unverified-account-taken", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, oauthOptions(srv))
+		state, code := startOAuth(t, srv, mockUserRepo, u)
+
+		mockUserRepo.On("GetIdentity", mock.Anything, `test`, srv.Subject).Return(new(entity.Identity), nil).Once()
+		mockUserRepo.On("GetByEmail", mock.Anything, srv.Email).Return(&entity.User{ID: 1, Email: srv.Email}, nil).Once()
+
+		res, err := u.LoginOAuth(context.TODO(), `test`, state, code, new(entity.Session), mockOrigin)
+
+		assert.Equal(t, response.ErrAlreadyExist, err)
+		assert.Nil(t, res)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("mfa-challenge", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		opts := oauthOptions(srv)
+		opts.MFAChallengeTTL = 5 * time.Minute
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, opts)
+		state, code := startOAuth(t, srv, mockUserRepo, u)
+
+		confirmedAt := time.Now()
+		mockUserRepo.On("GetIdentity", mock.Anything, `test`, srv.Subject).Return(&entity.Identity{ID: 3, UserID: 1}, nil).Once()
+		mockUserRepo.On("GetByID", mock.Anything, int64(1)).Return(&entity.User{ID: 1, Email: srv.Email}, nil).Once()
+		mockUserRepo.On("GetMFA", mock.Anything, int64(1)).Return(&entity.MFA{UserID: 1, ConfirmedAt: &confirmedAt}, nil).Once()
+		mockUserRepo.On("StoreUserToken", mock.Anything, mock.AnythingOfType("*entity.UserToken")).Return(nil).Once()
+
+		res, err := u.LoginOAuth(context.TODO(), `test`, state, code, new(entity.Session), mockOrigin)
+
+		assert.NoError(t, err)
+		assert.NotEmpty(t, res.ChallengeToken)
+		assert.Empty(t, res.Token)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("unknown-state", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("UseOAuthState", mock.Anything, helper.HashToken(`state`)).Return(new(entity.OAuthState), nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, oauthOptions(srv))
+
+		res, err := u.LoginOAuth(context.TODO(), `test`, `state`, `code`, new(entity.Session), mockOrigin)
+
+		assert.Equal(t, response.ErrInvalidToken, err)
+		assert.Nil(t, res)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("state-of-other-provider", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("UseOAuthState", mock.Anything, helper.HashToken(`state`)).Return(&entity.OAuthState{Provider: `github`}, nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, oauthOptions(srv))
+
+		res, err := u.LoginOAuth(context.TODO(), `test`, `state`, `code`, new(entity.Session), mockOrigin)
+
+		assert.Equal(t, response.ErrInvalidToken, err)
+		assert.Nil(t, res)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("wrong-code", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, oauthOptions(srv))
+		state, _ := startOAuth(t, srv, mockUserRepo, u)
+
+		res, err := u.LoginOAuth(context.TODO(), `test`, state, `wrong`, new(entity.Session), mockOrigin)
+
+		assert.Equal(t, oidc.ErrExchange, err)
+		assert.Nil(t, res)
+		mockUserRepo.AssertExpectations(t)
+	})
+}
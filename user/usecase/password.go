@@ -0,0 +1,165 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/andhikagama/lmnlo/helper"
+	"github.com/andhikagama/lmnlo/mailer"
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/andhikagama/lmnlo/models/response"
+)
+
+const _ResetPasswordBody = `Someone asked to reset the password of your lmnlo account.
+
+Open the link below to choose a new password:
+
+%s
+
+If it was not you, you can ignore this email, your password stays the same.
+`
+
+// ForgotPassword mails a reset link to the account of email. It answers
+// the same whether or not the account exists.
+func (u *userUsecase) ForgotPassword(ctx context.Context, email string) error {
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+
+	usr, err := u.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+
+	if usr.ID == 0 {
+		return nil
+	}
+
+	since := time.Now().Add(-u.opts.PasswordResetWindow)
+	count, err := u.userRepo.CountUserTokens(ctx, usr.ID, entity.TokenPurposeResetPassword, since)
+	if err != nil {
+		return err
+	}
+
+	if count >= int64(u.opts.PasswordResetLimit) {
+		log.Warnf(`password reset limit reached for user %d`, usr.ID)
+		return nil
+	}
+
+	// Only the most recent link works
+	if err := u.userRepo.InvalidateUserTokens(ctx, usr.ID, entity.TokenPurposeResetPassword); err != nil {
+		return err
+	}
+
+	token, err := u.issuePurposeToken(ctx, usr, entity.TokenPurposeResetPassword, u.opts.PasswordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	err = u.mailer.Send(&mailer.Message{
+		To:      usr.Email,
+		Subject: `Reset your password`,
+		Body:    fmt.Sprintf(_ResetPasswordBody, u.opts.PasswordResetURL+token),
+	})
+	if err != nil {
+		log.Error(err)
+	}
+
+	return nil
+}
+
+// ResetPassword sets a new password with a mailed reset token and ends
+// every session of the account
+func (u *userUsecase) ResetPassword(ctx context.Context, token string, password string, o *entity.Origin) error {
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+
+	if !u.opts.PasswordPolicy.Allows(password) {
+		return response.ErrWeakPassword
+	}
+
+	uid, _, err := u.usePurposeToken(ctx, token, entity.TokenPurposeResetPassword)
+	if err != nil {
+		return err
+	}
+
+	hashedPass, err := u.hasher.Hash(password)
+	if err != nil {
+		return err
+	}
+
+	ok, err := u.userRepo.UpdatePassword(ctx, uid, hashedPass)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return response.ErrNotFound
+	}
+
+	u.record(ctx, selfOrigin(o, uid), entity.AuditUserResetPassword, uid, nil, nil)
+
+	if err := u.userRepo.InvalidateUserTokens(ctx, uid, entity.TokenPurposeResetPassword); err != nil {
+		return err
+	}
+
+	return u.RevokeSessions(ctx, uid)
+}
+
+// ChangePassword replaces the password of uid after checking the current
+// one, and ends every session but the one of token
+func (u *userUsecase) ChangePassword(ctx context.Context, uid int64, token string, current string, password string, o *entity.Origin) error {
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+
+	hashed, err := u.userRepo.GetPassword(ctx, uid)
+	if err != nil {
+		return err
+	}
+
+	if hashed == `` {
+		return response.ErrNotFound
+	}
+
+	if !u.hasher.Verify(hashed, current) {
+		return response.ErrWrongPassword
+	}
+
+	if !u.opts.PasswordPolicy.Allows(password) {
+		return response.ErrWeakPassword
+	}
+
+	hashedPass, err := u.hasher.Hash(password)
+	if err != nil {
+		return err
+	}
+
+	ok, err := u.userRepo.UpdatePassword(ctx, uid, hashedPass)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return response.ErrNotFound
+	}
+
+	u.record(ctx, o, entity.AuditUserChangePassword, uid, nil, nil)
+
+	// A reset link mailed before the change must not undo it
+	if err := u.userRepo.InvalidateUserTokens(ctx, uid, entity.TokenPurposeResetPassword); err != nil {
+		return err
+	}
+
+	sess, err := u.userRepo.GetSessionByToken(ctx, helper.HashToken(token))
+	if err != nil {
+		return err
+	}
+
+	if err := u.userRepo.RevokeOtherSessions(ctx, uid, sess.ID); err != nil {
+		return err
+	}
+
+	return u.userRepo.RevokeOtherRefreshTokens(ctx, uid, sess.FamilyID)
+}
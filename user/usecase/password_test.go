@@ -0,0 +1,229 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/andhikagama/lmnlo/helper"
+	"github.com/andhikagama/lmnlo/mailer"
+	mailerMocks "github.com/andhikagama/lmnlo/mailer/mocks"
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/andhikagama/lmnlo/models/response"
+	"github.com/andhikagama/lmnlo/user/mocks"
+	"github.com/andhikagama/lmnlo/user/usecase"
+)
+
+// mailedResetToken asks for a password reset through the usecase and
+// return the token it mailed
+func mailedResetToken(t *testing.T, mockUserRepo *mocks.Repository) string {
+	token := ``
+	mockMailer := new(mailerMocks.Mailer)
+	mockMailer.On("Send", mock.AnythingOfType("*mailer.Message")).Return(nil).Run(func(args mock.Arguments) {
+		body := args.Get(0).(*mailer.Message).Body
+		token = strings.Fields(body[strings.Index(body, mockOptions.PasswordResetURL)+len(mockOptions.PasswordResetURL):])[0]
+	}).Once()
+
+	mockUserRepo.On("GetByEmail", mock.Anything, mockUser.Email).Return(&entity.User{ID: 1, Email: mockUser.Email}, nil).Once()
+	mockUserRepo.On("CountUserTokens", mock.Anything, int64(1), entity.TokenPurposeResetPassword, mock.AnythingOfType("time.Time")).Return(int64(0), nil).Once()
+	mockUserRepo.On("InvalidateUserTokens", mock.Anything, int64(1), entity.TokenPurposeResetPassword).Return(nil).Once()
+	mockUserRepo.On("StoreUserToken", mock.Anything, mock.MatchedBy(func(ut *entity.UserToken) bool {
+		return ut.Purpose == entity.TokenPurposeResetPassword
+	})).Return(nil).Once()
+
+	u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+	err := u.ForgotPassword(context.TODO(), mockUser.Email)
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, token)
+	mockMailer.AssertExpectations(t)
+	return token
+}
+
+func TestForgotPassword(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+
+		mailedResetToken(t, mockUserRepo)
+
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("unknown-email", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		mockMailer := new(mailerMocks.Mailer)
+		mockUserRepo.On("GetByEmail", mock.Anything, `nobody@lmnlo.io`).Return(new(entity.User), nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		err := u.ForgotPassword(context.TODO(), `nobody@lmnlo.io`)
+
+		assert.NoError(t, err)
+		mockUserRepo.AssertExpectations(t)
+		mockMailer.AssertExpectations(t)
+	})
+
+	t.Run("rate-limited", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		mockMailer := new(mailerMocks.Mailer)
+		mockUserRepo.On("GetByEmail", mock.Anything, mockUser.Email).Return(&entity.User{ID: 1, Email: mockUser.Email}, nil).Once()
+		mockUserRepo.On("CountUserTokens", mock.Anything, int64(1), entity.TokenPurposeResetPassword, mock.AnythingOfType("time.Time")).Return(int64(3), nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		err := u.ForgotPassword(context.TODO(), mockUser.Email)
+
+		assert.NoError(t, err)
+		mockUserRepo.AssertExpectations(t)
+		mockMailer.AssertExpectations(t)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("GetByEmail", mock.Anything, mockUser.Email).Return(nil, errors.New(`Error`)).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		err := u.ForgotPassword(context.TODO(), mockUser.Email)
+
+		assert.Error(t, err)
+		mockUserRepo.AssertExpectations(t)
+	})
+}
+
+func TestResetPassword(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		token := mailedResetToken(t, mockUserRepo)
+
+		mockUserRepo.On("UseUserToken", mock.Anything, int64(1), entity.TokenPurposeResetPassword, helper.HashToken(token)).Return(true, nil).Once()
+		mockUserRepo.On("UpdatePassword", mock.Anything, int64(1), mock.MatchedBy(func(hashed string) bool {
+			return mockHasher.Verify(hashed, `new-password`)
+		})).Return(true, nil).Once()
+		mockUserRepo.On("InvalidateUserTokens", mock.Anything, int64(1), entity.TokenPurposeResetPassword).Return(nil).Once()
+		mockUserRepo.On("RevokeSessionsByUser", mock.Anything, int64(1)).Return(nil).Once()
+		mockUserRepo.On("RevokeRefreshTokensByUser", mock.Anything, int64(1)).Return(nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		err := u.ResetPassword(context.TODO(), token, `new-password`, mockOrigin)
+
+		assert.NoError(t, err)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("used", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		token := mailedResetToken(t, mockUserRepo)
+
+		mockUserRepo.On("UseUserToken", mock.Anything, int64(1), entity.TokenPurposeResetPassword, helper.HashToken(token)).Return(false, nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		err := u.ResetPassword(context.TODO(), token, `new-password`, mockOrigin)
+
+		assert.Equal(t, response.ErrInvalidToken, err)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("verification-token", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		token := mailedToken(t, mockUserRepo, mockOptions)
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		err := u.ResetPassword(context.TODO(), token, `new-password`, mockOrigin)
+
+		assert.Equal(t, response.ErrInvalidToken, err)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("weak-password", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		err := u.ResetPassword(context.TODO(), `token`, ``, mockOrigin)
+
+		assert.Equal(t, response.ErrWeakPassword, err)
+		mockUserRepo.AssertExpectations(t)
+	})
+}
+
+func TestChangePassword(t *testing.T) {
+	hashedPass, _ := mockHasher.Hash(`aiueo`)
+	sess := &entity.Session{ID: 7, UserID: 1, FamilyID: `family`, TokenHash: helper.HashToken(`token`)}
+
+	t.Run("success", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("GetPassword", mock.Anything, int64(1)).Return(hashedPass, nil).Once()
+		mockUserRepo.On("UpdatePassword", mock.Anything, int64(1), mock.MatchedBy(func(hashed string) bool {
+			return mockHasher.Verify(hashed, `new-password`)
+		})).Return(true, nil).Once()
+		mockUserRepo.On("InvalidateUserTokens", mock.Anything, int64(1), entity.TokenPurposeResetPassword).Return(nil).Once()
+		mockUserRepo.On("GetSessionByToken", mock.Anything, helper.HashToken(`token`)).Return(sess, nil).Once()
+		mockUserRepo.On("RevokeOtherSessions", mock.Anything, int64(1), int64(7)).Return(nil).Once()
+		mockUserRepo.On("RevokeOtherRefreshTokens", mock.Anything, int64(1), `family`).Return(nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		err := u.ChangePassword(context.TODO(), 1, `token`, `aiueo`, `new-password`, mockOrigin)
+
+		assert.NoError(t, err)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("wrong-password", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("GetPassword", mock.Anything, int64(1)).Return(hashedPass, nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		err := u.ChangePassword(context.TODO(), 1, `token`, `wrong`, `new-password`, mockOrigin)
+
+		assert.Equal(t, response.ErrWrongPassword, err)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("weak-password", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("GetPassword", mock.Anything, int64(1)).Return(hashedPass, nil).Once()
+		opts := mockOptions
+		opts.PasswordPolicy = helper.PasswordPolicy{MinLength: 8, RequireDigit: true}
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, opts)
+
+		err := u.ChangePassword(context.TODO(), 1, `token`, `aiueo`, `new-password`, mockOrigin)
+
+		assert.Equal(t, response.ErrWeakPassword, err)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("not-found", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("GetPassword", mock.Anything, int64(99)).Return(``, nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		err := u.ChangePassword(context.TODO(), 99, `token`, `aiueo`, `new-password`, mockOrigin)
+
+		assert.Equal(t, response.ErrNotFound, err)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("GetPassword", mock.Anything, int64(1)).Return(``, errors.New(`Error`)).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		err := u.ChangePassword(context.TODO(), 1, `token`, `aiueo`, `new-password`, mockOrigin)
+
+		assert.Error(t, err)
+		mockUserRepo.AssertExpectations(t)
+	})
+}
+
+func TestPartialUpdatePassword(t *testing.T) {
+	mockUserRepo := new(mocks.Repository)
+	mockUserRepo.On("GetByID", mock.Anything, int64(1)).Return(&entity.User{ID: 1, Email: mockUser.Email}, nil).Once()
+	u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+	res, err := u.PartialUpdate(context.TODO(), 1, []byte(`[{"op":"add","path":"/password","value":"secret"}]`), mockOrigin)
+
+	assert.Equal(t, response.ErrBadRequest, err)
+	assert.Nil(t, res)
+	mockUserRepo.AssertExpectations(t)
+}
@@ -0,0 +1,124 @@
+package usecase
+
+import (
+	"context"
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/andhikagama/lmnlo/models/response"
+)
+
+// GetRoles ...
+func (u *userUsecase) GetRoles(ctx context.Context, id int64) ([]string, error) {
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+
+	usr, err := u.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if usr.ID == 0 {
+		return nil, response.ErrNotFound
+	}
+
+	return u.userRepo.GetRoles(ctx, id)
+}
+
+// AssignRoles replaces the roles of a user. Changes apply to the next
+// request of the user.
+func (u *userUsecase) AssignRoles(ctx context.Context, id int64, roles []string, o *entity.Origin) error {
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+
+	ok, err := u.userRepo.ExistRoles(ctx, roles)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return response.ErrBadRequest
+	}
+
+	usr, err := u.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if usr.ID == 0 {
+		return response.ErrNotFound
+	}
+
+	before, err := u.userRepo.GetRoles(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := u.userRepo.SetRoles(ctx, id, roles); err != nil {
+		return err
+	}
+
+	u.users.forget(id)
+	u.record(ctx, o, entity.AuditUserAssignRoles, id, &entity.User{ID: id, Roles: before}, &entity.User{ID: id, Roles: roles})
+	return nil
+}
+
+// loadAuthorization fills roles and permissions of usr
+func (u *userUsecase) loadAuthorization(ctx context.Context, usr *entity.User) error {
+	roles, err := u.userRepo.GetRoles(ctx, usr.ID)
+	if err != nil {
+		return err
+	}
+
+	permissions, err := u.userRepo.GetPermissions(ctx, usr.ID)
+	if err != nil {
+		return err
+	}
+
+	usr.Roles = roles
+	usr.Permissions = permissions
+
+	return u.enforceMFA(ctx, usr)
+}
+
+// enforceMFA takes away roles listed in MFARoles, together with every
+// permission, from users without confirmed MFA. They can still sign in and
+// enroll, but act as plain users until they do.
+func (u *userUsecase) enforceMFA(ctx context.Context, usr *entity.User) error {
+	needsMFA := false
+	for _, r := range usr.Roles {
+		needsMFA = needsMFA || containsString(u.opts.MFARoles, r)
+	}
+
+	if !needsMFA {
+		return nil
+	}
+
+	mfa, err := u.userRepo.GetMFA(ctx, usr.ID)
+	if err != nil {
+		return err
+	}
+
+	if mfa.Confirmed() {
+		return nil
+	}
+
+	roles := make([]string, 0, len(usr.Roles))
+	for _, r := range usr.Roles {
+		if !containsString(u.opts.MFARoles, r) {
+			roles = append(roles, r)
+		}
+	}
+
+	usr.Roles = roles
+	usr.Permissions = nil
+
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/andhikagama/lmnlo/models/response"
+	"github.com/andhikagama/lmnlo/user/mocks"
+	"github.com/andhikagama/lmnlo/user/usecase"
+)
+
+func TestGetRoles(t *testing.T) {
+	mockUserRepo := new(mocks.Repository)
+
+	t.Run("success", func(t *testing.T) {
+		mockUserRepo.On("GetByID", mock.Anything, int64(1)).Return(&mockUser, nil).Once()
+		mockUserRepo.On("GetRoles", mock.Anything, int64(1)).Return([]string{entity.RoleAdmin}, nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		res, err := u.GetRoles(context.TODO(), 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{entity.RoleAdmin}, res)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("not-found", func(t *testing.T) {
+		mockUserRepo.On("GetByID", mock.Anything, int64(99)).Return(new(entity.User), nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		res, err := u.GetRoles(context.TODO(), 99)
+
+		assert.Equal(t, response.ErrNotFound, err)
+		assert.Nil(t, res)
+		mockUserRepo.AssertExpectations(t)
+	})
+}
+
+func TestAssignRoles(t *testing.T) {
+	mockUserRepo := new(mocks.Repository)
+	roles := []string{entity.RoleAdmin, entity.RoleUser}
+
+	t.Run("success", func(t *testing.T) {
+		mockUserRepo.On("ExistRoles", mock.Anything, roles).Return(true, nil).Once()
+		mockUserRepo.On("GetByID", mock.Anything, int64(1)).Return(&mockUser, nil).Once()
+		mockUserRepo.On("GetRoles", mock.Anything, int64(1)).Return([]string{entity.RoleUser}, nil).Once()
+		mockUserRepo.On("SetRoles", mock.Anything, int64(1), roles).Return(nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		err := u.AssignRoles(context.TODO(), 1, roles, mockOrigin)
+
+		assert.NoError(t, err)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("unknown-role", func(t *testing.T) {
+		mockUserRepo.On("ExistRoles", mock.Anything, []string{`wizard`}).Return(false, nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		err := u.AssignRoles(context.TODO(), 1, []string{`wizard`}, mockOrigin)
+
+		assert.Equal(t, response.ErrBadRequest, err)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("not-found", func(t *testing.T) {
+		mockUserRepo.On("ExistRoles", mock.Anything, roles).Return(true, nil).Once()
+		mockUserRepo.On("GetByID", mock.Anything, int64(99)).Return(new(entity.User), nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		err := u.AssignRoles(context.TODO(), 99, roles, mockOrigin)
+
+		assert.Equal(t, response.ErrNotFound, err)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		mockUserRepo.On("ExistRoles", mock.Anything, roles).Return(false, errors.New(`Error`)).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		err := u.AssignRoles(context.TODO(), 1, roles, mockOrigin)
+
+		assert.Error(t, err)
+		mockUserRepo.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,76 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/andhikagama/lmnlo/helper"
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/andhikagama/lmnlo/models/response"
+)
+
+// Logout ends the session of token together with its refresh tokens
+func (u *userUsecase) Logout(ctx context.Context, token string, o *entity.Origin) error {
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+
+	sess, err := u.userRepo.GetSessionByToken(ctx, helper.HashToken(token))
+	if err != nil {
+		return err
+	}
+
+	if sess.ID == 0 {
+		return response.ErrUnAuthorized
+	}
+
+	if _, err := u.userRepo.RevokeSession(ctx, sess.ID); err != nil {
+		return err
+	}
+
+	u.record(ctx, o, entity.AuditUserLogout, sess.UserID, nil, nil)
+
+	if sess.FamilyID == `` {
+		return nil
+	}
+
+	return u.userRepo.RevokeRefreshTokenFamily(ctx, sess.FamilyID)
+}
+
+// FetchSessions lists sessions of uid, flagging the one token belongs to
+func (u *userUsecase) FetchSessions(ctx context.Context, uid int64, token string) ([]*entity.Session, error) {
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+
+	sessions, err := u.userRepo.FetchSessions(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenHash := helper.HashToken(token)
+	for _, sess := range sessions {
+		sess.Current = sess.TokenHash == tokenHash
+	}
+
+	return sessions, nil
+}
+
+// RevokeSessions ends every session of uid
+func (u *userUsecase) RevokeSessions(ctx context.Context, uid int64) error {
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+
+	if err := u.userRepo.RevokeSessionsByUser(ctx, uid); err != nil {
+		return err
+	}
+
+	return u.userRepo.RevokeRefreshTokensByUser(ctx, uid)
+}
+
+// SweepSessions deletes sessions that expired or were revoked longer than
+// SessionRetention ago and returns how many
+func (u *userUsecase) SweepSessions(ctx context.Context) (int64, error) {
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+
+	return u.userRepo.PurgeTokens(ctx, time.Now().Add(-u.opts.SessionRetention))
+}
@@ -0,0 +1,153 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/andhikagama/lmnlo/helper"
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/andhikagama/lmnlo/models/response"
+	"github.com/andhikagama/lmnlo/user/mocks"
+	"github.com/andhikagama/lmnlo/user/usecase"
+)
+
+var mockSession = entity.Session{
+	ID:        1,
+	UserID:    1,
+	FamilyID:  `family`,
+	TokenHash: helper.HashToken(`token`),
+	UserAgent: `curl/7.64`,
+	IP:        `127.0.0.1`,
+}
+
+func TestLogout(t *testing.T) {
+	mockUserRepo := new(mocks.Repository)
+
+	t.Run("success", func(t *testing.T) {
+		sess := mockSession
+		mockUserRepo.On("GetSessionByToken", mock.Anything, helper.HashToken(`token`)).Return(&sess, nil).Once()
+		mockUserRepo.On("RevokeSession", mock.Anything, int64(1)).Return(true, nil).Once()
+		mockUserRepo.On("RevokeRefreshTokenFamily", mock.Anything, `family`).Return(nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		err := u.Logout(context.TODO(), `token`, mockOrigin)
+
+		assert.NoError(t, err)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("not-found", func(t *testing.T) {
+		mockUserRepo.On("GetSessionByToken", mock.Anything, helper.HashToken(`token`)).Return(new(entity.Session), nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		err := u.Logout(context.TODO(), `token`, mockOrigin)
+
+		assert.Equal(t, response.ErrUnAuthorized, err)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		sess := mockSession
+		mockUserRepo.On("GetSessionByToken", mock.Anything, helper.HashToken(`token`)).Return(&sess, nil).Once()
+		mockUserRepo.On("RevokeSession", mock.Anything, int64(1)).Return(false, errors.New(`Error`)).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		err := u.Logout(context.TODO(), `token`, mockOrigin)
+
+		assert.Error(t, err)
+		mockUserRepo.AssertExpectations(t)
+	})
+}
+
+func TestFetchSessions(t *testing.T) {
+	mockUserRepo := new(mocks.Repository)
+
+	t.Run("success", func(t *testing.T) {
+		current := mockSession
+		other := mockSession
+		other.ID = 2
+		other.TokenHash = helper.HashToken(`other`)
+
+		mockUserRepo.On("FetchSessions", mock.Anything, int64(1)).Return([]*entity.Session{&other, &current}, nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		res, err := u.FetchSessions(context.TODO(), 1, `token`)
+
+		assert.NoError(t, err)
+		assert.Len(t, res, 2)
+		assert.False(t, res[0].Current)
+		assert.True(t, res[1].Current)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		mockUserRepo.On("FetchSessions", mock.Anything, int64(1)).Return(nil, errors.New(`Error`)).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		res, err := u.FetchSessions(context.TODO(), 1, `token`)
+
+		assert.Error(t, err)
+		assert.Nil(t, res)
+		mockUserRepo.AssertExpectations(t)
+	})
+}
+
+func TestRevokeSessions(t *testing.T) {
+	mockUserRepo := new(mocks.Repository)
+
+	t.Run("success", func(t *testing.T) {
+		mockUserRepo.On("RevokeSessionsByUser", mock.Anything, int64(1)).Return(nil).Once()
+		mockUserRepo.On("RevokeRefreshTokensByUser", mock.Anything, int64(1)).Return(nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		err := u.RevokeSessions(context.TODO(), 1)
+
+		assert.NoError(t, err)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		mockUserRepo.On("RevokeSessionsByUser", mock.Anything, int64(1)).Return(errors.New(`Error`)).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		err := u.RevokeSessions(context.TODO(), 1)
+
+		assert.Error(t, err)
+		mockUserRepo.AssertExpectations(t)
+	})
+}
+
+func TestSweepSessions(t *testing.T) {
+	opts := mockOptions
+	opts.SessionRetention = 24 * time.Hour
+
+	t.Run("success", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("PurgeTokens", mock.Anything, mock.MatchedBy(func(before time.Time) bool {
+			return before.Before(time.Now().Add(-23 * time.Hour))
+		})).Return(int64(3), nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, opts)
+
+		n, err := u.SweepSessions(context.TODO())
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), n)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("PurgeTokens", mock.Anything, mock.AnythingOfType("time.Time")).Return(int64(0), errors.New(`Error`)).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, opts)
+
+		_, err := u.SweepSessions(context.TODO())
+
+		assert.Error(t, err)
+		mockUserRepo.AssertExpectations(t)
+	})
+}
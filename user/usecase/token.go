@@ -0,0 +1,277 @@
+package usecase
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/andhikagama/lmnlo/helper"
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/andhikagama/lmnlo/models/response"
+)
+
+const (
+	_RefreshTokenBytes = 32
+	_FamilyIDBytes     = 16
+)
+
+// Refresh exchanges a refresh token for a new access and refresh token pair.
+// Presenting a token that was already rotated means it leaked, so the whole
+// family is revoked and the legitimate holder has to log in again.
+func (u *userUsecase) Refresh(ctx context.Context, refreshToken string, o *entity.Origin) (*entity.User, error) {
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+
+	if refreshToken == `` {
+		return nil, response.ErrUnAuthorized
+	}
+
+	rt, err := u.userRepo.GetRefreshToken(ctx, helper.HashToken(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+
+	if rt.ID == 0 || rt.RevokedAt != nil {
+		return nil, response.ErrUnAuthorized
+	}
+
+	if rt.UsedAt != nil {
+		return nil, u.revokeFamily(ctx, rt, o)
+	}
+
+	if time.Now().After(rt.ExpiresAt) {
+		return nil, response.ErrUnAuthorized
+	}
+
+	usr, err := u.userRepo.GetByID(ctx, rt.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if usr.ID == 0 {
+		return nil, response.ErrUnAuthorized
+	}
+
+	if err := u.loadAuthorization(ctx, usr); err != nil {
+		return nil, err
+	}
+
+	next, err := u.newRefreshToken(usr, rt.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+
+	ok, err := u.userRepo.RotateRefreshToken(ctx, rt.ID, next)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		// Somebody else rotated the same token in the meantime
+		return nil, u.revokeFamily(ctx, rt, o)
+	}
+
+	sess := &entity.Session{FamilyID: rt.FamilyID, ExpiresAt: next.ExpiresAt}
+	if err := u.signAccessToken(usr, sess); err != nil {
+		return nil, err
+	}
+
+	ok, err = u.userRepo.ReplaceToken(ctx, sess)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		// The session was logged out while its refresh token was in flight
+		return nil, response.ErrUnAuthorized
+	}
+
+	return usr, nil
+}
+
+// AuthenticateToken return the user of a session access token. The session
+// is looked up by jti and has to be active. The user is loaded rather than
+// read from the token, so that changed roles, a disabled second factor or
+// a deleted account apply at once.
+func (u *userUsecase) AuthenticateToken(ctx context.Context, token string) (*entity.User, error) {
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+
+	cc := &entity.Claims{Leeway: u.opts.TokenLeeway}
+	if _, err := u.keyRing.Parse(token, cc); err != nil {
+		return nil, response.ErrUnAuthorized
+	}
+
+	if u.opts.TokenIssuer != `` && cc.Issuer != u.opts.TokenIssuer {
+		return nil, response.ErrUnAuthorized
+	}
+
+	if u.opts.TokenAudience != `` && cc.Audience != u.opts.TokenAudience {
+		return nil, response.ErrUnAuthorized
+	}
+
+	uid, err := strconv.ParseInt(cc.Subject, 10, 64)
+	if err != nil || cc.Id == `` {
+		return nil, response.ErrUnAuthorized
+	}
+
+	ok, err := u.userRepo.ValidateToken(ctx, cc.Id, helper.HashToken(token))
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return nil, response.ErrUnAuthorized
+	}
+
+	if err := u.userRepo.TouchToken(ctx, cc.Id); err != nil {
+		log.Error(err)
+	}
+
+	usr, err := u.authorizedUser(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	if cc.Act != nil {
+		usr.ActorID, err = u.authenticateActor(ctx, cc.Act)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return usr, nil
+}
+
+// authorizedUser loads the user of uid with roles and permissions, from
+// the cache when enabled. A user that is gone is unauthorized.
+func (u *userUsecase) authorizedUser(ctx context.Context, uid int64) (*entity.User, error) {
+	if usr, ok := u.users.get(uid); ok {
+		return usr, nil
+	}
+
+	usr, err := u.userRepo.GetByID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	if usr.ID == 0 {
+		return nil, response.ErrUnAuthorized
+	}
+
+	if err := u.loadAuthorization(ctx, usr); err != nil {
+		return nil, err
+	}
+
+	u.users.put(usr)
+	return usr, nil
+}
+
+// issueTokens starts a new session for usr and sets its access and refresh
+// token on usr
+func (u *userUsecase) issueTokens(ctx context.Context, usr *entity.User, sess *entity.Session) error {
+	rt, err := u.newRefreshToken(usr, ``)
+	if err != nil {
+		return err
+	}
+
+	sess.UserID = usr.ID
+	sess.FamilyID = rt.FamilyID
+	sess.ExpiresAt = rt.ExpiresAt
+
+	if err := u.signAccessToken(usr, sess); err != nil {
+		return err
+	}
+
+	if err := u.userRepo.InsertToken(ctx, sess); err != nil {
+		return err
+	}
+
+	return u.userRepo.StoreRefreshToken(ctx, rt)
+}
+
+// signAccessToken signs a token naming usr and sets it on usr, sess gets
+// its jti and digest. It carries no user data, which would go stale for
+// the lifetime of the token.
+func (u *userUsecase) signAccessToken(usr *entity.User, sess *entity.Session) error {
+	return u.signToken(usr, sess, nil, u.opts.AccessTokenTTL)
+}
+
+// signToken is signAccessToken for tokens acting on behalf of act, when
+// not nil, that last ttl
+func (u *userUsecase) signToken(usr *entity.User, sess *entity.Session, act *entity.Actor, ttl time.Duration) error {
+	now := time.Now()
+
+	jti, err := helper.GenerateRandomHex(_TokenIDBytes)
+	if err != nil {
+		return err
+	}
+
+	cc := new(entity.Claims)
+	cc.Roles = usr.Roles
+	cc.Act = act
+	cc.Subject = strconv.FormatInt(usr.ID, 10)
+	cc.Issuer = u.opts.TokenIssuer
+	cc.Audience = u.opts.TokenAudience
+	cc.Id = jti
+	cc.IssuedAt = now.Unix()
+	cc.ExpiresAt = now.Add(ttl).Unix()
+
+	token, err := u.keyRing.Sign(cc)
+	if err != nil {
+		return err
+	}
+
+	sess.TokenID = jti
+	sess.TokenHash = helper.HashToken(token)
+	usr.Token = token
+	usr.ExpiresIn = int64(ttl / time.Second)
+
+	return nil
+}
+
+// newRefreshToken sets the plain refresh token on usr and returns the
+// record to persist, which only carries its digest
+func (u *userUsecase) newRefreshToken(usr *entity.User, familyID string) (*entity.RefreshToken, error) {
+	if familyID == `` {
+		id, err := helper.GenerateRandomHex(_FamilyIDBytes)
+		if err != nil {
+			return nil, err
+		}
+		familyID = id
+	}
+
+	token, err := helper.GenerateRandomHex(_RefreshTokenBytes)
+	if err != nil {
+		return nil, err
+	}
+	usr.RefreshToken = token
+
+	return &entity.RefreshToken{
+		UserID:    usr.ID,
+		FamilyID:  familyID,
+		TokenHash: helper.HashToken(token),
+		ExpiresAt: time.Now().Add(u.opts.RefreshTokenTTL),
+	}, nil
+}
+
+func (u *userUsecase) revokeFamily(ctx context.Context, rt *entity.RefreshToken, o *entity.Origin) error {
+	log.WithFields(log.Fields{
+		`user_id`:   rt.UserID,
+		`family_id`: rt.FamilyID,
+	}).Warn(`refresh token reuse detected, revoking token family`)
+
+	if err := u.userRepo.RevokeRefreshTokenFamily(ctx, rt.FamilyID); err != nil {
+		return err
+	}
+
+	// The access token issued with the stolen refresh token goes too
+	if err := u.userRepo.RevokeSessionFamily(ctx, rt.FamilyID); err != nil {
+		return err
+	}
+
+	u.record(ctx, o, entity.AuditUserRevokeTokenFamily, rt.UserID, nil, nil)
+	return response.ErrUnAuthorized
+}
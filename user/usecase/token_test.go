@@ -0,0 +1,353 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/andhikagama/lmnlo/helper"
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/andhikagama/lmnlo/models/response"
+	"github.com/andhikagama/lmnlo/user/mocks"
+	"github.com/andhikagama/lmnlo/user/usecase"
+)
+
+func TestRefresh(t *testing.T) {
+	mockUserRepo := new(mocks.Repository)
+
+	refreshToken := `0123456789ABCDEF`
+	newMockRefreshToken := func() *entity.RefreshToken {
+		return &entity.RefreshToken{
+			ID:        7,
+			UserID:    mockUser.ID,
+			FamilyID:  `family`,
+			TokenHash: helper.HashToken(refreshToken),
+			ExpiresAt: time.Now().Add(time.Hour),
+		}
+	}
+
+	t.Run("success", func(t *testing.T) {
+		mockUserRepo.On("GetRefreshToken", mock.Anything, helper.HashToken(refreshToken)).Return(newMockRefreshToken(), nil).Once()
+		mockUserRepo.On("GetByID", mock.Anything, mockUser.ID).Return(&entity.User{ID: mockUser.ID, Email: mockUser.Email}, nil).Once()
+		mockUserRepo.On("GetRoles", mock.Anything, int64(1)).Return([]string{entity.RoleUser}, nil).Once()
+		mockUserRepo.On("GetPermissions", mock.Anything, int64(1)).Return([]string{}, nil).Once()
+		mockUserRepo.On("RotateRefreshToken", mock.Anything, int64(7), mock.MatchedBy(func(next *entity.RefreshToken) bool {
+			return next.FamilyID == `family` && next.TokenHash != helper.HashToken(refreshToken)
+		})).Return(true, nil).Once()
+		mockUserRepo.On("ReplaceToken", mock.Anything, mock.MatchedBy(func(sess *entity.Session) bool {
+			return sess.FamilyID == `family` && sess.TokenID != `` && sess.TokenHash != ``
+		})).Return(true, nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		res, err := u.Refresh(context.TODO(), refreshToken, mockOrigin)
+
+		assert.NoError(t, err)
+		assert.NotEmpty(t, res.Token)
+		assert.NotEmpty(t, res.RefreshToken)
+		assert.NotEqual(t, refreshToken, res.RefreshToken)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("reused", func(t *testing.T) {
+		used := newMockRefreshToken()
+		usedAt := time.Now().Add(-time.Minute)
+		used.UsedAt = &usedAt
+
+		mockUserRepo.On("GetRefreshToken", mock.Anything, helper.HashToken(refreshToken)).Return(used, nil).Once()
+		mockUserRepo.On("RevokeRefreshTokenFamily", mock.Anything, `family`).Return(nil).Once()
+		mockUserRepo.On("RevokeSessionFamily", mock.Anything, `family`).Return(nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		res, err := u.Refresh(context.TODO(), refreshToken, mockOrigin)
+
+		assert.Equal(t, response.ErrUnAuthorized, err)
+		assert.Nil(t, res)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("concurrent-rotation", func(t *testing.T) {
+		mockUserRepo.On("GetRefreshToken", mock.Anything, helper.HashToken(refreshToken)).Return(newMockRefreshToken(), nil).Once()
+		mockUserRepo.On("GetByID", mock.Anything, mockUser.ID).Return(&entity.User{ID: mockUser.ID}, nil).Once()
+		mockUserRepo.On("GetRoles", mock.Anything, int64(1)).Return([]string{entity.RoleUser}, nil).Once()
+		mockUserRepo.On("GetPermissions", mock.Anything, int64(1)).Return([]string{}, nil).Once()
+		mockUserRepo.On("RotateRefreshToken", mock.Anything, int64(7), mock.AnythingOfType("*entity.RefreshToken")).Return(false, nil).Once()
+		mockUserRepo.On("RevokeRefreshTokenFamily", mock.Anything, `family`).Return(nil).Once()
+		mockUserRepo.On("RevokeSessionFamily", mock.Anything, `family`).Return(nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		res, err := u.Refresh(context.TODO(), refreshToken, mockOrigin)
+
+		assert.Equal(t, response.ErrUnAuthorized, err)
+		assert.Nil(t, res)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("session-logged-out", func(t *testing.T) {
+		mockUserRepo.On("GetRefreshToken", mock.Anything, helper.HashToken(refreshToken)).Return(newMockRefreshToken(), nil).Once()
+		mockUserRepo.On("GetByID", mock.Anything, mockUser.ID).Return(&entity.User{ID: mockUser.ID}, nil).Once()
+		mockUserRepo.On("GetRoles", mock.Anything, int64(1)).Return([]string{entity.RoleUser}, nil).Once()
+		mockUserRepo.On("GetPermissions", mock.Anything, int64(1)).Return([]string{}, nil).Once()
+		mockUserRepo.On("RotateRefreshToken", mock.Anything, int64(7), mock.AnythingOfType("*entity.RefreshToken")).Return(true, nil).Once()
+		mockUserRepo.On("ReplaceToken", mock.Anything, mock.MatchedBy(func(sess *entity.Session) bool {
+			return sess.FamilyID == `family` && sess.TokenID != `` && sess.TokenHash != ``
+		})).Return(false, nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		res, err := u.Refresh(context.TODO(), refreshToken, mockOrigin)
+
+		assert.Equal(t, response.ErrUnAuthorized, err)
+		assert.Nil(t, res)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		expired := newMockRefreshToken()
+		expired.ExpiresAt = time.Now().Add(-time.Minute)
+
+		mockUserRepo.On("GetRefreshToken", mock.Anything, helper.HashToken(refreshToken)).Return(expired, nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		res, err := u.Refresh(context.TODO(), refreshToken, mockOrigin)
+
+		assert.Equal(t, response.ErrUnAuthorized, err)
+		assert.Nil(t, res)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("not-found", func(t *testing.T) {
+		mockUserRepo.On("GetRefreshToken", mock.Anything, mock.AnythingOfType("string")).Return(new(entity.RefreshToken), nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		res, err := u.Refresh(context.TODO(), `unknown`, mockOrigin)
+
+		assert.Equal(t, response.ErrUnAuthorized, err)
+		assert.Nil(t, res)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		mockUserRepo.On("GetRefreshToken", mock.Anything, mock.AnythingOfType("string")).Return(nil, errors.New(`Error`)).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		res, err := u.Refresh(context.TODO(), refreshToken, mockOrigin)
+
+		assert.Error(t, err)
+		assert.Nil(t, res)
+		mockUserRepo.AssertExpectations(t)
+	})
+}
+
+// accessToken signs session claims as the usecase does, edited by mod
+func accessToken(mod func(cc *entity.Claims)) string {
+	now := time.Now()
+	cc := &entity.Claims{StandardClaims: jwt.StandardClaims{
+		Id:        `jti`,
+		Subject:   `1`,
+		Issuer:    mockOptions.TokenIssuer,
+		Audience:  mockOptions.TokenAudience,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(time.Minute).Unix(),
+	}}
+	if mod != nil {
+		mod(cc)
+	}
+
+	token, _ := mockKeyRing.Sign(cc)
+	return token
+}
+
+// activeSession expects token to be checked against an active session
+func activeSession(mockUserRepo *mocks.Repository, token string) {
+	mockUserRepo.On("ValidateToken", mock.Anything, `jti`, helper.HashToken(token)).Return(true, nil).Once()
+	mockUserRepo.On("TouchToken", mock.Anything, `jti`).Return(nil).Once()
+}
+
+func TestAuthenticateToken(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("GetByID", mock.Anything, int64(1)).Return(&entity.User{ID: 1, Email: `fresh@example.com`}, nil).Once()
+		mockUserRepo.On("GetRoles", mock.Anything, int64(1)).Return([]string{entity.RoleAdmin}, nil).Once()
+		mockUserRepo.On("GetPermissions", mock.Anything, int64(1)).Return([]string{entity.PermissionUserDelete}, nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		// Roles in the token are not trusted
+		token := accessToken(func(cc *entity.Claims) {
+			cc.Roles = []string{entity.RoleUser}
+		})
+		activeSession(mockUserRepo, token)
+
+		res, err := u.AuthenticateToken(context.TODO(), token)
+
+		assert.NoError(t, err)
+		assert.Equal(t, `fresh@example.com`, res.Email)
+		assert.Equal(t, []string{entity.RoleAdmin}, res.Roles)
+		assert.Equal(t, []string{entity.PermissionUserDelete}, res.Permissions)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("issued-token", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("GetRefreshToken", mock.Anything, mock.AnythingOfType("string")).Return(&entity.RefreshToken{
+			ID:        7,
+			UserID:    1,
+			FamilyID:  `family`,
+			ExpiresAt: time.Now().Add(time.Hour),
+		}, nil).Once()
+		mockUserRepo.On("GetByID", mock.Anything, int64(1)).Return(&entity.User{ID: 1, Email: mockUser.Email}, nil)
+		mockUserRepo.On("GetRoles", mock.Anything, int64(1)).Return([]string{entity.RoleUser}, nil)
+		mockUserRepo.On("GetPermissions", mock.Anything, int64(1)).Return([]string{}, nil)
+		mockUserRepo.On("RotateRefreshToken", mock.Anything, int64(7), mock.AnythingOfType("*entity.RefreshToken")).Return(true, nil).Once()
+		var replaced *entity.Session
+		mockUserRepo.On("ReplaceToken", mock.Anything, mock.AnythingOfType("*entity.Session")).Run(func(args mock.Arguments) {
+			replaced = args.Get(1).(*entity.Session)
+		}).Return(true, nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		issued, err := u.Refresh(context.TODO(), `0123456789ABCDEF`, mockOrigin)
+		assert.NoError(t, err)
+		assert.Equal(t, helper.HashToken(issued.Token), replaced.TokenHash)
+		mockUserRepo.On("ValidateToken", mock.Anything, replaced.TokenID, replaced.TokenHash).Return(true, nil).Once()
+		mockUserRepo.On("TouchToken", mock.Anything, replaced.TokenID).Return(nil).Once()
+
+		cc := jwt.MapClaims{}
+		_, _, err = new(jwt.Parser).ParseUnverified(issued.Token, cc)
+		assert.NoError(t, err)
+		assert.Equal(t, replaced.TokenID, cc[`jti`])
+		assert.Equal(t, `1`, cc[`sub`])
+		assert.Equal(t, `lmnlo`, cc[`iss`])
+		assert.Equal(t, `lmnlo-api`, cc[`aud`])
+		assert.NotContains(t, cc, `user`)
+		assert.NotContains(t, issued.Token, issued.RefreshToken)
+
+		res, err := u.AuthenticateToken(context.TODO(), issued.Token)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), res.ID)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("clock-skew", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("GetByID", mock.Anything, int64(1)).Return(&entity.User{ID: 1}, nil).Once()
+		mockUserRepo.On("GetRoles", mock.Anything, int64(1)).Return([]string{}, nil).Once()
+		mockUserRepo.On("GetPermissions", mock.Anything, int64(1)).Return([]string{}, nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		token := accessToken(func(cc *entity.Claims) {
+			cc.IssuedAt = time.Now().Add(30 * time.Second).Unix()
+			cc.ExpiresAt = time.Now().Add(-30 * time.Second).Unix()
+		})
+		activeSession(mockUserRepo, token)
+
+		res, err := u.AuthenticateToken(context.TODO(), token)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), res.ID)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	cases := []struct {
+		name string
+		mod  func(cc *entity.Claims)
+	}{
+		{`expired`, func(cc *entity.Claims) { cc.ExpiresAt = time.Now().Add(-2 * time.Minute).Unix() }},
+		{`no-expiry`, func(cc *entity.Claims) { cc.ExpiresAt = 0 }},
+		{`not-yet-valid`, func(cc *entity.Claims) { cc.NotBefore = time.Now().Add(2 * time.Minute).Unix() }},
+		{`wrong-issuer`, func(cc *entity.Claims) { cc.Issuer = `someone-else` }},
+		{`wrong-audience`, func(cc *entity.Claims) { cc.Audience = `client` }},
+		{`bad-subject`, func(cc *entity.Claims) { cc.Subject = `admin` }},
+		{`no-jti`, func(cc *entity.Claims) { cc.Id = `` }},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockUserRepo := new(mocks.Repository)
+			u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+			res, err := u.AuthenticateToken(context.TODO(), accessToken(tc.mod))
+
+			assert.Equal(t, response.ErrUnAuthorized, err)
+			assert.Nil(t, res)
+			mockUserRepo.AssertExpectations(t)
+		})
+	}
+
+	t.Run("revoked", func(t *testing.T) {
+		token := accessToken(nil)
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("ValidateToken", mock.Anything, `jti`, helper.HashToken(token)).Return(false, nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		res, err := u.AuthenticateToken(context.TODO(), token)
+
+		assert.Equal(t, response.ErrUnAuthorized, err)
+		assert.Nil(t, res)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("user-gone", func(t *testing.T) {
+		token := accessToken(nil)
+		mockUserRepo := new(mocks.Repository)
+		activeSession(mockUserRepo, token)
+		mockUserRepo.On("GetByID", mock.Anything, int64(1)).Return(new(entity.User), nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		res, err := u.AuthenticateToken(context.TODO(), token)
+
+		assert.Equal(t, response.ErrUnAuthorized, err)
+		assert.Nil(t, res)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		token := accessToken(nil)
+		mockUserRepo := new(mocks.Repository)
+		activeSession(mockUserRepo, token)
+		mockUserRepo.On("GetByID", mock.Anything, int64(1)).Return(nil, errors.New(`Unexpected Error`)).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		res, err := u.AuthenticateToken(context.TODO(), token)
+
+		assert.Error(t, err)
+		assert.Nil(t, res)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("cached", func(t *testing.T) {
+		opts := mockOptions
+		opts.UserCacheTTL = time.Minute
+
+		token := accessToken(nil)
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("ValidateToken", mock.Anything, `jti`, helper.HashToken(token)).Return(true, nil).Times(3)
+		mockUserRepo.On("TouchToken", mock.Anything, `jti`).Return(nil).Times(3)
+		mockUserRepo.On("GetByID", mock.Anything, int64(1)).Return(&entity.User{ID: 1}, nil).Times(3)
+		mockUserRepo.On("GetRoles", mock.Anything, int64(1)).Return([]string{entity.RoleUser}, nil).Twice()
+		mockUserRepo.On("GetRoles", mock.Anything, int64(1)).Return([]string{entity.RoleAdmin}, nil).Once()
+		mockUserRepo.On("GetPermissions", mock.Anything, int64(1)).Return([]string{}, nil).Twice()
+		mockUserRepo.On("ExistRoles", mock.Anything, []string{entity.RoleAdmin}).Return(true, nil).Once()
+		mockUserRepo.On("SetRoles", mock.Anything, int64(1), []string{entity.RoleAdmin}).Return(nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, opts)
+
+		first, err := u.AuthenticateToken(context.TODO(), token)
+		assert.NoError(t, err)
+		first.Roles = nil
+
+		second, err := u.AuthenticateToken(context.TODO(), token)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{entity.RoleUser}, second.Roles)
+
+		// Assigned roles apply to the next request
+		assert.NoError(t, u.AssignRoles(context.TODO(), 1, []string{entity.RoleAdmin}, mockOrigin))
+
+		third, err := u.AuthenticateToken(context.TODO(), token)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{entity.RoleAdmin}, third.Roles)
+		mockUserRepo.AssertExpectations(t)
+	})
+}
@@ -1,38 +1,129 @@
 package usecase
 
 import (
+	"context"
 	"encoding/json"
+	"strings"
 	"time"
 
+	log "github.com/sirupsen/logrus"
 	patch "gopkg.in/evanphx/json-patch.v4"
 
+	"github.com/andhikagama/lmnlo/audit"
 	"github.com/andhikagama/lmnlo/helper"
+	"github.com/andhikagama/lmnlo/keyring"
+	"github.com/andhikagama/lmnlo/lockout"
+	"github.com/andhikagama/lmnlo/mailer"
 	"github.com/andhikagama/lmnlo/models/entity"
 	"github.com/andhikagama/lmnlo/models/filter"
 	"github.com/andhikagama/lmnlo/models/response"
+	"github.com/andhikagama/lmnlo/oidc"
 	"github.com/andhikagama/lmnlo/user"
 )
 
+// _DummyPassword is hashed once so that logins for unknown emails spend
+// the same time verifying as logins for existing accounts
+const _DummyPassword = `lmnlo-dummy-password`
+
+// readOnlyPaths are the members of a user a patch may not touch, they
+// change through their own endpoints or not at all
+var readOnlyPaths = []string{`/id`, `/password`, `/roles`, `/permissions`, `/verified_at`}
+
+// Options holds tunables of the user usecase
+type Options struct {
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	VerifyEmailTTL  time.Duration
+	// VerifyEmailURL is the link mailed for verification, the token is
+	// appended to it
+	VerifyEmailURL string
+	// RequireVerifiedEmail blocks login until the email is verified
+	RequireVerifiedEmail bool
+	PasswordResetTTL     time.Duration
+	// PasswordResetURL is the link mailed for password reset, the token is
+	// appended to it
+	PasswordResetURL string
+	// PasswordResetLimit is how many reset mails one account may be sent
+	// within PasswordResetWindow
+	PasswordResetLimit  int
+	PasswordResetWindow time.Duration
+	PasswordPolicy      helper.PasswordPolicy
+	MFAChallengeTTL     time.Duration
+	// MFAIssuer names the service in authenticator apps
+	MFAIssuer string
+	// MFARoles only apply to users who have confirmed MFA
+	MFARoles []string
+	// OAuthProviders are the external identity providers by name
+	OAuthProviders map[string]oidc.Provider
+	OAuthStateTTL  time.Duration
+	// TokenIssuer and TokenAudience are stamped into access tokens and
+	// required of them when set
+	TokenIssuer   string
+	TokenAudience string
+	// TokenLeeway tolerates clock skew when token times are checked
+	TokenLeeway time.Duration
+	// SessionRetention keeps expired and revoked sessions around before
+	// SweepSessions deletes them
+	SessionRetention time.Duration
+	// UserCacheTTL keeps users loaded for requests in memory, changes made
+	// through another replica show up once it passed. Zero disables it.
+	UserCacheTTL time.Duration
+	// ImpersonationTTL is how long the token of a support admin acting as
+	// another user lasts, it can not be refreshed
+	ImpersonationTTL time.Duration
+	// ContextTimeout bounds every call, the database work of a request is
+	// cancelled once it passed or the client went away. Zero only stops at
+	// the latter.
+	ContextTimeout time.Duration
+}
+
 type userUsecase struct {
-	userRepo user.Repository
+	userRepo  user.Repository
+	hasher    helper.PasswordHasher
+	keyRing   *keyring.KeyRing
+	mailer    mailer.Mailer
+	lockout   lockout.Usecase
+	audit     audit.Usecase
+	opts      Options
+	dummyHash string
+	users     *userCache
 }
 
 // NewUserUsecase ...
 func NewUserUsecase(
 	r user.Repository,
+	h helper.PasswordHasher,
+	kr *keyring.KeyRing,
+	m mailer.Mailer,
+	lk lockout.Usecase,
+	au audit.Usecase,
+	opts Options,
 ) user.Usecase {
+	dummyHash, _ := h.Hash(_DummyPassword)
+
 	return &userUsecase{
 		r,
+		h,
+		kr,
+		m,
+		lk,
+		au,
+		opts,
+		dummyHash,
+		newUserCache(opts.UserCacheTTL),
 	}
 }
 
 // Register ...
-func (u *userUsecase) Register(usr *entity.User) error {
+func (u *userUsecase) Register(ctx context.Context, usr *entity.User, o *entity.Origin) error {
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+
 	f := new(filter.User)
 	f.Email = usr.Email
 	f.Num = 1
 
-	usrs, err := u.userRepo.Fetch(f)
+	usrs, err := u.userRepo.Fetch(ctx, f)
 	if err != nil {
 		return err
 	}
@@ -41,23 +132,61 @@ func (u *userUsecase) Register(usr *entity.User) error {
 		return response.ErrAlreadyExist
 	}
 
-	encryptedPass, err := helper.EncryptToString(usr.Password)
+	if !u.opts.PasswordPolicy.Allows(usr.Password) {
+		return response.ErrWeakPassword
+	}
+
+	hashedPass, err := u.hasher.Hash(usr.Password)
 	if err != nil {
 		return err
 	}
-	usr.Password = encryptedPass
+	usr.Password = hashedPass
+	usr.Roles = []string{entity.RoleUser}
+
+	if err := u.userRepo.Store(ctx, usr); err != nil {
+		return err
+	}
+	usr.Password = ``
+	u.record(ctx, o, entity.AuditUserRegister, usr.ID, nil, usr)
 
-	return u.userRepo.Store(usr)
+	// A failed mail leaves the account in place, the user can ask for
+	// another verification mail
+	if err := u.sendVerification(ctx, usr); err != nil {
+		log.Error(err)
+	}
+
+	return nil
 }
 
 // Fetch ...
-func (u *userUsecase) Fetch(f *filter.User) ([]*entity.User, error) {
-	return u.userRepo.Fetch(f)
+func (u *userUsecase) Fetch(ctx context.Context, f *filter.User) ([]*entity.User, error) {
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+
+	return u.userRepo.Fetch(ctx, f)
 }
 
 // Update ...
-func (u *userUsecase) Update(usr *entity.User) error {
-	ok, err := u.userRepo.Update(usr)
+func (u *userUsecase) Update(ctx context.Context, usr *entity.User, o *entity.Origin) error {
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+
+	// The password is only changed through ChangePassword and ResetPassword
+	if usr.Password != `` {
+		return response.ErrBadRequest
+	}
+
+	before, err := u.userRepo.GetByID(ctx, usr.ID)
+	if err != nil {
+		return err
+	}
+
+	if before.ID == 0 {
+		return response.ErrNotFound
+	}
+
+	ok, err := u.userRepo.Update(ctx, usr)
+	u.users.forget(usr.ID)
 
 	if err != nil {
 		return err
@@ -67,17 +196,33 @@ func (u *userUsecase) Update(usr *entity.User) error {
 		return response.ErrNotFound
 	}
 
+	// Only email and address are written
+	after := *before
+	after.Email = usr.Email
+	after.Address = usr.Address
+	if after.Email != before.Email {
+		after.VerifiedAt = nil
+	}
+	u.record(ctx, o, entity.AuditUserUpdate, usr.ID, before, &after)
+
 	return nil
 }
 
 // GetByID ...
-func (u *userUsecase) GetByID(id int64) (*entity.User, error) {
-	return u.userRepo.GetByID(id)
+func (u *userUsecase) GetByID(ctx context.Context, id int64) (*entity.User, error) {
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+
+	return u.userRepo.GetByID(ctx, id)
 }
 
 // Delete ...
-func (u *userUsecase) Delete(id int64) error {
-	ok, err := u.userRepo.Delete(id)
+func (u *userUsecase) Delete(ctx context.Context, id int64, o *entity.Origin) error {
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+
+	ok, err := u.userRepo.Delete(ctx, id)
+	u.users.forget(id)
 
 	if err != nil {
 		return err
@@ -87,17 +232,33 @@ func (u *userUsecase) Delete(id int64) error {
 		return response.ErrNotFound
 	}
 
+	if err := u.userRepo.RevokeSessionsByUser(ctx, id); err != nil {
+		return err
+	}
+
+	if err := u.userRepo.RevokeRefreshTokensByUser(ctx, id); err != nil {
+		return err
+	}
+
+	if err := u.userRepo.DeleteAPIKeysByUser(ctx, id); err != nil {
+		return err
+	}
+
+	u.record(ctx, o, entity.AuditUserDelete, id, nil, nil)
 	return nil
 }
 
 // PartialUpdate ...
-func (u *userUsecase) PartialUpdate(id int64, byteObj []byte) (*entity.User, error) {
-	existingUser, err := u.userRepo.GetByID(id)
+func (u *userUsecase) PartialUpdate(ctx context.Context, id int64, byteObj []byte, o *entity.Origin) (*entity.User, error) {
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+
+	existingUser, err := u.userRepo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
-	if existingUser == nil {
+	if existingUser.ID == 0 {
 		return new(entity.User), response.ErrNotFound
 	}
 
@@ -108,6 +269,12 @@ func (u *userUsecase) PartialUpdate(id int64, byteObj []byte) (*entity.User, err
 		return nil, err
 	}
 
+	for _, op := range patchObj {
+		if touchesReadOnly(op) {
+			return nil, response.ErrBadRequest
+		}
+	}
+
 	jsonTarget, err = patchObj.Apply(jsonTarget)
 	if err != nil {
 		return nil, err
@@ -120,7 +287,18 @@ func (u *userUsecase) PartialUpdate(id int64, byteObj []byte) (*entity.User, err
 		return nil, err
 	}
 
-	ok, err := u.userRepo.Update(updatedUser)
+	if updatedUser.Password != `` {
+		return nil, response.ErrBadRequest
+	}
+	updatedUser.ID = id
+
+	// The repository unverifies a changed address
+	if updatedUser.Email != existingUser.Email {
+		updatedUser.VerifiedAt = nil
+	}
+
+	ok, err := u.userRepo.Update(ctx, updatedUser)
+	u.users.forget(id)
 	if err != nil {
 		return nil, err
 	}
@@ -129,45 +307,95 @@ func (u *userUsecase) PartialUpdate(id int64, byteObj []byte) (*entity.User, err
 		return nil, response.ErrNotFound
 	}
 
+	u.record(ctx, o, entity.AuditUserUpdate, id, existingUser, updatedUser)
 	return updatedUser, nil
 }
 
+// touchesReadOnly reports whether op writes or reads one of readOnlyPaths,
+// a copy from the password would leak its hash into another member
+func touchesReadOnly(op patch.Operation) bool {
+	path, _ := op.Path()
+	from, _ := op.From()
+
+	for _, ro := range readOnlyPaths {
+		for _, p := range []string{path, from} {
+			if p == ro || strings.HasPrefix(p, ro+`/`) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
 // Login ...
-func (u *userUsecase) Login(usr *entity.User) (*entity.User, error) {
-	encryptedPass, _ := helper.EncryptToString(usr.Password)
-	usr.Password = encryptedPass
+func (u *userUsecase) Login(ctx context.Context, usr *entity.User, sess *entity.Session, o *entity.Origin) (*entity.User, error) {
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
 
-	f := new(filter.User)
-	f.Email = usr.Email
-	f.Password = usr.Password
-	f.Num = 1
+	if err := u.checkLockout(ctx, usr.Email, sess); err != nil {
+		return nil, err
+	}
 
-	usrs, err := u.userRepo.Fetch(f)
-	if len(usrs) == 0 {
+	existingUser, err := u.userRepo.GetByEmail(ctx, usr.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	if existingUser.ID == 0 {
+		u.hasher.Verify(u.dummyHash, usr.Password)
+		u.failLogin(ctx, usr.Email, sess)
+		u.record(ctx, o, entity.AuditUserLoginFailed, 0, nil, nil)
 		return nil, response.ErrLogin
 	}
 
+	if !u.hasher.Verify(existingUser.Password, usr.Password) {
+		u.failLogin(ctx, usr.Email, sess)
+		u.record(ctx, o, entity.AuditUserLoginFailed, existingUser.ID, nil, nil)
+		return nil, response.ErrLogin
+	}
+
+	if u.opts.RequireVerifiedEmail && existingUser.VerifiedAt == nil {
+		return nil, response.ErrUnverified
+	}
+
+	if u.hasher.NeedsRehash(existingUser.Password) {
+		u.rehashPassword(ctx, existingUser.ID, usr.Password)
+	}
+
+	usr = existingUser
+	usr.Password = ``
+
+	mfa, err := u.userRepo.GetMFA(ctx, usr.ID)
 	if err != nil {
 		return nil, err
 	}
 
-	usr = usrs[0]
-	usr.Password = ``
+	// Users with a second factor are recorded once LoginMFA passes
+	if mfa.Confirmed() {
+		return u.challengeMFA(ctx, usr)
+	}
 
-	cc := new(entity.Claims)
-	cc.User = usr
-	cc.IssuedAt = time.Now().Unix()
-	cc.ExpiresAt = time.Now().AddDate(0, 1, 0).Unix()
+	u.record(ctx, selfOrigin(o, usr.ID), entity.AuditUserLogin, usr.ID, nil, nil)
+	return u.completeLogin(ctx, usr, sess)
+}
 
-	token := helper.GenerateTokenString(cc)
-	usr.Token = token
+// completeLogin issues the tokens of a user who passed every factor
+func (u *userUsecase) completeLogin(ctx context.Context, usr *entity.User, sess *entity.Session) (*entity.User, error) {
+	if err := u.loadAuthorization(ctx, usr); err != nil {
+		return nil, err
+	}
 
-	err = u.userRepo.InsertToken(usr.ID, token)
-	if err != nil {
+	if sess == nil {
+		sess = new(entity.Session)
+	}
+
+	if err := u.issueTokens(ctx, usr, sess); err != nil {
 		return nil, err
 	}
 
-	ok, err := u.userRepo.Update(usr)
+	ok, err := u.userRepo.Update(ctx, usr)
+	u.users.forget(usr.ID)
 	if err != nil {
 		return nil, err
 	}
@@ -176,5 +404,85 @@ func (u *userUsecase) Login(usr *entity.User) (*entity.User, error) {
 		return nil, nil
 	}
 
+	// Failures are only cleared once every factor passed, otherwise a
+	// known password would reset the count of guessed codes
+	if err := u.lockout.Succeed(ctx, usr.Email); err != nil {
+		log.Error(err)
+	}
+
 	return usr, nil
 }
+
+// checkLockout refuses attempts while the account or the address of sess
+// is locked
+func (u *userUsecase) checkLockout(ctx context.Context, email string, sess *entity.Session) error {
+	wait, err := u.lockout.Check(ctx, email, sessionIP(sess))
+	if err != nil {
+		return err
+	}
+
+	if wait > 0 {
+		return &response.LockedError{RetryAfter: wait}
+	}
+
+	return nil
+}
+
+// failLogin counts a failed attempt, failures to count are logged only
+func (u *userUsecase) failLogin(ctx context.Context, email string, sess *entity.Session) {
+	if err := u.lockout.Fail(ctx, email, sessionIP(sess)); err != nil {
+		log.Error(err)
+	}
+}
+
+func sessionIP(sess *entity.Session) string {
+	if sess == nil {
+		return ``
+	}
+
+	return sess.IP
+}
+
+// withTimeout derives the context of a call from ctx, bounded by
+// ContextTimeout when set
+func (u *userUsecase) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if u.opts.ContextTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, u.opts.ContextTimeout)
+}
+
+// record writes an audit event, failures are logged only so that a broken
+// sink does not take the audited action down with it
+func (u *userUsecase) record(ctx context.Context, o *entity.Origin, action string, targetID int64, before interface{}, after interface{}) {
+	if err := u.audit.Record(ctx, o, action, targetID, before, after); err != nil {
+		log.Error(err)
+	}
+}
+
+// selfOrigin return o acting as uid, for anonymous requests such as a
+// login that identified the user along the way
+func selfOrigin(o *entity.Origin, uid int64) *entity.Origin {
+	if o == nil {
+		return nil
+	}
+
+	self := *o
+	self.ActorID = uid
+	return &self
+}
+
+// rehashPassword upgrades a stored hash to the current algorithm. Failures
+// are logged only, the user already proved knowledge of the password.
+func (u *userUsecase) rehashPassword(ctx context.Context, id int64, password string) {
+	hashedPass, err := u.hasher.Hash(password)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	if _, err := u.userRepo.UpdatePassword(ctx, id, hashedPass); err != nil {
+		log.Error(err)
+	}
+}
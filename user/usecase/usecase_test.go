@@ -1,9 +1,21 @@
 package usecase_test
 
 import (
+	"context"
 	"errors"
+	"strings"
 	"testing"
+	"time"
 
+	"golang.org/x/crypto/bcrypt"
+
+	auditUsecase "github.com/andhikagama/lmnlo/audit/usecase"
+	"github.com/andhikagama/lmnlo/helper"
+	"github.com/andhikagama/lmnlo/keyring"
+	lockoutMemory "github.com/andhikagama/lmnlo/lockout/repository/memory"
+	lockoutUsecase "github.com/andhikagama/lmnlo/lockout/usecase"
+	"github.com/andhikagama/lmnlo/mailer"
+	mailerMocks "github.com/andhikagama/lmnlo/mailer/mocks"
 	"github.com/andhikagama/lmnlo/models/filter"
 	"github.com/andhikagama/lmnlo/models/response"
 
@@ -25,25 +37,94 @@ var mockUsers = []*entity.User{
 	&mockUser,
 }
 
+var mockHasher = helper.NewBcryptHasher(bcrypt.MinCost)
+
+var mockKeyRing, _ = keyring.NewKeyRing(`test`, keyring.NewHMACKey(`test`, []byte(`secret`)))
+
+var mockMailer = new(mailerMocks.Mailer)
+
+// mockLockout tracks nothing, lockout is tested on its own usecase
+var mockLockout = lockoutUsecase.NewLockoutUsecase(lockoutMemory.NewLockoutRepository(), lockoutUsecase.Options{})
+
+// mockAudit records nowhere, what is recorded is tested in TestAudit
+var mockAudit = auditUsecase.NewAuditUsecase()
+
+var mockOrigin = &entity.Origin{IP: `10.0.0.1`, RequestID: `request`}
+
+var mockOptions = usecase.Options{
+	AccessTokenTTL:  15 * time.Minute,
+	RefreshTokenTTL: 24 * time.Hour,
+	VerifyEmailTTL:  48 * time.Hour,
+	VerifyEmailURL:  `http://localhost/verify-email?token=`,
+
+	PasswordResetTTL:    time.Hour,
+	PasswordResetURL:    `http://localhost/password/reset?token=`,
+	PasswordResetLimit:  3,
+	PasswordResetWindow: time.Hour,
+	TokenIssuer:         `lmnlo`,
+	TokenAudience:       `lmnlo-api`,
+	TokenLeeway:         time.Minute,
+	ImpersonationTTL:    5 * time.Minute,
+}
+
 func TestStore(t *testing.T) {
 	mockUserRepo := new(mocks.Repository)
 
 	t.Run("success", func(t *testing.T) {
-		mockUserRepo.On("Fetch", mock.AnythingOfType("*filter.User")).Return(make([]*entity.User, 0), nil).Once()
-		mockUserRepo.On("Store", mock.AnythingOfType("*entity.User")).Return(nil).Once()
-		u := usecase.NewUserUsecase(mockUserRepo)
+		mockUserRepo.On("Fetch", mock.Anything, mock.AnythingOfType("*filter.User")).Return(make([]*entity.User, 0), nil).Once()
+		mockUserRepo.On("Store", mock.Anything, mock.MatchedBy(func(usr *entity.User) bool {
+			return len(usr.Roles) == 1 && usr.Roles[0] == entity.RoleUser
+		})).Return(nil).Once()
+		mockUserRepo.On("StoreUserToken", mock.Anything, mock.MatchedBy(func(ut *entity.UserToken) bool {
+			return ut.Purpose == entity.TokenPurposeVerifyEmail && ut.TokenHash != ``
+		})).Return(nil).Once()
+		mockMailer.On("Send", mock.MatchedBy(func(msg *mailer.Message) bool {
+			return msg.To == mockUser.Email && strings.Contains(msg.Body, mockOptions.VerifyEmailURL)
+		})).Return(nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+		usr := mockUser
+
+		err := u.Register(context.TODO(), &usr, mockOrigin)
+
+		assert.NoError(t, err)
+		assert.Empty(t, usr.Password)
+		mockUserRepo.AssertExpectations(t)
+		mockMailer.AssertExpectations(t)
+	})
 
-		err := u.Register(&mockUser)
+	t.Run("success-mail-error", func(t *testing.T) {
+		mockUserRepo.On("Fetch", mock.Anything, mock.AnythingOfType("*filter.User")).Return(make([]*entity.User, 0), nil).Once()
+		mockUserRepo.On("Store", mock.Anything, mock.AnythingOfType("*entity.User")).Return(nil).Once()
+		mockUserRepo.On("StoreUserToken", mock.Anything, mock.AnythingOfType("*entity.UserToken")).Return(nil).Once()
+		mockMailer.On("Send", mock.AnythingOfType("*mailer.Message")).Return(errors.New(`error`)).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+		usr := mockUser
+
+		err := u.Register(context.TODO(), &usr, mockOrigin)
 
 		assert.NoError(t, err)
 		mockUserRepo.AssertExpectations(t)
+		mockMailer.AssertExpectations(t)
+	})
+
+	t.Run("weak-password", func(t *testing.T) {
+		mockUserRepo.On("Fetch", mock.Anything, mock.AnythingOfType("*filter.User")).Return(make([]*entity.User, 0), nil).Once()
+		opts := mockOptions
+		opts.PasswordPolicy = helper.PasswordPolicy{MinLength: 8}
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, opts)
+
+		err := u.Register(context.TODO(), &entity.User{Email: mockUser.Email, Password: `aiueo`}, mockOrigin)
+
+		assert.Equal(t, response.ErrWeakPassword, err)
+		mockUserRepo.AssertExpectations(t)
 	})
 
 	t.Run("already-exist", func(t *testing.T) {
-		mockUserRepo.On("Fetch", mock.AnythingOfType("*filter.User")).Return(mockUsers, nil).Once()
-		u := usecase.NewUserUsecase(mockUserRepo)
+		mockUserRepo.On("Fetch", mock.Anything, mock.AnythingOfType("*filter.User")).Return(mockUsers, nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+		usr := mockUser
 
-		err := u.Register(&mockUser)
+		err := u.Register(context.TODO(), &usr, mockOrigin)
 
 		assert.Error(t, err)
 		assert.EqualError(t, err, response.ErrAlreadyExist.Error())
@@ -51,11 +132,12 @@ func TestStore(t *testing.T) {
 	})
 
 	t.Run("error", func(t *testing.T) {
-		mockUserRepo.On("Fetch", mock.AnythingOfType("*filter.User")).Return(make([]*entity.User, 0), nil).Once()
-		mockUserRepo.On("Store", mock.AnythingOfType("*entity.User")).Return(errors.New(`error`)).Once()
-		u := usecase.NewUserUsecase(mockUserRepo)
+		mockUserRepo.On("Fetch", mock.Anything, mock.AnythingOfType("*filter.User")).Return(make([]*entity.User, 0), nil).Once()
+		mockUserRepo.On("Store", mock.Anything, mock.AnythingOfType("*entity.User")).Return(errors.New(`error`)).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+		usr := mockUser
 
-		err := u.Register(&mockUser)
+		err := u.Register(context.TODO(), &usr, mockOrigin)
 
 		assert.Error(t, err)
 		mockUserRepo.AssertExpectations(t)
@@ -67,10 +149,10 @@ func TestFetch(t *testing.T) {
 
 	t.Run("success", func(t *testing.T) {
 		f := new(filter.User)
-		mockUserRepo.On("Fetch", mock.AnythingOfType("*filter.User")).Return(mockUsers, nil).Once()
-		u := usecase.NewUserUsecase(mockUserRepo)
+		mockUserRepo.On("Fetch", mock.Anything, mock.AnythingOfType("*filter.User")).Return(mockUsers, nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
 
-		res, err := u.Fetch(f)
+		res, err := u.Fetch(context.TODO(), f)
 
 		assert.NoError(t, err)
 		assert.Equal(t, mockUsers, res)
@@ -80,10 +162,10 @@ func TestFetch(t *testing.T) {
 	t.Run("success-no-data", func(t *testing.T) {
 		f := new(filter.User)
 		mockEmptyUsers := make([]*entity.User, 0)
-		mockUserRepo.On("Fetch", mock.AnythingOfType("*filter.User")).Return(mockEmptyUsers, nil).Once()
-		u := usecase.NewUserUsecase(mockUserRepo)
+		mockUserRepo.On("Fetch", mock.Anything, mock.AnythingOfType("*filter.User")).Return(mockEmptyUsers, nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
 
-		res, err := u.Fetch(f)
+		res, err := u.Fetch(context.TODO(), f)
 
 		assert.NoError(t, err)
 		assert.Equal(t, mockEmptyUsers, res)
@@ -93,10 +175,10 @@ func TestFetch(t *testing.T) {
 	t.Run("error", func(t *testing.T) {
 		f := new(filter.User)
 
-		mockUserRepo.On("Fetch", mock.AnythingOfType("*filter.User")).Return(nil, errors.New(`Error`)).Once()
-		u := usecase.NewUserUsecase(mockUserRepo)
+		mockUserRepo.On("Fetch", mock.Anything, mock.AnythingOfType("*filter.User")).Return(nil, errors.New(`Error`)).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
 
-		res, err := u.Fetch(f)
+		res, err := u.Fetch(context.TODO(), f)
 
 		assert.Error(t, err)
 		assert.Nil(t, res)
@@ -108,45 +190,67 @@ func TestUpdate(t *testing.T) {
 	mockUserRepo := new(mocks.Repository)
 
 	t.Run("success", func(t *testing.T) {
-		mockUserRepo.On("Update", mock.AnythingOfType("*entity.User")).Return(true, nil).Once()
-		u := usecase.NewUserUsecase(mockUserRepo)
+		mockUserRepo.On("GetByID", mock.Anything, mockUser.ID).Return(&entity.User{ID: mockUser.ID, Email: `old@lmnlo.io`}, nil).Once()
+		mockUserRepo.On("Update", mock.Anything, mock.AnythingOfType("*entity.User")).Return(true, nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
 
-		err := u.Update(&mockUser)
+		err := u.Update(context.TODO(), &entity.User{ID: mockUser.ID, Email: mockUser.Email, Address: mockUser.Address}, mockOrigin)
 
 		assert.NoError(t, err)
 		mockUserRepo.AssertExpectations(t)
 	})
 
 	t.Run("error", func(t *testing.T) {
-		mockUserRepo.On("Update", mock.AnythingOfType("*entity.User")).Return(false, errors.New(`error`)).Once()
-		u := usecase.NewUserUsecase(mockUserRepo)
+		mockUserRepo.On("GetByID", mock.Anything, mockUser.ID).Return(&entity.User{ID: mockUser.ID, Email: `old@lmnlo.io`}, nil).Once()
+		mockUserRepo.On("Update", mock.Anything, mock.AnythingOfType("*entity.User")).Return(false, errors.New(`error`)).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
 
-		err := u.Update(&mockUser)
+		err := u.Update(context.TODO(), &entity.User{ID: mockUser.ID, Email: mockUser.Email, Address: mockUser.Address}, mockOrigin)
 
 		assert.Error(t, err)
 		mockUserRepo.AssertExpectations(t)
 	})
 
 	t.Run("no-data", func(t *testing.T) {
-		mockUserRepo.On("Update", mock.AnythingOfType("*entity.User")).Return(false, nil).Once()
-		u := usecase.NewUserUsecase(mockUserRepo)
+		mockUserRepo.On("GetByID", mock.Anything, mockUser.ID).Return(&entity.User{ID: mockUser.ID, Email: `old@lmnlo.io`}, nil).Once()
+		mockUserRepo.On("Update", mock.Anything, mock.AnythingOfType("*entity.User")).Return(false, nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
 
-		err := u.Update(&mockUser)
+		err := u.Update(context.TODO(), &entity.User{ID: mockUser.ID, Email: mockUser.Email, Address: mockUser.Address}, mockOrigin)
 
 		assert.Error(t, err)
 		assert.Equal(t, response.ErrNotFound, err)
 		mockUserRepo.AssertExpectations(t)
 	})
+
+	t.Run("not-found", func(t *testing.T) {
+		mockUserRepo.On("GetByID", mock.Anything, int64(99)).Return(new(entity.User), nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		err := u.Update(context.TODO(), &entity.User{ID: 99, Email: mockUser.Email}, mockOrigin)
+
+		assert.Equal(t, response.ErrNotFound, err)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("password", func(t *testing.T) {
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		err := u.Update(context.TODO(), &entity.User{ID: mockUser.ID, Email: mockUser.Email, Password: `secret`}, mockOrigin)
+
+		assert.Equal(t, response.ErrBadRequest, err)
+		mockUserRepo.AssertExpectations(t)
+	})
 }
 
 func TestGetByID(t *testing.T) {
 	mockUserRepo := new(mocks.Repository)
 
 	t.Run("success", func(t *testing.T) {
-		mockUserRepo.On("GetByID", mock.AnythingOfType("int64")).Return(&mockUser, nil).Once()
-		u := usecase.NewUserUsecase(mockUserRepo)
+		mockUserRepo.On("GetByID", mock.Anything, mock.AnythingOfType("int64")).Return(&mockUser, nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
 
-		res, err := u.GetByID(1)
+		res, err := u.GetByID(context.TODO(), 1)
 
 		assert.NoError(t, err)
 		assert.Equal(t, &mockUser, res)
@@ -154,10 +258,10 @@ func TestGetByID(t *testing.T) {
 	})
 
 	t.Run("success-no-data", func(t *testing.T) {
-		mockUserRepo.On("GetByID", mock.AnythingOfType("int64")).Return(new(entity.User), nil).Once()
-		u := usecase.NewUserUsecase(mockUserRepo)
+		mockUserRepo.On("GetByID", mock.Anything, mock.AnythingOfType("int64")).Return(new(entity.User), nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
 
-		res, err := u.GetByID(99)
+		res, err := u.GetByID(context.TODO(), 99)
 
 		assert.NoError(t, err)
 		assert.Equal(t, int64(0), res.ID)
@@ -165,48 +269,262 @@ func TestGetByID(t *testing.T) {
 	})
 
 	t.Run("error", func(t *testing.T) {
-		mockUserRepo.On("GetByID", mock.AnythingOfType("int64")).Return(nil, errors.New(`Error`)).Once()
-		u := usecase.NewUserUsecase(mockUserRepo)
+		mockUserRepo.On("GetByID", mock.Anything, mock.AnythingOfType("int64")).Return(nil, errors.New(`Error`)).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
 
-		res, err := u.GetByID(22)
+		res, err := u.GetByID(context.TODO(), 22)
 
 		assert.Error(t, err)
 		assert.Nil(t, res)
 		mockUserRepo.AssertExpectations(t)
 	})
+
+	t.Run("success-timeout", func(t *testing.T) {
+		mockUserRepo.On("GetByID", mock.MatchedBy(func(ctx context.Context) bool {
+			deadline, ok := ctx.Deadline()
+			return ok && time.Until(deadline) <= time.Second
+		}), int64(1)).Return(&mockUser, nil).Once()
+
+		opts := mockOptions
+		opts.ContextTimeout = time.Second
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, opts)
+
+		res, err := u.GetByID(context.TODO(), 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, &mockUser, res)
+		mockUserRepo.AssertExpectations(t)
+	})
 }
 
 func TestDelete(t *testing.T) {
 	mockUserRepo := new(mocks.Repository)
 
 	t.Run("success", func(t *testing.T) {
-		mockUserRepo.On("Delete", mock.AnythingOfType("int64")).Return(true, nil).Once()
-		u := usecase.NewUserUsecase(mockUserRepo)
+		mockUserRepo.On("Delete", mock.Anything, mockUser.ID).Return(true, nil).Once()
+		mockUserRepo.On("RevokeSessionsByUser", mock.Anything, mockUser.ID).Return(nil).Once()
+		mockUserRepo.On("RevokeRefreshTokensByUser", mock.Anything, mockUser.ID).Return(nil).Once()
+		mockUserRepo.On("DeleteAPIKeysByUser", mock.Anything, mockUser.ID).Return(nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
 
-		err := u.Delete(mockUser.ID)
+		err := u.Delete(context.TODO(), mockUser.ID, mockOrigin)
 
 		assert.NoError(t, err)
 		mockUserRepo.AssertExpectations(t)
 	})
 
+	t.Run("revoke-error", func(t *testing.T) {
+		mockUserRepo.On("Delete", mock.Anything, mockUser.ID).Return(true, nil).Once()
+		mockUserRepo.On("RevokeSessionsByUser", mock.Anything, mockUser.ID).Return(errors.New(`error`)).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		err := u.Delete(context.TODO(), mockUser.ID, mockOrigin)
+
+		assert.Error(t, err)
+		mockUserRepo.AssertExpectations(t)
+	})
+
 	t.Run("error", func(t *testing.T) {
-		mockUserRepo.On("Delete", mock.AnythingOfType("int64")).Return(false, errors.New(`error`)).Once()
-		u := usecase.NewUserUsecase(mockUserRepo)
+		mockUserRepo.On("Delete", mock.Anything, mock.AnythingOfType("int64")).Return(false, errors.New(`error`)).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
 
-		err := u.Delete(mockUser.ID)
+		err := u.Delete(context.TODO(), mockUser.ID, mockOrigin)
 
 		assert.Error(t, err)
 		mockUserRepo.AssertExpectations(t)
 	})
 
 	t.Run("no-data", func(t *testing.T) {
-		mockUserRepo.On("Delete", mock.AnythingOfType("int64")).Return(false, nil).Once()
-		u := usecase.NewUserUsecase(mockUserRepo)
+		mockUserRepo.On("Delete", mock.Anything, mock.AnythingOfType("int64")).Return(false, nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
 
-		err := u.Delete(mockUser.ID)
+		err := u.Delete(context.TODO(), mockUser.ID, mockOrigin)
 
 		assert.Error(t, err)
 		assert.Equal(t, response.ErrNotFound, err)
 		mockUserRepo.AssertExpectations(t)
 	})
 }
+
+func TestPartialUpdate(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("GetByID", mock.Anything, int64(1)).Return(&entity.User{ID: 1, Email: mockUser.Email, Address: `Menteng`}, nil).Once()
+		mockUserRepo.On("Update", mock.Anything, mock.MatchedBy(func(usr *entity.User) bool {
+			return usr.ID == 1 && usr.Address == `Kemang`
+		})).Return(true, nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		res, err := u.PartialUpdate(context.TODO(), 1, []byte(`[{"op":"replace","path":"/address","value":"Kemang"}]`), mockOrigin)
+
+		assert.NoError(t, err)
+		assert.Equal(t, `Kemang`, res.Address)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("not-found", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("GetByID", mock.Anything, int64(1)).Return(new(entity.User), nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		_, err := u.PartialUpdate(context.TODO(), 1, []byte(`[{"op":"replace","path":"/address","value":"Kemang"}]`), mockOrigin)
+
+		assert.Equal(t, response.ErrNotFound, err)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	readOnly := []string{
+		`[{"op":"replace","path":"/id","value":2},{"op":"replace","path":"/email","value":"pwned@x.test"}]`,
+		`[{"op":"add","path":"/roles/-","value":"admin"}]`,
+		`[{"op":"add","path":"/verified_at","value":"2020-01-01T00:00:00Z"}]`,
+		`[{"op":"remove","path":"/password"}]`,
+		`[{"op":"copy","from":"/password","path":"/address"}]`,
+	}
+	for _, body := range readOnly {
+		t.Run("read-only", func(t *testing.T) {
+			mockUserRepo := new(mocks.Repository)
+			mockUserRepo.On("GetByID", mock.Anything, int64(1)).Return(&entity.User{ID: 1, Email: mockUser.Email}, nil).Once()
+			u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+			res, err := u.PartialUpdate(context.TODO(), 1, []byte(body), mockOrigin)
+
+			assert.Equal(t, response.ErrBadRequest, err, body)
+			assert.Nil(t, res)
+			mockUserRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestLogin(t *testing.T) {
+	mockUserRepo := new(mocks.Repository)
+
+	hashedPass, _ := mockHasher.Hash(`aiueo`)
+	legacyPass, _ := helper.EncryptToString(`aiueo`)
+
+	t.Run("success", func(t *testing.T) {
+		existingUser := &entity.User{ID: 1, Email: mockUser.Email, Password: hashedPass}
+		mockUserRepo.On("GetByEmail", mock.Anything, mock.AnythingOfType("string")).Return(existingUser, nil).Once()
+		mockUserRepo.On("GetMFA", mock.Anything, int64(1)).Return(new(entity.MFA), nil).Once()
+		var inserted *entity.Session
+		mockUserRepo.On("InsertToken", mock.Anything, mock.AnythingOfType("*entity.Session")).Run(func(args mock.Arguments) {
+			inserted = args.Get(1).(*entity.Session)
+		}).Return(nil).Once()
+		mockUserRepo.On("GetRoles", mock.Anything, int64(1)).Return([]string{entity.RoleUser}, nil).Once()
+		mockUserRepo.On("GetPermissions", mock.Anything, int64(1)).Return([]string{}, nil).Once()
+		mockUserRepo.On("StoreRefreshToken", mock.Anything, mock.AnythingOfType("*entity.RefreshToken")).Return(nil).Once()
+		mockUserRepo.On("Update", mock.Anything, mock.AnythingOfType("*entity.User")).Return(true, nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		res, err := u.Login(context.TODO(), &entity.User{Email: mockUser.Email, Password: `aiueo`}, new(entity.Session), mockOrigin)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), res.ID)
+		assert.Empty(t, res.Password)
+		assert.NotEmpty(t, res.Token)
+		// Only the digest of the token is stored
+		assert.NotEmpty(t, inserted.TokenID)
+		assert.Equal(t, helper.HashToken(res.Token), inserted.TokenHash)
+		assert.True(t, inserted.ExpiresAt.After(time.Now().Add(mockOptions.AccessTokenTTL)))
+		assert.NotEmpty(t, res.RefreshToken)
+		assert.Equal(t, int64(900), res.ExpiresIn)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("success-rehash-legacy", func(t *testing.T) {
+		existingUser := &entity.User{ID: 1, Email: mockUser.Email, Password: legacyPass}
+		mockUserRepo.On("GetByEmail", mock.Anything, mock.AnythingOfType("string")).Return(existingUser, nil).Once()
+		mockUserRepo.On("UpdatePassword", mock.Anything, int64(1), mock.MatchedBy(func(hashed string) bool {
+			return !mockHasher.NeedsRehash(hashed) && mockHasher.Verify(hashed, `aiueo`)
+		})).Return(true, nil).Once()
+		mockUserRepo.On("GetMFA", mock.Anything, int64(1)).Return(new(entity.MFA), nil).Once()
+		mockUserRepo.On("InsertToken", mock.Anything, mock.AnythingOfType("*entity.Session")).Return(nil).Once()
+		mockUserRepo.On("GetRoles", mock.Anything, int64(1)).Return([]string{entity.RoleUser}, nil).Once()
+		mockUserRepo.On("GetPermissions", mock.Anything, int64(1)).Return([]string{}, nil).Once()
+		mockUserRepo.On("StoreRefreshToken", mock.Anything, mock.AnythingOfType("*entity.RefreshToken")).Return(nil).Once()
+		mockUserRepo.On("Update", mock.Anything, mock.AnythingOfType("*entity.User")).Return(true, nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		res, err := u.Login(context.TODO(), &entity.User{Email: mockUser.Email, Password: `aiueo`}, new(entity.Session), mockOrigin)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), res.ID)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("wrong-password", func(t *testing.T) {
+		existingUser := &entity.User{ID: 1, Email: mockUser.Email, Password: hashedPass}
+		mockUserRepo.On("GetByEmail", mock.Anything, mock.AnythingOfType("string")).Return(existingUser, nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		res, err := u.Login(context.TODO(), &entity.User{Email: mockUser.Email, Password: `wrong`}, new(entity.Session), mockOrigin)
+
+		assert.Equal(t, response.ErrLogin, err)
+		assert.Nil(t, res)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("unverified", func(t *testing.T) {
+		existingUser := &entity.User{ID: 1, Email: mockUser.Email, Password: hashedPass}
+		mockUserRepo.On("GetByEmail", mock.Anything, mock.AnythingOfType("string")).Return(existingUser, nil).Once()
+		opts := mockOptions
+		opts.RequireVerifiedEmail = true
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, opts)
+
+		res, err := u.Login(context.TODO(), &entity.User{Email: mockUser.Email, Password: `aiueo`}, new(entity.Session), mockOrigin)
+
+		assert.Equal(t, response.ErrUnverified, err)
+		assert.Nil(t, res)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("not-found", func(t *testing.T) {
+		mockUserRepo.On("GetByEmail", mock.Anything, mock.AnythingOfType("string")).Return(new(entity.User), nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		res, err := u.Login(context.TODO(), &entity.User{Email: `nobody@lmnlo.io`, Password: `aiueo`}, new(entity.Session), mockOrigin)
+
+		assert.Equal(t, response.ErrLogin, err)
+		assert.Nil(t, res)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		mockUserRepo.On("GetByEmail", mock.Anything, mock.AnythingOfType("string")).Return(nil, errors.New(`Error`)).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		res, err := u.Login(context.TODO(), &entity.User{Email: mockUser.Email, Password: `aiueo`}, new(entity.Session), mockOrigin)
+
+		assert.Error(t, err)
+		assert.Nil(t, res)
+		mockUserRepo.AssertExpectations(t)
+	})
+}
+
+func TestLoginLockout(t *testing.T) {
+	hashedPass, _ := mockHasher.Hash(`aiueo`)
+	existingUser := &entity.User{ID: 1, Email: mockUser.Email, Password: hashedPass}
+
+	mockUserRepo := new(mocks.Repository)
+	mockUserRepo.On("GetByEmail", mock.Anything, mockUser.Email).Return(existingUser, nil).Twice()
+	lockout := lockoutUsecase.NewLockoutUsecase(lockoutMemory.NewLockoutRepository(), lockoutUsecase.Options{
+		AccountThreshold: 2,
+		Window:           time.Hour,
+		LockDuration:     time.Hour,
+	})
+	u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, lockout, mockAudit, mockOptions)
+
+	for i := 0; i < 2; i++ {
+		_, err := u.Login(context.TODO(), &entity.User{Email: mockUser.Email, Password: `wrong`}, &entity.Session{IP: `1.2.3.4`}, mockOrigin)
+		assert.Equal(t, response.ErrLogin, err)
+	}
+
+	// The right password is refused without reaching the repository
+	res, err := u.Login(context.TODO(), &entity.User{Email: mockUser.Email, Password: `aiueo`}, &entity.Session{IP: `1.2.3.4`}, mockOrigin)
+
+	locked, ok := err.(*response.LockedError)
+	if assert.True(t, ok) {
+		assert.InDelta(t, float64(time.Hour), float64(locked.RetryAfter), float64(time.Second))
+	}
+	assert.Nil(t, res)
+	mockUserRepo.AssertExpectations(t)
+}
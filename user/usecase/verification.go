@@ -0,0 +1,179 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+
+	"github.com/andhikagama/lmnlo/helper"
+	"github.com/andhikagama/lmnlo/mailer"
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/andhikagama/lmnlo/models/response"
+)
+
+const _TokenIDBytes = 16
+
+const _VerifyEmailBody = `Welcome to lmnlo!
+
+Please confirm your email address by opening the link below:
+
+%s
+
+If you did not create an account you can ignore this email.
+`
+
+// VerifyEmail ...
+func (u *userUsecase) VerifyEmail(ctx context.Context, token string) error {
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+
+	uid, email, err := u.parsePurposeToken(token, entity.TokenPurposeVerifyEmail)
+	if err != nil {
+		return err
+	}
+
+	// The token only verifies the address it was mailed to, which the user
+	// may have changed since
+	usr, err := u.userRepo.GetByID(ctx, uid)
+	if err != nil {
+		return err
+	}
+
+	if usr.ID == 0 || usr.Email != email {
+		return response.ErrInvalidToken
+	}
+
+	if err := u.spendPurposeToken(ctx, uid, token, entity.TokenPurposeVerifyEmail); err != nil {
+		return err
+	}
+
+	// Already verified accounts are not an error, the token is spent anyway
+	_, err = u.userRepo.SetVerified(ctx, uid, email)
+	u.users.forget(uid)
+	return err
+}
+
+// ResendVerification ...
+func (u *userUsecase) ResendVerification(ctx context.Context, email string) error {
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+
+	usr, err := u.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+
+	// Unknown and verified addresses get the same answer as pending ones so
+	// the endpoint cannot be used to probe for accounts
+	if usr.ID == 0 || usr.VerifiedAt != nil {
+		return nil
+	}
+
+	if err := u.userRepo.InvalidateUserTokens(ctx, usr.ID, entity.TokenPurposeVerifyEmail); err != nil {
+		return err
+	}
+
+	return u.sendVerification(ctx, usr)
+}
+
+func (u *userUsecase) sendVerification(ctx context.Context, usr *entity.User) error {
+	token, err := u.issuePurposeToken(ctx, usr, entity.TokenPurposeVerifyEmail, u.opts.VerifyEmailTTL)
+	if err != nil {
+		return err
+	}
+
+	return u.mailer.Send(&mailer.Message{
+		To:      usr.Email,
+		Subject: `Verify your email address`,
+		Body:    fmt.Sprintf(_VerifyEmailBody, u.opts.VerifyEmailURL+token),
+	})
+}
+
+// issuePurposeToken return a signed single-use token for usr and its
+// current email whose digest is stored so it can be spent only once
+func (u *userUsecase) issuePurposeToken(ctx context.Context, usr *entity.User, purpose string, ttl time.Duration) (string, error) {
+	jti, err := helper.GenerateRandomHex(_TokenIDBytes)
+	if err != nil {
+		return ``, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+
+	token, err := u.keyRing.Sign(&entity.PurposeClaims{
+		Purpose: purpose,
+		Email:   usr.Email,
+		StandardClaims: jwt.StandardClaims{
+			Id:        jti,
+			Subject:   strconv.FormatInt(usr.ID, 10),
+			IssuedAt:  now.Unix(),
+			ExpiresAt: expiresAt.Unix(),
+		},
+	})
+	if err != nil {
+		return ``, err
+	}
+
+	err = u.userRepo.StoreUserToken(ctx, &entity.UserToken{
+		UserID:    usr.ID,
+		Purpose:   purpose,
+		TokenHash: helper.HashToken(token),
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return ``, err
+	}
+
+	return token, nil
+}
+
+// usePurposeToken verifies token was issued for purpose and spends it,
+// returning the user it was issued to and the email they had then
+func (u *userUsecase) usePurposeToken(ctx context.Context, token string, purpose string) (int64, string, error) {
+	uid, email, err := u.parsePurposeToken(token, purpose)
+	if err != nil {
+		return 0, ``, err
+	}
+
+	if err := u.spendPurposeToken(ctx, uid, token, purpose); err != nil {
+		return 0, ``, err
+	}
+
+	return uid, email, nil
+}
+
+// parsePurposeToken verifies token was issued for purpose without spending
+// it, returning the user it was issued to and the email they had then
+func (u *userUsecase) parsePurposeToken(token string, purpose string) (int64, string, error) {
+	claims := new(entity.PurposeClaims)
+	if _, err := u.keyRing.Parse(token, claims); err != nil {
+		return 0, ``, response.ErrInvalidToken
+	}
+
+	if claims.Purpose != purpose {
+		return 0, ``, response.ErrInvalidToken
+	}
+
+	uid, err := strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil {
+		return 0, ``, response.ErrInvalidToken
+	}
+
+	return uid, claims.Email, nil
+}
+
+func (u *userUsecase) spendPurposeToken(ctx context.Context, uid int64, token string, purpose string) error {
+	ok, err := u.userRepo.UseUserToken(ctx, uid, purpose, helper.HashToken(token))
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return response.ErrInvalidToken
+	}
+
+	return nil
+}
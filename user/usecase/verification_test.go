@@ -0,0 +1,180 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/andhikagama/lmnlo/helper"
+	"github.com/andhikagama/lmnlo/mailer"
+	mailerMocks "github.com/andhikagama/lmnlo/mailer/mocks"
+	"github.com/andhikagama/lmnlo/models/entity"
+	"github.com/andhikagama/lmnlo/models/response"
+	"github.com/andhikagama/lmnlo/user/mocks"
+	"github.com/andhikagama/lmnlo/user/usecase"
+)
+
+// mailedToken registers the user through the usecase and return the
+// verification token it mailed
+func mailedToken(t *testing.T, mockUserRepo *mocks.Repository, opts usecase.Options) string {
+	token := ``
+	mockMailer := new(mailerMocks.Mailer)
+	mockMailer.On("Send", mock.AnythingOfType("*mailer.Message")).Return(nil).Run(func(args mock.Arguments) {
+		msg := args.Get(0).(*mailer.Message)
+		body := msg.Body[strings.Index(msg.Body, opts.VerifyEmailURL)+len(opts.VerifyEmailURL):]
+		token = strings.Fields(body)[0]
+	}).Once()
+
+	mockUserRepo.On("Fetch", mock.Anything, mock.AnythingOfType("*filter.User")).Return(make([]*entity.User, 0), nil).Once()
+	mockUserRepo.On("Store", mock.Anything, mock.AnythingOfType("*entity.User")).Return(nil).Run(func(args mock.Arguments) {
+		args.Get(1).(*entity.User).ID = 1
+	}).Once()
+	mockUserRepo.On("StoreUserToken", mock.Anything, mock.AnythingOfType("*entity.UserToken")).Return(nil).Once()
+
+	u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, opts)
+	err := u.Register(context.TODO(), &entity.User{Email: mockUser.Email, Password: `aiueo`}, mockOrigin)
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, token)
+	return token
+}
+
+func TestVerifyEmail(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		token := mailedToken(t, mockUserRepo, mockOptions)
+
+		mockUserRepo.On("GetByID", mock.Anything, int64(1)).Return(&entity.User{ID: 1, Email: mockUser.Email}, nil).Once()
+		mockUserRepo.On("UseUserToken", mock.Anything, int64(1), entity.TokenPurposeVerifyEmail, helper.HashToken(token)).Return(true, nil).Once()
+		mockUserRepo.On("SetVerified", mock.Anything, int64(1), mockUser.Email).Return(true, nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		err := u.VerifyEmail(context.TODO(), token)
+
+		assert.NoError(t, err)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("used", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		token := mailedToken(t, mockUserRepo, mockOptions)
+
+		mockUserRepo.On("GetByID", mock.Anything, int64(1)).Return(&entity.User{ID: 1, Email: mockUser.Email}, nil).Once()
+		mockUserRepo.On("UseUserToken", mock.Anything, int64(1), entity.TokenPurposeVerifyEmail, helper.HashToken(token)).Return(false, nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		err := u.VerifyEmail(context.TODO(), token)
+
+		assert.Equal(t, response.ErrInvalidToken, err)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("changed-email", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		token := mailedToken(t, mockUserRepo, mockOptions)
+
+		mockUserRepo.On("GetByID", mock.Anything, int64(1)).Return(&entity.User{ID: 1, Email: `other@lmnlo.test`}, nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		err := u.VerifyEmail(context.TODO(), token)
+
+		assert.Equal(t, response.ErrInvalidToken, err)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("wrong-purpose", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		token, _ := mockKeyRing.Sign(&entity.Claims{StandardClaims: jwt.StandardClaims{Subject: `1`, ExpiresAt: time.Now().Add(time.Hour).Unix()}})
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		err := u.VerifyEmail(context.TODO(), token)
+
+		assert.Equal(t, response.ErrInvalidToken, err)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		opts := mockOptions
+		opts.VerifyEmailTTL = -time.Minute
+		token := mailedToken(t, mockUserRepo, opts)
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, opts)
+
+		err := u.VerifyEmail(context.TODO(), token)
+
+		assert.Equal(t, response.ErrInvalidToken, err)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("garbage", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		err := u.VerifyEmail(context.TODO(), `garbage`)
+
+		assert.Equal(t, response.ErrInvalidToken, err)
+		mockUserRepo.AssertExpectations(t)
+	})
+}
+
+func TestResendVerification(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		mockMailer := new(mailerMocks.Mailer)
+		mockUserRepo.On("GetByEmail", mock.Anything, mockUser.Email).Return(&entity.User{ID: 1, Email: mockUser.Email}, nil).Once()
+		mockUserRepo.On("InvalidateUserTokens", mock.Anything, int64(1), entity.TokenPurposeVerifyEmail).Return(nil).Once()
+		mockUserRepo.On("StoreUserToken", mock.Anything, mock.AnythingOfType("*entity.UserToken")).Return(nil).Once()
+		mockMailer.On("Send", mock.AnythingOfType("*mailer.Message")).Return(nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		err := u.ResendVerification(context.TODO(), mockUser.Email)
+
+		assert.NoError(t, err)
+		mockUserRepo.AssertExpectations(t)
+		mockMailer.AssertExpectations(t)
+	})
+
+	t.Run("already-verified", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		mockMailer := new(mailerMocks.Mailer)
+		now := time.Now()
+		mockUserRepo.On("GetByEmail", mock.Anything, mockUser.Email).Return(&entity.User{ID: 1, VerifiedAt: &now}, nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		err := u.ResendVerification(context.TODO(), mockUser.Email)
+
+		assert.NoError(t, err)
+		mockUserRepo.AssertExpectations(t)
+		mockMailer.AssertExpectations(t)
+	})
+
+	t.Run("unknown-email", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		mockMailer := new(mailerMocks.Mailer)
+		mockUserRepo.On("GetByEmail", mock.Anything, `nobody@lmnlo.io`).Return(new(entity.User), nil).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		err := u.ResendVerification(context.TODO(), `nobody@lmnlo.io`)
+
+		assert.NoError(t, err)
+		mockUserRepo.AssertExpectations(t)
+		mockMailer.AssertExpectations(t)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		mockUserRepo := new(mocks.Repository)
+		mockUserRepo.On("GetByEmail", mock.Anything, mockUser.Email).Return(nil, errors.New(`Error`)).Once()
+		u := usecase.NewUserUsecase(mockUserRepo, mockHasher, mockKeyRing, mockMailer, mockLockout, mockAudit, mockOptions)
+
+		err := u.ResendVerification(context.TODO(), mockUser.Email)
+
+		assert.Error(t, err)
+		mockUserRepo.AssertExpectations(t)
+	})
+}
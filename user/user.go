@@ -1,28 +1,99 @@
 package user
 
 import (
+	"context"
+	"time"
+
 	"github.com/andhikagama/lmnlo/models/entity"
 	"github.com/andhikagama/lmnlo/models/filter"
 )
 
 // Repository represents database manipulation
 type Repository interface {
-	Store(usr *entity.User) error
-	Fetch(f *filter.User) ([]*entity.User, error)
-	Update(usr *entity.User) (bool, error)
-	GetByID(id int64) (*entity.User, error)
-	Delete(id int64) (bool, error)
-	InsertToken(uid int64, token string) error
-	ValidateToken(token string) (bool, error)
+	Store(ctx context.Context, usr *entity.User) error
+	Fetch(ctx context.Context, f *filter.User) ([]*entity.User, error)
+	Update(ctx context.Context, usr *entity.User) (bool, error)
+	GetByID(ctx context.Context, id int64) (*entity.User, error)
+	GetByEmail(ctx context.Context, email string) (*entity.User, error)
+	GetPassword(ctx context.Context, id int64) (string, error)
+	UpdatePassword(ctx context.Context, id int64, password string) (bool, error)
+	Delete(ctx context.Context, id int64) (bool, error)
+	GetRoles(ctx context.Context, uid int64) ([]string, error)
+	GetPermissions(ctx context.Context, uid int64) ([]string, error)
+	SetRoles(ctx context.Context, uid int64, roles []string) error
+	ExistRoles(ctx context.Context, roles []string) (bool, error)
+	InsertToken(ctx context.Context, sess *entity.Session) error
+	ValidateToken(ctx context.Context, jti string, tokenHash string) (bool, error)
+	ReplaceToken(ctx context.Context, sess *entity.Session) (bool, error)
+	TouchToken(ctx context.Context, jti string) error
+	GetSessionByToken(ctx context.Context, tokenHash string) (*entity.Session, error)
+	FetchSessions(ctx context.Context, uid int64) ([]*entity.Session, error)
+	RevokeSession(ctx context.Context, id int64) (bool, error)
+	RevokeSessionsByUser(ctx context.Context, uid int64) error
+	RevokeSessionFamily(ctx context.Context, familyID string) error
+	RevokeOtherSessions(ctx context.Context, uid int64, keepID int64) error
+	PurgeTokens(ctx context.Context, before time.Time) (int64, error)
+	StoreRefreshToken(ctx context.Context, rt *entity.RefreshToken) error
+	GetRefreshToken(ctx context.Context, tokenHash string) (*entity.RefreshToken, error)
+	RotateRefreshToken(ctx context.Context, id int64, next *entity.RefreshToken) (bool, error)
+	RevokeRefreshTokenFamily(ctx context.Context, familyID string) error
+	RevokeRefreshTokensByUser(ctx context.Context, uid int64) error
+	RevokeOtherRefreshTokens(ctx context.Context, uid int64, keepFamilyID string) error
+	StoreUserToken(ctx context.Context, ut *entity.UserToken) error
+	UseUserToken(ctx context.Context, uid int64, purpose string, tokenHash string) (bool, error)
+	InvalidateUserTokens(ctx context.Context, uid int64, purpose string) error
+	CountUserTokens(ctx context.Context, uid int64, purpose string, since time.Time) (int64, error)
+	SetVerified(ctx context.Context, uid int64, email string) (bool, error)
+	GetMFA(ctx context.Context, uid int64) (*entity.MFA, error)
+	StoreMFA(ctx context.Context, mfa *entity.MFA) error
+	ConfirmMFA(ctx context.Context, uid int64) (bool, error)
+	UseMFAStep(ctx context.Context, uid int64, step int64) (bool, error)
+	DeleteMFA(ctx context.Context, uid int64) error
+	SetRecoveryCodes(ctx context.Context, uid int64, codeHashes []string) error
+	UseRecoveryCode(ctx context.Context, uid int64, codeHash string) (bool, error)
+	StoreAPIKey(ctx context.Context, key *entity.APIKey) error
+	GetAPIKeyByPrefix(ctx context.Context, prefix string) (*entity.APIKey, error)
+	FetchAPIKeys(ctx context.Context, uid int64) ([]*entity.APIKey, error)
+	DeleteAPIKey(ctx context.Context, uid int64, id int64) (bool, error)
+	DeleteAPIKeysByUser(ctx context.Context, uid int64) error
+	TouchAPIKey(ctx context.Context, id int64) error
+	GetIdentity(ctx context.Context, provider string, subject string) (*entity.Identity, error)
+	StoreIdentity(ctx context.Context, id *entity.Identity) error
+	StoreOAuthState(ctx context.Context, st *entity.OAuthState) error
+	UseOAuthState(ctx context.Context, stateHash string) (*entity.OAuthState, error)
 }
 
 // Usecase represents business logic
 type Usecase interface {
-	Register(usr *entity.User) error
-	Fetch(f *filter.User) ([]*entity.User, error)
-	Update(usr *entity.User) error
-	GetByID(id int64) (*entity.User, error)
-	Delete(id int64) error
-	PartialUpdate(id int64, byteFacility []byte) (*entity.User, error)
-	Login(u *entity.User) (*entity.User, error)
+	Register(ctx context.Context, usr *entity.User, o *entity.Origin) error
+	Fetch(ctx context.Context, f *filter.User) ([]*entity.User, error)
+	Update(ctx context.Context, usr *entity.User, o *entity.Origin) error
+	GetByID(ctx context.Context, id int64) (*entity.User, error)
+	Delete(ctx context.Context, id int64, o *entity.Origin) error
+	PartialUpdate(ctx context.Context, id int64, byteFacility []byte, o *entity.Origin) (*entity.User, error)
+	GetRoles(ctx context.Context, id int64) ([]string, error)
+	AssignRoles(ctx context.Context, id int64, roles []string, o *entity.Origin) error
+	Login(ctx context.Context, u *entity.User, sess *entity.Session, o *entity.Origin) (*entity.User, error)
+	Refresh(ctx context.Context, refreshToken string, o *entity.Origin) (*entity.User, error)
+	AuthenticateToken(ctx context.Context, token string) (*entity.User, error)
+	SweepSessions(ctx context.Context) (int64, error)
+	Logout(ctx context.Context, token string, o *entity.Origin) error
+	FetchSessions(ctx context.Context, uid int64, token string) ([]*entity.Session, error)
+	RevokeSessions(ctx context.Context, uid int64) error
+	VerifyEmail(ctx context.Context, token string) error
+	ResendVerification(ctx context.Context, email string) error
+	ForgotPassword(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token string, password string, o *entity.Origin) error
+	ChangePassword(ctx context.Context, uid int64, token string, current string, password string, o *entity.Origin) error
+	LoginMFA(ctx context.Context, challenge string, code string, sess *entity.Session, o *entity.Origin) (*entity.User, error)
+	EnrollMFA(ctx context.Context, uid int64) (*entity.MFAEnrollment, error)
+	ConfirmMFA(ctx context.Context, uid int64, code string, o *entity.Origin) ([]string, error)
+	DisableMFA(ctx context.Context, uid int64, code string, o *entity.Origin) error
+	CreateAPIKey(ctx context.Context, usr *entity.User, key *entity.APIKey, o *entity.Origin) error
+	FetchAPIKeys(ctx context.Context, uid int64) ([]*entity.APIKey, error)
+	RevokeAPIKey(ctx context.Context, uid int64, id int64, o *entity.Origin) error
+	AuthenticateAPIKey(ctx context.Context, key string) (*entity.User, error)
+	OAuthURL(ctx context.Context, provider string) (string, string, error)
+	LoginOAuth(ctx context.Context, provider string, state string, code string, sess *entity.Session, o *entity.Origin) (*entity.User, error)
+	Impersonate(ctx context.Context, actor *entity.User, uid int64, sess *entity.Session, o *entity.Origin) (*entity.User, error)
 }